@@ -0,0 +1,351 @@
+// Package backup models the metadata of iOS device backups made by iTunes and Finder: the
+// Info.plist, Manifest.plist and Status.plist files at the top of a backup directory, and the
+// keyed archives that describe each backed-up file in Manifest.db.
+//
+// Keys not modeled by Info, Manifest or Status are preserved in their Extra maps, so documents
+// round-trip through Unmarshal and Marshal without losing information. Dates are held by
+// pointer, so that a document without one does not gain one when it is written back.
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"howett.net/plist"
+	"howett.net/plist/internal/extrakeys"
+	"howett.net/plist/keyedarchive"
+)
+
+// Info represents a backup's Info.plist, which describes the device that was backed up.
+type Info struct {
+	BuildVersion          string                 `plist:"Build Version,omitempty"`
+	DeviceName            string                 `plist:"Device Name,omitempty"`
+	DisplayName           string                 `plist:"Display Name,omitempty"`
+	GUID                  string                 `plist:"GUID,omitempty"`
+	ICCID                 string                 `plist:"ICCID,omitempty"`
+	IMEI                  string                 `plist:"IMEI,omitempty"`
+	MEID                  string                 `plist:"MEID,omitempty"`
+	PhoneNumber           string                 `plist:"Phone Number,omitempty"`
+	ProductName           string                 `plist:"Product Name,omitempty"`
+	ProductType           string                 `plist:"Product Type,omitempty"`
+	ProductVersion        string                 `plist:"Product Version,omitempty"`
+	SerialNumber          string                 `plist:"Serial Number,omitempty"`
+	TargetIdentifier      string                 `plist:"Target Identifier,omitempty"`
+	TargetType            string                 `plist:"Target Type,omitempty"`
+	UniqueIdentifier      string                 `plist:"Unique Identifier,omitempty"`
+	ITunesVersion         string                 `plist:"iTunes Version,omitempty"`
+	LastBackupDate        *time.Time             `plist:"Last Backup Date,omitempty"`
+	InstalledApplications []string               `plist:"Installed Applications,omitempty"`
+	Applications          map[string]AppInfo     `plist:"Applications,omitempty"`
+	ITunesFiles           map[string][]byte      `plist:"iTunes Files,omitempty"`
+	ITunesSettings        map[string]interface{} `plist:"iTunes Settings,omitempty"`
+
+	// Extra holds every key that is not represented by one of the fields above.
+	Extra map[string]interface{} `plist:"-"`
+}
+
+// AppInfo describes an installed application (an entry in Info.plist's Applications.)
+type AppInfo struct {
+	ApplicationSINF []byte `plist:"ApplicationSINF,omitempty"`
+	ITunesMetadata  []byte `plist:"iTunesMetadata,omitempty"`
+	PlaceholderIcon []byte `plist:"PlaceholderIcon,omitempty"`
+}
+
+// Metadata decodes the application's iTunesMetadata, which is itself a property list.
+func (a *AppInfo) Metadata() (map[string]interface{}, error) {
+	if len(a.ITunesMetadata) == 0 {
+		return nil, nil
+	}
+	var metadata map[string]interface{}
+	if _, err := plist.Unmarshal(a.ITunesMetadata, &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// Manifest represents a backup's Manifest.plist, which describes the backup's contents and
+// how it is protected.
+type Manifest struct {
+	Version              string                 `plist:"Version,omitempty"`
+	Date                 *time.Time             `plist:"Date,omitempty"`
+	SystemDomainsVersion string                 `plist:"SystemDomainsVersion,omitempty"`
+	IsEncrypted          bool                   `plist:"IsEncrypted"`
+	WasPasscodeSet       bool                   `plist:"WasPasscodeSet"`
+	BackupKeyBag         []byte                 `plist:"BackupKeyBag,omitempty"`
+	ManifestKey          []byte                 `plist:"ManifestKey,omitempty"`
+	Lockdown             *Lockdown              `plist:"Lockdown,omitempty"`
+	Applications         map[string]ManifestApp `plist:"Applications,omitempty"`
+
+	// Extra holds every key that is not represented by one of the fields above.
+	Extra map[string]interface{} `plist:"-"`
+}
+
+// Lockdown holds the device properties recorded in Manifest.plist.
+type Lockdown struct {
+	DeviceName     string `plist:"DeviceName,omitempty"`
+	ProductType    string `plist:"ProductType,omitempty"`
+	ProductVersion string `plist:"ProductVersion,omitempty"`
+	BuildVersion   string `plist:"BuildVersion,omitempty"`
+	UniqueDeviceID string `plist:"UniqueDeviceID,omitempty"`
+	SerialNumber   string `plist:"SerialNumber,omitempty"`
+}
+
+// ManifestApp describes an application whose data is in the backup (an entry in
+// Manifest.plist's Applications.)
+type ManifestApp struct {
+	BundleIdentifier      string `plist:"CFBundleIdentifier,omitempty"`
+	BundleVersion         string `plist:"CFBundleVersion,omitempty"`
+	ContainerContentClass string `plist:"ContainerContentClass,omitempty"`
+	Path                  string `plist:"Path,omitempty"`
+}
+
+// Status represents a backup's Status.plist, which records whether the backup completed.
+type Status struct {
+	Version       string     `plist:"Version,omitempty"`
+	UUID          string     `plist:"UUID,omitempty"`
+	Date          *time.Time `plist:"Date,omitempty"`
+	BackupState   string     `plist:"BackupState,omitempty"`
+	SnapshotState string     `plist:"SnapshotState,omitempty"`
+	IsFullBackup  bool       `plist:"IsFullBackup"`
+
+	// Extra holds every key that is not represented by one of the fields above.
+	Extra map[string]interface{} `plist:"-"`
+}
+
+// Finished reports whether the backup's snapshot completed.
+func (s *Status) Finished() bool {
+	return s.SnapshotState == "finished"
+}
+
+// infoFields, manifestFields and statusFields have the same fields as Info, Manifest and
+// Status, but none of their methods.
+type (
+	infoFields     Info
+	manifestFields Manifest
+	statusFields   Status
+)
+
+// UnmarshalPlist implements plist.Unmarshaler.
+func (i *Info) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	if err := unmarshal((*infoFields)(i)); err != nil {
+		return err
+	}
+
+	var all map[string]interface{}
+	if err := unmarshal(&all); err != nil {
+		return err
+	}
+	i.Extra = extrakeys.Strip((*infoFields)(i), all)
+	return nil
+}
+
+// MarshalPlist implements plist.Marshaler.
+func (i Info) MarshalPlist() (interface{}, error) {
+	return extrakeys.Merge((*infoFields)(&i), i.Extra), nil
+}
+
+// UnmarshalPlist implements plist.Unmarshaler.
+func (m *Manifest) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	if err := unmarshal((*manifestFields)(m)); err != nil {
+		return err
+	}
+
+	var all map[string]interface{}
+	if err := unmarshal(&all); err != nil {
+		return err
+	}
+	m.Extra = extrakeys.Strip((*manifestFields)(m), all)
+	return nil
+}
+
+// MarshalPlist implements plist.Marshaler.
+func (m Manifest) MarshalPlist() (interface{}, error) {
+	return extrakeys.Merge((*manifestFields)(&m), m.Extra), nil
+}
+
+// UnmarshalPlist implements plist.Unmarshaler.
+func (s *Status) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	if err := unmarshal((*statusFields)(s)); err != nil {
+		return err
+	}
+
+	var all map[string]interface{}
+	if err := unmarshal(&all); err != nil {
+		return err
+	}
+	s.Extra = extrakeys.Strip((*statusFields)(s), all)
+	return nil
+}
+
+// MarshalPlist implements plist.Marshaler.
+func (s Status) MarshalPlist() (interface{}, error) {
+	return extrakeys.Merge((*statusFields)(&s), s.Extra), nil
+}
+
+// ParseInfo decodes an Info.plist document in any property list format.
+func ParseInfo(data []byte) (*Info, error) {
+	info := &Info{}
+	if _, err := plist.Unmarshal(data, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ParseManifest decodes a Manifest.plist document in any property list format.
+func ParseManifest(data []byte) (*Manifest, error) {
+	manifest := &Manifest{}
+	if _, err := plist.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// ParseStatus decodes a Status.plist document in any property list format.
+func ParseStatus(data []byte) (*Status, error) {
+	status := &Status{}
+	if _, err := plist.Unmarshal(data, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// File describes a backed-up file, as archived (in an MBFile object) in the file column of
+// Manifest.db. Times are in UTC.
+type File struct {
+	RelativePath     string
+	Target           string // the destination of a symbolic link
+	Size             uint64
+	Mode             uint32
+	UserID           uint32
+	GroupID          uint32
+	InodeNumber      uint64
+	Flags            uint64
+	ProtectionClass  int
+	Birth            time.Time
+	LastModified     time.Time
+	LastStatusChange time.Time
+
+	// EncryptionKey holds the file's wrapped encryption key in an encrypted backup.
+	EncryptionKey []byte
+
+	// ExtendedAttributes holds the file's extended attributes, keyed by name.
+	ExtendedAttributes map[string][]byte
+}
+
+// fileClass is the archived class of the objects decoded by DecodeFile.
+const fileClass = "MBFile"
+
+// fileUnarchiver decodes MBFile archives, allowing only the classes they contain.
+var fileUnarchiver = func() *keyedarchive.Unarchiver {
+	u := new(keyedarchive.Unarchiver)
+	u.AllowClasses(fileClass, "NSData", "NSMutableData", "NSString", "NSMutableString",
+		"NSDictionary", "NSMutableDictionary")
+	u.RegisterClass(fileClass, decodeFile)
+	return u
+}()
+
+// DecodeFile decodes the keyed archive stored for a file in Manifest.db.
+func DecodeFile(data []byte) (*File, error) {
+	root, err := fileUnarchiver.Unarchive(data)
+	if err != nil {
+		return nil, err
+	}
+
+	file, ok := root.(*File)
+	if !ok {
+		return nil, fmt.Errorf("backup: archive holds a %T, not an %s", root, fileClass)
+	}
+	return file, nil
+}
+
+func intField(obj *keyedarchive.Object, key string) (uint64, error) {
+	switch n := obj.Fields[key].(type) {
+	case nil:
+		return 0, nil
+	case uint64:
+		return n, nil
+	case int64:
+		return uint64(n), nil
+	}
+	return 0, fmt.Errorf("%s is not an integer", key)
+}
+
+func timeField(obj *keyedarchive.Object, key string) (time.Time, error) {
+	secs, err := intField(obj, key)
+	if err != nil || secs == 0 {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(secs), 0).In(time.UTC), nil
+}
+
+func decodeFile(obj *keyedarchive.Object) (interface{}, error) {
+	f := &File{}
+
+	var ok bool
+	if f.RelativePath, ok = obj.Fields["RelativePath"].(string); !ok {
+		return nil, errors.New("RelativePath is not a string")
+	}
+	if target, present := obj.Fields["Target"]; present && target != nil {
+		if f.Target, ok = target.(string); !ok {
+			return nil, errors.New("Target is not a string")
+		}
+	}
+
+	ints := []struct {
+		key string
+		set func(uint64)
+	}{
+		{"Size", func(n uint64) { f.Size = n }},
+		{"Mode", func(n uint64) { f.Mode = uint32(n) }},
+		{"UserID", func(n uint64) { f.UserID = uint32(n) }},
+		{"GroupID", func(n uint64) { f.GroupID = uint32(n) }},
+		{"InodeNumber", func(n uint64) { f.InodeNumber = n }},
+		{"Flags", func(n uint64) { f.Flags = n }},
+		{"ProtectionClass", func(n uint64) { f.ProtectionClass = int(n) }},
+	}
+	for _, field := range ints {
+		n, err := intField(obj, field.key)
+		if err != nil {
+			return nil, err
+		}
+		field.set(n)
+	}
+
+	times := []struct {
+		key string
+		t   *time.Time
+	}{
+		{"Birth", &f.Birth},
+		{"LastModified", &f.LastModified},
+		{"LastStatusChange", &f.LastStatusChange},
+	}
+	for _, field := range times {
+		t, err := timeField(obj, field.key)
+		if err != nil {
+			return nil, err
+		}
+		*field.t = t
+	}
+
+	switch key := obj.Fields["EncryptionKey"].(type) {
+	case nil:
+	case []byte:
+		f.EncryptionKey = key
+	default:
+		return nil, errors.New("EncryptionKey is not data")
+	}
+
+	// Extended attributes are archived as data holding a property list dictionary.
+	switch xattrs := obj.Fields["ExtendedAttributes"].(type) {
+	case nil:
+	case []byte:
+		if _, err := plist.Unmarshal(xattrs, &f.ExtendedAttributes); err != nil {
+			return nil, fmt.Errorf("ExtendedAttributes: %v", err)
+		}
+	default:
+		return nil, errors.New("ExtendedAttributes is not data")
+	}
+
+	return f, nil
+}
@@ -0,0 +1,160 @@
+package backup
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"howett.net/plist"
+)
+
+func TestParseInfo(t *testing.T) {
+	metadata, err := plist.Marshal(map[string]interface{}{"itemName": "Example"}, plist.BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := plist.Marshal(map[string]interface{}{
+		"Device Name":      "iPhone",
+		"Product Version":  "17.4",
+		"Last Backup Date": time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		"Applications": map[string]interface{}{
+			"com.example.app": map[string]interface{}{"iTunesMetadata": metadata},
+		},
+		"Installed Applications": []string{"com.example.app"},
+		"Unmodeled Key":          "kept",
+	}, plist.XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := ParseInfo(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DeviceName != "iPhone" || info.ProductVersion != "17.4" || info.LastBackupDate == nil || info.LastBackupDate.Year() != 2024 {
+		t.Errorf("Unexpected info %+v", info)
+	}
+	if info.Extra["Unmodeled Key"] != "kept" {
+		t.Errorf("Expected unmodeled keys in Extra, got %v", info.Extra)
+	}
+
+	app := info.Applications["com.example.app"]
+	m, err := app.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["itemName"] != "Example" {
+		t.Errorf("Unexpected iTunesMetadata %v", m)
+	}
+}
+
+func TestParseManifestAndStatus(t *testing.T) {
+	manifest, err := ParseManifest([]byte(`{
+		Version = "10.0";
+		IsEncrypted = <*BY>;
+		Lockdown = { DeviceName = iPad; UniqueDeviceID = 0123abcd; };
+		Applications = { "com.example.app" = { CFBundleIdentifier = "com.example.app"; Path = "/var/containers/Example.app"; }; };
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !manifest.IsEncrypted || manifest.Lockdown == nil || manifest.Lockdown.DeviceName != "iPad" {
+		t.Errorf("Unexpected manifest %+v", manifest)
+	}
+	if manifest.Applications["com.example.app"].Path != "/var/containers/Example.app" {
+		t.Errorf("Unexpected applications %v", manifest.Applications)
+	}
+
+	status, err := ParseStatus([]byte(`{ SnapshotState = finished; IsFullBackup = <*BN>; BackupState = new; }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Finished() || status.IsFullBackup || status.BackupState != "new" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+}
+
+func TestRoundTripWithoutDates(t *testing.T) {
+	tests := []struct {
+		doc string
+		v   interface{}
+	}{
+		{`{ "Device Name" = iPhone; "Unmodeled Key" = kept; }`, &Info{}},
+		{`{ Version = "10.0"; IsEncrypted = <*BN>; WasPasscodeSet = <*BY>; }`, &Manifest{}},
+		{`{ SnapshotState = finished; IsFullBackup = <*BN>; }`, &Status{}},
+	}
+
+	for _, test := range tests {
+		if _, err := plist.Unmarshal([]byte(test.doc), test.v); err != nil {
+			t.Fatal(err)
+		}
+		out, err := plist.Marshal(test.v, plist.GNUStepFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var expected, received map[string]interface{}
+		plist.Unmarshal([]byte(test.doc), &expected)
+		if _, err := plist.Unmarshal(out, &received); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(received, expected) {
+			t.Errorf("Expected %v to round-trip, received %s", expected, out)
+		}
+	}
+}
+
+func TestDecodeFile(t *testing.T) {
+	xattrs, err := plist.Marshal(map[string][]byte{"com.apple.quarantine": []byte("q")}, plist.BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := plist.Marshal(map[string]interface{}{
+		"$archiver": "NSKeyedArchiver",
+		"$version":  100000,
+		"$top":      map[string]interface{}{"root": plist.UID(1)},
+		"$objects": []interface{}{
+			"$null",
+			map[string]interface{}{
+				"$class":             plist.UID(2),
+				"RelativePath":       plist.UID(3),
+				"Size":               1024,
+				"Mode":               0100644,
+				"UserID":             501,
+				"LastModified":       1700000000,
+				"ProtectionClass":    3,
+				"EncryptionKey":      plist.UID(4),
+				"ExtendedAttributes": plist.UID(6),
+			},
+			map[string]interface{}{"$classname": "MBFile", "$classes": []interface{}{"MBFile", "NSObject"}},
+			"Library/Preferences/com.example.plist",
+			map[string]interface{}{"$class": plist.UID(5), "NS.data": []byte{1, 2, 3, 4}},
+			map[string]interface{}{"$classname": "NSMutableData", "$classes": []interface{}{"NSMutableData", "NSData", "NSObject"}},
+			xattrs,
+		},
+	}, plist.BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := DecodeFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &File{
+		RelativePath:       "Library/Preferences/com.example.plist",
+		Size:               1024,
+		Mode:               0100644,
+		UserID:             501,
+		ProtectionClass:    3,
+		LastModified:       time.Unix(1700000000, 0).In(time.UTC),
+		EncryptionKey:      []byte{1, 2, 3, 4},
+		ExtendedAttributes: map[string][]byte{"com.apple.quarantine": []byte("q")},
+	}
+	if !reflect.DeepEqual(file, expected) {
+		t.Errorf("Unexpected file\nexpected %+v\nreceived %+v", expected, file)
+	}
+}
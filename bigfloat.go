@@ -0,0 +1,47 @@
+package plist
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+)
+
+var bigFloatType = reflect.TypeOf(big.Float{})
+
+// marshalBigFloat marshals a big.Float as a 64-bit real, rounding it to the nearest float64 in
+// the direction given by the Encoder's rounding mode.
+func (p *Encoder) marshalBigFloat(val reflect.Value) cfValue {
+	var f *big.Float
+	if val.CanAddr() {
+		f = val.Addr().Interface().(*big.Float)
+	} else {
+		v := val.Interface().(big.Float)
+		f = &v
+	}
+
+	f64, _ := new(big.Float).SetPrec(53).SetMode(p.bigFloatRounding).Set(f).Float64()
+	return &cfReal{wide: true, value: f64}
+}
+
+// unmarshalBigFloat stores a real or integer in val, a big.Float, exactly. It returns false for
+// other values, which are left to be decoded as usual (strings, through UnmarshalText.)
+func (p *Decoder) unmarshalBigFloat(pval cfValue, val reflect.Value) bool {
+	f := val.Addr().Interface().(*big.Float)
+	switch pval := pval.(type) {
+	case *cfReal:
+		if math.IsNaN(pval.value) {
+			panic(errors.New("plist: cannot store NaN in a big.Float"))
+		}
+		f.SetFloat64(pval.value)
+	case *cfNumber:
+		if pval.signed {
+			f.SetInt64(int64(pval.value))
+		} else {
+			f.SetUint64(pval.value)
+		}
+	default:
+		return false
+	}
+	return true
+}
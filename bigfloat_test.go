@@ -0,0 +1,67 @@
+package plist
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestBigFloatMarshal(t *testing.T) {
+	// 1 + 2^-60 lies between 1 and the next float64 up.
+	f := new(big.Float).SetPrec(100).SetInt64(1)
+	f.Add(f, new(big.Float).SetMantExp(big.NewFloat(1), -60))
+
+	tests := []struct {
+		mode     big.RoundingMode
+		expected string
+	}{
+		{big.ToNearestEven, "<real>1</real>"},
+		{big.AwayFromZero, "<real>1.0000000000000002</real>"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.SetBigFloatRounding(test.mode)
+		if err := enc.Encode(struct{ F *big.Float }{f}); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), test.expected) {
+			t.Errorf("%v: Expected %s in %s", test.mode, test.expected, buf.String())
+		}
+	}
+}
+
+func TestBigFloatUnmarshal(t *testing.T) {
+	var v struct {
+		Real    *big.Float
+		Integer big.Float
+		String  *big.Float
+	}
+	doc := `<plist version="1.0"><dict><key>Real</key><real>0.1</real><key>Integer</key><integer>18446744073709551615</integer><key>String</key><string>2.5</string></dict></plist>`
+	if _, err := Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatal(err)
+	}
+	if f, _ := v.Real.Float64(); f != 0.1 || v.Real.Prec() != 53 {
+		t.Errorf("Expected exactly 0.1 as a float64, got %v", v.Real)
+	}
+	if u, acc := v.Integer.Uint64(); u != 18446744073709551615 || acc != big.Exact {
+		t.Errorf("Expected the largest uint64, got %v", &v.Integer)
+	}
+	if f, _ := v.String.Float64(); f != 2.5 {
+		t.Errorf("Expected 2.5 from a string, got %v", v.String)
+	}
+
+	// Re-encoding a decoded real does not change it.
+	out, err := Marshal(v.Real, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "<real>0.1</real>") {
+		t.Errorf("Unexpected re-encoding %s", out)
+	}
+
+	if _, err := Unmarshal([]byte(`<plist version="1.0"><real>nan</real></plist>`), new(big.Float)); err == nil {
+		t.Error("Expected an error decoding NaN into a big.Float")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"time"
+	"unicode"
 	"unicode/utf16"
 )
 
@@ -32,61 +33,140 @@ func bplistValueShouldUnique(pval cfValue) bool {
 
 type bplistGenerator struct {
 	writer   *countedWriter
-	objmap   map[interface{}]uint64 // maps pValue.hash()es to object locations
+	objmap   map[interface{}]uint64 // maps pValue.hash()es to object locations; nil until objkeys fills
+	objkeys  []bplistUniqueObject   // the same, searched linearly, for small documents
 	objtable []cfValue
+	refs     [][]uint64 // object ID to the IDs of its keys and values, for containers
 	trailer  bplistTrailer
+
+	scratch []byte // reusable buffer for string encoding
+
+	minOffsetIntSize int // lower bounds for the trailer's integer sizes; 0 means "as small as possible"
+	minObjectRefSize int
+}
+
+// validBplistIntSize reports whether n may be used as a minimum offset or object reference size.
+func validBplistIntSize(n int) bool {
+	switch n {
+	case 0, 1, 2, 4, 8:
+		return true
+	}
+	return false
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
 }
 
-func (p *bplistGenerator) flattenPlistValue(pval cfValue) {
-	key := pval.hash()
-	if bplistValueShouldUnique(pval) {
-		if _, ok := p.objmap[key]; ok {
+// bplistLinearUniqueLimit is the number of uniqued objects up to which they are found by linear
+// search, rather than by building a map: most documents are small, and for them the map costs
+// more than it saves.
+const bplistLinearUniqueLimit = 32
+
+type bplistUniqueObject struct {
+	key interface{} // the object's hash()
+	idx uint64
+}
+
+// uniqueObject returns the ID of the uniqued object whose hash is key, if there is one.
+func (p *bplistGenerator) uniqueObject(key interface{}) (uint64, bool) {
+	if p.objmap != nil {
+		idx, ok := p.objmap[key]
+		return idx, ok
+	}
+	for _, o := range p.objkeys {
+		if o.key == key {
+			return o.idx, true
+		}
+	}
+	return 0, false
+}
+
+// addUniqueObject records that the uniqued object whose hash is key has the ID idx.
+func (p *bplistGenerator) addUniqueObject(key interface{}, idx uint64) {
+	if p.objmap == nil {
+		if len(p.objkeys) < bplistLinearUniqueLimit {
+			p.objkeys = append(p.objkeys, bplistUniqueObject{key, idx})
 			return
 		}
+		p.objmap = make(map[interface{}]uint64, 2*len(p.objkeys))
+		for _, o := range p.objkeys {
+			p.objmap[o.key] = o.idx
+		}
+		p.objkeys = nil
+	}
+	p.objmap[key] = idx
+}
+
+// flattenPlistValue adds pval and everything inside it to the object table, and returns its
+// object ID. Each value is hashed only here; containers remember the IDs of their contents, so
+// that writing them needs no further lookups.
+func (p *bplistGenerator) flattenPlistValue(pval cfValue) uint64 {
+	if pval == nil {
+		// Every object needs bytes of its own; an empty one would share the next object's offset.
+		panic(errors.New("plist: cannot encode a missing value in a binary property list"))
+	}
+
+	unique := bplistValueShouldUnique(pval)
+	var key interface{}
+	if unique {
+		key = pval.hash()
+		if idx, ok := p.uniqueObject(key); ok {
+			return idx
+		}
 	}
 
-	p.objmap[key] = uint64(len(p.objtable))
+	idx := uint64(len(p.objtable))
+	if unique {
+		p.addUniqueObject(key, idx)
+	}
 	p.objtable = append(p.objtable, pval)
+	p.refs = append(p.refs, nil)
 
+	var refs []uint64
 	switch pval := pval.(type) {
 	case *cfDictionary:
 		pval.sort()
+		refs = make([]uint64, 0, 2*len(pval.keys))
 		for _, k := range pval.keys {
-			p.flattenPlistValue(cfString(k))
+			refs = append(refs, p.flattenPlistValue(cfString(k)))
 		}
 		for _, v := range pval.values {
-			p.flattenPlistValue(v)
+			refs = append(refs, p.flattenPlistValue(v))
 		}
 	case *cfArray:
+		refs = make([]uint64, 0, len(pval.values))
 		for _, v := range pval.values {
-			p.flattenPlistValue(v)
+			refs = append(refs, p.flattenPlistValue(v))
 		}
 	}
-}
-
-func (p *bplistGenerator) indexForPlistValue(pval cfValue) (uint64, bool) {
-	v, ok := p.objmap[pval.hash()]
-	return v, ok
+	p.refs[idx] = refs
+	return idx
 }
 
 func (p *bplistGenerator) generateDocument(root cfValue) {
 	p.objtable = make([]cfValue, 0, 16)
-	p.objmap = make(map[interface{}]uint64)
-	p.flattenPlistValue(root)
+	p.objmap = nil
+	p.objkeys = make([]bplistUniqueObject, 0, bplistLinearUniqueLimit)
+	p.refs = make([][]uint64, 0, 16)
+	top := p.flattenPlistValue(root)
 
 	p.trailer.NumObjects = uint64(len(p.objtable))
-	p.trailer.ObjectRefSize = uint8(bplistMinimumIntSize(p.trailer.NumObjects))
+	p.trailer.ObjectRefSize = uint8(maxInt(bplistMinimumIntSize(p.trailer.NumObjects), p.minObjectRefSize))
 
 	p.writer.Write([]byte("bplist00"))
 
 	offtable := make([]uint64, p.trailer.NumObjects)
 	for i, pval := range p.objtable {
 		offtable[i] = uint64(p.writer.BytesWritten())
-		p.writePlistValue(pval)
+		p.writePlistValue(pval, p.refs[i])
 	}
 
-	p.trailer.OffsetIntSize = uint8(bplistMinimumIntSize(uint64(p.writer.BytesWritten())))
-	p.trailer.TopObject = p.objmap[root.hash()]
+	p.trailer.OffsetIntSize = uint8(maxInt(bplistMinimumIntSize(uint64(p.writer.BytesWritten())), p.minOffsetIntSize))
+	p.trailer.TopObject = top
 	p.trailer.OffsetTableOffset = uint64(p.writer.BytesWritten())
 
 	for _, offset := range offtable {
@@ -96,16 +176,13 @@ func (p *bplistGenerator) generateDocument(root cfValue) {
 	binary.Write(p.writer, binary.BigEndian, p.trailer)
 }
 
-func (p *bplistGenerator) writePlistValue(pval cfValue) {
-	if pval == nil {
-		return
-	}
-
+// writePlistValue writes pval, whose contents (if it is a container) have the object IDs in refs.
+func (p *bplistGenerator) writePlistValue(pval cfValue, refs []uint64) {
 	switch pval := pval.(type) {
 	case *cfDictionary:
-		p.writeDictionaryTag(pval)
+		p.writeDictionaryTag(pval, refs)
 	case *cfArray:
-		p.writeArrayTag(pval.values)
+		p.writeArrayTag(refs)
 	case cfString:
 		p.writeStringTag(string(pval))
 	case *cfNumber:
@@ -211,8 +288,7 @@ func (p *bplistGenerator) writeRealTag(n float64, bits int) {
 
 func (p *bplistGenerator) writeDateTag(t time.Time) {
 	tag := uint8(bpTagDate) | 0x3
-	val := float64(t.In(time.UTC).UnixNano()) / float64(time.Second)
-	val -= 978307200 // Adjust to Apple Epoch
+	val := AbsoluteTime(t)
 
 	binary.Write(p.writer, binary.BigEndian, tag)
 	binary.Write(p.writer, binary.BigEndian, val)
@@ -241,9 +317,7 @@ func (p *bplistGenerator) writeDataTag(data []byte) {
 func (p *bplistGenerator) writeStringTag(str string) {
 	for _, r := range str {
 		if r > 0x7F {
-			utf16Runes := utf16.Encode([]rune(str))
-			p.writeCountedTag(bpTagUTF16String, uint64(len(utf16Runes)))
-			binary.Write(p.writer, binary.BigEndian, utf16Runes)
+			p.writeUTF16StringTag(str)
 			return
 		}
 	}
@@ -252,43 +326,42 @@ func (p *bplistGenerator) writeStringTag(str string) {
 	binary.Write(p.writer, binary.BigEndian, []byte(str))
 }
 
-func (p *bplistGenerator) writeDictionaryTag(dict *cfDictionary) {
-	// assumption: sorted already; flattenPlistValue did this.
-	cnt := len(dict.keys)
-	p.writeCountedTag(bpTagDictionary, uint64(cnt))
-	vals := make([]uint64, cnt*2)
-	for i, k := range dict.keys {
-		// invariant: keys have already been "uniqued" (as PStrings)
-		keyIdx, ok := p.objmap[cfString(k).hash()]
-		if !ok {
-			panic(errors.New("failed to find key " + k + " in object map during serialization"))
+// writeUTF16StringTag encodes str as big-endian UTF-16 code units directly into a scratch buffer
+// that is reused for every string in the document.
+func (p *bplistGenerator) writeUTF16StringTag(str string) {
+	buf := p.scratch[:0]
+	for _, r := range str {
+		switch {
+		case r < 0xD800, r >= 0xE000 && r < 0x10000:
+			buf = append(buf, byte(r>>8), byte(r))
+		case r >= 0x10000 && r <= unicode.MaxRune:
+			r1, r2 := utf16.EncodeRune(r)
+			buf = append(buf, byte(r1>>8), byte(r1), byte(r2>>8), byte(r2))
+		default:
+			// Unpaired surrogates cannot be represented; utf16.Encode makes the same substitution.
+			buf = append(buf, byte(unicode.ReplacementChar>>8), byte(unicode.ReplacementChar&0xFF))
 		}
-		vals[i] = keyIdx
 	}
+	p.scratch = buf
 
-	for i, v := range dict.values {
-		// invariant: values have already been "uniqued"
-		objIdx, ok := p.indexForPlistValue(v)
-		if !ok {
-			panic(errors.New("failed to find value in object map during serialization"))
-		}
-		vals[i+cnt] = objIdx
-	}
+	p.writeCountedTag(bpTagUTF16String, uint64(len(buf)/2))
+	p.writer.Write(buf)
+}
 
-	for _, v := range vals {
-		p.writeSizedInt(v, int(p.trailer.ObjectRefSize))
+// writeDictionaryTag writes dict, given the object IDs of its keys followed by those of its values.
+func (p *bplistGenerator) writeDictionaryTag(dict *cfDictionary, refs []uint64) {
+	// assumption: sorted already; flattenPlistValue did this.
+	p.writeCountedTag(bpTagDictionary, uint64(len(dict.keys)))
+	for _, ref := range refs {
+		p.writeSizedInt(ref, int(p.trailer.ObjectRefSize))
 	}
 }
 
-func (p *bplistGenerator) writeArrayTag(arr []cfValue) {
-	p.writeCountedTag(bpTagArray, uint64(len(arr)))
-	for _, v := range arr {
-		objIdx, ok := p.indexForPlistValue(v)
-		if !ok {
-			panic(errors.New("failed to find value in object map during serialization"))
-		}
-
-		p.writeSizedInt(objIdx, int(p.trailer.ObjectRefSize))
+// writeArrayTag writes an array, given the object IDs of its values.
+func (p *bplistGenerator) writeArrayTag(refs []uint64) {
+	p.writeCountedTag(bpTagArray, uint64(len(refs)))
+	for _, ref := range refs {
+		p.writeSizedInt(ref, int(p.trailer.ObjectRefSize))
 	}
 }
 
@@ -9,8 +9,10 @@ import (
 	"io/ioutil"
 	"math"
 	"runtime"
-	"time"
+	"strings"
+	"sync"
 	"unicode/utf16"
+	"unicode/utf8"
 )
 
 const (
@@ -29,6 +31,15 @@ type bplistParser struct {
 	trailerOffset uint64
 
 	containerStack []offset // slice of object offsets; manipulated during container deserialization
+
+	workers      int           // number of goroutines used to pre-parse scalar objects
+	objectPanics []interface{} // object ID to the panic raised while pre-parsing it
+
+	recovering bool     // skip damaged objects rather than failing (see RecoverBinary)
+	offsets    []offset // object ID to offset, replacing the offset table if non-nil
+	lost       []string // descriptions of the objects skipped while recovering
+
+	events *eventEmitter // if non-nil, objects are delivered here rather than returned
 }
 
 func (p *bplistParser) validateDocumentTrailer() {
@@ -49,7 +60,7 @@ func (p *bplistParser) validateDocumentTrailer() {
 	}
 
 	maxObjectRef := uint64(1) << (8 * p.trailer.ObjectRefSize)
-	if p.trailer.NumObjects > maxObjectRef {
+	if p.trailer.ObjectRefSize < uint8(8) && p.trailer.NumObjects > maxObjectRef {
 		panic(fmt.Errorf("more objects (%v) than object ref size (%v bytes) can support", p.trailer.NumObjects, p.trailer.ObjectRefSize))
 	}
 
@@ -62,6 +73,96 @@ func (p *bplistParser) validateDocumentTrailer() {
 	}
 }
 
+// parseHeader reads the 8-byte "bplistNN" header.
+func (p *bplistParser) parseHeader(header []byte) {
+	if !bytes.Equal(header[0:6], []byte{'b', 'p', 'l', 'i', 's', 't'}) {
+		panic(errors.New("incomprehensible magic"))
+	}
+
+	p.version = int(((header[6] - '0') * 10) + (header[7] - '0'))
+
+	if p.version > 1 {
+		panic(fmt.Errorf("unexpected version %d", p.version))
+	}
+}
+
+// parseTrailer reads and validates the 32-byte trailer found at trailerOffset.
+func (p *bplistParser) parseTrailer(trailer []byte, trailerOffset uint64) {
+	p.trailerOffset = trailerOffset
+	p.trailer = bplistTrailer{
+		SortVersion:       trailer[5],
+		OffsetIntSize:     trailer[6],
+		ObjectRefSize:     trailer[7],
+		NumObjects:        binary.BigEndian.Uint64(trailer[8:]),
+		TopObject:         binary.BigEndian.Uint64(trailer[16:]),
+		OffsetTableOffset: binary.BigEndian.Uint64(trailer[24:]),
+	}
+
+	p.validateDocumentTrailer()
+}
+
+// BinaryInfo describes the header and trailer of a binary property list.
+type BinaryInfo struct {
+	Version           int    // format version, from the "bplistNN" header
+	SortVersion       uint8  // unused by CoreFoundation; usually 0
+	OffsetIntSize     int    // size in bytes of each entry in the offset table
+	ObjectRefSize     int    // size in bytes of each reference from a container to an object
+	NumObjects        uint64 // number of objects in the document
+	TopObject         uint64 // index of the root object
+	OffsetTableOffset uint64 // location of the offset table
+}
+
+// Stat reads the header and trailer of the binary property list in r and reports the metadata
+// they contain. None of the document's objects are decoded. Stat returns an error if r does not
+// contain a binary property list or its trailer is inconsistent.
+func Stat(r io.ReadSeeker) (info *BinaryInfo, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = plistParseError{"binary", r.(error)}
+		}
+	}()
+
+	length, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if length < 40 {
+		panic(errors.New("not enough data"))
+	}
+
+	var header [8]byte
+	var trailer [32]byte
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(length-32, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, trailer[:]); err != nil {
+		return nil, err
+	}
+
+	p := &bplistParser{}
+	p.parseHeader(header[:])
+	p.parseTrailer(trailer[:], uint64(length-32))
+
+	return &BinaryInfo{
+		Version:           p.version,
+		SortVersion:       p.trailer.SortVersion,
+		OffsetIntSize:     int(p.trailer.OffsetIntSize),
+		ObjectRefSize:     int(p.trailer.ObjectRefSize),
+		NumObjects:        p.trailer.NumObjects,
+		TopObject:         p.trailer.TopObject,
+		OffsetTableOffset: p.trailer.OffsetTableOffset,
+	}, nil
+}
+
 func (p *bplistParser) parseDocument() (pval cfValue, parseError error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -80,27 +181,8 @@ func (p *bplistParser) parseDocument() (pval cfValue, parseError error) {
 		panic(errors.New("not enough data"))
 	}
 
-	if !bytes.Equal(p.buffer[0:6], []byte{'b', 'p', 'l', 'i', 's', 't'}) {
-		panic(errors.New("incomprehensible magic"))
-	}
-
-	p.version = int(((p.buffer[6] - '0') * 10) + (p.buffer[7] - '0'))
-
-	if p.version > 1 {
-		panic(fmt.Errorf("unexpected version %d", p.version))
-	}
-
-	p.trailerOffset = uint64(l - 32)
-	p.trailer = bplistTrailer{
-		SortVersion:       p.buffer[p.trailerOffset+5],
-		OffsetIntSize:     p.buffer[p.trailerOffset+6],
-		ObjectRefSize:     p.buffer[p.trailerOffset+7],
-		NumObjects:        binary.BigEndian.Uint64(p.buffer[p.trailerOffset+8:]),
-		TopObject:         binary.BigEndian.Uint64(p.buffer[p.trailerOffset+16:]),
-		OffsetTableOffset: binary.BigEndian.Uint64(p.buffer[p.trailerOffset+24:]),
-	}
-
-	p.validateDocumentTrailer()
+	p.parseHeader(p.buffer[0:8])
+	p.parseTrailer(p.buffer[l-32:], uint64(l-32))
 
 	// INVARIANTS:
 	// - Entire offset table is before trailer
@@ -109,8 +191,17 @@ func (p *bplistParser) parseDocument() (pval cfValue, parseError error) {
 	// - Object IDs are big enough to support the number of objects in this plist
 	// - Top object is in range
 
+	if p.events != nil {
+		p.emitObjectAtIndex(p.trailer.TopObject)
+		return nil, nil
+	}
+
 	p.objects = make([]cfValue, p.trailer.NumObjects)
 
+	if p.workers > 1 {
+		p.parseScalarObjectsConcurrently()
+	}
+
 	pval = p.objectAtIndex(p.trailer.TopObject)
 	return
 }
@@ -165,15 +256,70 @@ func (p *bplistParser) objectAtIndex(index uint64) cfValue {
 		return pval
 	}
 
+	if p.objectPanics != nil && p.objectPanics[index] != nil {
+		// This object failed to parse ahead of time; fail as if we had just parsed it.
+		panic(p.objectPanics[index])
+	}
+
+	pval := p.parseTagAtOffset(p.offsetForObjectAtIndex(index))
+	p.objects[index] = pval
+	return pval
+
+}
+
+func (p *bplistParser) offsetForObjectAtIndex(index uint64) offset {
+	if p.offsets != nil {
+		return p.offsets[index]
+	}
 	off, _ := p.parseOffsetAtOffset(offset(p.trailer.OffsetTableOffset + (index * uint64(p.trailer.OffsetIntSize))))
 	if off > offset(p.trailer.OffsetTableOffset-1) {
 		panic(fmt.Errorf("object#%d starts beyond beginning of object table (0x%x, table@0x%x)", index, off, p.trailer.OffsetTableOffset))
 	}
+	return off
+}
 
-	pval := p.parseTagAtOffset(off)
-	p.objects[index] = pval
-	return pval
+// parseScalarObjectsConcurrently parses every non-container object in the document using a pool
+// of p.workers goroutines. Scalar objects do not reference other objects, so they can be parsed in
+// any order; containers are left for objectAtIndex to assemble (and check for cycles) as usual.
+//
+// Objects are parsed whether or not they are reachable from the top object, so any failures
+// are recorded and only reported if objectAtIndex later reaches the object that caused them.
+func (p *bplistParser) parseScalarObjectsConcurrently() {
+	n := p.trailer.NumObjects
+	p.objectPanics = make([]interface{}, n)
+
+	chunk := (n + uint64(p.workers) - 1) / uint64(p.workers)
+	var wg sync.WaitGroup
+	for start := uint64(0); start < n; start += chunk {
+		end := start + chunk
+		if end > n {
+			end = n
+		}
 
+		wg.Add(1)
+		go func(start, end uint64) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				p.parseScalarObjectAtIndex(i)
+			}
+		}(start, end)
+	}
+	wg.Wait()
+}
+
+func (p *bplistParser) parseScalarObjectAtIndex(index uint64) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.objectPanics[index] = r
+		}
+	}()
+
+	off := p.offsetForObjectAtIndex(index)
+	switch p.buffer[off] & 0xF0 {
+	case bpTagArray, bpTagDictionary:
+		return
+	}
+	p.objects[index] = p.parseTagAtOffset(off)
 }
 
 func (p *bplistParser) pushNestedObject(off offset) {
@@ -227,15 +373,7 @@ func (p *bplistParser) parseTagAtOffset(off offset) cfValue {
 		panic(errors.New("illegal float size"))
 	case bpTagDate:
 		bits := binary.BigEndian.Uint64(p.buffer[off+1:])
-		val := math.Float64frombits(bits)
-
-		// Apple Epoch is 20110101000000Z
-		// Adjust for UNIX Time
-		val += 978307200
-
-		sec, fsec := math.Modf(val)
-		time := time.Unix(int64(sec), int64(fsec*float64(time.Second))).In(time.UTC)
-		return cfDate(time)
+		return cfDate(TimeFromAbsoluteTime(math.Float64frombits(bits)))
 	case bpTagData:
 		data := p.parseDataAtOffset(off)
 		return cfData(data)
@@ -295,17 +433,53 @@ func (p *bplistParser) parseUTF16StringAtOffset(off offset) string {
 		panic(fmt.Errorf("utf16 string@0x%x too long (%v bytes, max is %v)", off, bytes, p.trailer.OffsetTableOffset-uint64(start)))
 	}
 
-	u16s := make([]uint16, len)
-	for i := offset(0); i < offset(len); i++ {
-		u16s[i] = binary.BigEndian.Uint16(p.buffer[start+(i*2):])
+	return decodeUTF16BE(p.buffer[start : start+offset(bytes)])
+}
+
+// nextUTF16Rune decodes the character at the start of b, big-endian UTF-16 with an even length,
+// and returns it with the number of bytes it occupied. Unpaired surrogates become U+FFFD, as
+// they do in utf16.Decode.
+func nextUTF16Rune(b []byte) (rune, int) {
+	r := rune(binary.BigEndian.Uint16(b))
+	if !utf16.IsSurrogate(r) {
+		return r, 2
 	}
-	runes := utf16.Decode(u16s)
-	return string(runes)
+	if len(b) >= 4 {
+		if dec := utf16.DecodeRune(r, rune(binary.BigEndian.Uint16(b[2:]))); dec != utf8.RuneError {
+			return dec, 4
+		}
+	}
+	return utf8.RuneError, 2
+}
+
+// decodeUTF16BE converts big-endian UTF-16 to a string with a single allocation, measuring the
+// result before writing it.
+func decodeUTF16BE(b []byte) string {
+	size := 0
+	for i := 0; i < len(b); {
+		r, w := nextUTF16Rune(b[i:])
+		size += utf8.RuneLen(r)
+		i += w
+	}
+
+	var sb strings.Builder
+	sb.Grow(size)
+	for i := 0; i < len(b); {
+		r, w := nextUTF16Rune(b[i:])
+		sb.WriteRune(r)
+		i += w
+	}
+	return sb.String()
 }
 
 func (p *bplistParser) parseObjectListAtOffset(off offset, count uint64) []cfValue {
 	if off+offset(count*uint64(p.trailer.ObjectRefSize)) > offset(p.trailer.OffsetTableOffset) {
-		panic(fmt.Errorf("list@0x%x length (%v) puts its end beyond the offset table at 0x%x", off, count, p.trailer.OffsetTableOffset))
+		if !p.recovering {
+			panic(fmt.Errorf("list@0x%x length (%v) puts its end beyond the offset table at 0x%x", off, count, p.trailer.OffsetTableOffset))
+		}
+		fits := (p.trailer.OffsetTableOffset - uint64(off)) / uint64(p.trailer.ObjectRefSize)
+		p.lost = append(p.lost, fmt.Sprintf("list@0x%x: truncated from %d to %d entries", off, count, fits))
+		count = fits
 	}
 	objects := make([]cfValue, count)
 
@@ -313,7 +487,12 @@ func (p *bplistParser) parseObjectListAtOffset(off offset, count uint64) []cfVal
 	var oid uint64
 	for i := uint64(0); i < count; i++ {
 		oid, next = p.parseObjectRefAtOffset(next)
-		objects[i] = p.objectAtIndex(oid)
+		if p.recovering {
+			// Damaged objects are left nil, for the container to skip.
+			objects[i] = p.recoverObjectAtIndex(oid)
+		} else {
+			objects[i] = p.objectAtIndex(oid)
+		}
 	}
 
 	return objects
@@ -326,6 +505,9 @@ func (p *bplistParser) parseDictionaryAtOffset(off offset) *cfDictionary {
 	// a dictionary is an object list of [key key key val val val]
 	cnt, start := p.countForTagAtOffset(off)
 	objects := p.parseObjectListAtOffset(start, cnt*2)
+	if p.recovering {
+		return p.recoverDictionary(off, cnt, objects)
+	}
 
 	keys := make([]string, cnt)
 	for i := uint64(0); i < cnt; i++ {
@@ -348,7 +530,17 @@ func (p *bplistParser) parseArrayAtOffset(off offset) *cfArray {
 
 	// an array is just an object list
 	cnt, start := p.countForTagAtOffset(off)
-	return &cfArray{p.parseObjectListAtOffset(start, cnt)}
+	objects := p.parseObjectListAtOffset(start, cnt)
+	if p.recovering {
+		present := objects[:0]
+		for _, pval := range objects {
+			if pval != nil {
+				present = append(present, pval)
+			}
+		}
+		objects = present
+	}
+	return &cfArray{objects}
 }
 
 func newBplistParser(r io.ReadSeeker) *bplistParser {
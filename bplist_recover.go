@@ -0,0 +1,320 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// A BinaryRecovery holds the values RecoverBinary salvaged from a damaged binary property list.
+type BinaryRecovery struct {
+	// Lost describes each object, reference or list entry that could not be recovered.
+	// It is empty if the document was intact.
+	Lost []string
+
+	// TrailerRebuilt reports whether the document's trailer or offset table was unusable, so
+	// its objects had to be located by scanning the file.
+	TrailerRebuilt bool
+
+	// Objects is the number of objects the recovered document was found to contain.
+	Objects uint64
+
+	root cfValue
+}
+
+// Unmarshal stores the recovered root value in v, as Unmarshal would. Damaged values are absent
+// from the recovered document: arrays are missing the elements that could not be read, and
+// dictionaries are missing the corresponding keys.
+func (r *BinaryRecovery) Unmarshal(v interface{}) error {
+	return unmarshalRecovered(r.root, BinaryFormat, v)
+}
+
+// unmarshalRecovered stores a value salvaged from a damaged document in v.
+func unmarshalRecovered(pval cfValue, format int, v interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+	}()
+
+	d := &Decoder{Format: format}
+	d.unmarshal(pval, reflect.ValueOf(v))
+	return nil
+}
+
+// RecoverBinary makes a best-effort attempt to read a truncated or corrupt binary property
+// list. Objects that cannot be read are skipped and described in the result's Lost list rather
+// than failing the whole document.
+//
+// If the trailer or offset table is damaged (as it is in any truncated file, since both are
+// stored at the end), RecoverBinary locates the objects by scanning the file from its header.
+// This relies on the objects being stored contiguously, as every known writer does, and takes
+// the first of them to be the root, as CoreFoundation and this package write it.
+//
+// RecoverBinary returns an error only if data is not a binary property list or no root value
+// can be recovered at all.
+func RecoverBinary(data []byte) (rec *BinaryRecovery, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = plistParseError{"binary", r.(error)}
+		}
+	}()
+
+	if len(data) < 9 || !bytes.HasPrefix(data, []byte("bplist")) {
+		return nil, errors.New("plist: not a binary property list")
+	}
+
+	p := &bplistParser{buffer: data, recovering: true}
+	p.parseHeader(data[:8])
+
+	rec = &BinaryRecovery{}
+	if reason := p.tryTrailer(); reason != nil {
+		p.lost = append(p.lost, fmt.Sprintf("trailer: %v", reason))
+	} else {
+		rec.root = p.recoverRoot()
+	}
+
+	if rec.root == nil {
+		if len(p.lost) > 0 && !strings.HasPrefix(p.lost[0], "trailer: ") {
+			// The trailer was valid, but the root could not be reached through it; the
+			// objects found by scanning will be reported afresh.
+			p.lost = []string{"offset table: " + p.lost[len(p.lost)-1]}
+		}
+		rec.TrailerRebuilt = true
+		if !p.rebuildTrailer() {
+			return nil, errors.New("plist: no objects could be recovered")
+		}
+		rec.root = p.recoverRoot()
+		if rec.root == nil {
+			return nil, fmt.Errorf("plist: root object could not be recovered: %s", p.lost[len(p.lost)-1])
+		}
+	}
+
+	rec.Lost = p.lost
+	rec.Objects = p.trailer.NumObjects
+	return rec, nil
+}
+
+// tryTrailer reads the trailer at the end of the buffer, returning the reason it is unusable.
+func (p *bplistParser) tryTrailer() (reason interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			reason = r
+		}
+	}()
+
+	l := len(p.buffer)
+	if l < 40 {
+		return errors.New("not enough data")
+	}
+	p.parseTrailer(p.buffer[l-32:], uint64(l-32))
+	return nil
+}
+
+func (p *bplistParser) recoverRoot() cfValue {
+	p.objects = make([]cfValue, p.trailer.NumObjects)
+	p.objectPanics = make([]interface{}, p.trailer.NumObjects)
+	p.containerStack = nil
+	return p.recoverObjectAtIndex(p.trailer.TopObject)
+}
+
+// recoverObjectAtIndex returns the object with the given ID, or nil if it cannot be parsed.
+func (p *bplistParser) recoverObjectAtIndex(index uint64) (pval cfValue) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			if index < p.trailer.NumObjects {
+				if p.objectPanics[index] != nil {
+					// Already reported when it was first reached.
+					return
+				}
+				p.objectPanics[index] = r
+			}
+			p.lost = append(p.lost, fmt.Sprintf("object #%d: %v", index, r))
+			pval = nil
+		}
+	}()
+	return p.objectAtIndex(index)
+}
+
+// recoverDictionary assembles a dictionary from a list of keys and values that may be damaged
+// or truncated, keeping only the complete entries. A truncated list still holds the first cnt
+// keys, so only the entries whose values survived are complete; the count itself may be garbage.
+func (p *bplistParser) recoverDictionary(off offset, cnt uint64, objects []cfValue) *cfDictionary {
+	complete := uint64(0)
+	if n := uint64(len(objects)); n > cnt {
+		complete = n - cnt
+	}
+	if complete < cnt {
+		p.lost = append(p.lost, fmt.Sprintf("dictionary@0x%x: truncated from %d to %d entries", off, cnt, complete))
+	}
+
+	dict := &cfDictionary{}
+	for i := uint64(0); i < complete; i++ {
+		key, ok := objects[i].(cfString)
+		val := objects[cnt+i]
+		if !ok || val == nil {
+			if objects[i] != nil && !ok {
+				p.lost = append(p.lost, fmt.Sprintf("dictionary@0x%x: entry %d has a non-string key", off, i))
+			}
+			continue
+		}
+		dict.keys = append(dict.keys, string(key))
+		dict.values = append(dict.values, val)
+	}
+	return dict
+}
+
+// rebuildTrailer replaces the document's trailer and offset table with ones reconstructed by
+// scanning its objects. It tries each possible object reference size and keeps the one that
+// finds the most objects, less the references it leaves dangling; a wrong guess tends to
+// swallow many objects into one container whose references lead nowhere.
+func (p *bplistParser) rebuildTrailer() bool {
+	var best []offset
+	var bestEnd offset
+	bestScore, bestRefSize := 0, 0
+	for _, refSize := range []int{1, 2, 4, 8} {
+		offsets, end, dangling := p.scanObjects(refSize)
+		if score := len(offsets) - dangling; best == nil || score > bestScore {
+			best, bestEnd, bestScore, bestRefSize = offsets, end, score, refSize
+		}
+	}
+
+	if len(best) == 0 {
+		return false
+	}
+
+	p.offsets = best
+	p.trailer = bplistTrailer{
+		ObjectRefSize:     uint8(bestRefSize),
+		NumObjects:        uint64(len(best)),
+		OffsetTableOffset: uint64(bestEnd),
+	}
+	return true
+}
+
+// scanObjects walks the objects following the header, assuming that containers use
+// refSize-byte object references, until it reaches one that is invalid or incomplete. It
+// returns the offset of each complete object, the offset just past the last of them, and the
+// number of references to objects that were not found.
+func (p *bplistParser) scanObjects(refSize int) (offsets []offset, end offset, dangling int) {
+	var refs []uint64
+	off := offset(8)
+	for off < offset(len(p.buffer)) {
+		next, objectRefs, ok := p.scanObjectAtOffset(off, refSize)
+		if !ok {
+			break
+		}
+		offsets = append(offsets, off)
+		refs = append(refs, objectRefs...)
+		off = next
+	}
+
+	for _, ref := range refs {
+		if ref >= uint64(len(offsets)) {
+			dangling++
+		}
+	}
+	return offsets, off, dangling
+}
+
+// scanObjectAtOffset determines the extent of the object at off without parsing it. It returns
+// the offset just past the object and, for containers, the IDs of the objects it refers to.
+func (p *bplistParser) scanObjectAtOffset(off offset, refSize int) (next offset, refs []uint64, ok bool) {
+	limit := uint64(len(p.buffer))
+	fits := func(start offset, n uint64) bool {
+		return n <= limit && uint64(start) <= limit-n
+	}
+
+	tag := p.buffer[off]
+	nibble := tag & 0x0F
+	var size uint64
+	start := off + 1
+
+	switch tag & 0xF0 {
+	case bpTagNull:
+		switch tag {
+		case bpTagNull, bpTagBoolFalse, bpTagBoolTrue, 0x0F:
+		default:
+			return 0, nil, false
+		}
+	case bpTagInteger:
+		if nibble > 4 {
+			return 0, nil, false
+		}
+		size = 1 << nibble
+	case bpTagReal:
+		if nibble != 2 && nibble != 3 {
+			return 0, nil, false
+		}
+		size = 1 << nibble
+	case bpTagDate:
+		if nibble != 3 {
+			return 0, nil, false
+		}
+		size = 8
+	case bpTagUID:
+		size = uint64(nibble) + 1
+	case bpTagData, bpTagASCIIString, bpTagUTF16String, bpTagArray, bpTagDictionary:
+		cnt := uint64(nibble)
+		if nibble == 0xF {
+			if !fits(start, 1) || p.buffer[start]&0xF0 != bpTagInteger || p.buffer[start]&0x0F > 3 {
+				return 0, nil, false
+			}
+			n := uint64(1) << (p.buffer[start] & 0x0F)
+			if !fits(start+1, n) {
+				return 0, nil, false
+			}
+			cnt = 0
+			for _, b := range p.buffer[start+1 : start+1+offset(n)] {
+				cnt = cnt<<8 | uint64(b)
+			}
+			start += 1 + offset(n)
+		}
+
+		switch tag & 0xF0 {
+		case bpTagUTF16String:
+			cnt *= 2
+		case bpTagDictionary:
+			cnt *= 2
+			fallthrough
+		case bpTagArray:
+			if cnt > limit {
+				return 0, nil, false
+			}
+			cnt *= uint64(refSize)
+		}
+		size = cnt
+	default:
+		return 0, nil, false
+	}
+
+	if !fits(start, size) {
+		return 0, nil, false
+	}
+	next = start + offset(size)
+
+	if tag&0xF0 == bpTagArray || tag&0xF0 == bpTagDictionary {
+		var buf [8]byte
+		for ref := start; ref < next; ref += offset(refSize) {
+			copy(buf[8-refSize:], p.buffer[ref:ref+offset(refSize)])
+			refs = append(refs, binary.BigEndian.Uint64(buf[:]))
+		}
+	}
+	return next, refs, true
+}
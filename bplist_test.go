@@ -5,7 +5,11 @@ import (
 	"encoding/binary"
 	"io/ioutil"
 	"math"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"unicode/utf16"
 )
 
 func BenchmarkBplistGenerate(b *testing.B) {
@@ -15,6 +19,18 @@ func BenchmarkBplistGenerate(b *testing.B) {
 	}
 }
 
+func BenchmarkBplistGenerateData(b *testing.B) {
+	blobs := &cfArray{}
+	for i := 0; i < 1000; i++ {
+		blobs.values = append(blobs.values, cfData(bytes.Repeat([]byte{byte(i), byte(i >> 8)}, 2048)))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d := newBplistGenerator(ioutil.Discard)
+		d.generateDocument(blobs)
+	}
+}
+
 func BenchmarkBplistParse(b *testing.B) {
 	buf := bytes.NewReader(plistValueTreeAsBplist)
 	b.ResetTimer()
@@ -176,3 +192,293 @@ func TestBplistNonPowerOfTwoOffsetIntSizes(t *testing.T) {
 		t.Error("Unexpected error", err)
 	}
 }
+
+func TestBplistConcurrentDecode(t *testing.T) {
+	for _, test := range tests {
+		doc, ok := test.Documents[BinaryFormat]
+		if !ok || test.SkipDecode[BinaryFormat] {
+			continue
+		}
+
+		subtest(t, test.Name, func(t *testing.T) {
+			var sequential, concurrent interface{}
+			if err := NewDecoder(bytes.NewReader(doc)).Decode(&sequential); err != nil {
+				t.Fatal(err)
+			}
+
+			d := NewDecoder(bytes.NewReader(doc))
+			d.SetBinaryParallelism(4)
+			if err := d.Decode(&concurrent); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(sequential, concurrent) {
+				t.Logf("Expected: %#v", sequential)
+				t.Logf("Received: %#v", concurrent)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestBplistUTF16StringEncoding(t *testing.T) {
+	strs := []string{
+		"Hello, 世界",
+		"\U0001F600 grinning",
+		"invalid \xff byte",
+		"é￿\U0010FFFF",
+	}
+
+	for _, str := range strs {
+		buf := &bytes.Buffer{}
+		g := newBplistGenerator(buf)
+		g.writeStringTag(str)
+
+		expected := &bytes.Buffer{}
+		u16 := utf16.Encode([]rune(str))
+		newBplistGenerator(expected).writeCountedTag(bpTagUTF16String, uint64(len(u16)))
+		binary.Write(expected, binary.BigEndian, u16)
+
+		if !bytes.Equal(buf.Bytes(), expected.Bytes()) {
+			t.Errorf("%q: Expected %x, received %x", str, expected.Bytes(), buf.Bytes())
+		}
+	}
+}
+
+func TestBplistStat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := NewBinaryEncoder(buf).Encode([]string{"a", "b", "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := Stat(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &BinaryInfo{
+		Version:           0,
+		OffsetIntSize:     1,
+		ObjectRefSize:     1,
+		NumObjects:        4,
+		TopObject:         0,
+		OffsetTableOffset: uint64(buf.Len() - 32 - 4),
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("Expected %+v, received %+v", expected, info)
+	}
+
+	if _, err := Stat(bytes.NewReader([]byte(`<plist version="1.0"><string>a</string></plist>`))); err == nil {
+		t.Error("Expected an error for a non-binary property list")
+	}
+}
+
+func TestBplistIntSizes(t *testing.T) {
+	value := map[string]interface{}{"a": []int{1, 2, 3}, "b": "hello"}
+
+	for _, size := range []int{1, 2, 4, 8} {
+		buf := &bytes.Buffer{}
+		enc := NewBinaryEncoder(buf)
+		enc.SetBinaryIntSizes(size, size)
+		if err := enc.Encode(value); err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := Stat(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.OffsetIntSize != size || info.ObjectRefSize != size {
+			t.Errorf("Expected %d-byte sizes, received offset %d and object ref %d", size, info.OffsetIntSize, info.ObjectRefSize)
+		}
+
+		var decoded map[string]interface{}
+		if _, err := Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Errorf("%d: %v", size, err)
+		}
+	}
+
+	enc := NewBinaryEncoder(&bytes.Buffer{})
+	enc.SetBinaryIntSizes(3, 0)
+	if err := enc.Encode(value); err == nil {
+		t.Error("Expected an error for an invalid offset size")
+	}
+}
+
+func TestBplistRecover(t *testing.T) {
+	type doc struct {
+		Name  string `plist:"name"`
+		Count int    `plist:"count"`
+		List  []int  `plist:"list"`
+		Zlast string `plist:"zlast"`
+	}
+	in := doc{Name: "example", Count: 1000, List: []int{2, 3, 4}, Zlast: "this string is stored last"}
+	data, err := Marshal(in, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An intact document is recovered completely.
+	rec, err := RecoverBinary(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out doc
+	if err := rec.Unmarshal(&out); err != nil || !reflect.DeepEqual(in, out) || len(rec.Lost) != 0 || rec.TrailerRebuilt {
+		t.Errorf("Intact recovery failed: %+v %+v %v", out, rec, err)
+	}
+
+	// Truncating the file removes its trailer, offset table and the end of the last string.
+	info, err := Stat(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := data[:info.OffsetTableOffset-5]
+	if _, err := Unmarshal(truncated, &out); err == nil {
+		t.Fatal("Expected the truncated document to fail to decode normally")
+	}
+	rec, err = RecoverBinary(truncated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = doc{}
+	if err := rec.Unmarshal(&out); err != nil {
+		t.Fatal(err)
+	}
+	expected := in
+	expected.Zlast = ""
+	if !rec.TrailerRebuilt || len(rec.Lost) < 2 || !reflect.DeepEqual(out, expected) {
+		t.Errorf("Truncated recovery failed: %+v %+v", out, rec)
+	}
+
+	// A corrupt object is skipped when the trailer is intact.
+	corrupt := append([]byte(nil), data...)
+	i := bytes.Index(corrupt, []byte("example"))
+	corrupt[i-1] = 0x70 // not a valid object tag
+	rec, err = RecoverBinary(corrupt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = doc{}
+	if err := rec.Unmarshal(&out); err != nil {
+		t.Fatal(err)
+	}
+	expected = in
+	expected.Name = ""
+	if rec.TrailerRebuilt || len(rec.Lost) != 1 || !reflect.DeepEqual(out, expected) {
+		t.Errorf("Corrupt recovery failed: %+v %+v", out, rec)
+	}
+
+	// A dictionary whose count is corrupt is cut short, with a single note.
+	wide := make(map[string]int)
+	for i := 0; i < 20; i++ {
+		wide[strconv.Itoa(i)] = i
+	}
+	data, err = Marshal(wide, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[8] != 0xDF || data[9] != 0x10 {
+		t.Fatalf("Unexpected dictionary header % x", data[8:10])
+	}
+	data[9] = 0x13 // the count now spans the next eight bytes, which are object references
+	rec, err = RecoverBinary(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := 0
+	for _, lost := range rec.Lost {
+		if strings.HasPrefix(lost, "dictionary@") {
+			notes++
+		}
+	}
+	if notes != 1 {
+		t.Errorf("Expected the truncated dictionary to be reported once, got %d notes: %q", notes, rec.Lost)
+	}
+
+	if _, err := RecoverBinary([]byte("<plist/>")); err == nil {
+		t.Error("Expected a non-binary document to be rejected")
+	}
+}
+
+func TestBplistUniquing(t *testing.T) {
+	// Below and above the number of objects that are uniqued without a map.
+	for _, n := range []int{bplistLinearUniqueLimit / 4, bplistLinearUniqueLimit * 4} {
+		arr := &cfArray{}
+		for i := 0; i < n; i++ {
+			arr.values = append(arr.values, cfString(string(rune('A'+i))), &cfNumber{value: uint64(i)}, cfData{byte(i)})
+		}
+		arr.values = append(arr.values, arr.values...)
+
+		var buf bytes.Buffer
+		newBplistGenerator(&buf).generateDocument(arr)
+		p := newBplistParser(bytes.NewReader(buf.Bytes()))
+		pval, err := p.parseDocument()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.trailer.NumObjects != uint64(1+3*n) {
+			t.Errorf("%d values: expected %d objects, found %d", n, 1+3*n, p.trailer.NumObjects)
+		}
+		if !cfValueEqual(pval, arr) {
+			t.Errorf("%d values: the document did not round-trip", n)
+		}
+	}
+}
+
+func TestBplistUTF16Decoding(t *testing.T) {
+	units := [][]uint16{
+		{},
+		{'a', 0xe9, 0x4e16},
+		{0xd83d, 0xde00, 'x'},    // a surrogate pair
+		{0xde00, 0xd83d, 'x'},    // reversed
+		{'x', 0xd83d},            // a trailing high surrogate
+		{0xd83d, 0xd83d, 0xde00}, // a lone high surrogate before a pair
+		{0xffff, 0xfffe},
+	}
+	for _, u := range units {
+		b := make([]byte, 2*len(u))
+		for i, c := range u {
+			binary.BigEndian.PutUint16(b[2*i:], c)
+		}
+		expected := string(utf16.Decode(u))
+		if s := decodeUTF16BE(b); s != expected {
+			t.Errorf("%x: expected %q, received %q", u, expected, s)
+		}
+		if allocs := testing.AllocsPerRun(10, func() { decodeUTF16BE(b) }); len(u) > 0 && allocs != 1 {
+			t.Errorf("%x: expected 1 allocation, received %v", u, allocs)
+		}
+	}
+}
+
+// buildBplist assembles a binary property list from its encoded objects, the first of which is
+// the root. The objects must refer to one another with one-byte references.
+func buildBplist(objects ...[]byte) []byte {
+	doc := []byte("bplist00")
+	offsets := make([]uint16, len(objects))
+	for i, o := range objects {
+		offsets[i] = uint16(len(doc))
+		doc = append(doc, o...)
+	}
+	offtable := len(doc)
+	for _, off := range offsets {
+		doc = append(doc, byte(off>>8), byte(off))
+	}
+	trailer := make([]byte, 32)
+	trailer[6], trailer[7] = 2, 1
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(objects)))
+	binary.BigEndian.PutUint64(trailer[24:], uint64(offtable))
+	return append(doc, trailer...)
+}
+
+// sharedBplist returns a binary property list of depth nested arrays, each of which holds the
+// next one twice, around a single string. It is a few dozen bytes long, but has 2^depth paths
+// from the root to the string.
+func sharedBplist(depth int) []byte {
+	objects := make([][]byte, 0, depth+1)
+	for i := 1; i <= depth; i++ {
+		objects = append(objects, []byte{0xA2, byte(i), byte(i)})
+	}
+	objects = append(objects, []byte{0x51, 'x'})
+	return buildBplist(objects...)
+}
@@ -0,0 +1,74 @@
+package plist
+
+import (
+	"io"
+)
+
+// A DictBuilder constructs a dictionary one key at a time, for documents whose shape is only
+// known at run time:
+//
+//	job := plist.Dict().
+//		Set("Label", "com.example.agent").
+//		Set("RunAtLoad", true).
+//		Array("ProgramArguments", "/usr/local/bin/agent", "--verbose")
+//	data, err := job.Marshal(plist.XMLFormat)
+//
+// Keys are written in the order they were set. Each value keeps the property list type of its
+// Go type, so an int is always written as an integer and a float64 as a real; a *DictBuilder
+// may be used as a value to nest one dictionary inside another.
+type DictBuilder struct {
+	dict OrderedDict
+}
+
+// Dict returns an empty DictBuilder.
+func Dict() *DictBuilder {
+	return &DictBuilder{}
+}
+
+// Set stores v under key, replacing any value it already has, and returns b.
+func (b *DictBuilder) Set(key string, v interface{}) *DictBuilder {
+	b.dict.Set(key, v)
+	return b
+}
+
+// Array stores an array of the given values under key, and returns b.
+func (b *DictBuilder) Array(key string, values ...interface{}) *DictBuilder {
+	if values == nil {
+		values = []interface{}{}
+	}
+	b.dict.Set(key, values)
+	return b
+}
+
+// Dict stores the dictionary built by fn under key, and returns b.
+func (b *DictBuilder) Dict(key string, fn func(*DictBuilder)) *DictBuilder {
+	nested := Dict()
+	fn(nested)
+	b.dict.Set(key, nested)
+	return b
+}
+
+// Build returns the dictionary constructed so far. Later changes to b do not affect it, but
+// the values it holds are shared.
+func (b *DictBuilder) Build() *OrderedDict {
+	d := &OrderedDict{}
+	for _, k := range b.dict.keys {
+		d.Set(k, b.dict.values[k])
+	}
+	return d
+}
+
+// MarshalPlist implements Marshaler.
+func (b *DictBuilder) MarshalPlist() (interface{}, error) {
+	return orderedDictContents(b.dict), nil
+}
+
+// Marshal returns the dictionary encoded in the given format, as Marshal would.
+func (b *DictBuilder) Marshal(format int) ([]byte, error) {
+	return Marshal(b, format)
+}
+
+// Encode writes the dictionary to w in the given format.
+func (b *DictBuilder) Encode(w io.Writer, format int) error {
+	return NewEncoderForFormat(w, format).Encode(b)
+}
@@ -0,0 +1,46 @@
+package plist
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDictBuilder(t *testing.T) {
+	b := Dict().
+		Set("Label", "com.example.agent").
+		Set("RunAtLoad", true).
+		Array("ProgramArguments", "/usr/local/bin/agent", "--verbose").
+		Dict("KeepAlive", func(d *DictBuilder) {
+			d.Set("SuccessfulExit", false)
+		}).
+		Set("Nice", 1).
+		Set("Weight", 1.0)
+
+	out, err := b.Marshal(OpenStepFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{Label="com.example.agent";RunAtLoad=1;ProgramArguments=(/usr/local/bin/agent,"--verbose",);KeepAlive={SuccessfulExit=0;};Nice=1;Weight=1;}`
+	if string(out) != expected {
+		t.Errorf("Expected %s, received %s", expected, out)
+	}
+
+	var buf bytes.Buffer
+	if err := b.Encode(&buf, XMLFormat); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("<key>Nice</key><integer>1</integer><key>Weight</key><real>1</real>")) {
+		t.Errorf("Expected Nice to be an integer and Weight a real:\n%s", buf.String())
+	}
+
+	d := b.Build()
+	b.Set("Disabled", true)
+	if !reflect.DeepEqual(d.Keys(), []string{"Label", "RunAtLoad", "ProgramArguments", "KeepAlive", "Nice", "Weight"}) {
+		t.Errorf("Unexpected keys %v", d.Keys())
+	}
+
+	if out, err := Dict().Array("Empty").Marshal(XMLFormat); err != nil || !bytes.Contains(out, []byte("<array/>")) {
+		t.Errorf("Expected an empty array, received %s (%v)", out, err)
+	}
+}
@@ -0,0 +1,188 @@
+package plist
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// ExpandBuildSettings expands the references to build settings in s the way Xcode does when it
+// processes an Info.plist file, using the values in settings. References take the forms
+// $(NAME), ${NAME} and $NAME; settings that are not defined expand to nothing. The values of
+// settings may themselves refer to other settings, and a name may be built from references,
+// as in $(CONFIG_$(PLATFORM)).
+//
+// A parenthesized or braced reference may apply one or more modifiers to the value, separated
+// by colons, as in $(PRODUCT_NAME:rfc1034identifier). The supported modifiers are:
+//
+//	lower, upper            change the case of the value
+//	identifier              replace each character that cannot appear in a C identifier with _
+//	c99extidentifier        as identifier, but allow non-ASCII letters and digits
+//	rfc1034identifier       replace each character other than A-Z, a-z, 0-9, - and . with -
+//	quote                   escape spaces and shell metacharacters with backslashes
+//	dir, file, base, suffix the directory, last element, last element without its extension,
+//	                        or extension of a path
+//	standardizepath         clean a path as path.Clean does
+//	default=VALUE           use VALUE if the value is empty
+//
+// An error is returned for an unknown modifier or a setting that refers to itself.
+func ExpandBuildSettings(s string, settings map[string]string) (expanded string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+	}()
+
+	e := &buildSettingExpander{settings: settings, expanding: make(map[string]bool)}
+	return e.expand(s), nil
+}
+
+type buildSettingExpander struct {
+	settings  map[string]string
+	expanding map[string]bool // the settings whose values are being expanded, to catch cycles
+}
+
+func isBuildSettingNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// closingBracket returns the index of the bracket that closes the one at s[start], or -1.
+func closingBracket(s string, start int) int {
+	open, close := s[start], byte(')')
+	if open == '{' {
+		close = '}'
+	}
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func (e *buildSettingExpander) expand(s string) string {
+	if strings.IndexByte(s, '$') < 0 {
+		return s
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			out.WriteByte(s[i])
+			continue
+		}
+		switch c := s[i+1]; {
+		case c == '(' || c == '{':
+			end := closingBracket(s, i+1)
+			if end < 0 {
+				out.WriteByte(s[i])
+				continue
+			}
+			out.WriteString(e.reference(e.expand(s[i+2 : end])))
+			i = end
+		case isBuildSettingNameStart(c):
+			end := i + 2
+			for end < len(s) && isVariableNameByte(s[end]) {
+				end++
+			}
+			out.WriteString(e.reference(s[i+1 : end]))
+			i = end - 1
+		default:
+			out.WriteByte(s[i])
+		}
+	}
+	return out.String()
+}
+
+// reference returns the value of a reference, which consists of a setting's name followed by
+// any modifiers.
+func (e *buildSettingExpander) reference(ref string) string {
+	parts := strings.Split(ref, ":")
+	name := parts[0]
+	if e.expanding[name] {
+		panic(fmt.Errorf("plist: build setting %s refers to itself", name))
+	}
+	e.expanding[name] = true
+	value := e.expand(e.settings[name])
+	delete(e.expanding, name)
+
+	for i := 1; i < len(parts); i++ {
+		modifier := parts[i]
+		if strings.HasPrefix(modifier, "default=") {
+			// The default value extends to the end of the reference, colons and all.
+			if value == "" {
+				value = strings.Join(parts[i:], ":")[len("default="):]
+			}
+			break
+		}
+		value = applyBuildSettingModifier(modifier, value)
+	}
+	return value
+}
+
+func applyBuildSettingModifier(modifier, value string) string {
+	switch modifier {
+	case "lower":
+		return strings.ToLower(value)
+	case "upper":
+		return strings.ToUpper(value)
+	case "identifier", "c99extidentifier":
+		ext := modifier == "c99extidentifier"
+		id := strings.Map(func(r rune) rune {
+			if r < 0x80 && isVariableNameByte(byte(r)) || ext && r >= 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return r
+			}
+			return '_'
+		}, value)
+		if id != "" && id[0] >= '0' && id[0] <= '9' {
+			id = "_" + id
+		}
+		return id
+	case "rfc1034identifier":
+		return strings.Map(func(r rune) rune {
+			if r == '-' || r == '.' || r < 0x80 && r != '_' && isVariableNameByte(byte(r)) {
+				return r
+			}
+			return '-'
+		}, value)
+	case "quote":
+		var out strings.Builder
+		for _, r := range value {
+			if strings.ContainsRune(" \t\n\\'\"`$&|;<>()[]{}*?!#~", r) {
+				out.WriteByte('\\')
+			}
+			out.WriteRune(r)
+		}
+		return out.String()
+	case "dir":
+		if i := strings.LastIndexByte(value, '/'); i >= 0 {
+			return value[:i+1]
+		}
+		return "./"
+	case "file":
+		return value[strings.LastIndexByte(value, '/')+1:]
+	case "base":
+		file := value[strings.LastIndexByte(value, '/')+1:]
+		return strings.TrimSuffix(file, path.Ext(file))
+	case "suffix":
+		return path.Ext(value[strings.LastIndexByte(value, '/')+1:])
+	case "standardizepath":
+		if value == "" {
+			return value
+		}
+		return path.Clean(value)
+	}
+	panic(fmt.Errorf("plist: unknown build setting modifier %q", modifier))
+}
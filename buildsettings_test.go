@@ -0,0 +1,78 @@
+package plist
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandBuildSettings(t *testing.T) {
+	settings := map[string]string{
+		"PRODUCT_NAME":              "My App_2",
+		"PRODUCT_BUNDLE_IDENTIFIER": "com.example.$(PRODUCT_NAME:rfc1034identifier)",
+		"PLATFORM":                  "IOS",
+		"CONFIG_IOS":                "phone",
+		"PATH_VALUE":                "/a/b/../c/file.tar.gz",
+		"LOOP":                      "$(LOOP2)",
+		"LOOP2":                     "${LOOP}",
+		"NUMBER":                    "1st item",
+	}
+
+	tests := []struct {
+		in, out string
+	}{
+		{"$(PRODUCT_BUNDLE_IDENTIFIER)", "com.example.My-App-2"},
+		{"${PRODUCT_NAME:lower} $PRODUCT_NAME:upper", "my app_2 My App_2:upper"},
+		{"$(PRODUCT_NAME:identifier) $(NUMBER:c99extidentifier)", "My_App_2 _1st_item"},
+		{"$(CONFIG_$(PLATFORM))", "phone"},
+		{"[$(UNDEFINED)] $(UNDEFINED:default=x:y) $(PLATFORM:default=z)", "[] x:y IOS"},
+		{"$(PRODUCT_NAME:quote)", `My\ App_2`},
+		{"$(PATH_VALUE:dir) $(PATH_VALUE:file) $(PATH_VALUE:base) $(PATH_VALUE:suffix)", "/a/b/../c/ file.tar.gz file.tar .gz"},
+		{"$(PATH_VALUE:standardizepath)", "/a/c/file.tar.gz"},
+		{"$ $$ $(unterminated $1", "$ $$ $(unterminated $1"},
+	}
+	for _, test := range tests {
+		out, err := ExpandBuildSettings(test.in, settings)
+		if err != nil {
+			t.Errorf("%s: %v", test.in, err)
+		} else if out != test.out {
+			t.Errorf("%s: expected %q, received %q", test.in, test.out, out)
+		}
+	}
+
+	for _, in := range []string{"$(LOOP)", "$(PRODUCT_NAME:bogus)"} {
+		if _, err := ExpandBuildSettings(in, settings); err == nil {
+			t.Errorf("%s: expected an error", in)
+		}
+	}
+}
+
+func TestDecodeBuildSettings(t *testing.T) {
+	doc := `<plist version="1.0"><dict>
+		<key>CFBundleIdentifier</key><string>$(PRODUCT_BUNDLE_IDENTIFIER)</string>
+		<key>CFBundleName</key><string>${PRODUCT_NAME}</string>
+		<key>CFBundleVersion</key><integer>1</integer>
+	</dict></plist>`
+
+	var v map[string]interface{}
+	d := NewDecoder(strings.NewReader(doc), WithBuildSettings(map[string]string{
+		"PRODUCT_NAME":              "Hello World",
+		"PRODUCT_BUNDLE_IDENTIFIER": "com.example.$(PRODUCT_NAME:rfc1034identifier)",
+	}))
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"CFBundleIdentifier": "com.example.Hello-World",
+		"CFBundleName":       "Hello World",
+		"CFBundleVersion":    uint64(1),
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("Expected %#v, received %#v", expected, v)
+	}
+
+	d = NewDecoder(strings.NewReader(doc), WithBuildSettings(map[string]string{"PRODUCT_NAME": "$(PRODUCT_NAME)"}))
+	if err := d.Decode(&v); err == nil {
+		t.Error("Expected an error for a setting that refers to itself")
+	}
+}
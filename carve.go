@@ -0,0 +1,174 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+var (
+	carveBinaryMagic = []byte("bplist0")
+	carveXMLMagic    = []byte("<?xml")
+	carveXMLEnd      = []byte("</plist>")
+)
+
+// A CarvedPlist is a property list found by a Carver.
+type CarvedPlist struct {
+	Offset int    // the position of the property list in the Carver's input
+	Format int    // BinaryFormat or XMLFormat
+	Data   []byte // the property list, which shares storage with the Carver's input
+}
+
+// A Carver finds the property lists embedded in arbitrary data, such as memory dumps, log files
+// and database blobs. It recognizes binary property lists by their "bplist0" header, and XML
+// property lists by their <?xml prolog; each candidate must parse successfully to be reported.
+//
+// Property lists nested inside another that has been found (such as a binary property list
+// stored in a data value) are not reported separately.
+//
+// A Carver is used in the manner of bufio.Scanner:
+//
+//	c := plist.NewCarver(dump)
+//	for c.Next() {
+//		found := c.Plist()
+//		...
+//	}
+type Carver struct {
+	data []byte
+	pos  int
+	cur  CarvedPlist
+
+	// trailers maps the start of each binary property list header to the positions of the
+	// trailers that could end it, in order. It is built on first use.
+	trailers map[int][]int
+}
+
+// NewCarver returns a Carver that searches data.
+func NewCarver(data []byte) *Carver {
+	return &Carver{data: data}
+}
+
+// Next advances to the next property list in the input, which will then be available through
+// Plist. It returns false when there are no more property lists.
+func (c *Carver) Next() bool {
+	for c.pos < len(c.data) {
+		start, format := c.nextCandidate()
+		if start < 0 {
+			c.pos = len(c.data)
+			return false
+		}
+
+		var end int
+		if format == BinaryFormat {
+			end = c.binaryEnd(start)
+		} else {
+			end = c.xmlEnd(start)
+		}
+
+		if end < 0 {
+			c.pos = start + 1
+			continue
+		}
+
+		c.cur = CarvedPlist{Offset: start, Format: format, Data: c.data[start:end:end]}
+		c.pos = end
+		return true
+	}
+	return false
+}
+
+// Plist returns the property list most recently found by Next.
+func (c *Carver) Plist() CarvedPlist {
+	return c.cur
+}
+
+// nextCandidate returns the position and format of the next property list header, or -1.
+func (c *Carver) nextCandidate() (int, int) {
+	rest := c.data[c.pos:]
+	bin := bytes.Index(rest, carveBinaryMagic)
+	xml := bytes.Index(rest, carveXMLMagic)
+	switch {
+	case bin < 0 && xml < 0:
+		return -1, 0
+	case xml < 0 || bin >= 0 && bin < xml:
+		return c.pos + bin, BinaryFormat
+	}
+	return c.pos + xml, XMLFormat
+}
+
+// binaryEnd returns the end of the binary property list beginning at start, or -1.
+//
+// Binary property lists do not record their length; they end with a trailer that locates the
+// offset table immediately preceding it. Each trailer that is consistent with a document
+// beginning at start is tried in turn, and the first that produces a valid document is taken
+// to be its end.
+func (c *Carver) binaryEnd(start int) int {
+	if c.trailers == nil {
+		c.indexTrailers()
+	}
+	for _, t := range c.trailers[start] {
+		if c.parses(c.data[start:t+32], BinaryFormat) {
+			return t + 32
+		}
+	}
+	return -1
+}
+
+// indexTrailers finds, in a single pass over the input, every position that could hold a
+// binary property list trailer, and records it under the start of the document it implies
+// (the offset table's offset, plus its length, before the trailer) if a header is there.
+func (c *Carver) indexTrailers() {
+	c.trailers = make(map[int][]int)
+	for t := 0; t+32 <= len(c.data); t++ {
+		trailer := c.data[t : t+32]
+		if !bytes.Equal(trailer[:5], []byte{0, 0, 0, 0, 0}) {
+			continue
+		}
+		offsetIntSize, objectRefSize := uint64(trailer[6]), uint64(trailer[7])
+		if !validBplistIntSize(int(offsetIntSize)) || offsetIntSize == 0 || !validBplistIntSize(int(objectRefSize)) || objectRefSize == 0 {
+			continue
+		}
+		numObjects := binary.BigEndian.Uint64(trailer[8:])
+		topObject := binary.BigEndian.Uint64(trailer[16:])
+		offsetTableOffset := binary.BigEndian.Uint64(trailer[24:])
+		if numObjects == 0 || topObject >= numObjects || numObjects > uint64(t) {
+			continue
+		}
+		length := numObjects * offsetIntSize
+		if length > uint64(t) || offsetTableOffset > uint64(t)-length {
+			continue
+		}
+
+		start := t - int(offsetTableOffset+length)
+		if t-start < 9 || uint64(t-start) < numObjects || !bytes.HasPrefix(c.data[start:], carveBinaryMagic) {
+			continue
+		}
+		c.trailers[start] = append(c.trailers[start], t)
+	}
+}
+
+// xmlEnd returns the end of the XML property list beginning at start, or -1.
+func (c *Carver) xmlEnd(start int) int {
+	// The prolog must be complete; encoding/xml would otherwise accept a stray "<?xml" that
+	// happened to precede a real prolog as part of it.
+	prolog := bytes.Index(c.data[start:], []byte("?>"))
+	if prolog < 0 || bytes.IndexByte(c.data[start+1:start+prolog], '<') >= 0 {
+		return -1
+	}
+
+	i := bytes.Index(c.data[start:], carveXMLEnd)
+	if i < 0 {
+		return -1
+	}
+	end := start + i + len(carveXMLEnd)
+	if !c.parses(c.data[start:end], XMLFormat) {
+		return -1
+	}
+	return end
+}
+
+// parses reports whether doc is a valid property list of the given format.
+func (c *Carver) parses(doc []byte, format int) bool {
+	d := NewDecoder(bytes.NewReader(doc))
+	_, err := d.parseDocument()
+	return err == nil && d.Format == format
+}
@@ -0,0 +1,78 @@
+package plist
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCarver(t *testing.T) {
+	bin, err := Marshal(map[string]interface{}{"kind": "binary", "nested": []byte("bplist00 lookalike")}, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xml, err := MarshalIndent(map[string]interface{}{"kind": "xml"}, XMLFormat, "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dump bytes.Buffer
+	dump.WriteString("\x00\x01garbage bplist00 that is not a plist\xff")
+	binOffset := dump.Len()
+	dump.Write(bin)
+	dump.WriteString("log line <?xml but nothing more\n")
+	xmlOffset := dump.Len()
+	dump.Write(xml)
+	dump.Write(bin[:len(bin)-4]) // truncated, so not reported
+	dump.WriteString("trailing bytes")
+
+	var found []CarvedPlist
+	c := NewCarver(dump.Bytes())
+	for c.Next() {
+		found = append(found, c.Plist())
+	}
+
+	if len(found) != 2 {
+		t.Fatalf("Expected 2 property lists, found %d: %+v", len(found), found)
+	}
+	expected := []CarvedPlist{
+		{Offset: binOffset, Format: BinaryFormat, Data: bin},
+		{Offset: xmlOffset, Format: XMLFormat, Data: xml},
+	}
+	for i := range expected {
+		if found[i].Offset != expected[i].Offset || found[i].Format != expected[i].Format || !bytes.Equal(found[i].Data, expected[i].Data) {
+			t.Errorf("Property list %d: expected offset %d format %d, got offset %d format %d (%d bytes)", i,
+				expected[i].Offset, expected[i].Format, found[i].Offset, found[i].Format, len(found[i].Data))
+		}
+
+		var v map[string]interface{}
+		if _, err := Unmarshal(found[i].Data, &v); err != nil {
+			t.Error(err)
+		}
+	}
+
+	var v map[string]interface{}
+	Unmarshal(found[0].Data, &v)
+	if !reflect.DeepEqual(v["kind"], "binary") {
+		t.Errorf("Unexpected carved document %v", v)
+	}
+}
+
+func BenchmarkCarver(b *testing.B) {
+	// Every header is a false start, which must not cost a search of the rest of the input.
+	bin, err := Marshal(map[string]interface{}{"kind": "binary"}, BinaryFormat)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var dump bytes.Buffer
+	for dump.Len() < 256<<10 {
+		dump.Write(bin[:len(bin)-4])
+	}
+	b.SetBytes(int64(dump.Len()))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for c := NewCarver(dump.Bytes()); c.Next(); {
+		}
+	}
+}
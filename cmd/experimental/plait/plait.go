@@ -2,10 +2,15 @@ package main
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"os"
-	"syscall/js"
+	"strconv"
+	"strings"
+	"time"
 
 	"howett.net/plist"
 )
@@ -20,49 +25,228 @@ var nameFormatMap = map[string]int{
 	"json":     JSONFormat,
 }
 
-func main() {
-	convert := os.Args[1]
-	format, ok := nameFormatMap[convert]
-	if !ok {
-		fmt.Fprintf(os.Stderr, "unknown output format %s\n", convert)
-		return
-	}
-
-	jsConverter := js.Global().Get("ply")
-	jsDocumentLength := jsConverter.Call("readDocument").Int()
-	document := make([]byte, jsDocumentLength)
-	jsDocumentTemp := js.TypedArrayOf(document)
-	jsConverter.Call("readDocument", jsDocumentTemp, jsDocumentLength)
-	jsDocumentTemp.Release()
-
-	file := bytes.NewReader(document)
-	outfile := &bytes.Buffer{}
-
+// convert decodes a property list in any format and re-encodes it in the given format.
+func convert(document []byte, format int, indent string, opts jsonOptions) ([]byte, error) {
 	var val interface{}
-	dec := plist.NewDecoder(file)
+	dec := plist.NewDecoder(bytes.NewReader(document))
 	err := dec.Decode(&val)
 
 	if err != nil {
-		bail(err)
+		return nil, err
 	}
+	return encode(val, format, indent, opts)
+}
+
+// encode writes val in the given format. opts controls how values that JSON lacks are written.
+func encode(val interface{}, format int, indent string, opts jsonOptions) ([]byte, error) {
+	outfile := &bytes.Buffer{}
 
+	var err error
 	if format == JSONFormat {
+		if val, err = opts.jsonValue(val); err != nil {
+			return nil, err
+		}
 		enc := json.NewEncoder(outfile)
-		enc.SetIndent("", "\t")
+		enc.SetIndent("", indent)
 		err = enc.Encode(val)
 	} else {
 		enc := plist.NewEncoderForFormat(outfile, format)
-		enc.Indent("\t")
+		enc.Indent(indent)
 		err = enc.Encode(val)
 	}
 
 	if err != nil {
-		bail(err)
+		return nil, err
+	}
+	return outfile.Bytes(), nil
+}
+
+// jsonOptions controls how the property list types that JSON lacks are mapped onto JSON values,
+// and back. The zero value writes dates as RFC 3339 strings, data as base64 strings, UIDs as
+// numbers and rejects NaN and infinite reals, and reads JSON strings and numbers as they are.
+type jsonOptions struct {
+	dates      string // how dates are written: "rfc3339", "unix" or "apple" (seconds since that epoch)
+	data       string // how data is written: "base64" or "array" (of byte values)
+	uids       string // how UIDs are written: "number" or "object" ({"CF$UID": n}, as plutil does)
+	nonFinite  string // how NaN and infinite reals are written: "error", "null" or "string"
+	dataPrefix string // base64 data is written after this prefix, and strings beginning with it are read as data
+	parseDates bool   // strings in RFC 3339 format are read as dates
+}
+
+// jsonPolicies lists the accepted values of each jsonOptions policy; the first is the default.
+var jsonPolicies = map[string][]string{
+	"dates":     {"rfc3339", "unix", "apple"},
+	"data":      {"base64", "array"},
+	"uids":      {"number", "object"},
+	"nonFinite": {"error", "null", "string"},
+}
+
+// validate returns an error if any policy in opts is not one of those listed in jsonPolicies.
+func (opts jsonOptions) validate() error {
+	for name, val := range map[string]string{"dates": opts.dates, "data": opts.data, "uids": opts.uids, "nonFinite": opts.nonFinite} {
+		if val == "" {
+			continue
+		}
+		valid := false
+		for _, p := range jsonPolicies[name] {
+			valid = valid || val == p
+		}
+		if !valid {
+			return fmt.Errorf("unknown %s policy %q (expected one of %s)", name, val, strings.Join(jsonPolicies[name], ", "))
+		}
+	}
+	return nil
+}
+
+// uidKey is the key of the single-entry dictionary that stands for a UID in JSON.
+const uidKey = "CF$UID"
+
+// nonFiniteStrings are the strings NaN and the infinities are written as under the "string"
+// policy, matching the spelling used by JavaScript.
+var nonFiniteStrings = map[string]float64{
+	"NaN":       math.NaN(),
+	"Infinity":  math.Inf(1),
+	"-Infinity": math.Inf(-1),
+}
+
+// jsonValue converts a decoded property list value into one that encoding/json writes according
+// to opts.
+func (opts jsonOptions) jsonValue(val interface{}) (interface{}, error) {
+	switch val := val.(type) {
+	case time.Time:
+		switch opts.dates {
+		case "unix":
+			return epochSeconds(plist.UnixEpoch, val), nil
+		case "apple":
+			return plist.AbsoluteTime(val), nil
+		}
+		return val.UTC().Format(time.RFC3339Nano), nil
+	case []byte:
+		if opts.data == "array" {
+			a := make([]int, len(val))
+			for i, b := range val {
+				a[i] = int(b)
+			}
+			return a, nil
+		}
+		return opts.dataPrefix + base64.StdEncoding.EncodeToString(val), nil
+	case plist.UID:
+		if opts.uids == "object" {
+			return map[string]interface{}{uidKey: uint64(val)}, nil
+		}
+		return uint64(val), nil
+	case float32:
+		return opts.jsonReal(float64(val))
+	case float64:
+		return opts.jsonReal(val)
+	case []interface{}:
+		for i := range val {
+			v, err := opts.jsonValue(val[i])
+			if err != nil {
+				return nil, err
+			}
+			val[i] = v
+		}
+	case map[string]interface{}:
+		for k := range val {
+			v, err := opts.jsonValue(val[k])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", k, err)
+			}
+			val[k] = v
+		}
 	}
+	return val, nil
+}
+
+func (opts jsonOptions) jsonReal(f float64) (interface{}, error) {
+	if !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return f, nil
+	}
+	switch opts.nonFinite {
+	case "null":
+		return nil, nil
+	case "string":
+		for s, v := range nonFiniteStrings {
+			if math.IsNaN(f) && math.IsNaN(v) || f == v {
+				return s, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("real %v has no JSON equivalent", f)
+}
 
-	a := js.TypedArrayOf(outfile.Bytes())
-	jsConverter.Call("writeDocument", a)
-	a.Release()
+// epochSeconds returns the number of seconds from epoch to t.
+func epochSeconds(epoch, t time.Time) float64 {
+	return float64(t.Unix()-epoch.Unix()) + float64(t.Nanosecond()-epoch.Nanosecond())/float64(time.Second)
+}
+
+// decodeJSON decodes a JSON document into the values a property list would decode to.
+func decodeJSON(document []byte, opts jsonOptions) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(document))
+	dec.UseNumber()
+
+	var val interface{}
+	if err := dec.Decode(&val); err != nil {
+		return nil, err
+	}
+	return opts.plistValue(val)
+}
+
+func (opts jsonOptions) plistValue(val interface{}) (interface{}, error) {
+	switch val := val.(type) {
+	case nil:
+		return nil, errors.New("JSON null has no property list equivalent")
+	case json.Number:
+		if i, err := strconv.ParseInt(string(val), 10, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(string(val), 10, 64); err == nil {
+			return u, nil
+		}
+		return val.Float64()
+	case string:
+		if opts.dataPrefix != "" && strings.HasPrefix(val, opts.dataPrefix) {
+			data, err := base64.StdEncoding.DecodeString(val[len(opts.dataPrefix):])
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 data %q: %v", val, err)
+			}
+			return data, nil
+		}
+		if opts.parseDates {
+			if t, err := time.Parse(time.RFC3339, val); err == nil {
+				return t, nil
+			}
+		}
+		if opts.nonFinite == "string" {
+			if f, ok := nonFiniteStrings[val]; ok {
+				return f, nil
+			}
+		}
+		return val, nil
+	case []interface{}:
+		for i := range val {
+			v, err := opts.plistValue(val[i])
+			if err != nil {
+				return nil, err
+			}
+			val[i] = v
+		}
+	case map[string]interface{}:
+		if n, ok := val[uidKey].(json.Number); ok && len(val) == 1 && opts.uids == "object" {
+			if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+				return plist.UID(u), nil
+			}
+		}
+		for k := range val {
+			v, err := opts.plistValue(val[k])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", k, err)
+			}
+			val[k] = v
+		}
+	}
+	return val, nil
 }
 
 func bail(err error) {
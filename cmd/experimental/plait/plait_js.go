@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall/js"
+)
+
+func main() {
+	convertTo := os.Args[1]
+	format, ok := nameFormatMap[convertTo]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown output format %s\n", convertTo)
+		return
+	}
+
+	jsConverter := js.Global().Get("ply")
+	jsDocumentLength := jsConverter.Call("readDocument").Int()
+	document := make([]byte, jsDocumentLength)
+	jsDocumentTemp := js.TypedArrayOf(document)
+	jsConverter.Call("readDocument", jsDocumentTemp, jsDocumentLength)
+	jsDocumentTemp.Release()
+
+	output, err := convert(document, format, "\t", jsonOptions{})
+	if err != nil {
+		bail(err)
+	}
+
+	a := js.TypedArrayOf(output)
+	jsConverter.Call("writeDocument", a)
+	a.Release()
+}
@@ -0,0 +1,136 @@
+// +build !js
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	formatName := flag.String("f", "xml", "output `format` (xml, binary, openstep, gnustep or json)")
+	indent := flag.String("i", "\t", "indent `string` for formats that support indentation; empty for compact output")
+	output := flag.String("o", "", "write the output to `file` instead of standard output")
+	inPlace := flag.Bool("w", false, "overwrite each input file with its converted contents")
+	fromJSON := flag.Bool("json", false, "read JSON rather than property lists")
+	var jsonOpts jsonOptions
+	flag.StringVar(&jsonOpts.dataPrefix, "json-data", "", "write data to JSON as base64 after `prefix` (such as \"base64:\"), and with -json, decode strings beginning with it as data")
+	flag.BoolVar(&jsonOpts.parseDates, "json-dates", false, "with -json, treat strings in RFC 3339 format as dates")
+	flag.StringVar(&jsonOpts.dates, "dates", "rfc3339", "write dates to JSON as `policy`: rfc3339, or seconds since the unix or apple epoch")
+	flag.StringVar(&jsonOpts.data, "data", "base64", "write data to JSON as `policy`: base64 strings, or arrays of byte values")
+	flag.StringVar(&jsonOpts.uids, "uids", "number", "write UIDs to JSON as `policy`: number, or {\"CF$UID\": n} objects (read back with -json)")
+	flag.StringVar(&jsonOpts.nonFinite, "nonfinite", "error", "write NaN and infinite reals to JSON as `policy`: error, null, or string (\"NaN\", \"Infinity\" or \"-Infinity\", read back with -json)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] [file ...]\n\nConverts property lists (or JSON documents) from files, or from standard input if none are named.\n\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	format, ok := nameFormatMap[*formatName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown output format %s\n", *formatName)
+		os.Exit(2)
+	}
+	if err := jsonOpts.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	convert := convert
+	if *fromJSON {
+		convert = func(document []byte, format int, indent string, opts jsonOptions) ([]byte, error) {
+			val, err := decodeJSON(document, opts)
+			if err != nil {
+				return nil, err
+			}
+			return encode(val, format, indent, opts)
+		}
+	}
+
+	files := flag.Args()
+	switch {
+	case *inPlace && len(files) == 0:
+		fmt.Fprintln(os.Stderr, "-w requires at least one file")
+		os.Exit(2)
+	case *inPlace && *output != "":
+		fmt.Fprintln(os.Stderr, "-w and -o cannot be used together")
+		os.Exit(2)
+	case !*inPlace && len(files) > 1:
+		fmt.Fprintln(os.Stderr, "converting more than one file requires -w")
+		os.Exit(2)
+	}
+
+	if *inPlace {
+		for _, name := range files {
+			document, err := ioutil.ReadFile(name)
+			if err != nil {
+				bail(err)
+			}
+			converted, err := convert(document, format, *indent, jsonOpts)
+			if err != nil {
+				bail(fmt.Errorf("%s: %v", name, err))
+			}
+			if err := writeFileInPlace(name, converted); err != nil {
+				bail(err)
+			}
+		}
+		return
+	}
+
+	var document []byte
+	var err error
+	if len(files) == 0 || files[0] == "-" {
+		document, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		document, err = ioutil.ReadFile(files[0])
+	}
+	if err != nil {
+		bail(err)
+	}
+
+	converted, err := convert(document, format, *indent, jsonOpts)
+	if err != nil {
+		bail(err)
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(converted)
+	} else {
+		err = ioutil.WriteFile(*output, converted, 0644)
+	}
+	if err != nil {
+		bail(err)
+	}
+}
+
+// writeFileInPlace replaces the contents of the file name, keeping its permissions. The new
+// contents are written to a temporary file that is then renamed over the original, so that the
+// original survives a failed write.
+func writeFileInPlace(name string, data []byte) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
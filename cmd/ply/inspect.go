@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"time"
+
+	"howett.net/plist"
+)
+
+// walkKeypaths calls fn for every value beneath val, in order, with the keypath that leads to it
+// (relative to path, the keypath of val) and the dictionary key it is stored under, if any.
+func walkKeypaths(val interface{}, path string, fn func(path, key string, val interface{})) {
+	tv := reflect.ValueOf(val)
+	switch tv.Kind() {
+	case reflect.Map:
+		keys := make([]string, 0, tv.Len())
+		for _, kval := range tv.MapKeys() {
+			if kval.Kind() == reflect.Interface {
+				kval = kval.Elem()
+			}
+			if kval.Kind() == reflect.String {
+				keys = append(keys, kval.String())
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v := tv.MapIndex(reflect.ValueOf(k).Convert(tv.Type().Key())).Interface()
+			fn(path+"/"+k, k, v)
+			walkKeypaths(v, path+"/"+k, fn)
+		}
+	case reflect.Slice:
+		if _, ok := val.([]byte); ok {
+			return
+		}
+		for i := 0; i < tv.Len(); i++ {
+			subpath := fmt.Sprintf("%s[%d]", path, i)
+			v := tv.Index(i).Interface()
+			fn(subpath, "", v)
+			walkKeypaths(v, subpath, fn)
+		}
+	}
+}
+
+// scalarString formats val for display after its keypath, and reports whether it is a scalar
+// (rather than a dictionary or array.)
+func scalarString(val interface{}) (string, bool) {
+	switch tv := val.(type) {
+	case string:
+		return tv, true
+	case []byte:
+		return fmt.Sprintf("<%x>", tv), true
+	case plist.UID:
+		return fmt.Sprintf("#%d", uint64(tv)), true
+	case time.Time:
+		return tv.Format(time.RFC3339), true
+	}
+	switch reflect.ValueOf(val).Kind() {
+	case reflect.Map, reflect.Slice:
+		return "", false
+	}
+	return fmt.Sprintf("%v", val), true
+}
+
+// ListKeypaths writes the keypath of every value beneath val, one to a line.
+func ListKeypaths(w io.Writer, val interface{}, path string) {
+	walkKeypaths(val, path, func(path, key string, val interface{}) {
+		fmt.Fprintln(w, path)
+	})
+}
+
+// GrepKeypaths writes the keypath of every value beneath val whose key, or whose value if it is
+// a scalar, matches re. Scalars are written with their values.
+func GrepKeypaths(w io.Writer, re *regexp.Regexp, val interface{}, path string) {
+	walkKeypaths(val, path, func(path, key string, val interface{}) {
+		s, scalar := scalarString(val)
+		if !(key != "" && re.MatchString(key)) && !(scalar && re.MatchString(s)) {
+			return
+		}
+		if scalar {
+			fmt.Fprintf(w, "%s: %s\n", path, s)
+		} else {
+			fmt.Fprintln(w, path)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"howett.net/plist"
+)
+
+// MergeFiles merges the property lists in the files ours and theirs, both derived from base,
+// and replaces ours with the result, as a git merge driver does. Conflicts are described on w;
+// it returns the number of them.
+func MergeFiles(w io.Writer, base, ours, theirs string) (int, error) {
+	var docs [3][]byte
+	for i, filename := range []string{base, ours, theirs} {
+		var err error
+		if docs[i], err = ioutil.ReadFile(filename); err != nil {
+			return 0, err
+		}
+	}
+
+	var buf bytes.Buffer
+	conflicts, err := plist.Merge(&buf, docs[0], docs[1], docs[2])
+	if err != nil {
+		return 0, err
+	}
+	for _, c := range conflicts {
+		fmt.Fprintf(w, "conflict at /%s: base %s, ours %s, theirs %s\n", strings.Join(c.Path, "/"), mergeValueString(c.Base), mergeValueString(c.Ours), mergeValueString(c.Theirs))
+	}
+	return len(conflicts), ioutil.WriteFile(ours, buf.Bytes(), 0666)
+}
+
+func mergeValueString(val interface{}) string {
+	if val == nil {
+		return "absent"
+	}
+	if s, ok := scalarString(val); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	if reflect.ValueOf(val).Kind() == reflect.Map {
+		return "a dictionary"
+	}
+	return "an array"
+}
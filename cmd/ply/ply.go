@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -59,6 +60,7 @@ var opts struct {
 
 func main() {
 	parser := flags.NewParser(&opts, flags.Default)
+	parser.Usage = "[OPTIONS] <file>\n  ply [OPTIONS] keys <file>\n  ply [OPTIONS] grep <regex> <file>\n  ply merge <base> <ours> <theirs>"
 	args, err := parser.Parse()
 	if err != nil {
 		// flags.Default implies flags.PrintError; there's no reason to print it here
@@ -83,6 +85,35 @@ func main() {
 		return
 	}
 
+	// "merge" is used as a git merge driver: it replaces ours with the merged property list, and
+	// fails if there were conflicts.
+	if len(args) == 4 && args[0] == "merge" {
+		conflicts, err := MergeFiles(os.Stderr, args[1], args[2], args[3])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(2)
+		}
+		if conflicts > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// "keys" and "grep" list keypaths instead of printing or converting the property list.
+	command := ""
+	var pattern *regexp.Regexp
+	switch {
+	case len(args) >= 2 && args[0] == "keys":
+		command, args = args[0], args[1:]
+	case len(args) >= 3 && args[0] == "grep":
+		pattern, err = regexp.Compile(args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return
+		}
+		command, args = args[0], args[2:]
+	}
+
 	filename := args[0]
 
 	keypath := opts.Keypath
@@ -117,6 +148,28 @@ func main() {
 	}
 	file.Close()
 
+	keypathContext := &KeypathWalker{}
+	rval, err := keypathContext.WalkKeypath(reflect.ValueOf(val), keypath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return
+	}
+	val = rval.Interface()
+
+	// Keypaths are listed in full, beginning with the one given by -k.
+	base := strings.Trim(keypath, "/")
+	if base != "" {
+		base = "/" + base
+	}
+	switch command {
+	case "keys":
+		ListKeypaths(os.Stdout, val, base)
+		return
+	case "grep":
+		GrepKeypaths(os.Stdout, pattern, val, base)
+		return
+	}
+
 	convert := strings.ToLower(opts.Convert)
 	format, ok := nameFormatMap[convert]
 	if !ok {
@@ -149,14 +202,6 @@ func main() {
 		outputStream = outfile
 	}
 
-	keypathContext := &KeypathWalker{}
-	rval, err := keypathContext.WalkKeypath(reflect.ValueOf(val), keypath)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		return
-	}
-	val = rval.Interface()
-
 	switch {
 	case format >= 0 && format < PrettyFormat:
 		enc := plist.NewEncoderForFormat(outputStream, format)
@@ -0,0 +1,122 @@
+package plist
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"runtime"
+)
+
+// sameFormat asks reencode to write a document in the format it was read in.
+const sameFormat = -1
+
+// reencode parses the property list in src, which may be in any format, and writes the value
+// returned by fn to w in the given format (or in the format of src, for sameFormat). fn is
+// given the parsed document, along with the Decoder that parsed it and the Encoder that will
+// write the result, which it may configure; if it returns an error, nothing is written.
+//
+// As no Go types are involved, values keep their exact form (including the width of each number
+// and any UIDs) wherever the format can express it.
+func reencode(w io.Writer, src []byte, format int, fn func(d *Decoder, enc *Encoder, pval cfValue) (cfValue, error)) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+	}()
+
+	d := NewDecoder(bytes.NewReader(src))
+	pval, err := d.parseDocument()
+	if err != nil {
+		return err
+	}
+
+	if format == sameFormat {
+		format = d.Format
+	}
+	enc := NewEncoderForFormat(w, format)
+	enc.validate()
+	enc.fractionalSeconds = format == GNUStepFormat && d.Format == GNUStepFormat // don't lose precision from GNUStep dates
+
+	if pval, err = fn(d, enc, pval); err != nil {
+		return err
+	}
+	enc.generate(pval)
+	return nil
+}
+
+// convert writes the property list in src, which may be in any format, to w in the given
+// format. Like Indent, it works on the parsed document, so values keep their exact form
+// (including the width of each number and any UIDs) wherever the target format can express it.
+func convert(w io.Writer, src []byte, format int) error {
+	return reencode(w, src, format, func(d *Decoder, enc *Encoder, pval cfValue) (cfValue, error) {
+		return pval, nil
+	})
+}
+
+type convertingReader struct {
+	r      io.Reader
+	format int
+	out    *bytes.Reader
+	err    error
+}
+
+// NewConvertingReader returns a reader that yields the property list read from r, which may be
+// in any format, re-encoded in the given format. The whole of r is read and converted on the
+// first call to Read; any error in doing so is returned by that call and every one after it.
+func NewConvertingReader(r io.Reader, format int) io.Reader {
+	return &convertingReader{r: r, format: format}
+}
+
+func (c *convertingReader) Read(b []byte) (int, error) {
+	if c.out == nil && c.err == nil {
+		src, err := ioutil.ReadAll(c.r)
+		if err != nil {
+			c.err = err
+			return 0, err
+		}
+		var buf bytes.Buffer
+		if c.err = convert(&buf, src, c.format); c.err != nil {
+			return 0, c.err
+		}
+		c.out = bytes.NewReader(buf.Bytes())
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.out.Read(b)
+}
+
+type convertingWriter struct {
+	w      io.Writer
+	format int
+	buf    bytes.Buffer
+	closed bool
+}
+
+// NewConvertingWriter returns a writer that accepts a property list in any format and writes it
+// to w re-encoded in the given format. A property list cannot be converted until all of it has
+// been seen, so nothing is written to w until Close is called. Close does not close w.
+func NewConvertingWriter(w io.Writer, format int) io.WriteCloser {
+	return &convertingWriter{w: w, format: format}
+}
+
+func (c *convertingWriter) Write(b []byte) (int, error) {
+	if c.closed {
+		return 0, errors.New("plist: write to closed converting writer")
+	}
+	return c.buf.Write(b)
+}
+
+// Close converts the property list written so far and writes the result to the underlying
+// writer.
+func (c *convertingWriter) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return convert(c.w, c.buf.Bytes(), c.format)
+}
@@ -0,0 +1,65 @@
+package plist
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConvertingReader(t *testing.T) {
+	in := map[string]interface{}{"name": "plist", "sizes": []interface{}{uint64(1), uint64(2)}, "uid": UID(7)}
+	bin, err := Marshal(in, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	xml, err := ioutil.ReadAll(NewConvertingReader(bytes.NewReader(bin), XMLFormat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(xml, []byte("<?xml")) {
+		t.Fatalf("Expected an XML property list, received %s", xml)
+	}
+
+	var out map[string]interface{}
+	if _, err := Unmarshal(xml, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("Expected %v, received %v", in, out)
+	}
+
+	r := NewConvertingReader(strings.NewReader("{ a = "), XMLFormat)
+	for i := 0; i < 2; i++ {
+		if _, err := r.Read(make([]byte, 16)); err == nil || err == io.EOF {
+			t.Errorf("Read %d: Expected a parse error, received %v", i, err)
+		}
+	}
+}
+
+func TestConvertingWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewConvertingWriter(&buf, OpenStepFormat)
+	io.Copy(w, strings.NewReader(`<plist version="1.0"><dict><key>a</key><array><string>b</string></array></dict></plist>`))
+	if buf.Len() != 0 {
+		t.Error("Expected no output before Close")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "{a=(b,);}" {
+		t.Errorf("Expected an OpenStep property list, received %q", buf.String())
+	}
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Error("Expected an error writing after Close")
+	}
+
+	w = NewConvertingWriter(&buf, 42)
+	w.Write([]byte("hello"))
+	if err := w.Close(); err == nil {
+		t.Error("Expected an error converting to an unknown format")
+	}
+}
@@ -0,0 +1,70 @@
+package plist
+
+import (
+	"math"
+	"time"
+)
+
+var (
+	// UnixEpoch is the reference date for Unix timestamps, 1970-01-01 00:00:00 UTC.
+	UnixEpoch = time.Unix(0, 0).UTC()
+
+	// AppleEpoch is the reference date for CoreFoundation absolute times, 2001-01-01 00:00:00 UTC.
+	AppleEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// A DateRepresentation is a way of storing dates decoded into an empty interface.
+type DateRepresentation int
+
+const (
+	// TimeDates, the default, stores dates as time.Time.
+	TimeDates DateRepresentation = iota
+
+	// RFC3339Dates stores dates as strings in the format of time.RFC3339Nano, in UTC. Fractional
+	// seconds are included only when the date has them.
+	RFC3339Dates
+
+	// UnixSecondsDates stores dates as float64 numbers of seconds since UnixEpoch.
+	UnixSecondsDates
+
+	// AppleSecondsDates stores dates as float64 numbers of seconds since AppleEpoch, as
+	// CoreFoundation does.
+	AppleSecondsDates
+)
+
+// represent returns t in the representation r.
+func (r DateRepresentation) represent(t time.Time) interface{} {
+	switch r {
+	case RFC3339Dates:
+		return t.UTC().Format(time.RFC3339Nano)
+	case UnixSecondsDates:
+		return epochSeconds(UnixEpoch, t)
+	case AppleSecondsDates:
+		return AbsoluteTime(t)
+	}
+	return t
+}
+
+// epochSeconds returns the number of seconds from epoch to t.
+func epochSeconds(epoch, t time.Time) float64 {
+	return float64(t.Unix()-epoch.Unix()) + float64(t.Nanosecond()-epoch.Nanosecond())/float64(time.Second)
+}
+
+// AbsoluteTime returns t as a CoreFoundation absolute time (CFAbsoluteTime): the number of seconds
+// since AppleEpoch. Binary property lists store dates this way, and many documents store them
+// as plain reals in the same form.
+func AbsoluteTime(t time.Time) float64 {
+	return epochSeconds(AppleEpoch, t)
+}
+
+// TimeFromAbsoluteTime returns the time, in UTC, represented by the CoreFoundation absolute time
+// at. It is the inverse of AbsoluteTime.
+func TimeFromAbsoluteTime(at float64) time.Time {
+	return timeFromEpochSeconds(AppleEpoch, at)
+}
+
+// timeFromEpochSeconds returns the time secs seconds after epoch.
+func timeFromEpochSeconds(epoch time.Time, secs float64) time.Time {
+	whole, frac := math.Modf(secs)
+	return time.Unix(epoch.Unix()+int64(whole), int64(frac*float64(time.Second))).In(time.UTC)
+}
@@ -0,0 +1,27 @@
+package plist
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAbsoluteTime(t *testing.T) {
+	tests := []struct {
+		t  time.Time
+		at float64
+	}{
+		{AppleEpoch, 0},
+		{UnixEpoch, -978307200},
+		{time.Date(2024, 2, 29, 12, 30, 0, 500000000, time.UTC), 730902600.5},
+		{time.Date(2000, 12, 31, 23, 59, 59, 0, time.UTC), -1},
+	}
+
+	for _, test := range tests {
+		if at := AbsoluteTime(test.t); at != test.at {
+			t.Errorf("AbsoluteTime(%v) = %v, expected %v", test.t, at, test.at)
+		}
+		if tm := TimeFromAbsoluteTime(test.at); !tm.Equal(test.t) || tm.Location() != time.UTC {
+			t.Errorf("TimeFromAbsoluteTime(%v) = %v, expected %v", test.at, tm, test.t)
+		}
+	}
+}
@@ -2,9 +2,15 @@ package plist
 
 import (
 	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"reflect"
 	"runtime"
+	"sort"
+	"time"
 )
 
 type parser interface {
@@ -18,6 +24,67 @@ type Decoder struct {
 
 	reader io.ReadSeeker
 	lax    bool
+
+	typeDecoders map[reflect.Type]TypeDecoderFunc
+	workers      int
+
+	realsToIntegers  bool
+	trimXMLStrings   bool
+	xmlLimits        XMLLimits
+	xmlVersion       bool
+	keepEmptyStrings bool
+	rejectDupKeys    bool
+	textEncoding     TextEncoding
+	rejectEmpty      bool
+	skipToBinary     bool
+	gzipLimit        int64 // the largest decompressed size of a gzip-compressed document, if positive
+	strict           bool
+	requiredFormat   int
+	expandNested     bool
+	orderedDicts     bool
+	base64Data       bool
+	dateRep          DateRepresentation
+	durationFormat   DurationFormat
+	variables        VariableLookupFunc
+	buildSettings    map[string]string
+	fieldDuration    *DurationFormat // chosen by the tag of the struct field being decoded
+	hasDateEpoch     bool
+	dateEpoch        time.Time
+
+	preHook  PreHook
+	postHook PostHook
+	path     []string
+
+	collectStats bool
+	stats        *DecodeStats
+
+	progressFunc ProgressFunc
+	progress     *progressTracker // set during Decode if progressFunc is
+
+	reportUnset bool
+	unset       []string
+
+	reportUnused bool
+	unused       []string
+
+	events *eventEmitter // set during DecodeEvents
+}
+
+// A TypeDecoderFunc converts a property list value into a value of the type it was registered for.
+// The property list value is provided in the form Unmarshal would use when decoding into an empty
+// interface (string, uint64, []byte, map[string]interface{}, and so on.)
+type TypeDecoderFunc func(v interface{}) (interface{}, error)
+
+// RegisterTypeDecoder registers fn to be used whenever the Decoder would store a property list
+// value in a value of type typ. The value returned by fn must be assignable or convertible to typ.
+//
+// Type decoders take precedence over the Unmarshaler and encoding.TextUnmarshaler interfaces,
+// which makes them suitable for types that are defined in other packages (such as time.Duration).
+func (p *Decoder) RegisterTypeDecoder(typ reflect.Type, fn TypeDecoderFunc) {
+	if p.typeDecoders == nil {
+		p.typeDecoders = make(map[reflect.Type]TypeDecoderFunc)
+	}
+	p.typeDecoders[typ] = fn
 }
 
 // Decode works like Unmarshal, except it reads the decoder stream to find property list elements.
@@ -29,35 +96,125 @@ func (p *Decoder) Decode(v interface{}) (err error) {
 			if _, ok := r.(runtime.Error); ok {
 				panic(r)
 			}
-			err = r.(error)
+			// A panic leaves the path at the value being decoded when it happened.
+			err = withPath(r.(error), p.path)
+			p.path = nil
 		}
 	}()
 
+	p.stats = nil
+	p.unset = nil
+	p.unused = nil
+
+	if !validFormat(p.requiredFormat) || p.requiredFormat == PrettyFormat {
+		return fmt.Errorf("plist: cannot require unknown or undecodable format %d", p.requiredFormat)
+	}
+
+	if p.progressFunc != nil {
+		reader := p.reader
+		p.progress = &progressTracker{fn: p.progressFunc}
+		p.reader = &progressReader{ReadSeeker: reader, t: p.progress}
+		defer func() {
+			p.reader = reader
+			p.progress = nil
+		}()
+	}
+
+	pval, err := p.parseDocument()
+	if err != nil {
+		return err
+	}
+
+	if p.requiredFormat != AutomaticFormat && p.Format != p.requiredFormat {
+		return fmt.Errorf("plist: expected a %s property list, found %s", FormatNames[p.requiredFormat], FormatNames[p.Format])
+	}
+
+	if p.strict {
+		rejectDuplicateKeys(pval, make(map[cfValue]bool))
+	}
+
+	if p.collectStats {
+		p.stats = collectStats(pval)
+	}
+
+	if p.variables != nil {
+		pval = expandStrings(pval, func(s string) string { return expandString(s, p.variables) })
+	}
+	if p.buildSettings != nil {
+		e := &buildSettingExpander{settings: p.buildSettings, expanding: make(map[string]bool)}
+		pval = expandStrings(pval, e.expand)
+	}
+
+	p.unmarshalHooked(pval, reflect.ValueOf(v))
+	sort.Strings(p.unset)
+	sort.Strings(p.unused)
+	p.progress.done()
+	return
+}
+
+// parseDocument reads a property list of any format from the decoder stream, and sets Format.
+func (p *Decoder) parseDocument() (pval cfValue, err error) {
 	header := make([]byte, 6)
 	p.reader.Read(header)
 	p.reader.Seek(0, 0)
 
+	if p.gzipLimit > 0 && header[0] == 0x1f && header[1] == 0x8b {
+		// A gzip-compressed document: the parsers need to seek, so decompress it in full.
+		data, err := gunzip(p.reader, p.gzipLimit)
+		if err != nil {
+			return nil, plistParseError{"gzip-compressed", err}
+		}
+		defer func(reader io.ReadSeeker) { p.reader = reader }(p.reader)
+		p.reader = bytes.NewReader(data)
+		header = make([]byte, 6)
+		copy(header, data)
+	}
+
+	if p.skipToBinary {
+		// Leave the reader at the header, so that the binary parser sees only what follows it.
+		start, ok := findBinaryHeader(p.reader)
+		p.reader.Seek(start, 0)
+		if ok {
+			copy(header, "bplist")
+		}
+	}
+
 	var parser parser
-	var pval cfValue
 	if bytes.Equal(header, []byte("bplist")) {
-		parser = newBplistParser(p.reader)
+		bp := newBplistParser(p.reader)
+		bp.workers = p.workers
+		bp.events = p.events
+		parser = bp
 		pval, err = parser.parseDocument()
 		if err != nil {
 			// Had a bplist header, but still got an error: we have to die here.
-			return err
+			return nil, err
 		}
 		p.Format = BinaryFormat
 	} else {
-		parser = newXMLPlistParser(p.reader)
+		xp := newXMLPlistParser(p.reader)
+		xp.trimStrings = p.trimXMLStrings
+		xp.requireVersion = p.xmlVersion
+		xp.keepUIDDicts = p.strict
+		xp.setLimits(p.xmlLimits)
+		xp.events = p.events
+		parser = xp
 		pval, err = parser.parseDocument()
 		if _, ok := err.(invalidPlistError); ok {
 			// Rewind: the XML parser might have exhausted the file.
 			p.reader.Seek(0, 0)
 			// We don't use parser here because we want the textPlistParser type
 			tp := newTextPlistParser(p.reader)
+			tp.keepEmptyArrayStrings = p.keepEmptyStrings
+			tp.rejectDuplicateKeys = p.rejectDupKeys
+			tp.keepUIDDicts = p.strict
+			tp.encoding = p.textEncoding
 			pval, err = tp.parseDocument()
 			if err != nil {
-				return err
+				return nil, err
+			}
+			if tp.emptyDocument && p.rejectEmpty {
+				return nil, ErrEmptyDocument
 			}
 			p.Format = tp.format
 			if p.Format == OpenStepFormat {
@@ -67,20 +224,294 @@ func (p *Decoder) Decode(v interface{}) (err error) {
 			}
 		} else {
 			if err != nil {
-				return err
+				return nil, err
+			}
+			if xp.emptyDocument && p.rejectEmpty {
+				return nil, ErrEmptyDocument
 			}
 			p.Format = XMLFormat
 		}
 	}
 
-	p.unmarshal(pval, reflect.ValueOf(v))
-	return
+	return pval, nil
+}
+
+// gunzip decompresses the gzip stream r, failing if it holds more than limit bytes.
+func gunzip(r io.Reader, limit int64) ([]byte, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(zr, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("document is larger than %d bytes when decompressed", limit)
+	}
+	return data, nil
+}
+
+// findBinaryHeader returns the position of the first binary property list header in r, reading
+// it from the start.
+func findBinaryHeader(r io.ReadSeeker) (int64, bool) {
+	buf := make([]byte, 4096)
+	var pos int64 // the position in r of buf[0]
+	kept := 0     // bytes carried over from the end of the previous read
+	for {
+		n, err := io.ReadFull(r, buf[kept:])
+		n += kept
+		if i := bytes.Index(buf[:n], carveBinaryMagic); i >= 0 {
+			return pos + int64(i), true
+		}
+		if err != nil {
+			return 0, false
+		}
+		kept = len(carveBinaryMagic) - 1
+		copy(buf, buf[n-kept:n])
+		pos += int64(n - kept)
+	}
+}
+
+// SetBinaryParallelism allows the Decoder to use up to n goroutines to parse the objects in a
+// binary property list. This can significantly reduce the time it takes to decode very large
+// documents. Values of n less than 2 disable concurrent parsing, which is the default.
+func (p *Decoder) SetBinaryParallelism(n int) {
+	p.workers = n
+}
+
+// SetNestedBinaryExpansion controls whether the Decoder looks inside data values for embedded
+// binary property lists. When enabled, a data value that holds a valid binary property list is
+// decoded as if the embedded document had appeared in its place: into an empty interface it is
+// stored as a NestedPlist, and into any other type but a byte slice or array it is unmarshaled
+// directly. Data values that hold anything else are unaffected. This is disabled by default.
+func (p *Decoder) SetNestedBinaryExpansion(enabled bool) {
+	p.expandNested = enabled
+}
+
+// SetProgressFunc registers fn to be called with the Decoder's progress through each document it
+// decodes, for example to drive a progress bar. A nil fn disables progress reports.
+func (p *Decoder) SetProgressFunc(fn ProgressFunc) {
+	p.progressFunc = fn
+}
+
+// SetOrderedDictionaries controls whether dictionaries decoded into an empty interface are
+// stored as *OrderedDict, which keeps their keys in document order, rather than as
+// map[string]interface{}. This is disabled by default.
+func (p *Decoder) SetOrderedDictionaries(enabled bool) {
+	p.orderedDicts = enabled
+}
+
+// SetBase64Data controls whether data decoded into an empty interface is stored as a string
+// holding its standard base64 encoding, as in an XML property list, rather than as []byte. This
+// makes the decoded values behave the same way when they are rendered by text/template or
+// re-encoded (encoding/json, for example, already writes []byte as base64.) This is disabled by
+// default.
+func (p *Decoder) SetBase64Data(enabled bool) {
+	p.base64Data = enabled
+}
+
+// SetDateRepresentation controls how dates decoded into an empty interface are stored: as
+// time.Time (the default), as RFC 3339 strings, or as numbers of seconds since an epoch. Generic
+// documents that are re-encoded in another format can then carry their dates in whichever form
+// that format expects.
+func (p *Decoder) SetDateRepresentation(rep DateRepresentation) {
+	p.dateRep = rep
+}
+
+// SetDurationFormat sets the form in which the Decoder expects time.Duration values, unless the
+// struct field holding them chooses another with a "nanoseconds", "seconds" or "durationstring"
+// tag flag. Strings in the form accepted by time.ParseDuration are always accepted.
+func (p *Decoder) SetDurationFormat(format DurationFormat) {
+	p.durationFormat = format
+}
+
+// SetVariableExpansion makes the Decoder expand references of the form ${NAME} or $(NAME) in
+// every string value (but not dictionary key) of the documents it decodes, using lookup to find
+// the value of each variable; MapVariables and os.LookupEnv are suitable. References to
+// variables that lookup does not define are left as they are. A nil lookup, the default,
+// disables expansion.
+func (p *Decoder) SetVariableExpansion(lookup VariableLookupFunc) {
+	p.variables = lookup
+}
+
+// SetBuildSettings makes the Decoder expand references to Xcode build settings, such as
+// $(PRODUCT_NAME:rfc1034identifier), in every string value of the documents it decodes, as
+// ExpandBuildSettings does, so that Info.plist templates can be resolved outside Xcode. This is
+// done after any expansion set by SetVariableExpansion. A nil map, the default, disables it.
+func (p *Decoder) SetBuildSettings(settings map[string]string) {
+	p.buildSettings = settings
+}
+
+// SetRealToIntegerConversion controls whether the Decoder will store a property list real in an
+// integer value. When enabled, reals with no fractional part (such as 3.0) are converted; any other
+// real, or one that does not fit in the destination, is reported as an error.
+//
+// Many property list writers emit whole numbers as reals. This is disabled by default.
+func (p *Decoder) SetRealToIntegerConversion(enabled bool) {
+	p.realsToIntegers = enabled
+}
+
+// SetNumericDateEpoch allows the Decoder to store integers and reals in time.Time values by
+// interpreting them as a number of seconds since epoch (typically UnixEpoch or AppleEpoch.)
+//
+// Some property lists store timestamps as numbers rather than dates. By default, decoding a number
+// into a time.Time is an error.
+func (p *Decoder) SetNumericDateEpoch(epoch time.Time) {
+	p.hasDateEpoch = true
+	p.dateEpoch = epoch
+}
+
+// SetXMLStringTrimming controls whether the Decoder normalizes whitespace in the strings of XML
+// property lists. When enabled, leading and trailing whitespace is removed from every string, as is
+// the indentation at the beginning of each line of a multi-line string. Strings inside an element
+// that bears the attribute xml:space="preserve" are never modified.
+//
+// When disabled (the default), the contents of every string are preserved exactly, subject only to
+// the line ending normalization required by the XML specification.
+func (p *Decoder) SetXMLStringTrimming(enabled bool) {
+	p.trimXMLStrings = enabled
+}
+
+// SetXMLLimits bounds the resources the Decoder will spend parsing an XML property list.
+// Documents that exceed any of the limits fail to decode.
+func (p *Decoder) SetXMLLimits(limits XMLLimits) {
+	p.xmlLimits = limits
+}
+
+// SetXMLVersionRequired controls whether the Decoder insists that XML property lists have a
+// root <plist> element with a supported version attribute. By default, any well-formed
+// document is parsed on a best-effort basis; as with CFPropertyListCreateWithData, this includes
+// a bare <dict>, <array> or scalar element with no <plist> wrapper (such as the output of an
+// Encoder configured with SetFragment.) Requiring a version rejects such fragments.
+func (p *Decoder) SetXMLVersionRequired(required bool) {
+	p.xmlVersion = required
+}
+
+// SetKeepEmptyArrayStrings controls whether empty strings are kept when they appear as members
+// of arrays in text property lists. By default they are discarded.
+func (p *Decoder) SetKeepEmptyArrayStrings(keep bool) {
+	p.keepEmptyStrings = keep
+}
+
+// SetRejectDuplicateKeys controls whether the Decoder fails when a dictionary in a text property
+// list or strings file defines the same key more than once. The error reports the location of
+// both definitions. By default, the last definition of a key wins.
+func (p *Decoder) SetRejectDuplicateKeys(reject bool) {
+	p.rejectDupKeys = reject
+}
+
+// SetTextEncoding sets the character set the Decoder assumes for OpenStep and GNUStep property
+// lists and strings files that have no byte order mark. Documents that begin with a byte order
+// mark, or that are recognizably UTF-16 or UTF-32, are unaffected. The default is
+// UTF8TextEncoding.
+func (p *Decoder) SetTextEncoding(enc TextEncoding) {
+	p.textEncoding = enc
+}
+
+// SetCollectStats controls whether the Decoder gathers statistics about each document it parses.
+// The statistics for the most recent document are available from Stats.
+func (p *Decoder) SetCollectStats(collect bool) {
+	p.collectStats = collect
+}
+
+// Stats returns statistics about the document most recently parsed by Decode, or nil if
+// statistics collection is disabled or the document could not be parsed.
+func (p *Decoder) Stats() *DecodeStats {
+	return p.stats
+}
+
+// SetReportUnsetFields controls whether the Decoder records the struct fields that it leaves
+// unset because the document has no value for them. The fields for the most recent document are
+// available from UnsetFields.
+func (p *Decoder) SetReportUnsetFields(report bool) {
+	p.reportUnset = report
+}
+
+// UnsetFields returns the key paths of the struct fields that the most recent call to Decode left
+// unset, in sorted order. A key path is the sequence of dictionary keys and array indices leading
+// to the field, joined by slashes (as in "Accounts/0/Server".) Only the fields of structs that were
+// decoded are reported: if a struct-typed field is itself absent, it is reported, but its own
+// fields are not.
+//
+// UnsetFields returns nil if reporting is disabled or if every field was set.
+func (p *Decoder) UnsetFields() []string {
+	return p.unset
+}
+
+// SetReportUnusedKeys controls whether the Decoder records the dictionary keys that it ignores
+// because the struct it is decoding into has no field for them. This is a gentler alternative to
+// rejecting such keys: a caller can log deprecated or misspelled keys without failing. The keys
+// for the most recent document are available from UnusedKeys.
+func (p *Decoder) SetReportUnusedKeys(report bool) {
+	p.reportUnused = report
+}
+
+// UnusedKeys returns the key paths of the dictionary keys that the most recent call to Decode
+// ignored, in sorted order. Key paths are formed as for UnsetFields. Only keys in dictionaries
+// decoded into structs are reported; the contents of an ignored key's value are not.
+//
+// UnusedKeys returns nil if reporting is disabled or if every key was used.
+func (p *Decoder) UnusedKeys() []string {
+	return p.unused
+}
+
+// SetSkipToBinaryHeader controls whether the Decoder looks past any leading bytes, such as a
+// record header or cache metadata, for the "bplist0" header of a binary property list embedded
+// in its input. When enabled and a header is found, the document is decoded from it, with its
+// offsets taken relative to it; the binary property list must still run to the end of the
+// input. A document in which no header is found is decoded as usual.
+func (p *Decoder) SetSkipToBinaryHeader(enabled bool) {
+	p.skipToBinary = enabled
+}
+
+// SetGzipDecompression controls whether the Decoder decompresses gzip-compressed documents, of
+// any format, before parsing them. A positive limit enables decompression, and is the largest
+// size in bytes that a document may decompress to; larger documents are rejected with an error,
+// so that a small upload cannot exhaust memory. A limit of zero (the default) disables
+// decompression, and gzip-compressed documents fail to parse.
+func (p *Decoder) SetGzipDecompression(limit int64) {
+	p.gzipLimit = limit
+}
+
+// SetStrict controls whether the Decoder refuses to convert between types or resolve any
+// ambiguity on the caller's behalf, as validation tools need. In strict mode, strings are never
+// parsed into numbers, booleans or dates, even in OpenStep property lists (which can only store
+// strings) or when lax mode is enabled. UIDs can only be stored in plist.UID values and empty
+// interfaces, not in other integers. Dictionaries in XML and text property lists holding only a
+// CF$UID key remain dictionaries, rather than becoming UIDs. A dictionary in which a key appears
+// more than once is an error, in every format.
+func (p *Decoder) SetStrict(enabled bool) {
+	p.strict = enabled
+}
+
+// SetEmptyDocumentError controls how the Decoder treats empty documents: those with no content
+// at all, only whitespace and comments, or (for XML) only a prolog. By default, an empty document
+// decodes as an empty dictionary; when enabled, Decode returns ErrEmptyDocument instead.
+func (p *Decoder) SetEmptyDocumentError(enabled bool) {
+	p.rejectEmpty = enabled
+}
+
+// SetHooks registers functions to be called before and after each value is decoded.
+// Either may be nil.
+//
+// Hooks are not called for values nested inside a property list value that is decoded into an
+// empty interface, as those are decoded as a unit.
+func (p *Decoder) SetHooks(pre PreHook, post PostHook) {
+	p.preHook = pre
+	p.postHook = post
 }
 
 // NewDecoder returns a Decoder that reads property list elements from a stream reader, r.
 // NewDecoder requires a Seekable stream for the purposes of file type detection.
-func NewDecoder(r io.ReadSeeker) *Decoder {
-	return &Decoder{Format: InvalidFormat, reader: r, lax: false}
+// The Decoder is configured by opts, which are applied in order.
+func NewDecoder(r io.ReadSeeker, opts ...DecoderOption) *Decoder {
+	p := &Decoder{Format: InvalidFormat, reader: r, lax: false}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // Unmarshal parses a property list document and stores the result in the value pointed to by v.
@@ -98,7 +529,16 @@ func NewDecoder(r io.ReadSeeker) *Decoder {
 //     []interface{}, for plist arrays
 //     map[string]interface{}, for plist dictionaries
 //
-// If a property list value is not appropriate for a given value type, Unmarshal aborts immediately and returns an error.
+// Types with a Scan(src interface{}) error method, such as sql.NullString and sql.NullInt64, are given strings, numbers,
+// booleans, data and dates as database/sql would give them a column value (as int64, float64, bool, []byte, string or
+// time.Time.) A value that is absent from the property list leaves them untouched, and therefore not Valid.
+//
+// Strings can be stored in Runes values as well as in strings.
+//
+// Reals and integers are stored in big.Float values exactly; strings are parsed by big.Float's UnmarshalText.
+//
+// If a property list value is not appropriate for a given value type, Unmarshal aborts immediately and returns an error;
+// for a value inside a dictionary or array, the error is a *PathError locating it.
 //
 // As Go does not support 128-bit types, and we don't want to pretend we're giving the user integer types (as opposed to
 // secretly passing them structs), Unmarshal will drop the high 64 bits of any 128-bit integers encoded in binary property lists.
@@ -117,3 +557,88 @@ func Unmarshal(data []byte, v interface{}) (format int, err error) {
 	format = dec.Format
 	return
 }
+
+// DecodeAll decodes a series of property lists, in any combination of formats, into v as if
+// they were a single document: each one is overlaid on those before it. This is the usual way
+// to combine layers of configuration, such as defaults, machine-wide settings and user settings.
+//
+// Dictionaries are merged key by key, at every level of nesting; where a key appears in more
+// than one document, the value from the later document wins. Every other value, including an
+// array, replaces the corresponding value from earlier documents outright. The root values of
+// all the documents must therefore be dictionaries, unless there is only one.
+//
+// If any document is in the OpenStep format, the combined document is decoded in the relaxed
+// mode Unmarshal uses for OpenStep property lists.
+func DecodeAll(v interface{}, readers ...io.ReadSeeker) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+	}()
+
+	d := &Decoder{}
+	var merged cfValue
+	for i, r := range readers {
+		d.reader = r
+		pval, err := d.parseDocument()
+		if err != nil {
+			return fmt.Errorf("plist: in document %d: %v", i, err)
+		}
+
+		if merged == nil {
+			merged = pval
+			continue
+		}
+
+		base, ok := merged.(*cfDictionary)
+		overlay, ok2 := pval.(*cfDictionary)
+		if !ok || !ok2 {
+			return fmt.Errorf("plist: cannot overlay document %d: root values must be dictionaries", i)
+		}
+		merged = overlayDictionary(base, overlay)
+	}
+
+	if merged == nil {
+		return errors.New("plist: no documents to decode")
+	}
+	d.unmarshalHooked(merged, reflect.ValueOf(v))
+	return nil
+}
+
+// overlayDictionary returns the result of merging overlay into base: nested dictionaries are
+// merged in turn, and any other value in overlay replaces the one in base. Neither dictionary is
+// modified, as binary property lists may share one dictionary between several places.
+func overlayDictionary(base, overlay *cfDictionary) *cfDictionary {
+	merged := &cfDictionary{
+		keys:   append(make([]string, 0, len(base.keys)+len(overlay.keys)), base.keys...),
+		values: append(make([]cfValue, 0, len(base.keys)+len(overlay.keys)), base.values...),
+	}
+
+	index := make(map[string]int, len(merged.keys))
+	for i, k := range merged.keys {
+		index[k] = i
+	}
+
+	for i, k := range overlay.keys {
+		val := overlay.values[i]
+		j, ok := index[k]
+		if !ok {
+			index[k] = len(merged.keys)
+			merged.keys = append(merged.keys, k)
+			merged.values = append(merged.values, val)
+			continue
+		}
+
+		baseDict, ok := merged.values[j].(*cfDictionary)
+		overlayDict, ok2 := val.(*cfDictionary)
+		if ok && ok2 {
+			merged.values[j] = overlayDictionary(baseDict, overlayDict)
+		} else {
+			merged.values[j] = val
+		}
+	}
+	return merged
+}
@@ -2,9 +2,13 @@ package plist
 
 import (
 	"bytes"
+	"compress/gzip"
 	"fmt"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func BenchmarkXMLDecode(b *testing.B) {
@@ -238,3 +242,791 @@ func ExampleDecoder_Decode() {
 
 	// Output: {6.0 8388608 1 com.apple.diskimage.sparsebundle 4398046511104}
 }
+
+func TestDecoderTypeDecoder(t *testing.T) {
+	type Config struct {
+		Interval time.Duration
+		Timeout  *time.Duration
+		Name     string
+	}
+
+	buf := bytes.NewReader([]byte(`<plist version="1.0"><dict><key>Interval</key><string>1m30s</string><key>Timeout</key><string>5s</string><key>Name</key><string>x</string></dict></plist>`))
+	decoder := NewDecoder(buf)
+	decoder.RegisterTypeDecoder(reflect.TypeOf(time.Duration(0)), func(v interface{}) (interface{}, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected %T", v)
+		}
+		return time.ParseDuration(s)
+	})
+
+	var c Config
+	if err := decoder.Decode(&c); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Interval != 90*time.Second || c.Timeout == nil || *c.Timeout != 5*time.Second || c.Name != "x" {
+		t.Errorf("unexpected result %#v", c)
+	}
+}
+
+func TestDecoderTypeDecoderIncompatibleResult(t *testing.T) {
+	buf := bytes.NewReader([]byte(`<string>abc</string>`))
+	decoder := NewDecoder(buf)
+	decoder.RegisterTypeDecoder(reflect.TypeOf(time.Duration(0)), func(v interface{}) (interface{}, error) {
+		return "not a duration", nil
+	})
+
+	var d time.Duration
+	err := decoder.Decode(&d)
+	t.Logf("Error: %v", err)
+	if err == nil {
+		t.Error("Expected error, received nothing.")
+	}
+}
+
+func TestDecoderHooks(t *testing.T) {
+	type Account struct {
+		User     string
+		Password string
+		Hosts    []string
+	}
+
+	buf := bytes.NewReader([]byte(`{User=u;Password=hunter2;Hosts=(a,b);}`))
+	decoder := NewDecoder(buf)
+
+	var visited []string
+	decoder.SetHooks(func(path []string, v reflect.Value) (reflect.Value, error) {
+		if len(path) == 1 && path[0] == "Password" {
+			return reflect.Value{}, nil
+		}
+		return v, nil
+	}, func(path []string, v reflect.Value) error {
+		visited = append(visited, strings.Join(path, "/"))
+		if len(path) == 1 && path[0] == "User" {
+			v.SetString("user:" + v.String())
+		}
+		return nil
+	})
+
+	var a Account
+	if err := decoder.Decode(&a); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Account{User: "user:u", Hosts: []string{"a", "b"}}
+	if !reflect.DeepEqual(a, expected) {
+		t.Errorf("Expected %#v, received %#v", expected, a)
+	}
+
+	expectedPaths := []string{"User", "Hosts/0", "Hosts/1", "Hosts", ""}
+	if !reflect.DeepEqual(visited, expectedPaths) {
+		t.Errorf("Expected hooks for %v, received %v", expectedPaths, visited)
+	}
+}
+
+func TestRealToIntegerConversion(t *testing.T) {
+	type Data struct {
+		I int
+		U uint8
+	}
+
+	tests := []struct {
+		doc   string
+		valid bool
+	}{
+		{`<dict><key>I</key><real>-3.0</real><key>U</key><real>255</real></dict>`, true},
+		{`<dict><key>I</key><real>3.5</real></dict>`, false},
+		{`<dict><key>U</key><real>256</real></dict>`, false},
+		{`<dict><key>U</key><real>-1</real></dict>`, false},
+		{`<dict><key>I</key><real>nan</real></dict>`, false},
+	}
+
+	for _, test := range tests {
+		var d Data
+		decoder := NewDecoder(bytes.NewReader([]byte(test.doc)))
+		decoder.SetRealToIntegerConversion(true)
+		err := decoder.Decode(&d)
+		if test.valid && (err != nil || d.I != -3 || d.U != 255) {
+			t.Errorf("%s: unexpected result %#v (%v)", test.doc, d, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%s: Expected error, received %#v", test.doc, d)
+		}
+	}
+
+	var i int
+	if _, err := Unmarshal([]byte(`<real>3.0</real>`), &i); err == nil {
+		t.Error("Expected error when conversion is disabled, received nothing.")
+	}
+}
+
+func TestNumericDateEpoch(t *testing.T) {
+	type Data struct {
+		Modified time.Time
+		Created  time.Time
+	}
+
+	doc := []byte(`<dict><key>Modified</key><real>1.5</real><key>Created</key><integer>-86400</integer></dict>`)
+
+	var d Data
+	if _, err := Unmarshal(doc, &d); err == nil {
+		t.Error("Expected error when numeric dates are disabled, received nothing.")
+	}
+
+	decoder := NewDecoder(bytes.NewReader(doc))
+	decoder.SetNumericDateEpoch(AppleEpoch)
+	if err := decoder.Decode(&d); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Data{
+		Modified: time.Date(2001, 1, 1, 0, 0, 1, 500000000, time.UTC),
+		Created:  time.Date(2000, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+	if d != expected {
+		t.Errorf("Expected %v, received %v", expected, d)
+	}
+
+	decoder = NewDecoder(bytes.NewReader(doc))
+	decoder.SetNumericDateEpoch(UnixEpoch)
+	if err := decoder.Decode(&d); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Created.Equal(time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected Unix date %v", d.Created)
+	}
+}
+
+func TestLaxNumericDateEpoch(t *testing.T) {
+	var d struct{ Date time.Time }
+	decoder := NewDecoder(bytes.NewReader([]byte(`{Date=86400.25;}`)))
+	decoder.SetNumericDateEpoch(UnixEpoch)
+	if err := decoder.Decode(&d); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Date.Equal(time.Date(1970, 1, 2, 0, 0, 0, 250000000, time.UTC)) {
+		t.Errorf("unexpected date %v", d.Date)
+	}
+}
+
+func TestDecodeStats(t *testing.T) {
+	doc := `{ name = hello; name = again; list = (a, <0102>, { x = <*I1>; }); }`
+
+	var v interface{}
+	d := NewDecoder(strings.NewReader(doc))
+	d.SetCollectStats(true)
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &DecodeStats{
+		Values: map[string]int{
+			"dictionary": 2,
+			"array":      1,
+			"string":     3,
+			"data":       1,
+			"integer":    1,
+		},
+		Objects:       8,
+		MaxDepth:      4,
+		StringBytes:   int64(len("name" + "hello" + "name" + "again" + "list" + "a" + "x")),
+		DataBytes:     2,
+		LargestString: 5,
+		LargestData:   2,
+		DuplicateKeys: 1,
+	}
+	if !reflect.DeepEqual(d.Stats(), expected) {
+		t.Errorf("Expected %+v, received %+v", expected, d.Stats())
+	}
+
+	stats, err := DocumentStats([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Expected %+v from DocumentStats, received %+v", expected, stats)
+	}
+	if _, err := DocumentStats([]byte("{ a = ")); err == nil {
+		t.Error("Expected an error for an invalid document")
+	}
+
+	// Shared objects are counted for every place they appear, but examined once.
+	stats, err = DocumentStats(sharedBplist(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = &DecodeStats{
+		Values:        map[string]int{"array": 1<<12 - 1, "string": 1 << 12},
+		Objects:       1<<13 - 1,
+		MaxDepth:      13,
+		StringBytes:   1 << 12,
+		LargestString: 1,
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Expected %+v from a document of shared objects, received %+v", expected, stats)
+	}
+	if stats, err = DocumentStats(sharedBplist(80)); err != nil || stats.Objects != int(^uint(0)>>1) {
+		t.Errorf("Expected the object count to saturate, received %+v, %v", stats, err)
+	}
+
+	d = NewDecoder(strings.NewReader(doc))
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if d.Stats() != nil {
+		t.Errorf("Expected no statistics, received %+v", d.Stats())
+	}
+}
+
+func TestDecodeEmptyDocument(t *testing.T) {
+	documents := []string{
+		"",
+		"  \n\t",
+		"\xef\xbb\xbf",
+		"/* nothing to see here */\n// or here\n",
+		`<?xml version="1.0" encoding="UTF-8"?>` + "\n",
+		`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<!-- empty -->
+`,
+	}
+
+	for _, doc := range documents {
+		var v interface{}
+		d := NewDecoder(strings.NewReader(doc))
+		if err := d.Decode(&v); err != nil {
+			t.Errorf("%q: %v", doc, err)
+		} else if m, ok := v.(map[string]interface{}); !ok || len(m) != 0 {
+			t.Errorf("%q: Expected an empty dictionary, received %#v", doc, v)
+		}
+
+		d = NewDecoder(strings.NewReader(doc))
+		d.SetEmptyDocumentError(true)
+		if err := d.Decode(&v); err != ErrEmptyDocument {
+			t.Errorf("%q: Expected ErrEmptyDocument, received %v", doc, err)
+		}
+	}
+
+	d := NewDecoder(strings.NewReader("bplist00"))
+	d.SetEmptyDocumentError(true)
+	var v interface{}
+	if err := d.Decode(&v); err == nil || err == ErrEmptyDocument {
+		t.Errorf("Expected a truncated binary property list to fail to parse, received %v", err)
+	}
+}
+
+func TestDecoderOptions(t *testing.T) {
+	doc := `{ count = <*I3>; ratio = <*R2.0>; tags = (a, "", b); }`
+
+	var v struct {
+		Count int      `plist:"count"`
+		Ratio int      `plist:"ratio"`
+		Tags  []string `plist:"tags"`
+	}
+	d := NewDecoder(strings.NewReader(doc), WithRealToIntegerConversion(), WithKeepEmptyArrayStrings(), WithCollectStats())
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Count != 3 || v.Ratio != 2 || !reflect.DeepEqual(v.Tags, []string{"a", "", "b"}) {
+		t.Errorf("Unexpected result %+v", v)
+	}
+	if d.Stats() == nil {
+		t.Error("Expected statistics to be collected")
+	}
+
+	d = NewDecoder(strings.NewReader(doc), WithRequiredFormat(XMLFormat))
+	if err := d.Decode(&v); err == nil {
+		t.Error("Expected an error decoding a GNUStep document with XML required")
+	}
+
+	xml := `<plist version="1.0"><dict><key>count</key><string>7</string></dict></plist>`
+	if err := NewDecoder(strings.NewReader(xml)).Decode(&v); err == nil {
+		t.Error("Expected an error decoding a string into an int without lax mode")
+	}
+	if err := NewDecoder(strings.NewReader(xml), WithLax(), WithRequiredFormat(XMLFormat)).Decode(&v); err != nil || v.Count != 7 {
+		t.Errorf("Unexpected result %+v (%v)", v, err)
+	}
+}
+
+func TestNestedBinaryExpansion(t *testing.T) {
+	inner, err := Marshal(map[string]interface{}{"volume": uint64(7)}, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer, err := Marshal(map[string]interface{}{"prefs": inner, "raw": []byte("bplist but not really")}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var generic map[string]interface{}
+	if err := NewDecoder(bytes.NewReader(outer), WithNestedBinaryExpansion()).Decode(&generic); err != nil {
+		t.Fatal(err)
+	}
+	nested, ok := generic["prefs"].(NestedPlist)
+	if !ok || !reflect.DeepEqual(nested.Value, map[string]interface{}{"volume": uint64(7)}) {
+		t.Errorf("Expected an expanded NestedPlist, got %#v", generic["prefs"])
+	}
+	if _, ok := generic["raw"].([]byte); !ok {
+		t.Errorf("Expected invalid nested data to be left alone, got %#v", generic["raw"])
+	}
+
+	var typed struct {
+		Prefs struct {
+			Volume int `plist:"volume"`
+		} `plist:"prefs"`
+		Raw []byte `plist:"raw"`
+	}
+	if err := NewDecoder(bytes.NewReader(outer), WithNestedBinaryExpansion()).Decode(&typed); err != nil {
+		t.Fatal(err)
+	}
+	if typed.Prefs.Volume != 7 {
+		t.Errorf("Expected the nested document to decode into a struct, got %+v", typed)
+	}
+
+	// Re-encoding the expanded value embeds it again as binary data.
+	reencoded, err := Marshal(generic, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roundTrip struct {
+		Prefs NestedPlist `plist:"prefs"`
+	}
+	if _, err := Unmarshal(reencoded, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(roundTrip.Prefs.Value, nested.Value) {
+		t.Errorf("Round trip mismatch: %#v", roundTrip.Prefs.Value)
+	}
+}
+
+func TestBase64Data(t *testing.T) {
+	xml := `<plist version="1.0"><dict><key>blob</key><data>AQID/w==</data><key>list</key><array><data>aGk=</data></array></dict></plist>`
+
+	var generic map[string]interface{}
+	if err := NewDecoder(strings.NewReader(xml), WithBase64Data()).Decode(&generic); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"blob": "AQID/w==", "list": []interface{}{"aGk="}}
+	if !reflect.DeepEqual(generic, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, generic)
+	}
+
+	// Data decoded into a []byte is unaffected.
+	var typed struct {
+		Blob []byte `plist:"blob"`
+	}
+	if err := NewDecoder(strings.NewReader(xml), WithBase64Data()).Decode(&typed); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(typed.Blob, []byte{1, 2, 3, 255}) {
+		t.Errorf("Expected raw bytes, got %v", typed.Blob)
+	}
+}
+
+func TestDateRepresentation(t *testing.T) {
+	xml := `<plist version="1.0"><array><date>2001-01-01T00:01:00Z</date><date>1970-01-01T00:00:00Z</date></array></plist>`
+
+	tests := []struct {
+		rep      DateRepresentation
+		expected []interface{}
+	}{
+		{TimeDates, []interface{}{AppleEpoch.Add(time.Minute), UnixEpoch}},
+		{RFC3339Dates, []interface{}{"2001-01-01T00:01:00Z", "1970-01-01T00:00:00Z"}},
+		{UnixSecondsDates, []interface{}{978307260.0, 0.0}},
+		{AppleSecondsDates, []interface{}{60.0, -978307200.0}},
+	}
+
+	for _, test := range tests {
+		var v interface{}
+		if err := NewDecoder(strings.NewReader(xml), WithDateRepresentation(test.rep)).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("%d: Expected %#v, got %#v", test.rep, test.expected, v)
+		}
+	}
+
+	// Dates decoded into a time.Time are unaffected.
+	var dates []time.Time
+	if err := NewDecoder(strings.NewReader(xml), WithDateRepresentation(RFC3339Dates)).Decode(&dates); err != nil {
+		t.Fatal(err)
+	}
+	if len(dates) != 2 || !dates[1].Equal(UnixEpoch) {
+		t.Errorf("Unexpected dates %v", dates)
+	}
+}
+
+func TestUnsetFields(t *testing.T) {
+	type server struct {
+		Host string
+		Port int
+	}
+	var v struct {
+		Name    string
+		Count   int
+		Primary server
+		Backup  server
+		Mirrors []server
+		Ignored string `plist:"-"`
+	}
+
+	doc := `{ Name = ""; Primary = { Host = example.com; }; Mirrors = ({ Port = 22; }); }`
+	d := NewDecoder(strings.NewReader(doc), WithUnsetFieldReporting())
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"Backup", "Count", "Mirrors/0/Host", "Primary/Port"}
+	if !reflect.DeepEqual(d.UnsetFields(), expected) {
+		t.Errorf("Expected unset fields %v, got %v", expected, d.UnsetFields())
+	}
+
+	d = NewDecoder(strings.NewReader(doc))
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if d.UnsetFields() != nil {
+		t.Errorf("Expected no unset fields when reporting is disabled, got %v", d.UnsetFields())
+	}
+}
+
+func TestUnusedKeys(t *testing.T) {
+	type server struct {
+		Host string
+	}
+	var v struct {
+		Name    string
+		Servers []server
+		Options map[string]interface{}
+		Ignored string `plist:"-"`
+	}
+
+	doc := `{ Name = x; Colour = red; Ignored = y; Servers = ({ Host = a; Port = 22; }); Options = { Any = 1; }; Legacy = { Host = b; }; }`
+	d := NewDecoder(strings.NewReader(doc), WithUnusedKeyReporting())
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"Colour", "Ignored", "Legacy", "Servers/0/Port"}
+	if !reflect.DeepEqual(d.UnusedKeys(), expected) {
+		t.Errorf("Expected unused keys %v, got %v", expected, d.UnusedKeys())
+	}
+
+	d = NewDecoder(strings.NewReader(doc))
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if d.UnusedKeys() != nil {
+		t.Errorf("Expected no unused keys when reporting is disabled, got %v", d.UnusedKeys())
+	}
+}
+
+func TestDecodeAll(t *testing.T) {
+	type config struct {
+		Name    string
+		Verbose bool
+		Servers []string
+		Limits  map[string]int
+		Extra   map[string]interface{}
+	}
+
+	defaults := `<plist version="1.0"><dict>
+		<key>Name</key><string>default</string>
+		<key>Servers</key><array><string>a</string><string>b</string></array>
+		<key>Limits</key><dict><key>cpu</key><integer>1</integer><key>memory</key><integer>512</integer></dict>
+		<key>Extra</key><dict><key>keep</key><true/></dict>
+	</dict></plist>`
+	machine := `{ Servers = (c); Limits = { memory = 1024; }; }`
+	user := `{ Verbose = <*BY>; Extra = { added = <*I3>; }; }`
+
+	var c config
+	err := DecodeAll(&c, strings.NewReader(defaults), strings.NewReader(machine), strings.NewReader(user))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := config{
+		Name:    "default",
+		Verbose: true,
+		Servers: []string{"c"},
+		Limits:  map[string]int{"cpu": 1, "memory": 1024},
+		Extra:   map[string]interface{}{"keep": true, "added": uint64(3)},
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, c)
+	}
+
+	if err := DecodeAll(&c, strings.NewReader(defaults), strings.NewReader("(1, 2)")); err == nil {
+		t.Error("Expected an error overlaying an array on a dictionary")
+	}
+	if err := DecodeAll(&c, strings.NewReader(defaults), strings.NewReader("<plist><dict>")); err == nil {
+		t.Error("Expected an error for an invalid document")
+	}
+}
+
+func TestDecodeEvents(t *testing.T) {
+	doc := map[string]interface{}{
+		"Name":  "example",
+		"Items": []interface{}{"a", map[string]interface{}{"Deep": "b"}},
+		"Skip":  map[string]interface{}{"Hidden": "c"},
+	}
+
+	var events []string
+	h := &EventHandler{
+		StartDictionary: func(path []string) error {
+			events = append(events, "dict "+strings.Join(path, "/"))
+			return nil
+		},
+		EndDictionary: func(path []string) error {
+			events = append(events, "end dict "+strings.Join(path, "/"))
+			return nil
+		},
+		Key: func(path []string, key string) error {
+			if key == "Skip" {
+				return SkipValue
+			}
+			return nil
+		},
+		StartArray: func(path []string) error {
+			events = append(events, "array "+strings.Join(path, "/"))
+			return nil
+		},
+		EndArray: func(path []string) error {
+			events = append(events, "end array "+strings.Join(path, "/"))
+			return nil
+		},
+		Value: func(path []string, v interface{}) error {
+			events = append(events, fmt.Sprintf("%s=%v", strings.Join(path, "/"), v))
+			return nil
+		},
+	}
+
+	expected := []string{
+		"dict ",
+		"array Items",
+		"Items/0=a",
+		"dict Items/1",
+		"Items/1/Deep=b",
+		"end dict Items/1",
+		"end array Items",
+		"Name=example",
+		"end dict ",
+	}
+
+	for _, format := range []int{BinaryFormat, XMLFormat, OpenStepFormat} {
+		data, err := Marshal(doc, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		events = nil
+		d := NewDecoder(bytes.NewReader(data))
+		if err := d.DecodeEvents(h); err != nil {
+			t.Fatalf("%s: %v", FormatNames[format], err)
+		}
+		if d.Format != format {
+			t.Errorf("%s: expected format to be detected, got %s", FormatNames[format], FormatNames[d.Format])
+		}
+		if !reflect.DeepEqual(events, expected) {
+			t.Errorf("%s: expected events\n%v\ngot\n%v", FormatNames[format], expected, events)
+		}
+	}
+
+	stop := fmt.Errorf("stop")
+	data, _ := Marshal(doc, BinaryFormat)
+	err := NewDecoder(bytes.NewReader(data)).DecodeEvents(&EventHandler{
+		Value: func(path []string, v interface{}) error { return stop },
+	})
+	if err != stop {
+		t.Errorf("Expected the handler's error, got %v", err)
+	}
+}
+
+func TestDecodeEventsStop(t *testing.T) {
+	items := make([]map[string]interface{}, 100)
+	for i := range items {
+		items[i] = map[string]interface{}{"ID": i, "Name": fmt.Sprintf("item %d", i)}
+	}
+
+	for _, format := range []int{BinaryFormat, XMLFormat, GNUStepFormat} {
+		data, err := Marshal(items, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format == XMLFormat {
+			// Everything after the match is never read, so it need not even be valid.
+			data = append(data[:len(data)/2], "<broken"...)
+		}
+
+		var found string
+		values := 0
+		d := NewDecoder(bytes.NewReader(data))
+		err = d.DecodeEvents(&EventHandler{
+			Value: func(path []string, v interface{}) error {
+				values++
+				if path[1] == "Name" && v == "item 3" {
+					found = path[0]
+					return StopDecoding
+				}
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", FormatNames[format], err)
+		}
+		if found != "3" || values != 8 {
+			t.Errorf("%s: Expected to stop at item 3 after 8 values, found %q after %d", FormatNames[format], found, values)
+		}
+		if d.Format != format {
+			t.Errorf("%s: Expected the format to be recorded, got %s", FormatNames[format], FormatNames[d.Format])
+		}
+	}
+}
+
+func TestVariableExpansion(t *testing.T) {
+	doc := `{ "${HOME}" = "${HOME}/Library"; list = ("$(USER)@$(HOST)", "${UNSET} $(HOME", "$$ ${} $(a-b) $"); }`
+
+	var v map[string]interface{}
+	d := NewDecoder(strings.NewReader(doc), WithVariableExpansion(map[string]string{"HOME": "/Users/me", "USER": "me", "HOST": "example.com"}))
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"${HOME}": "/Users/me/Library",
+		"list":    []interface{}{"me@example.com", "${UNSET} $(HOME", "$$ ${} $(a-b) $"},
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("Expected %#v, received %#v", expected, v)
+	}
+
+	os.Setenv("PLIST_TEST_VARIABLE", "value")
+	defer os.Unsetenv("PLIST_TEST_VARIABLE")
+	var s struct{ Name string }
+	d = NewDecoder(strings.NewReader(`{ Name = "[$(PLIST_TEST_VARIABLE)]"; }`), WithEnvironmentExpansion())
+	if err := d.Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "[value]" {
+		t.Errorf("Expected [value], received %q", s.Name)
+	}
+}
+
+func TestSkipToBinaryHeader(t *testing.T) {
+	doc, err := Marshal(map[string]interface{}{"a": "b", "list": []int{1, 2}}, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, prefix := range [][]byte{nil, []byte("record 1\x00\xff"), bytes.Repeat([]byte("bplist garbage "), 300)} {
+		var v map[string]interface{}
+		d := NewDecoder(bytes.NewReader(append(prefix, doc...)), WithSkipToBinaryHeader())
+		if err := d.Decode(&v); err != nil {
+			t.Errorf("%d-byte prefix: %v", len(prefix), err)
+			continue
+		}
+		if d.Format != BinaryFormat || v["a"] != "b" {
+			t.Errorf("%d-byte prefix: unexpected %s document %#v", len(prefix), FormatNames[d.Format], v)
+		}
+
+		if len(prefix) > 0 {
+			if err := NewDecoder(bytes.NewReader(append(prefix, doc...))).Decode(&v); err == nil {
+				t.Errorf("%d-byte prefix: expected an error without SetSkipToBinaryHeader", len(prefix))
+			}
+		}
+	}
+
+	var v interface{}
+	d := NewDecoder(strings.NewReader(`{ a = b; }`), WithSkipToBinaryHeader())
+	if err := d.Decode(&v); err != nil || d.Format != OpenStepFormat {
+		t.Errorf("Expected a text document to decode as usual, received %s (%v)", FormatNames[d.Format], err)
+	}
+}
+
+func TestDecodeGzip(t *testing.T) {
+	for _, format := range []int{BinaryFormat, XMLFormat, OpenStepFormat} {
+		doc, err := Marshal(map[string]string{"a": "b"}, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(doc)
+		zw.Close()
+
+		var v interface{}
+		if _, err := Unmarshal(buf.Bytes(), &v); err == nil {
+			t.Errorf("%s: expected an error without gzip decompression enabled", FormatNames[format])
+		}
+
+		var m map[string]string
+		d := NewDecoder(bytes.NewReader(buf.Bytes()), WithGzipDecompression(1<<20))
+		err = d.Decode(&m)
+		if decodedFormat := d.Format; err != nil {
+			t.Errorf("%s: %v", FormatNames[format], err)
+		} else if decodedFormat != format || m["a"] != "b" {
+			t.Errorf("%s: unexpected %s document %#v", FormatNames[format], FormatNames[decodedFormat], m)
+		}
+	}
+
+	var v interface{}
+	d := NewDecoder(strings.NewReader("\x1f\x8b\x08\x00garbage"), WithGzipDecompression(1<<20))
+	if err := d.Decode(&v); err == nil {
+		t.Error("Expected an error for a corrupt gzip stream")
+	}
+
+	// A document that decompresses to more than the limit is rejected.
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("<plist><string>"))
+	zw.Write(make([]byte, 1<<16))
+	zw.Close()
+	d = NewDecoder(bytes.NewReader(buf.Bytes()), WithGzipDecompression(1<<12))
+	if err := d.Decode(&v); err == nil || !strings.Contains(err.Error(), "larger than 4096 bytes") {
+		t.Errorf("Expected the decompressed size to be limited, received %v", err)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	tests := []struct {
+		doc string
+		v   interface{}
+	}{
+		{`{ A = 1; }`, &struct{ A int }{}},
+		{`<plist version="1.0"><dict><key>A</key><string>true</string></dict></plist>`, &struct{ A bool }{}},
+		{`<plist version="1.0"><dict><key>A</key><dict><key>CF$UID</key><integer>1</integer></dict></dict></plist>`, &struct{ A UID }{}},
+		{`<plist version="1.0"><dict><key>A</key><string>x</string><key>A</key><string>y</string></dict></plist>`, &map[string]string{}},
+	}
+
+	for _, test := range tests {
+		if err := NewDecoder(strings.NewReader(test.doc), WithLax()).Decode(test.v); err != nil {
+			t.Errorf("%s: unexpected error without strict mode: %v", test.doc, err)
+		}
+		if err := NewDecoder(strings.NewReader(test.doc), WithLax(), WithStrict()).Decode(test.v); err == nil {
+			t.Errorf("%s: expected an error in strict mode", test.doc)
+		}
+	}
+
+	uids := struct {
+		A UID
+		B uint64
+	}{}
+	doc, err := Marshal(map[string]UID{"A": 1, "B": 2}, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Unmarshal(doc, &uids); err != nil || uids.B != 2 {
+		t.Errorf("Expected a UID to be stored in a uint64 without strict mode, received %v (%v)", uids.B, err)
+	}
+	if err := NewDecoder(bytes.NewReader(doc), WithStrict()).Decode(&uids); err == nil {
+		t.Error("Expected an error storing a UID in a uint64 in strict mode")
+	}
+
+	var v map[string]interface{}
+	d := NewDecoder(strings.NewReader(`{ A = { CF$UID = 1; }; }`), WithStrict())
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v["A"].(map[string]interface{}); !ok {
+		t.Errorf("Expected a CF$UID dictionary to remain a dictionary in strict mode, received %#v", v["A"])
+	}
+}
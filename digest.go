@@ -0,0 +1,134 @@
+package plist
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"math"
+	"time"
+)
+
+// Digest parses the property list in data, which may be in any format, and returns a SHA-256
+// hash of its contents. Documents that Equal reports as equal have the same digest: the hash is
+// independent of the document's format, key order, whitespace and the encoded width of its
+// numbers, so it can be used to find duplicate or changed documents without comparing them.
+func Digest(data []byte) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	pval, err := NewDecoder(bytes.NewReader(data)).parseDocument()
+	if err != nil {
+		return sum, err
+	}
+
+	d := &digester{h: sha256.New(), containers: make(map[cfValue][]byte)}
+	d.add(pval)
+	copy(sum[:], d.h.Sum(nil))
+	return sum, nil
+}
+
+// Type tags for the canonical form hashed by Digest.
+const (
+	digestString byte = iota + 1
+	digestInteger
+	digestNegativeInteger
+	digestReal
+	digestBoolean
+	digestUID
+	digestData
+	digestDate
+	digestArray
+	digestDictionary
+)
+
+// digester writes the canonical form of a value to a hash. Every value is written as a type
+// tag followed by a fixed-size representation or a length-prefixed sequence, so that distinct
+// values cannot produce the same stream of bytes.
+//
+// Arrays and dictionaries are hashed separately, and represented in the canonical form of their
+// container by their own hash. Each is hashed only once, however many places it appears in, so
+// that a small binary property list whose objects refer to one another many times over cannot
+// make hashing take exponential time.
+type digester struct {
+	h          hash.Hash
+	buf        [8]byte
+	containers map[cfValue][]byte
+}
+
+func (d *digester) uint64(tag byte, n uint64) {
+	d.h.Write([]byte{tag})
+	binary.BigEndian.PutUint64(d.buf[:], n)
+	d.h.Write(d.buf[:])
+}
+
+func (d *digester) bytes(tag byte, b []byte) {
+	d.uint64(tag, uint64(len(b)))
+	d.h.Write(b)
+}
+
+func (d *digester) add(pval cfValue) {
+	switch pval := pval.(type) {
+	case cfString:
+		d.bytes(digestString, []byte(pval))
+	case *cfNumber:
+		if cfNumberNegative(pval) {
+			d.uint64(digestNegativeInteger, pval.value)
+		} else {
+			d.uint64(digestInteger, pval.value)
+		}
+	case *cfReal:
+		f := pval.value
+		switch {
+		case math.IsNaN(f):
+			f = math.NaN()
+		case f == 0:
+			f = 0 // -0 is equal to 0
+		}
+		d.uint64(digestReal, math.Float64bits(f))
+	case cfBoolean:
+		var b uint64
+		if pval {
+			b = 1
+		}
+		d.uint64(digestBoolean, b)
+	case cfUID:
+		d.uint64(digestUID, uint64(pval))
+	case cfData:
+		d.bytes(digestData, pval)
+	case cfDate:
+		t := time.Time(pval)
+		d.uint64(digestDate, uint64(t.Unix()))
+		binary.BigEndian.PutUint32(d.buf[:4], uint32(t.Nanosecond()))
+		d.h.Write(d.buf[:4])
+	case *cfArray, *cfDictionary:
+		d.h.Write(d.container(pval))
+	}
+}
+
+// container returns the hash of the canonical form of the array or dictionary pval.
+func (d *digester) container(pval cfValue) []byte {
+	if sum, ok := d.containers[pval]; ok {
+		return sum
+	}
+
+	outer := d.h
+	d.h = sha256.New()
+	switch pval := pval.(type) {
+	case *cfArray:
+		d.uint64(digestArray, uint64(len(pval.values)))
+		for _, subval := range pval.values {
+			d.add(subval)
+		}
+	case *cfDictionary:
+		d.uint64(digestDictionary, uint64(len(pval.keys)))
+		pval.sort()
+		for i, key := range pval.keys {
+			d.bytes(digestString, []byte(key))
+			d.add(pval.values[i])
+		}
+	}
+	sum := d.h.Sum(nil)
+	d.h = outer
+
+	d.containers[pval] = sum
+	return sum
+}
@@ -2,4 +2,8 @@
 // Property lists come in three sorts: plain text (GNUStep and OpenStep), XML and binary.
 // plist supports all of them.
 // The mapping between property list and Go objects is described in the documentation for the Marshal and Unmarshal functions.
+//
+// XML property lists are parsed without reference to any DTD. External DTDs and entities are never
+// fetched, and documents that declare their own entities (or carry a DOCTYPE with an internal subset)
+// are rejected.
 package plist
@@ -0,0 +1,99 @@
+package plist
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// A DurationFormat is a way of storing time.Duration values in a property list.
+type DurationFormat int
+
+const (
+	// NanosecondDurations, the default, stores durations as integer numbers of nanoseconds, as
+	// for any other int64.
+	NanosecondDurations DurationFormat = iota
+
+	// SecondDurations stores durations as real numbers of seconds. This is how most property
+	// lists (such as launchd's) express intervals. When decoding, integers are also taken to
+	// be numbers of seconds.
+	SecondDurations
+
+	// StringDurations stores durations as strings in the form written by time.Duration's
+	// String method, such as "1m30s".
+	StringDurations
+)
+
+// durationFlags maps the struct tag flags that select a DurationFormat for a single field onto
+// the formats they select.
+var durationFlags = map[string]DurationFormat{
+	"nanoseconds":    NanosecondDurations,
+	"seconds":        SecondDurations,
+	"durationstring": StringDurations,
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// marshalDuration marshals d in the format chosen for the struct field being encoded, or in the
+// Encoder's format if the field does not choose one.
+func (p *Encoder) marshalDuration(d time.Duration) cfValue {
+	format := p.durationFormat
+	if p.fieldDuration != nil {
+		format = *p.fieldDuration
+	}
+
+	switch format {
+	case SecondDurations:
+		return &cfReal{wide: true, value: d.Seconds()}
+	case StringDurations:
+		return cfString(d.String())
+	}
+	return &cfNumber{signed: true, value: uint64(d)}
+}
+
+// unmarshalDuration stores a duration in val in any of the forms the Decoder's format allows.
+// Strings in the form written by time.Duration's String method are always accepted. It returns false for integers that are to be decoded as
+// nanoseconds, and for other values, which are left to be decoded as usual.
+func (p *Decoder) unmarshalDuration(pval cfValue, val reflect.Value) bool {
+	format := p.durationFormat
+	if p.fieldDuration != nil {
+		format = *p.fieldDuration
+	}
+
+	var d time.Duration
+	switch pval := pval.(type) {
+	case cfString:
+		var err error
+		if d, err = time.ParseDuration(string(pval)); err != nil {
+			if format != SecondDurations {
+				return false
+			}
+			// Text property lists store numbers as strings.
+			return p.unmarshalDuration(&cfReal{wide: true, value: mustParseFloat(string(pval), 64)}, val)
+		}
+	case *cfReal:
+		if format != SecondDurations {
+			return false
+		}
+		ns := math.Round(pval.value * float64(time.Second))
+		if math.IsNaN(ns) || ns < math.MinInt64 || ns >= math.MaxInt64 {
+			panic(fmt.Errorf("plist: %v seconds is out of range for a time.Duration", pval.value))
+		}
+		d = time.Duration(ns)
+	case *cfNumber:
+		if format != SecondDurations {
+			return false
+		}
+		secs := int64(pval.value)
+		if (!pval.signed && pval.value > math.MaxInt64) || secs > math.MaxInt64/int64(time.Second) || secs < math.MinInt64/int64(time.Second) {
+			panic(fmt.Errorf("plist: %v seconds is out of range for a time.Duration", pval.value))
+		}
+		d = time.Duration(secs) * time.Second
+	default:
+		return false
+	}
+
+	val.SetInt(int64(d))
+	return true
+}
@@ -0,0 +1,91 @@
+package plist
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type durationConfig struct {
+	Default  time.Duration
+	Interval time.Duration   `plist:",seconds"`
+	Timeout  time.Duration   `plist:",durationstring"`
+	Retries  []time.Duration `plist:",nanoseconds"`
+}
+
+func TestDurationEncoding(t *testing.T) {
+	v := durationConfig{
+		Default:  90 * time.Second,
+		Interval: 1500 * time.Millisecond,
+		Timeout:  2 * time.Minute,
+		Retries:  []time.Duration{time.Millisecond},
+	}
+
+	tests := []struct {
+		format   DurationFormat
+		expected string
+	}{
+		{NanosecondDurations, "{Default=90000000000;Interval=1.5;Retries=(1000000,);Timeout=2m0s;}"},
+		{SecondDurations, "{Default=90;Interval=1.5;Retries=(1000000,);Timeout=2m0s;}"},
+		{StringDurations, "{Default=1m30s;Interval=1.5;Retries=(1000000,);Timeout=2m0s;}"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		enc := NewEncoderForFormat(&buf, OpenStepFormat)
+		enc.SetDurationFormat(test.format)
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("%d: Expected %s, got %s", test.format, test.expected, buf.String())
+		}
+
+		var decoded durationConfig
+		if err := NewDecoder(bytes.NewReader(buf.Bytes()), WithDurationFormat(test.format)).Decode(&decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, v) {
+			t.Errorf("%d: Round trip mismatch: %+v", test.format, decoded)
+		}
+	}
+}
+
+func TestDurationDecoding(t *testing.T) {
+	tests := []struct {
+		doc      string
+		expected durationConfig
+	}{
+		{`<dict><key>Default</key><string>250ms</string><key>Timeout</key><string>1h</string></dict>`, durationConfig{Default: 250 * time.Millisecond, Timeout: time.Hour}},
+		{`<dict><key>Interval</key><integer>3600</integer></dict>`, durationConfig{Interval: time.Hour}},
+		{`<dict><key>Interval</key><real>0.25</real></dict>`, durationConfig{Interval: 250 * time.Millisecond}},
+		{`<dict><key>Interval</key><string>1m</string></dict>`, durationConfig{Interval: time.Minute}},
+	}
+	for _, test := range tests {
+		var v durationConfig
+		if err := NewDecoder(strings.NewReader(`<plist version="1.0">` + test.doc + `</plist>`)).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("%s: Expected %+v, got %+v", test.doc, test.expected, v)
+		}
+	}
+
+	invalid := []string{
+		`<real>1.5</real>`, // nanoseconds cannot be fractional
+		`<dict><key>Interval</key><real>1e300</real></dict>`,
+		`<dict><key>Timeout</key><string>soon</string></dict>`,
+	}
+	for _, doc := range invalid {
+		var v durationConfig
+		var d time.Duration
+		target := interface{}(&v)
+		if !strings.HasPrefix(doc, "<dict>") {
+			target = &d
+		}
+		if _, err := Unmarshal([]byte(`<plist version="1.0">`+doc+`</plist>`), target); err == nil {
+			t.Errorf("%s: Expected an error", doc)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package plist
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
+	"math/big"
 	"reflect"
 	"runtime"
 )
@@ -14,11 +16,45 @@ type generator interface {
 }
 
 // An Encoder writes a property list to an output stream.
+//
+// Output is reproducible: it depends only on the value being encoded and the Encoder's
+// settings, and never on map iteration order, the addresses of values or the location of a
+// time.Time. Dictionary keys are written in the order chosen by SetKeyOrdering (or by
+// SetXcodeCompatibility), except those of an OrderedDict, which are written in the order they
+// were set; equal values are always shared (or not) in the same way.
 type Encoder struct {
 	writer io.Writer
 	format int
 
 	indent string
+	inline inlineLimits
+
+	maxOutputSize int64
+
+	fractionalSeconds bool
+	iso8601Dates      bool
+	gnustepBase       bool
+	xmlVersion        string
+	fragment          bool
+	xcode             bool
+	minOffsetIntSize  int
+	minObjectRefSize  int
+
+	skipUnsupported        bool
+	unsupportedPlaceholder string
+
+	keyOrdering      KeyOrdering
+	bigFloatRounding big.RoundingMode
+	durationFormat   DurationFormat
+	fieldDuration    *DurationFormat // chosen by the tag of the struct field being encoded
+
+	preHook  PreHook
+	postHook PostHook
+	filter   FieldFilter
+	path     []string
+
+	progressFunc ProgressFunc
+	progress     *progressTracker // set during Encode if progressFunc is
 }
 
 // Encode writes the property list encoding of v to the stream.
@@ -28,35 +64,324 @@ func (p *Encoder) Encode(v interface{}) (err error) {
 			if _, ok := r.(runtime.Error); ok {
 				panic(r)
 			}
-			err = r.(error)
+			// A panic leaves the path at the value being encoded when it happened.
+			err = withPath(r.(error), p.path)
+			p.path = nil
 		}
 	}()
 
-	pval := p.marshal(reflect.ValueOf(v))
+	p.validate()
+
+	if p.progressFunc != nil {
+		p.progress = &progressTracker{fn: p.progressFunc}
+		defer func() { p.progress = nil }()
+	}
+
+	pval := p.marshalHooked(reflect.ValueOf(v))
 	if pval == nil {
 		panic(errors.New("plist: no root element to encode"))
 	}
 
+	p.generate(pval)
+	p.progress.done()
+	return
+}
+
+// validFormat reports whether format names a format that property lists can be encoded in.
+func validFormat(format int) bool {
+	switch format {
+	case AutomaticFormat, XMLFormat, BinaryFormat, OpenStepFormat, GNUStepFormat, PrettyFormat:
+		return true
+	}
+	return false
+}
+
+// validate panics if the Encoder's settings are invalid, or do not apply to its format.
+func (p *Encoder) validate() {
+	if !validFormat(p.format) {
+		panic(fmt.Errorf("plist: unknown format %d", p.format))
+	}
+
+	inapplicable := func(setting string) {
+		panic(fmt.Errorf("plist: %s cannot be used with the %s format", setting, FormatNames[p.format]))
+	}
+
+	if p.xmlVersion != "" {
+		if p.format != XMLFormat {
+			inapplicable("an XML version")
+		}
+		if !xmlPlistVersions[p.xmlVersion] {
+			panic(fmt.Errorf("plist: unsupported XML property list version %q", p.xmlVersion))
+		}
+	}
+
+	if p.fragment {
+		if p.format != XMLFormat && p.format != OpenStepFormat && p.format != GNUStepFormat {
+			inapplicable("fragment output")
+		}
+		if p.xmlVersion != "" {
+			panic(errors.New("plist: an XML version cannot be used with fragment output"))
+		}
+	}
+
+	if p.xcode {
+		if p.format != XMLFormat {
+			inapplicable("Xcode compatibility")
+		}
+		if p.xmlVersion != "" || p.fragment || p.inline != (inlineLimits{}) {
+			panic(errors.New("plist: an XML version, fragment output and inline containers cannot be used with Xcode compatibility"))
+		}
+	}
+
+	if p.minOffsetIntSize != 0 || p.minObjectRefSize != 0 {
+		if p.format != BinaryFormat && p.format != AutomaticFormat {
+			inapplicable("binary integer sizes")
+		}
+		if !validBplistIntSize(p.minOffsetIntSize) || !validBplistIntSize(p.minObjectRefSize) {
+			panic(fmt.Errorf("plist: invalid binary integer sizes (offset %d, object reference %d)", p.minOffsetIntSize, p.minObjectRefSize))
+		}
+	}
+
+	if p.fractionalSeconds && p.format != GNUStepFormat {
+		inapplicable("GNUStep fractional seconds")
+	}
+
+	if p.iso8601Dates && p.format != OpenStepFormat {
+		inapplicable("ISO 8601 dates")
+	}
+
+	if p.gnustepBase && p.format != GNUStepFormat {
+		inapplicable("gnustep-base compatibility")
+	}
+
+	if p.inline != (inlineLimits{}) {
+		if p.format != XMLFormat && p.format != OpenStepFormat && p.format != GNUStepFormat {
+			inapplicable("inline containers")
+		}
+		if p.gnustepBase {
+			panic(errors.New("plist: inline containers cannot be used with gnustep-base compatibility"))
+		}
+		if p.inline.maxMembers < 0 || p.inline.maxWidth < 0 {
+			panic(fmt.Errorf("plist: invalid inline container limits (%d members, width %d)", p.inline.maxMembers, p.inline.maxWidth))
+		}
+	}
+
+	if p.maxOutputSize < 0 {
+		panic(fmt.Errorf("plist: invalid maximum output size %d", p.maxOutputSize))
+	}
+
+	if p.durationFormat != NanosecondDurations && p.durationFormat != SecondDurations && p.durationFormat != StringDurations {
+		panic(fmt.Errorf("plist: unknown duration format %d", p.durationFormat))
+	}
+
+	if p.keyOrdering != BytewiseKeyOrdering && p.keyOrdering != NaturalKeyOrdering {
+		panic(fmt.Errorf("plist: unknown key ordering %d", p.keyOrdering))
+	}
+}
+
+// generate writes pval to the stream in the Encoder's format.
+func (p *Encoder) generate(pval cfValue) {
+	if p.keyOrdering == NaturalKeyOrdering || p.xcode {
+		applyKeyOrdering(pval, naturalKeyLess)
+	}
+
+	w := p.writer
+	if p.maxOutputSize > 0 {
+		w = &limitedWriter{Writer: w, limit: p.maxOutputSize}
+	}
+	if p.progress != nil {
+		w = &progressWriter{Writer: w, t: p.progress}
+	}
+
 	var g generator
 	switch p.format {
 	case XMLFormat:
-		g = newXMLPlistGenerator(p.writer)
+		xg := newXMLPlistGenerator(w)
+		xg.version = p.xmlVersion
+		xg.fragment = p.fragment
+		xg.inline = p.inline
+		xg.xcode = p.xcode
+		g = xg
 	case BinaryFormat, AutomaticFormat:
-		g = newBplistGenerator(p.writer)
+		bg := newBplistGenerator(w)
+		bg.minOffsetIntSize = p.minOffsetIntSize
+		bg.minObjectRefSize = p.minObjectRefSize
+		g = bg
+	case PrettyFormat:
+		g = newPrettyPlistGenerator(w)
 	case OpenStepFormat, GNUStepFormat:
-		g = newTextPlistGenerator(p.writer, p.format)
+		tg := newTextPlistGenerator(w, p.format)
+		tg.fractionalSeconds = p.fractionalSeconds
+		tg.iso8601Dates = p.iso8601Dates
+		tg.gnustepLayout = p.gnustepBase
+		tg.inline = p.inline
+		g = tg
 	}
 	g.Indent(p.indent)
 	g.generateDocument(pval)
-	return
+}
+
+// limitedWriter fails any write that would take the total written past limit bytes.
+type limitedWriter struct {
+	io.Writer
+	limit   int64
+	written int64
+}
+
+func (l *limitedWriter) Write(b []byte) (int, error) {
+	if l.written+int64(len(b)) > l.limit {
+		// Raise this error out-of-band: generators do not check for write errors.
+		panic(&limitExceededError{"bytes of output", l.limit})
+	}
+	n, err := l.Writer.Write(b)
+	l.written += int64(n)
+	return n, err
 }
 
 // Indent turns on pretty-printing for the XML and Text property list formats.
 // Each element begins on a new line and is preceded by one or more copies of indent according to its nesting depth.
+// PrettyFormat output is always indented, with two spaces unless another indent is given.
 func (p *Encoder) Indent(indent string) {
 	p.indent = indent
 }
 
+// SetInlineLimits makes indented XML, OpenStep and GNUStep output keep small dictionaries and
+// arrays on a single line, as they are often written by hand, while larger ones are still broken
+// across lines. A dictionary or array is written inline if it has no more than maxMembers members,
+// none of which is a non-empty dictionary or array, and if (when maxWidth is not 0) it then takes up
+// no more than maxWidth bytes. OpenStep and GNUStep containers are written as (1, 2, 3) and
+// { a = 1; b = 2; }; XML containers have no whitespace between their elements. A maxMembers of 0,
+// the default, disables inline containers.
+func (p *Encoder) SetInlineLimits(maxMembers, maxWidth int) {
+	p.inline = inlineLimits{maxMembers: maxMembers, maxWidth: maxWidth}
+}
+
+// SetGNUStepFractionalSeconds controls whether dates in GNUStep property lists are written with
+// sub-second precision (for example, <*D2013-11-27 00:34:00.25 +0000>). By default, dates are
+// truncated to whole seconds. Dates with fractional seconds are always accepted when decoding.
+func (p *Encoder) SetGNUStepFractionalSeconds(enabled bool) {
+	p.fractionalSeconds = enabled
+}
+
+// SetOpenStepISO8601Dates controls whether dates in OpenStep property lists, which are written as
+// strings, take the RFC 3339 (ISO 8601) form "2013-11-27T00:34:00Z" rather than the default
+// "2013-11-27 00:34:00 +0000". Strings of either form are accepted when decoding into a time.Time.
+func (p *Encoder) SetOpenStepISO8601Dates(enabled bool) {
+	p.iso8601Dates = enabled
+}
+
+// SetGNUStepBaseCompatibility controls whether GNUStep property lists are laid out exactly as
+// gnustep-base writes them, so that generated files diff cleanly against those written on
+// GNUstep systems. Every dictionary entry and array element is put on its own line, indented by
+// four columns per level with each eight columns written as a tab, and the last element of an
+// array is not followed by a comma. The Encoder's indent is ignored.
+func (p *Encoder) SetGNUStepBaseCompatibility(enabled bool) {
+	p.gnustepBase = enabled
+}
+
+// SetXMLVersion sets the version attribute written on the root <plist> element of XML property
+// lists. The default is "1.0"; "0.9" produces documents in the style of very early releases of
+// Mac OS X.
+func (p *Encoder) SetXMLVersion(version string) {
+	p.xmlVersion = version
+}
+
+// SetXcodeCompatibility controls whether XML property lists are written exactly as Xcode (and
+// other tools built on CoreFoundation) write them, so that files opened and saved again in Xcode
+// do not change. Dictionary keys are written in NaturalKeyOrdering; every element is indented by
+// one tab per level, with the root element at the same level as <plist>; data is written as lines
+// of base64 on their own; only &, < and > are escaped; empty strings are written as
+// <string></string>; and the document ends with a newline. The Encoder's indent and key ordering
+// are ignored.
+func (p *Encoder) SetXcodeCompatibility(enabled bool) {
+	p.xcode = enabled
+}
+
+// SetFragment controls whether the Encoder writes a bare value rather than a complete document,
+// for callers that embed property list values in larger documents of their own. XML output then
+// consists of the root value's element alone (such as <dict>...</dict>), with no XML declaration,
+// DOCTYPE or <plist> element. OpenStep and GNUStep output already consists of the bare value and
+// is unchanged. Fragment output cannot be used with the binary or pretty formats.
+func (p *Encoder) SetFragment(enabled bool) {
+	p.fragment = enabled
+}
+
+// SetBinaryIntSizes sets the minimum width, in bytes, of the offset table entries and object
+// references in binary property lists. Each must be 0, 1, 2, 4 or 8; the generator still uses
+// wider fields when a document needs them. Zero, the default, selects the smallest width that
+// fits. Fixed widths are useful for tools that patch binary property lists in place.
+func (p *Encoder) SetBinaryIntSizes(offsetSize, objectRefSize int) {
+	p.minOffsetIntSize = offsetSize
+	p.minObjectRefSize = objectRefSize
+}
+
+// SetMaxOutputSize makes Encode fail once the encoded document would exceed n bytes, rather than
+// generating the whole of an oversized document. Output is written as it is generated, so a
+// document that is too large may already have been partly written when Encode fails; nothing
+// beyond n bytes is ever written. Zero, the default, means that there is no limit.
+func (p *Encoder) SetMaxOutputSize(n int64) {
+	p.maxOutputSize = n
+}
+
+// SetProgressFunc registers fn to be called with the Encoder's progress through each document it
+// encodes, for example to drive a progress bar. A nil fn disables progress reports.
+func (p *Encoder) SetProgressFunc(fn ProgressFunc) {
+	p.progressFunc = fn
+}
+
+// SetSkipUnsupportedTypes controls whether values that have no property list representation
+// (channels, functions, complex numbers, maps whose keys are not strings, and so on) are omitted
+// from the struct, map or slice that contains them. By default, they cause Encode to fail.
+func (p *Encoder) SetSkipUnsupportedTypes(skip bool) {
+	p.skipUnsupported = skip
+}
+
+// SetUnsupportedTypePlaceholder arranges for values that have no property list representation to
+// be encoded as the string placeholder, rather than failing or being skipped. An empty placeholder
+// restores the behavior chosen by SetSkipUnsupportedTypes.
+func (p *Encoder) SetUnsupportedTypePlaceholder(placeholder string) {
+	p.unsupportedPlaceholder = placeholder
+}
+
+// SetKeyOrdering sets the order in which the Encoder writes the keys of each dictionary. Use
+// NaturalKeyOrdering to produce output that diffs cleanly against files written by plutil.
+func (p *Encoder) SetKeyOrdering(ordering KeyOrdering) {
+	p.keyOrdering = ordering
+}
+
+// SetBigFloatRounding sets the rounding mode used to convert *big.Float values, which are always
+// written as 64-bit reals, to the nearest float64. The default is big.ToNearestEven, as used by
+// (*big.Float).Float64.
+func (p *Encoder) SetBigFloatRounding(mode big.RoundingMode) {
+	p.bigFloatRounding = mode
+}
+
+// SetDurationFormat sets the form in which time.Duration values are written, unless the struct
+// field holding them chooses another with a "nanoseconds", "seconds" or "durationstring" tag flag.
+func (p *Encoder) SetDurationFormat(format DurationFormat) {
+	p.durationFormat = format
+}
+
+// SetHooks registers functions to be called before and after each value is encoded.
+// Either may be nil.
+func (p *Encoder) SetHooks(pre PreHook, post PostHook) {
+	p.preHook = pre
+	p.postHook = post
+}
+
+// A FieldFilter decides whether an Encoder includes a value in its output. path is the sequence
+// of dictionary keys (or struct field names, as they appear in the output) and array indices
+// leading to the value, as for a PreHook; its last element names the value itself.
+type FieldFilter func(path []string) bool
+
+// SetFieldFilter arranges for filter to be called for every value but the root, before it is
+// encoded. Values for which it returns false are left out of the output, along with everything
+// inside them. This allows one type to be encoded in full for some purposes and redacted for
+// others. A nil filter includes every value.
+func (p *Encoder) SetFieldFilter(filter FieldFilter) {
+	p.filter = filter
+}
+
 // NewEncoder returns an Encoder that writes an XML property list to w.
 func NewEncoder(w io.Writer) *Encoder {
 	return NewEncoderForFormat(w, XMLFormat)
@@ -82,14 +407,18 @@ func NewBinaryEncoder(w io.Writer) *Encoder {
 //
 // Marshal traverses the value v recursively.
 // Any nil values encountered, other than the root, will be silently discarded as
-// the property list format bears no representation for nil values.
+// the property list format bears no representation for nil values. The exceptions are
+// nil slices and maps, which are encoded as empty arrays and dictionaries (<array/> and
+// <dict/> in XML) so that the keys holding them are always present; use the omitempty
+// flag to leave them out instead.
 //
 // Strings, integers of varying size, floats and booleans are encoded unchanged.
+// big.Float values are encoded as 64-bit reals, rounded as set by SetBigFloatRounding.
 // Strings bearing non-ASCII runes will be encoded differently depending upon the property list format:
 // UTF-8 for XML property lists and UTF-16 for binary property lists.
 //
 // Slice and Array values are encoded as property list arrays, except for
-// []byte values, which are encoded as data.
+// []byte values, which are encoded as data. Runes values are encoded as strings.
 //
 // Map values encode as dictionaries. The map's key type must be string; there is no provision for encoding non-string dictionary keys.
 //
@@ -101,14 +430,24 @@ func NewBinaryEncoder(w io.Writer) *Encoder {
 // The following flags are supported:
 //
 //     omitempty    Only include the field if it is not set to the zero value for its type.
+//     seconds, nanoseconds, durationstring
+//                  Write the time.Duration values held by the field in the given form (see SetDurationFormat).
 //
 // If the key is "-", the field is ignored.
 //
 // Anonymous struct fields are encoded as if their exported fields were exposed via the outer struct.
+// So are the fields of a struct (or pointer to a struct) held by an untagged anonymous interface field;
+// they are shadowed by any field of the same name in the outer struct.
 //
 // Pointer values encode as the value pointed to.
 //
-// Channel, complex and function values cannot be encoded. Any attempt to do so causes Marshal to return an error.
+// Values that implement driver.Valuer (such as sql.NullString and sql.NullTime) encode as the value
+// they report. A NULL value is discarded, as a nil pointer would be.
+//
+// Iterator functions (such as iter.Seq[V] and iter.Seq2[string, V]) are encoded as arrays of the values they
+// produce, or as dictionaries if they produce pairs of string keys and values.
+//
+// Channel, complex and other function values cannot be encoded. Any attempt to do so causes Marshal to return an error.
 func Marshal(v interface{}, format int) ([]byte, error) {
 	return MarshalIndent(v, format, "")
 }
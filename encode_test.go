@@ -3,7 +3,10 @@ package plist
 import (
 	"bytes"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func BenchmarkXMLEncode(b *testing.B) {
@@ -153,3 +156,320 @@ func ExampleMarshal_gnustep() {
 	// 	size = <*I4398046511104>;
 	// }
 }
+
+func TestEncoderHooks(t *testing.T) {
+	type Account struct {
+		User     string
+		Password string
+		Hosts    []string
+	}
+
+	var emitted []string
+	buf := &bytes.Buffer{}
+	encoder := NewEncoderForFormat(buf, OpenStepFormat)
+	encoder.SetHooks(func(path []string, v reflect.Value) (reflect.Value, error) {
+		if len(path) == 1 && path[0] == "Password" {
+			return reflect.ValueOf("<redacted>"), nil
+		}
+		if len(path) == 2 && path[0] == "Hosts" && path[1] == "1" {
+			return reflect.Value{}, nil
+		}
+		return v, nil
+	}, func(path []string, v reflect.Value) error {
+		emitted = append(emitted, strings.Join(path, "/"))
+		return nil
+	})
+
+	err := encoder.Encode(&Account{User: "u", Password: "hunter2", Hosts: []string{"a", "b", "c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{Hosts=(a,c,);Password="<redacted>";User=u;}`
+	if buf.String() != expected {
+		t.Errorf("Expected %s, received %s", expected, buf.String())
+	}
+
+	expectedPaths := []string{"User", "Password", "Hosts/0", "Hosts/2", "Hosts", ""}
+	if !reflect.DeepEqual(emitted, expectedPaths) {
+		t.Errorf("Expected hooks for %v, received %v", expectedPaths, emitted)
+	}
+}
+
+func TestEncoderFieldFilter(t *testing.T) {
+	type Device struct {
+		Serial   string
+		Name     string
+		Secrets  map[string]string
+		Networks []string
+	}
+	device := Device{"C02XYZ", "Lab", map[string]string{"PSK": "hunter2", "Hint": "h"}, []string{"corp", "guest"}}
+
+	minimal := func(path []string) bool {
+		switch path[len(path)-1] {
+		case "Name", "PSK":
+			return false
+		}
+		return !(len(path) == 2 && path[0] == "Networks" && path[1] == "1")
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := NewEncoderForFormat(buf, OpenStepFormat)
+	encoder.SetFieldFilter(minimal)
+	if err := encoder.Encode(device); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{Networks=(corp,);Secrets={Hint=h;};Serial=C02XYZ;}`
+	if buf.String() != expected {
+		t.Errorf("Expected %s, received %s", expected, buf.String())
+	}
+
+	buf.Reset()
+	encoder = NewEncoderForFormat(buf, OpenStepFormat)
+	if err := encoder.Encode(device); err != nil {
+		t.Fatal(err)
+	}
+	expected = `{Name=Lab;Networks=(corp,guest,);Secrets={Hint=h;PSK=hunter2;};Serial=C02XYZ;}`
+	if buf.String() != expected {
+		t.Errorf("Expected %s, received %s", expected, buf.String())
+	}
+}
+
+func TestEncoderMaxOutputSize(t *testing.T) {
+	v := map[string]interface{}{"payload": strings.Repeat("x", 5000), "n": []int{1, 2, 3}}
+	for _, format := range []int{XMLFormat, BinaryFormat, OpenStepFormat, GNUStepFormat, PrettyFormat} {
+		full, err := Marshal(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, limit := range []int64{int64(len(full)), int64(len(full)) - 1, 100} {
+			buf := &bytes.Buffer{}
+			enc := NewEncoderForFormat(buf, format)
+			enc.SetMaxOutputSize(limit)
+			err := enc.Encode(v)
+			if limit == int64(len(full)) {
+				if err != nil || !bytes.Equal(buf.Bytes(), full) {
+					t.Errorf("%s: Expected the whole document within a limit of %d, received %v", FormatNames[format], limit, err)
+				}
+			} else if err == nil {
+				t.Errorf("%s: Expected an error with a limit of %d (document is %d bytes)", FormatNames[format], limit, len(full))
+			} else if int64(buf.Len()) > limit {
+				t.Errorf("%s: Wrote %d bytes, past the limit of %d", FormatNames[format], buf.Len(), limit)
+			}
+		}
+	}
+}
+
+func TestEncodeReproducible(t *testing.T) {
+	instant := time.Date(2019, 4, 1, 12, 30, 0, 0, time.UTC)
+	build := func(n int, loc *time.Location) map[string]interface{} {
+		m := make(map[string]interface{})
+		for i := 0; i < n; i++ {
+			m[fmt.Sprintf("key%d", (i*7)%n)] = map[string]interface{}{
+				"index": i,
+				"name":  fmt.Sprintf("value%d", i%5),
+				"data":  []byte{byte(i % 3)},
+			}
+		}
+		m["created"] = instant
+		m["modified"] = instant.In(loc)
+		for _, k := range []string{"Item 10", "item 2", "Item 2", "ITEM 2"} {
+			m[k] = k
+		}
+		return m
+	}
+	encode := func(v interface{}, format int, configure func(*Encoder)) []byte {
+		var buf bytes.Buffer
+		enc := NewEncoderForFormat(&buf, format)
+		configure(enc)
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	settings := map[string]func(*Encoder){
+		"default":          func(*Encoder) {},
+		"natural ordering": func(enc *Encoder) { enc.SetKeyOrdering(NaturalKeyOrdering) },
+	}
+	for _, format := range []int{XMLFormat, BinaryFormat, OpenStepFormat, GNUStepFormat} {
+		if format == XMLFormat {
+			settings["Xcode"] = func(enc *Encoder) { enc.SetXcodeCompatibility(true) }
+		} else {
+			delete(settings, "Xcode")
+		}
+
+		for name, configure := range settings {
+			expected := encode(build(64, time.UTC), format, configure)
+			for i := 0; i < 10; i++ {
+				for _, loc := range []*time.Location{time.UTC, time.FixedZone("PDT", -7*60*60), time.Local} {
+					if actual := encode(build(64, loc), format, configure); !bytes.Equal(expected, actual) {
+						t.Errorf("%s, %s: encoding #%d in %v differs from the first", FormatNames[format], name, i, loc)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeUnsupportedTypes(t *testing.T) {
+	type config struct {
+		Name     string
+		Updates  chan int
+		Callback func()
+		Weights  map[int]string
+		List     []interface{}
+	}
+	value := config{
+		Name:     "example",
+		Updates:  make(chan int),
+		Callback: func() {},
+		Weights:  map[int]string{1: "one"},
+		List:     []interface{}{"a", complex(1, 2), "b"},
+	}
+
+	if _, err := Marshal(value, OpenStepFormat); err == nil {
+		t.Error("Expected an error encoding unsupported types")
+	}
+
+	buf := &bytes.Buffer{}
+	enc := NewEncoderForFormat(buf, OpenStepFormat)
+	enc.SetSkipUnsupportedTypes(true)
+	if err := enc.Encode(value); err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{List=(a,b,);Name=example;}`; buf.String() != expected {
+		t.Errorf("Expected %s, received %s", expected, buf.String())
+	}
+
+	buf.Reset()
+	enc.SetUnsupportedTypePlaceholder("?")
+	if err := enc.Encode(value); err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{Callback="?";List=(a,"?",b,);Name=example;Updates="?";Weights="?";}`; buf.String() != expected {
+		t.Errorf("Expected %s, received %s", expected, buf.String())
+	}
+
+	enc = NewEncoder(&bytes.Buffer{})
+	enc.SetSkipUnsupportedTypes(true)
+	if err := enc.Encode(make(chan int)); err == nil {
+		t.Error("Expected an error encoding an unsupported root value")
+	}
+}
+
+func TestEncodeInvalidSettings(t *testing.T) {
+	tests := []struct {
+		name   string
+		format int
+		setup  func(*Encoder)
+	}{
+		{"unknown format", 99, func(*Encoder) {}},
+		{"XML version on binary", BinaryFormat, func(e *Encoder) { e.SetXMLVersion("1.0") }},
+		{"binary sizes on XML", XMLFormat, func(e *Encoder) { e.SetBinaryIntSizes(4, 4) }},
+		{"fractional seconds on XML", XMLFormat, func(e *Encoder) { e.SetGNUStepFractionalSeconds(true) }},
+		{"fractional seconds on OpenStep", OpenStepFormat, func(e *Encoder) { e.SetGNUStepFractionalSeconds(true) }},
+		{"ISO 8601 dates on GNUStep", GNUStepFormat, func(e *Encoder) { e.SetOpenStepISO8601Dates(true) }},
+		{"negative output size", XMLFormat, func(e *Encoder) { e.SetMaxOutputSize(-1) }},
+		{"fragment on binary", BinaryFormat, func(e *Encoder) { e.SetFragment(true) }},
+		{"fragment with XML version", XMLFormat, func(e *Encoder) { e.SetFragment(true); e.SetXMLVersion("0.9") }},
+		{"Xcode compatibility on OpenStep", OpenStepFormat, func(e *Encoder) { e.SetXcodeCompatibility(true) }},
+		{"Xcode compatibility with fragment", XMLFormat, func(e *Encoder) { e.SetXcodeCompatibility(true); e.SetFragment(true) }},
+		{"inline containers on binary", BinaryFormat, func(e *Encoder) { e.SetInlineLimits(4, 0) }},
+		{"negative inline width", OpenStepFormat, func(e *Encoder) { e.SetInlineLimits(4, -1) }},
+		{"inline containers with gnustep-base", GNUStepFormat, func(e *Encoder) { e.SetGNUStepBaseCompatibility(true); e.SetInlineLimits(4, 0) }},
+	}
+
+	for _, test := range tests {
+		enc := NewEncoderForFormat(&bytes.Buffer{}, test.format)
+		test.setup(enc)
+		if err := enc.Encode("hello"); err == nil {
+			t.Errorf("%s: Expected an error", test.name)
+		} else {
+			t.Logf("%s: %v", test.name, err)
+		}
+	}
+
+	if _, err := Marshal("hello", 42); err == nil {
+		t.Error("Expected Marshal to reject an unknown format")
+	}
+
+	var s string
+	if err := NewDecoder(strings.NewReader("hello"), WithRequiredFormat(42)).Decode(&s); err == nil {
+		t.Error("Expected Decode to reject an unknown required format")
+	}
+}
+
+func TestInlineLimits(t *testing.T) {
+	val := map[string]interface{}{
+		"Point": []int{1, 2},
+		"Long":  []string{"a rather long string", "another rather long string"},
+		"Size":  map[string]int{"w": 640, "h": 480},
+		"Many":  []int{1, 2, 3, 4, 5},
+		"Empty": []int{},
+	}
+
+	tests := []struct {
+		format   int
+		width    int
+		expected string
+	}{
+		{OpenStepFormat, 40, "{\n\tEmpty = ();\n\tLong = (\n\t\t\"a rather long string\",\n\t\t\"another rather long string\",\n\t);\n\tMany = (\n\t\t1,\n\t\t2,\n\t\t3,\n\t\t4,\n\t\t5,\n\t);\n\tPoint = (1, 2);\n\tSize = { h = 480; w = 640; };\n}"},
+		{XMLFormat, 90, xmlPreamble + "<plist version=\"1.0\">\n\t<dict>\n\t\t<key>Empty</key>\n\t\t<array/>\n\t\t<key>Long</key>\n\t\t<array>\n\t\t\t<string>a rather long string</string>\n\t\t\t<string>another rather long string</string>\n\t\t</array>\n\t\t<key>Many</key>\n\t\t<array>\n\t\t\t<integer>1</integer>\n\t\t\t<integer>2</integer>\n\t\t\t<integer>3</integer>\n\t\t\t<integer>4</integer>\n\t\t\t<integer>5</integer>\n\t\t</array>\n\t\t<key>Point</key>\n\t\t<array><integer>1</integer><integer>2</integer></array>\n\t\t<key>Size</key>\n\t\t<dict><key>h</key><integer>480</integer><key>w</key><integer>640</integer></dict>\n\t</dict>\n</plist>"},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		enc := NewEncoderForFormat(&buf, test.format)
+		enc.Indent("\t")
+		enc.SetInlineLimits(4, test.width)
+		if err := enc.Encode(val); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("%s: Expected:\n%s\nReceived:\n%s", FormatNames[test.format], test.expected, buf.String())
+		}
+	}
+}
+
+func TestNaturalKeyOrdering(t *testing.T) {
+	keys := []string{"Item 10", "item 1", "Item 2", "B", "a", "Item 02", "A"}
+	m := make(map[string]int, len(keys))
+	for _, k := range keys {
+		m[k] = 0
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoderForFormat(&buf, OpenStepFormat)
+	enc.SetKeyOrdering(NaturalKeyOrdering)
+	if err := enc.Encode(m); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{A=0;a=0;B=0;"item 1"=0;"Item 02"=0;"Item 2"=0;"Item 10"=0;}`
+	if buf.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, buf.String())
+	}
+
+	// Binary output must still decode to the same dictionary.
+	bin, err := func() ([]byte, error) {
+		var buf bytes.Buffer
+		enc := NewBinaryEncoder(&buf)
+		enc.SetKeyOrdering(NaturalKeyOrdering)
+		err := enc.Encode(m)
+		return buf.Bytes(), err
+	}()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]int
+	if _, err := Unmarshal(bin, &out); err != nil || !reflect.DeepEqual(out, m) {
+		t.Errorf("Binary round trip failed: %v %v", out, err)
+	}
+
+	enc.SetKeyOrdering(KeyOrdering(42))
+	if err := enc.Encode(m); err == nil {
+		t.Error("Expected an unknown key ordering to be rejected")
+	}
+}
@@ -0,0 +1,117 @@
+package plist
+
+import (
+	"bytes"
+	"math"
+	"time"
+)
+
+// Equal parses the property lists a and b, which may be in any format, and reports whether
+// they contain the same values. A binary property list and its XML conversion are equal.
+//
+// Dictionaries are equal when they hold the same keys with equal values, regardless of key
+// order. Integers and reals are compared by value, independent of their encoded width, and
+// NaN is equal to itself. Values of different types are never equal: since OpenStep property
+// lists can only store strings, they are rarely equal to documents of any other format.
+func Equal(a, b []byte) (bool, error) {
+	pa, err := NewDecoder(bytes.NewReader(a)).parseDocument()
+	if err != nil {
+		return false, err
+	}
+	pb, err := NewDecoder(bytes.NewReader(b)).parseDocument()
+	if err != nil {
+		return false, err
+	}
+	return cfValueEqual(pa, pb), nil
+}
+
+// cfValueEqual reports whether a and b hold the same values, as Equal does.
+func cfValueEqual(a, b cfValue) bool {
+	c := &cfComparer{}
+	return c.equal(a, b)
+}
+
+// cfComparer remembers the pairs of arrays and dictionaries it has compared, so that each pair
+// is compared only once, however many places it appears in. Without this, a small binary
+// property list whose objects refer to one another many times over could make a comparison take
+// exponential time.
+type cfComparer struct {
+	compared map[[2]cfValue]bool
+}
+
+// cfNumberNegative reports whether n holds a negative number.
+func cfNumberNegative(n *cfNumber) bool {
+	return n.signed && int64(n.value) < 0
+}
+
+func (c *cfComparer) equal(a, b cfValue) bool {
+	switch a.(type) {
+	case *cfArray, *cfDictionary:
+		pair := [2]cfValue{a, b}
+		if eq, ok := c.compared[pair]; ok {
+			return eq
+		}
+		eq := c.containersEqual(a, b)
+		if c.compared == nil {
+			c.compared = make(map[[2]cfValue]bool)
+		}
+		c.compared[pair] = eq
+		return eq
+	}
+
+	switch a := a.(type) {
+	case cfString:
+		b, ok := b.(cfString)
+		return ok && a == b
+	case *cfNumber:
+		b, ok := b.(*cfNumber)
+		return ok && a.value == b.value && cfNumberNegative(a) == cfNumberNegative(b)
+	case *cfReal:
+		b, ok := b.(*cfReal)
+		return ok && (a.value == b.value || math.IsNaN(a.value) && math.IsNaN(b.value))
+	case cfBoolean:
+		b, ok := b.(cfBoolean)
+		return ok && a == b
+	case cfUID:
+		b, ok := b.(cfUID)
+		return ok && a == b
+	case cfData:
+		b, ok := b.(cfData)
+		return ok && bytes.Equal(a, b)
+	case cfDate:
+		b, ok := b.(cfDate)
+		return ok && time.Time(a).Equal(time.Time(b))
+	}
+	return false
+}
+
+// containersEqual reports whether the array or dictionary a holds the same values as b.
+func (c *cfComparer) containersEqual(a, b cfValue) bool {
+	switch a := a.(type) {
+	case *cfArray:
+		b, ok := b.(*cfArray)
+		if !ok || len(a.values) != len(b.values) {
+			return false
+		}
+		for i := range a.values {
+			if !c.equal(a.values[i], b.values[i]) {
+				return false
+			}
+		}
+		return true
+	case *cfDictionary:
+		b, ok := b.(*cfDictionary)
+		if !ok || len(a.keys) != len(b.keys) {
+			return false
+		}
+		a.sort()
+		b.sort()
+		for i := range a.keys {
+			if a.keys[i] != b.keys[i] || !c.equal(a.values[i], b.values[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
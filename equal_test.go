@@ -0,0 +1,117 @@
+package plist
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEqual(t *testing.T) {
+	value := map[string]interface{}{
+		"string": "hello",
+		"int":    -42,
+		"uint":   uint64(math.MaxUint64),
+		"real":   float32(1.1),
+		"nan":    math.NaN(),
+		"bool":   true,
+		"data":   []byte{1, 2, 3},
+		"date":   time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+		"uid":    UID(9),
+		"array":  []interface{}{"a", 1, map[string]interface{}{"x": "y"}},
+	}
+
+	binary, err := Marshal(value, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xml, err := Marshal(value, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gnustep, err := Marshal(value, GNUStepFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, other := range [][]byte{binary, xml, gnustep} {
+		if eq, err := Equal(binary, other); err != nil || !eq {
+			t.Errorf("Expected binary and %s to be equal (%v)", other, err)
+		}
+	}
+
+	value["array"] = []interface{}{"a", 2, map[string]interface{}{"x": "y"}}
+	changed, err := Marshal(value, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eq, err := Equal(binary, changed); err != nil || eq {
+		t.Errorf("Expected documents to differ (%v)", err)
+	}
+
+	if eq, err := Equal([]byte(`(1, 2)`), []byte(`<plist><array><integer>1</integer><integer>2</integer></array></plist>`)); err != nil || eq {
+		t.Errorf("Expected OpenStep strings not to equal XML integers (%v)", err)
+	}
+
+	if _, err := Equal(binary, []byte("{ a = ")); err == nil {
+		t.Error("Expected an error for an invalid document")
+	}
+}
+
+func TestDigest(t *testing.T) {
+	xml := []byte(`<plist version="1.0"><dict>
+		<key>b</key><array><integer>1</integer><real>-0</real></array>
+		<key>a</key><string>x</string>
+	</dict></plist>`)
+	text := []byte(`{ a = x; b = (<*I1>, <*R0>); }`)
+
+	dx, err := Digest(xml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dt, err := Digest(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dx != dt {
+		t.Errorf("Expected equal documents to have the same digest")
+	}
+
+	changed, err := Digest([]byte(`{ a = x; b = (<*I2>, <*R0>); }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed == dx {
+		t.Errorf("Expected different documents to have different digests")
+	}
+
+	// The canonical form must not let a value's contents be mistaken for structure.
+	d1, _ := Digest([]byte(`("ab", "c")`))
+	d2, _ := Digest([]byte(`("a", "bc")`))
+	if d1 == d2 {
+		t.Errorf("Expected differently split strings to have different digests")
+	}
+}
+
+func TestEqualSharedObjects(t *testing.T) {
+	// Each of these documents has 2^48 paths to its string, through 48 shared arrays.
+	deep := sharedBplist(48)
+	if eq, err := Equal(deep, deep); err != nil || !eq {
+		t.Errorf("Expected a document to equal itself, received %v, %v", eq, err)
+	}
+	if _, err := Digest(deep); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sharing does not affect the digest.
+	shared, err := Digest(sharedBplist(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unshared, err := Digest([]byte(`((x, x), (x, x))`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shared != unshared {
+		t.Errorf("Expected shared and unshared documents to have the same digest")
+	}
+}
@@ -0,0 +1,370 @@
+package plist
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+)
+
+// SkipValue can be returned by the StartDictionary, StartArray and Key callbacks of an
+// EventHandler to skip a value: the container that is starting, or the value that follows
+// the key. No events are delivered for anything inside a skipped value. It is not returned as
+// an error by any function.
+var SkipValue = errors.New("skip this value")
+
+// StopDecoding can be returned by any callback of an EventHandler to end DecodeEvents early,
+// once the caller has found what it was looking for. DecodeEvents then returns nil without
+// reading the rest of the document, which is therefore not checked for errors.
+var StopDecoding = errors.New("stop decoding")
+
+// An EventHandler receives the contents of a property list from Decoder.DecodeEvents as a
+// sequence of events. Every callback is optional.
+//
+// path is the sequence of dictionary keys and array indices (formatted as decimal strings)
+// leading to the value the event concerns; the root value has an empty path. path is only
+// valid for the duration of the call. If a callback returns StopDecoding, DecodeEvents stops and
+// returns nil; if it returns any other error but SkipValue, DecodeEvents stops and returns it.
+type EventHandler struct {
+	// StartDictionary and EndDictionary are called at the beginning and end of a dictionary.
+	StartDictionary func(path []string) error
+	EndDictionary   func(path []string) error
+
+	// Key is called for each key in a dictionary, before the events for its value. path is
+	// the path of the dictionary; the key is appended to it for the value's events.
+	Key func(path []string, key string) error
+
+	// StartArray and EndArray are called at the beginning and end of an array.
+	StartArray func(path []string) error
+	EndArray   func(path []string) error
+
+	// Value is called for every value that is not a dictionary or array. v holds the value in
+	// the form Unmarshal would use when decoding into an empty interface.
+	Value func(path []string, v interface{}) error
+}
+
+// eventHandlerError carries an error returned by an EventHandler out of the parsers, which
+// would otherwise report it as a problem with the document.
+type eventHandlerError struct {
+	err error
+}
+
+func (e eventHandlerError) Error() string {
+	return e.err.Error()
+}
+
+// eventEmitter delivers events to an EventHandler, tracking the path to the current value.
+type eventEmitter struct {
+	h    *EventHandler
+	d    *Decoder
+	path []string
+}
+
+// call invokes a callback, reporting whether the value it concerns should be skipped.
+func (e *eventEmitter) call(fn func([]string) error) bool {
+	if fn == nil {
+		return false
+	}
+	err := fn(e.path)
+	if err == SkipValue {
+		return true
+	} else if err != nil {
+		panic(eventHandlerError{err})
+	}
+	return false
+}
+
+func (e *eventEmitter) startDictionary() bool { return e.call(e.h.StartDictionary) }
+func (e *eventEmitter) endDictionary()        { e.call(e.h.EndDictionary) }
+func (e *eventEmitter) startArray() bool      { return e.call(e.h.StartArray) }
+func (e *eventEmitter) endArray()             { e.call(e.h.EndArray) }
+
+func (e *eventEmitter) key(k string) bool {
+	if e.h.Key == nil {
+		return false
+	}
+	return e.call(func(path []string) error { return e.h.Key(path, k) })
+}
+
+func (e *eventEmitter) value(pval cfValue) {
+	if e.h.Value == nil {
+		return
+	}
+	e.call(func(path []string) error { return e.h.Value(path, e.d.valueInterface(pval)) })
+}
+
+func (e *eventEmitter) push(elem string) {
+	e.path = append(e.path, elem)
+}
+
+func (e *eventEmitter) pop() {
+	e.path = e.path[:len(e.path)-1]
+}
+
+// emitValue delivers the events for a value that has already been parsed.
+func (e *eventEmitter) emitValue(pval cfValue) {
+	switch pval := pval.(type) {
+	case *cfDictionary:
+		if e.startDictionary() {
+			return
+		}
+		for i, k := range pval.keys {
+			if e.key(k) {
+				continue
+			}
+			e.push(k)
+			e.emitValue(pval.values[i])
+			e.pop()
+		}
+		e.endDictionary()
+	case *cfArray:
+		if e.startArray() {
+			return
+		}
+		for i, v := range pval.values {
+			e.push(strconv.Itoa(i))
+			e.emitValue(v)
+			e.pop()
+		}
+		e.endArray()
+	default:
+		e.value(pval)
+	}
+}
+
+// DecodeEvents reads a property list from the decoder stream and delivers its contents to h as
+// a sequence of events, in document order, rather than decoding it into a value. After
+// DecodeEvents returns, the Decoder's Format field is set as it would be by Decode.
+//
+// Binary and XML property lists are streamed: no tree of values is built, and only the values
+// delivered to h.Value are allocated, so a few fields can be extracted cheaply from very large
+// documents. In XML property lists, a dictionary holding only a CF$UID key is reported as a
+// dictionary rather than as a UID. OpenStep and GNUStep property lists are parsed in full
+// before their events are delivered.
+func (p *Decoder) DecodeEvents(h *EventHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+		// Errors from h are returned as they are, even if a parser has wrapped them.
+		if pe, ok := err.(plistParseError); ok {
+			if he, ok := pe.err.(eventHandlerError); ok {
+				err = he.err
+				if err == StopDecoding {
+					// The parser stopped before the Decoder could record the format.
+					p.Format = streamedFormats[pe.format]
+				}
+			}
+		} else if he, ok := err.(eventHandlerError); ok {
+			err = he.err
+		}
+		if err == StopDecoding {
+			err = nil
+		}
+	}()
+
+	p.events = &eventEmitter{h: h, d: p}
+	defer func() { p.events = nil }()
+
+	pval, err := p.parseDocument()
+	if err != nil {
+		return err
+	}
+	if pval != nil {
+		// Not streamed by the parser.
+		p.events.emitValue(pval)
+	}
+	return nil
+}
+
+// streamedFormats maps the names the streaming parsers give in their errors to their formats.
+var streamedFormats = map[string]int{
+	"binary": BinaryFormat,
+	"XML":    XMLFormat,
+}
+
+// emitObjectAtIndex delivers the events for the object with the given ID in a binary property
+// list, reading containers directly from their object lists.
+func (p *bplistParser) emitObjectAtIndex(index uint64) {
+	if index >= p.trailer.NumObjects {
+		panic(fmt.Errorf("invalid object#%d (max %d)", index, p.trailer.NumObjects))
+	}
+
+	e := p.events
+	off := p.offsetForObjectAtIndex(index)
+	switch p.buffer[off] & 0xF0 {
+	case bpTagDictionary:
+		p.pushNestedObject(off)
+		defer p.popNestedObject()
+		if e.startDictionary() {
+			return
+		}
+
+		// a dictionary is an object list of [key key key val val val]
+		cnt, start := p.countForTagAtOffset(off)
+		refs := p.objectRefsAtOffset(start, cnt*2)
+		for i := uint64(0); i < cnt; i++ {
+			key, ok := p.scalarObjectAtIndex(refs[i]).(cfString)
+			if !ok {
+				panic(fmt.Errorf("dictionary@0x%x contains non-string key at index %d", off, i))
+			}
+			if e.key(string(key)) {
+				continue
+			}
+			e.push(string(key))
+			p.emitObjectAtIndex(refs[cnt+i])
+			e.pop()
+		}
+		e.endDictionary()
+	case bpTagArray:
+		p.pushNestedObject(off)
+		defer p.popNestedObject()
+		if e.startArray() {
+			return
+		}
+
+		cnt, start := p.countForTagAtOffset(off)
+		for i, oid := range p.objectRefsAtOffset(start, cnt) {
+			e.push(strconv.Itoa(i))
+			p.emitObjectAtIndex(oid)
+			e.pop()
+		}
+		e.endArray()
+	default:
+		e.value(p.parseTagAtOffset(off))
+	}
+}
+
+// objectRefsAtOffset reads the object IDs in the object list at off.
+func (p *bplistParser) objectRefsAtOffset(off offset, count uint64) []uint64 {
+	if off+offset(count*uint64(p.trailer.ObjectRefSize)) > offset(p.trailer.OffsetTableOffset) {
+		panic(fmt.Errorf("list@0x%x length (%v) puts its end beyond the offset table at 0x%x", off, count, p.trailer.OffsetTableOffset))
+	}
+
+	refs := make([]uint64, count)
+	next := off
+	for i := range refs {
+		refs[i], next = p.parseObjectRefAtOffset(next)
+	}
+	return refs
+}
+
+// scalarObjectAtIndex parses the object with the given ID, which must not be a container.
+func (p *bplistParser) scalarObjectAtIndex(index uint64) cfValue {
+	if index >= p.trailer.NumObjects {
+		panic(fmt.Errorf("invalid object#%d (max %d)", index, p.trailer.NumObjects))
+	}
+
+	off := p.offsetForObjectAtIndex(index)
+	switch p.buffer[off] & 0xF0 {
+	case bpTagArray, bpTagDictionary:
+		return nil
+	}
+	return p.parseTagAtOffset(off)
+}
+
+// emitXMLElement delivers the events for an element of an XML property list, reading the
+// contents of containers as they are delivered.
+func (p *xmlPlistParser) emitXMLElement(element xml.StartElement) {
+	if space, ok := xmlSpaceValue(element); ok {
+		// xml:space is inherited by every element nested inside this one.
+		defer func(preserve bool) { p.preserveSpace = preserve }(p.preserveSpace)
+		p.preserveSpace = space == "preserve"
+	}
+
+	e := p.events
+	switch element.Name.Local {
+	case "plist":
+		p.ntags++
+		for {
+			token, err := p.xmlDecoder.Token()
+			if err != nil {
+				panic(err)
+			}
+
+			if el, ok := token.(xml.EndElement); ok && el.Name.Local == "plist" {
+				return
+			}
+
+			if el, ok := token.(xml.StartElement); ok {
+				p.emitXMLElement(el)
+				return
+			}
+		}
+	case "dict":
+		p.ntags++
+		if e.startDictionary() {
+			p.xmlDecoder.Skip()
+			return
+		}
+
+		var key *string
+		skip := false
+		for {
+			token, err := p.xmlDecoder.Token()
+			if err != nil {
+				panic(err)
+			}
+
+			if el, ok := token.(xml.EndElement); ok && el.Name.Local == "dict" {
+				if key != nil {
+					panic(errors.New("missing value in dictionary"))
+				}
+				break
+			}
+
+			if el, ok := token.(xml.StartElement); ok {
+				if el.Name.Local == "key" {
+					var k string
+					p.xmlDecoder.DecodeElement(&k, &el)
+					key = &k
+					skip = e.key(k)
+				} else {
+					if key == nil {
+						panic(errors.New("missing key in dictionary"))
+					}
+					if skip {
+						p.xmlDecoder.Skip()
+					} else {
+						e.push(*key)
+						p.emitXMLElement(el)
+						e.pop()
+					}
+					key = nil
+				}
+			}
+		}
+		e.endDictionary()
+	case "array":
+		p.ntags++
+		if e.startArray() {
+			p.xmlDecoder.Skip()
+			return
+		}
+
+		for i := 0; ; {
+			token, err := p.xmlDecoder.Token()
+			if err != nil {
+				panic(err)
+			}
+
+			if el, ok := token.(xml.EndElement); ok && el.Name.Local == "array" {
+				break
+			}
+
+			if el, ok := token.(xml.StartElement); ok {
+				e.push(strconv.Itoa(i))
+				p.emitXMLElement(el)
+				e.pop()
+				i++
+			}
+		}
+		e.endArray()
+	default:
+		e.value(p.parseXMLElement(element))
+	}
+}
@@ -0,0 +1,142 @@
+package plist
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxExcerpt is the longest stretch of a line that is quoted in an error message.
+const maxExcerpt = 72
+
+// sourceExcerpt formats line, the line of a document on which a problem was found, for inclusion
+// in an error message. A caret is placed beneath the character at col (a byte offset into line);
+// long lines are trimmed to the part surrounding it.
+func sourceExcerpt(line string, col int) string {
+	line = strings.TrimRight(line, "\r")
+	if col > len(line) {
+		col = len(line)
+	}
+
+	start, end := 0, len(line)
+	if end-start > maxExcerpt {
+		start = col - maxExcerpt/2
+		if start < 0 {
+			start = 0
+		}
+		end = start + maxExcerpt
+		if end > len(line) {
+			end = len(line)
+			start = end - maxExcerpt
+		}
+		for start > 0 && !utf8.RuneStart(line[start]) {
+			start--
+		}
+		for end < len(line) && !utf8.RuneStart(line[end]) {
+			end++
+		}
+	}
+
+	var excerpt, caret strings.Builder
+	if start > 0 {
+		excerpt.WriteString("...")
+		caret.WriteString("   ")
+	}
+	excerpt.WriteString(line[start:end])
+	if end < len(line) {
+		excerpt.WriteString("...")
+	}
+	for _, r := range line[start:col] {
+		if r == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+	}
+	caret.WriteByte('^')
+
+	// Converting through []rune replaces invalid UTF-8 with U+FFFD, as ranging over line did.
+	return "\n\t" + string([]rune(excerpt.String())) + "\n\t" + caret.String()
+}
+
+// lastRuneStart returns the offset of the last character in s before pos, or pos if there is none.
+func lastRuneStart(s string, pos int) int {
+	if pos == 0 {
+		return 0
+	}
+	_, w := utf8.DecodeLastRuneInString(s[:pos])
+	return pos - w
+}
+
+// excerptError adds an excerpt of the document to a parse error.
+type excerptError struct {
+	err     error
+	excerpt string
+}
+
+func (e excerptError) Error() string {
+	return e.err.Error() + e.excerpt
+}
+
+// excerptReader remembers the end of the line it has most recently read from, so that an
+// excerpt of the document can be shown when the XML parser fails part of the way through it.
+type excerptReader struct {
+	r    io.ByteReader
+	line int    // the current line, from 1 as encoding/xml counts them
+	col  int    // the number of bytes read from the current line
+	tail []byte // the last few bytes of the current line
+}
+
+func newExcerptReader(r io.ByteReader) *excerptReader {
+	return &excerptReader{r: r, line: 1}
+}
+
+func (e *excerptReader) ReadByte() (byte, error) {
+	b, err := e.r.ReadByte()
+	if err != nil {
+		return b, err
+	}
+	if b == '\n' {
+		e.line++
+		e.col = 0
+		e.tail = e.tail[:0]
+		return b, nil
+	}
+	e.col++
+	if len(e.tail) == 2*maxExcerpt {
+		copy(e.tail, e.tail[maxExcerpt:])
+		e.tail = e.tail[:maxExcerpt]
+	}
+	e.tail = append(e.tail, b)
+	return b, nil
+}
+
+func (e *excerptReader) Read(b []byte) (int, error) {
+	for i := range b {
+		c, err := e.ReadByte()
+		if err != nil {
+			return i, err
+		}
+		b[i] = c
+	}
+	return len(b), nil
+}
+
+// wrap adds the position of the last byte read, and an excerpt of the line holding it, to err.
+// The rest of the line is read from the input to complete the excerpt.
+func (e *excerptReader) wrap(err error) error {
+	line, col := e.line, e.col
+	text := append([]byte(nil), e.tail...)
+	caret := len(text)
+	for len(text) < caret+maxExcerpt {
+		b, rerr := e.r.ReadByte()
+		if rerr != nil || b == '\n' {
+			break
+		}
+		text = append(text, b)
+	}
+
+	caret = lastRuneStart(string(text), caret)
+	return excerptError{fmt.Errorf("%v (at line %d character %d)", err, line, col), sourceExcerpt(string(text), caret)}
+}
@@ -0,0 +1,61 @@
+package plist
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+var updateFuzzSeeds = flag.Bool("update-fuzz-seeds", false, "rewrite plistfuzz/seeds.go from the test tables")
+
+const fuzzSeedsFile = "plistfuzz/seeds.go"
+
+// fuzzSeedsSource returns the source of the plistfuzz package's seed corpus: every document in
+// the test tables, once each.
+func fuzzSeedsSource() []byte {
+	formatNames := map[int]string{
+		XMLFormat:      "plist.XMLFormat",
+		BinaryFormat:   "plist.BinaryFormat",
+		OpenStepFormat: "plist.OpenStepFormat",
+		GNUStepFormat:  "plist.GNUStepFormat",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by \"go test -run TestFuzzSeeds -update-fuzz-seeds\" in howett.net/plist. DO NOT EDIT.\n\n")
+	buf.WriteString("package plistfuzz\n\nimport \"howett.net/plist\"\n\nvar seeds = []seed{\n")
+	seen := make(map[string]bool)
+	for _, test := range tests {
+		for _, format := range []int{XMLFormat, BinaryFormat, OpenStepFormat, GNUStepFormat} {
+			doc, ok := test.Documents[format]
+			if !ok || seen[string(doc)] {
+				continue
+			}
+			seen[string(doc)] = true
+			fmt.Fprintf(&buf, "\t{%s, []byte(%q)},\n", formatNames[format], doc)
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+// TestFuzzSeeds checks that the plistfuzz seed corpus matches the test tables, or rewrites it
+// when run with -update-fuzz-seeds.
+func TestFuzzSeeds(t *testing.T) {
+	src := fuzzSeedsSource()
+	if *updateFuzzSeeds {
+		if err := ioutil.WriteFile(fuzzSeedsFile, src, 0666); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	existing, err := ioutil.ReadFile(fuzzSeedsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(existing, src) {
+		t.Errorf("%s is out of date with the test tables; run go test -run TestFuzzSeeds -update-fuzz-seeds", fuzzSeedsFile)
+	}
+}
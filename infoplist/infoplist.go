@@ -0,0 +1,151 @@
+// Package infoplist models the standard keys found in the Info.plist files of application and
+// framework bundles.
+//
+// Keys not modeled by InfoPlist are preserved in its Extra map, so documents round-trip through
+// Unmarshal and Marshal without losing information.
+package infoplist
+
+import (
+	"strings"
+
+	"howett.net/plist"
+	"howett.net/plist/internal/extrakeys"
+)
+
+// InfoPlist represents the contents of a bundle's Info.plist.
+type InfoPlist struct {
+	BundleIdentifier            string   `plist:"CFBundleIdentifier,omitempty"`
+	BundleName                  string   `plist:"CFBundleName,omitempty"`
+	BundleDisplayName           string   `plist:"CFBundleDisplayName,omitempty"`
+	BundleExecutable            string   `plist:"CFBundleExecutable,omitempty"`
+	BundlePackageType           string   `plist:"CFBundlePackageType,omitempty"`
+	BundleSignature             string   `plist:"CFBundleSignature,omitempty"`
+	BundleShortVersionString    string   `plist:"CFBundleShortVersionString,omitempty"`
+	BundleVersion               string   `plist:"CFBundleVersion,omitempty"`
+	BundleInfoDictionaryVersion string   `plist:"CFBundleInfoDictionaryVersion,omitempty"`
+	BundleDevelopmentRegion     string   `plist:"CFBundleDevelopmentRegion,omitempty"`
+	BundleLocalizations         []string `plist:"CFBundleLocalizations,omitempty"`
+	BundleIconFile              string   `plist:"CFBundleIconFile,omitempty"`
+	BundleIconName              string   `plist:"CFBundleIconName,omitempty"`
+	BundleSupportedPlatforms    []string `plist:"CFBundleSupportedPlatforms,omitempty"`
+	BundleGetInfoString         string   `plist:"CFBundleGetInfoString,omitempty"`
+
+	BundleDocumentTypes            []DocumentType    `plist:"CFBundleDocumentTypes,omitempty"`
+	BundleURLTypes                 []URLType         `plist:"CFBundleURLTypes,omitempty"`
+	ExportedTypeDeclarations       []TypeDeclaration `plist:"UTExportedTypeDeclarations,omitempty"`
+	ImportedTypeDeclarations       []TypeDeclaration `plist:"UTImportedTypeDeclarations,omitempty"`
+	ApplicationQueriesSchemes      []string          `plist:"LSApplicationQueriesSchemes,omitempty"`
+	ApplicationCategoryType        string            `plist:"LSApplicationCategoryType,omitempty"`
+	MinimumSystemVersion           string            `plist:"LSMinimumSystemVersion,omitempty"`
+	MinimumOSVersion               string            `plist:"MinimumOSVersion,omitempty"`
+	RequiresIPhoneOS               *bool             `plist:"LSRequiresIPhoneOS,omitempty"`
+	UIElement                      *bool             `plist:"LSUIElement,omitempty"`
+	BackgroundOnly                 *bool             `plist:"LSBackgroundOnly,omitempty"`
+	HumanReadableCopyright         string            `plist:"NSHumanReadableCopyright,omitempty"`
+	PrincipalClass                 string            `plist:"NSPrincipalClass,omitempty"`
+	MainNibFile                    string            `plist:"NSMainNibFile,omitempty"`
+	MainStoryboardFile             string            `plist:"NSMainStoryboardFile,omitempty"`
+	HighResolutionCapable          *bool             `plist:"NSHighResolutionCapable,omitempty"`
+	LaunchStoryboardName           string            `plist:"UILaunchStoryboardName,omitempty"`
+	DeviceFamily                   []int             `plist:"UIDeviceFamily,omitempty"`
+	RequiredDeviceCapabilities     []string          `plist:"UIRequiredDeviceCapabilities,omitempty"`
+	SupportedInterfaceOrientations []string          `plist:"UISupportedInterfaceOrientations,omitempty"`
+	BackgroundModes                []string          `plist:"UIBackgroundModes,omitempty"`
+
+	AppleEventsUsageDescription                string `plist:"NSAppleEventsUsageDescription,omitempty"`
+	BluetoothAlwaysUsageDescription            string `plist:"NSBluetoothAlwaysUsageDescription,omitempty"`
+	CalendarsUsageDescription                  string `plist:"NSCalendarsUsageDescription,omitempty"`
+	CameraUsageDescription                     string `plist:"NSCameraUsageDescription,omitempty"`
+	ContactsUsageDescription                   string `plist:"NSContactsUsageDescription,omitempty"`
+	FaceIDUsageDescription                     string `plist:"NSFaceIDUsageDescription,omitempty"`
+	LocalNetworkUsageDescription               string `plist:"NSLocalNetworkUsageDescription,omitempty"`
+	LocationAlwaysAndWhenInUseUsageDescription string `plist:"NSLocationAlwaysAndWhenInUseUsageDescription,omitempty"`
+	LocationWhenInUseUsageDescription          string `plist:"NSLocationWhenInUseUsageDescription,omitempty"`
+	MicrophoneUsageDescription                 string `plist:"NSMicrophoneUsageDescription,omitempty"`
+	MotionUsageDescription                     string `plist:"NSMotionUsageDescription,omitempty"`
+	PhotoLibraryAddUsageDescription            string `plist:"NSPhotoLibraryAddUsageDescription,omitempty"`
+	PhotoLibraryUsageDescription               string `plist:"NSPhotoLibraryUsageDescription,omitempty"`
+	RemindersUsageDescription                  string `plist:"NSRemindersUsageDescription,omitempty"`
+	SpeechRecognitionUsageDescription          string `plist:"NSSpeechRecognitionUsageDescription,omitempty"`
+	UserTrackingUsageDescription               string `plist:"NSUserTrackingUsageDescription,omitempty"`
+
+	// Extra holds every key that is not represented by one of the fields above.
+	Extra map[string]interface{} `plist:"-"`
+}
+
+// DocumentType describes a document type an application can open (an entry in CFBundleDocumentTypes.)
+type DocumentType struct {
+	Name                string   `plist:"CFBundleTypeName,omitempty"`
+	Role                string   `plist:"CFBundleTypeRole,omitempty"`
+	IconFile            string   `plist:"CFBundleTypeIconFile,omitempty"`
+	IconFiles           []string `plist:"CFBundleTypeIconFiles,omitempty"`
+	Extensions          []string `plist:"CFBundleTypeExtensions,omitempty"`
+	MIMETypes           []string `plist:"CFBundleTypeMIMETypes,omitempty"`
+	ContentTypes        []string `plist:"LSItemContentTypes,omitempty"`
+	HandlerRank         string   `plist:"LSHandlerRank,omitempty"`
+	IsPackage           *bool    `plist:"LSTypeIsPackage,omitempty"`
+	DocumentClass       string   `plist:"NSDocumentClass,omitempty"`
+	SupportsOpenInPlace *bool    `plist:"LSSupportsOpeningDocumentsInPlace,omitempty"`
+}
+
+// URLType describes a URL scheme an application handles (an entry in CFBundleURLTypes.)
+type URLType struct {
+	Name     string   `plist:"CFBundleURLName,omitempty"`
+	Role     string   `plist:"CFBundleTypeRole,omitempty"`
+	IconFile string   `plist:"CFBundleURLIconFile,omitempty"`
+	Schemes  []string `plist:"CFBundleURLSchemes,omitempty"`
+}
+
+// TypeDeclaration declares a Uniform Type Identifier (an entry in UTExportedTypeDeclarations or
+// UTImportedTypeDeclarations.)
+type TypeDeclaration struct {
+	Identifier       string                 `plist:"UTTypeIdentifier,omitempty"`
+	Description      string                 `plist:"UTTypeDescription,omitempty"`
+	ConformsTo       []string               `plist:"UTTypeConformsTo,omitempty"`
+	IconFile         string                 `plist:"UTTypeIconFile,omitempty"`
+	ReferenceURL     string                 `plist:"UTTypeReferenceURL,omitempty"`
+	TagSpecification map[string]interface{} `plist:"UTTypeTagSpecification,omitempty"`
+}
+
+// infoPlistFields has the same fields as InfoPlist, but none of its methods.
+type infoPlistFields InfoPlist
+
+// UnmarshalPlist implements plist.Unmarshaler.
+func (i *InfoPlist) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	if err := unmarshal((*infoPlistFields)(i)); err != nil {
+		return err
+	}
+
+	var all map[string]interface{}
+	if err := unmarshal(&all); err != nil {
+		return err
+	}
+	i.Extra = extrakeys.Strip((*infoPlistFields)(i), all)
+	return nil
+}
+
+// MarshalPlist implements plist.Marshaler.
+func (i InfoPlist) MarshalPlist() (interface{}, error) {
+	return extrakeys.Merge((*infoPlistFields)(&i), i.Extra), nil
+}
+
+// Parse decodes an Info.plist document in any property list format.
+func Parse(data []byte) (*InfoPlist, error) {
+	info := &InfoPlist{}
+	if _, err := plist.Unmarshal(data, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// UsageDescriptions returns every privacy usage description string in the document, keyed by
+// its Info.plist key (for example, NSCameraUsageDescription), including those only found in Extra.
+func (i *InfoPlist) UsageDescriptions() map[string]string {
+	descriptions := make(map[string]string)
+	for k, v := range extrakeys.Merge((*infoPlistFields)(i), i.Extra) {
+		if s, ok := v.(string); ok && s != "" && strings.HasSuffix(k, "UsageDescription") {
+			descriptions[k] = s
+		}
+	}
+	return descriptions
+}
@@ -0,0 +1,95 @@
+package infoplist
+
+import (
+	"reflect"
+	"testing"
+
+	"howett.net/plist"
+)
+
+const sampleInfoPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleIdentifier</key>
+	<string>com.example.app</string>
+	<key>CFBundleShortVersionString</key>
+	<string>1.2</string>
+	<key>CFBundleVersion</key>
+	<string>42</string>
+	<key>LSRequiresIPhoneOS</key>
+	<false/>
+	<key>CFBundleURLTypes</key>
+	<array>
+		<dict>
+			<key>CFBundleURLSchemes</key>
+			<array><string>example</string></array>
+		</dict>
+	</array>
+	<key>NSCameraUsageDescription</key>
+	<string>Scan documents</string>
+	<key>NSWidgetUsageDescription</key>
+	<string>Show widgets</string>
+	<key>CustomKey</key>
+	<integer>7</integer>
+</dict>
+</plist>`
+
+func TestParse(t *testing.T) {
+	info, err := Parse([]byte(sampleInfoPlist))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.BundleIdentifier != "com.example.app" || info.BundleShortVersionString != "1.2" || info.BundleVersion != "42" {
+		t.Errorf("unexpected bundle details: %#v", info)
+	}
+
+	if info.RequiresIPhoneOS == nil || *info.RequiresIPhoneOS {
+		t.Errorf("expected LSRequiresIPhoneOS to be explicitly false")
+	}
+
+	if len(info.BundleURLTypes) != 1 || !reflect.DeepEqual(info.BundleURLTypes[0].Schemes, []string{"example"}) {
+		t.Errorf("unexpected URL types: %#v", info.BundleURLTypes)
+	}
+
+	expectedExtra := map[string]interface{}{
+		"CustomKey":                uint64(7),
+		"NSWidgetUsageDescription": "Show widgets",
+	}
+	if !reflect.DeepEqual(info.Extra, expectedExtra) {
+		t.Errorf("Expected extra keys %v, received %v", expectedExtra, info.Extra)
+	}
+
+	expectedDescriptions := map[string]string{
+		"NSCameraUsageDescription": "Scan documents",
+		"NSWidgetUsageDescription": "Show widgets",
+	}
+	if d := info.UsageDescriptions(); !reflect.DeepEqual(d, expectedDescriptions) {
+		t.Errorf("Expected usage descriptions %v, received %v", expectedDescriptions, d)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	info, err := Parse([]byte(sampleInfoPlist))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := plist.Marshal(*info, plist.BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var original, roundTripped map[string]interface{}
+	if _, err := plist.Unmarshal([]byte(sampleInfoPlist), &original); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plist.Unmarshal(data, &roundTripped); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(original, roundTripped) {
+		t.Errorf("Expected %v, received %v", original, roundTripped)
+	}
+}
@@ -0,0 +1,37 @@
+package plist
+
+// inlineLimits decides which dictionaries and arrays an indenting generator writes on a single
+// line rather than one member to a line.
+type inlineLimits struct {
+	maxMembers int // 0 disables inline containers
+	maxWidth   int // the longest line an inline container may take up, or 0 for no limit
+}
+
+// allows reports whether a container holding values is small enough to be written on one line:
+// it has no more than maxMembers members, and none of them is a non-empty dictionary or array.
+func (l inlineLimits) allows(values []cfValue) bool {
+	if l.maxMembers == 0 || len(values) > l.maxMembers {
+		return false
+	}
+	for _, v := range values {
+		switch v := v.(type) {
+		case *cfDictionary:
+			if len(v.keys) > 0 {
+				return false
+			}
+		case *cfArray:
+			if len(v.values) > 0 {
+				return false
+			}
+		case cfUID:
+			// UIDs are written as dictionaries.
+			return false
+		}
+	}
+	return true
+}
+
+// fits reports whether an inline container written as s is narrow enough.
+func (l inlineLimits) fits(s []byte) bool {
+	return l.maxWidth == 0 || len(s) <= l.maxWidth
+}
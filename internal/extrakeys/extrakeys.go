@@ -0,0 +1,67 @@
+// Package extrakeys helps model property list dictionaries that have a set of well-known keys
+// (represented by struct fields) alongside arbitrary additional keys (represented by a map).
+package extrakeys
+
+import (
+	"reflect"
+
+	"howett.net/plist/internal/plisttag"
+)
+
+func fieldKey(f reflect.StructField) (key string, omitEmpty bool, ok bool) {
+	if plisttag.Ignored(f) {
+		return "", false, false
+	}
+
+	key, flags := plisttag.Parse(f)
+	if key == "" {
+		key = f.Name
+	}
+	for _, flag := range flags {
+		if flag == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return key, omitEmpty, true
+}
+
+// Strip deletes every key that is represented by a field of the struct pointed to by v from m.
+// It returns nil if no keys remain.
+func Strip(v interface{}, m map[string]interface{}) map[string]interface{} {
+	typ := reflect.TypeOf(v).Elem()
+	for i := 0; i < typ.NumField(); i++ {
+		if key, _, ok := fieldKey(typ.Field(i)); ok {
+			delete(m, key)
+		}
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+// Merge returns a map containing every encodable field of the struct pointed to by v, as well as
+// every entry in extra. Fields take precedence over entries in extra.
+func Merge(v interface{}, extra map[string]interface{}) map[string]interface{} {
+	val := reflect.ValueOf(v).Elem()
+	typ := val.Type()
+
+	m := make(map[string]interface{}, len(extra)+typ.NumField())
+	for k, v := range extra {
+		m[k] = v
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		key, omitEmpty, ok := fieldKey(typ.Field(i))
+		if !ok {
+			continue
+		}
+
+		fval := val.Field(i)
+		if omitEmpty && plisttag.IsEmpty(fval) {
+			continue
+		}
+		m[key] = fval.Interface()
+	}
+	return m
+}
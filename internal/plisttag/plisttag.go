@@ -0,0 +1,39 @@
+// Package plisttag interprets the `plist` struct tags understood by package plist, so that the
+// packages built on it treat struct fields the same way it does.
+package plisttag
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Ignored reports whether f is left out of property lists: it is unexported, or tagged "-".
+func Ignored(f reflect.StructField) bool {
+	return f.PkgPath != "" || f.Tag.Get("plist") == "-"
+}
+
+// Parse splits the plist tag of f into the key it names and the flags that follow it, such as
+// "omitempty". The key is empty if the tag does not name one.
+func Parse(f reflect.StructField) (key string, flags []string) {
+	tokens := strings.Split(f.Tag.Get("plist"), ",")
+	return tokens[0], tokens[1:]
+}
+
+// IsEmpty reports whether v is the empty value that omitempty leaves out.
+func IsEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -494,3 +494,17 @@ func TestInvalidBinaryPlists(t *testing.T) {
 		}
 	}
 }
+
+func TestInvalidBinaryPlistsConcurrently(t *testing.T) {
+	for _, data := range InvalidBplists {
+		buf := bytes.NewReader(data)
+		d := newBplistParser(buf)
+		d.workers = 4
+		_, err := d.parseDocument()
+		if err == nil {
+			t.Fatal("invalid plist failed to throw error")
+		} else {
+			t.Log(err)
+		}
+	}
+}
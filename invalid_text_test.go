@@ -63,3 +63,48 @@ func TestInvalidTextPlists(t *testing.T) {
 		})
 	}
 }
+
+func TestInvalidTextPlistPositions(t *testing.T) {
+	tests := []struct {
+		doc      string
+		position string
+	}{
+		{"{\n\ta = b;\n\tc = d\n}", "line 3 character 1"},
+		{"(\n  <*I5>,\n  <*Q1>\n)", "line 2 character 5"},
+		{"{ key = \"value\"; other = <zz>; }", "line 0 character 27"},
+		{"(a, b,\n\n  /* unterminated", "line 2 character 2"},
+	}
+
+	for _, test := range tests {
+		var v interface{}
+		_, err := Unmarshal([]byte(test.doc), &v)
+		if err == nil {
+			t.Errorf("%q: Expected an error", test.doc)
+		} else if !strings.Contains(err.Error(), " at "+test.position) {
+			t.Errorf("%q: Expected an error at %s, received %v", test.doc, test.position, err)
+		}
+	}
+}
+
+func TestParseErrorExcerpts(t *testing.T) {
+	tests := []struct {
+		doc     string
+		excerpt string
+	}{
+		{"{\n\ta = b;\n\tc = d\n}", "\n\t}\n\t^"},
+		{"{ key = \"value\"; other = <zz>; }", "\n\t{ key = \"value\"; other = <zz>; }\n\t                          ^"},
+		{"<plist>\n<dict>\n  <key>a</key>\n  <integer>zz</integer>\n</dict></plist>", " (at line 4 character 23)\n\t  <integer>zz</integer>\n\t                      ^"},
+		{"<plist>\n<array>\n\t<string>x</strin>\n</array></plist>", "\n\t\t<string>x</strin>\n\t\t                ^"},
+		{strings.Repeat("x", 200) + " = (<zz>)", "\n\t..." + strings.Repeat("x", 63) + " = (<zz>)\n\t" + strings.Repeat(" ", 71) + "^"},
+	}
+
+	for _, test := range tests {
+		var v interface{}
+		_, err := Unmarshal([]byte(test.doc), &v)
+		if err == nil {
+			t.Errorf("%q: Expected an error", test.doc)
+		} else if !strings.HasSuffix(err.Error(), test.excerpt) {
+			t.Errorf("%q: Expected an error ending in %q, received %q", test.doc, test.excerpt, err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package keyedarchive
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"howett.net/plist"
+)
+
+// A UUID is the decoded form of an archived NSUUID.
+type UUID [16]byte
+
+// String returns u in its canonical textual form, 12345678-9ABC-DEF0-1234-56789ABCDEF0.
+func (u UUID) String() string {
+	return fmt.Sprintf("%X-%X-%X-%X-%X", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// A Range is the decoded form of an NSValue holding an NSRange.
+type Range struct {
+	Location uint64
+	Length   uint64
+}
+
+// foundationDecoders decode the commonly-archived Foundation classes into native Go values.
+var foundationDecoders = map[string]ClassDecoderFunc{
+	"NSString":                  decodeString,
+	"NSMutableString":           decodeString,
+	"NSAttributedString":        decodeAttributedString,
+	"NSMutableAttributedString": decodeAttributedString,
+	"NSData":                    decodeData,
+	"NSMutableData":             decodeData,
+	"NSDate":                    decodeDate,
+	"NSURL":                     decodeURL,
+	"NSUUID":                    decodeUUID,
+	"NSArray":                   decodeArray,
+	"NSMutableArray":            decodeArray,
+	"NSSet":                     decodeArray,
+	"NSMutableSet":              decodeArray,
+	"NSOrderedSet":              decodeArray,
+	"NSMutableOrderedSet":       decodeArray,
+	"NSDictionary":              decodeDictionary,
+	"NSMutableDictionary":       decodeDictionary,
+	"NSValue":                   decodeValue,
+	"NSNull":                    decodeNull,
+}
+
+func stringField(obj *Object, key string) (string, error) {
+	s, ok := obj.Fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("%s is not a string", key)
+	}
+	return s, nil
+}
+
+func objectsField(obj *Object) ([]interface{}, error) {
+	objects, ok := obj.Fields["NS.objects"].([]interface{})
+	if !ok {
+		if _, present := obj.Fields["NS.objects"]; present {
+			return nil, errors.New("NS.objects is not an array")
+		}
+		// Empty collections may omit NS.objects entirely.
+		return []interface{}{}, nil
+	}
+	return objects, nil
+}
+
+func numberField(obj *Object, key string) (float64, error) {
+	switch n := obj.Fields[key].(type) {
+	case float64:
+		return n, nil
+	case uint64:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	}
+	return 0, fmt.Errorf("%s is not a number", key)
+}
+
+func decodeString(obj *Object) (interface{}, error) {
+	return stringField(obj, "NS.string")
+}
+
+func decodeAttributedString(obj *Object) (interface{}, error) {
+	// The attributes (NSAttributes) are discarded; only the text is kept.
+	return stringField(obj, "NSString")
+}
+
+func decodeData(obj *Object) (interface{}, error) {
+	for _, key := range []string{"NS.data", "NS.bytes"} {
+		if data, ok := obj.Fields[key].([]byte); ok {
+			return data, nil
+		}
+	}
+	return nil, errors.New("NS.data is not data")
+}
+
+func decodeDate(obj *Object) (interface{}, error) {
+	secs, err := numberField(obj, "NS.time")
+	if err != nil {
+		return nil, err
+	}
+	return plist.TimeFromAbsoluteTime(secs), nil
+}
+
+func decodeURL(obj *Object) (interface{}, error) {
+	relative, err := stringField(obj, "NS.relative")
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(relative)
+	if err != nil {
+		return nil, err
+	}
+
+	switch base := obj.Fields["NS.base"].(type) {
+	case nil:
+		return u, nil
+	case *url.URL:
+		return base.ResolveReference(u), nil
+	}
+	return nil, errors.New("NS.base is not a URL")
+}
+
+func decodeUUID(obj *Object) (interface{}, error) {
+	b, ok := obj.Fields["NS.uuidbytes"].([]byte)
+	if !ok || len(b) != 16 {
+		return nil, errors.New("NS.uuidbytes is not 16 bytes of data")
+	}
+	var u UUID
+	copy(u[:], b)
+	return u, nil
+}
+
+func decodeArray(obj *Object) (interface{}, error) {
+	return objectsField(obj)
+}
+
+func decodeDictionary(obj *Object) (interface{}, error) {
+	keys, _ := obj.Fields["NS.keys"].([]interface{})
+	values, err := objectsField(obj)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) != len(values) {
+		return nil, fmt.Errorf("%d keys for %d values", len(keys), len(values))
+	}
+
+	m := make(map[string]interface{}, len(keys))
+	for i, k := range keys {
+		s, ok := k.(string)
+		if !ok {
+			// There is no Go map type that can hold arbitrary archived keys.
+			return obj, nil
+		}
+		m[s] = values[i]
+	}
+	return m, nil
+}
+
+// NSValue's NS.special field identifies the kind of value it holds.
+const (
+	nsValuePoint = 1
+	nsValueSize  = 2
+	nsValueRect  = 3
+	nsValueRange = 4
+)
+
+func decodeValue(obj *Object) (interface{}, error) {
+	special, err := numberField(obj, "NS.special")
+	if err != nil {
+		return nil, err
+	}
+
+	switch special {
+	case nsValuePoint:
+		return stringField(obj, "NS.pointval")
+	case nsValueSize:
+		return stringField(obj, "NS.sizeval")
+	case nsValueRect:
+		return stringField(obj, "NS.rectval")
+	case nsValueRange:
+		location, err := numberField(obj, "NS.rangeval.location")
+		if err != nil {
+			return nil, err
+		}
+		length, err := numberField(obj, "NS.rangeval.length")
+		if err != nil {
+			return nil, err
+		}
+		return Range{uint64(location), uint64(length)}, nil
+	}
+	// Other kinds of NSValue are left undecoded.
+	return obj, nil
+}
+
+func decodeNull(obj *Object) (interface{}, error) {
+	return nil, nil
+}
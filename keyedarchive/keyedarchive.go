@@ -0,0 +1,342 @@
+// Package keyedarchive decodes the object graphs stored by NSKeyedArchiver.
+//
+// A keyed archive is a property list holding a flat table of objects ($objects) that refer
+// to one another by plist.UID. Unarchive rebuilds the graph those references describe: each
+// archived object becomes an *Object, and references between objects become pointers, so
+// objects that refer to each other (a parent and its children, for example) are linked
+// rather than copied.
+//
+// Archives from untrusted sources should be decoded with an Unarchiver that allows only the
+// classes its caller expects; see Unarchiver.AllowClasses.
+package keyedarchive
+
+import (
+	"errors"
+	"fmt"
+
+	"howett.net/plist"
+)
+
+const (
+	archiverName    = "NSKeyedArchiver"
+	archiverVersion = 100000
+	nullObject      = "$null"
+)
+
+// An Object is an archived instance of a class.
+type Object struct {
+	// Class is the name of the object's class.
+	Class string
+
+	// Classes lists the object's class and its superclasses, most derived first.
+	Classes []string
+
+	// Fields holds the values the object encoded, keyed by the names it encoded them with.
+	// References to other objects have been resolved.
+	Fields map[string]interface{}
+}
+
+// Unarchive decodes the keyed archive in data, which may be in any property list format,
+// and returns the object archived as its root. Every class is allowed.
+//
+// Archived strings, numbers, data and other property list values are returned as they
+// would be by plist.Unmarshal into an empty interface. Instances of common Foundation classes
+// are decoded into native values:
+//
+//	NSString, NSMutableString                   string
+//	NSAttributedString (and mutable)            string, without its attributes
+//	NSData, NSMutableData                       []byte
+//	NSDate                                      time.Time
+//	NSURL                                       *url.URL
+//	NSUUID                                      UUID
+//	NSArray, NSSet, NSOrderedSet (and mutable)  []interface{}
+//	NSDictionary (and mutable)                  map[string]interface{}
+//	NSValue holding a point, size or rect       string, such as "{{0, 0}, {10, 20}}"
+//	NSValue holding a range                     Range
+//	NSNull                                      nil
+//
+// Every other object is returned as an *Object. The archive's null object is returned as nil.
+func Unarchive(data []byte) (interface{}, error) {
+	return new(Unarchiver).Unarchive(data)
+}
+
+// UnarchiveTop decodes the keyed archive in data and returns all of its top-level objects,
+// keyed by the names they were archived with. Every class is allowed.
+func UnarchiveTop(data []byte) (map[string]interface{}, error) {
+	return new(Unarchiver).UnarchiveTop(data)
+}
+
+// An Unarchiver decodes keyed archives. The zero value is ready to use, and allows every class.
+type Unarchiver struct {
+	allowed  map[string]bool // nil if every class is allowed
+	decoders map[string]ClassDecoderFunc
+}
+
+// A ClassDecoderFunc converts an archived object into a Go value. By the time it is called,
+// every object that obj refers to has been decoded, except for those that (directly or
+// indirectly) refer back to obj; those are still presented as *Objects.
+type ClassDecoderFunc func(obj *Object) (interface{}, error)
+
+// RegisterClass arranges for objects of the named class, or of classes derived from it, to be
+// decoded with fn. It replaces any built-in decoder for that class.
+func (u *Unarchiver) RegisterClass(name string, fn ClassDecoderFunc) {
+	if u.decoders == nil {
+		u.decoders = make(map[string]ClassDecoderFunc)
+	}
+	u.decoders[name] = fn
+}
+
+// decoderFor returns the decoder for obj's class or its nearest superclass that has one.
+func (u *Unarchiver) decoderFor(obj *Object) ClassDecoderFunc {
+	classes := append([]string{obj.Class}, obj.Classes...)
+	for _, class := range classes {
+		if fn, ok := u.decoders[class]; ok {
+			return fn
+		}
+		if fn, ok := foundationDecoders[class]; ok {
+			return fn
+		}
+	}
+	return nil
+}
+
+// AllowClasses restricts the Unarchiver to the named classes, in the manner of NSSecureCoding.
+// Once it has been called, only objects whose class appears in an allowed list are decoded into
+// an *Object; every other object is returned as an opaque *RawObject, and nothing it refers to
+// is decoded on its behalf. Foundation classes, such as NSArray and NSDate, must be allowed too.
+// AllowClasses may be called more than once to allow more classes.
+func (u *Unarchiver) AllowClasses(names ...string) {
+	if u.allowed == nil {
+		u.allowed = make(map[string]bool, len(names))
+	}
+	for _, name := range names {
+		u.allowed[name] = true
+	}
+}
+
+// A RawObject is an archived object whose class was not allowed by the Unarchiver that
+// decoded it.
+type RawObject struct {
+	// Class is the name of the object's class.
+	Class string
+
+	// UID is the object's index in the archive.
+	UID plist.UID
+
+	// Fields holds the values the object encoded, exactly as they were stored: references to
+	// other objects are left as plist.UID values.
+	Fields map[string]interface{}
+}
+
+// Unarchive decodes the keyed archive in data and returns the object archived as its root.
+func (u *Unarchiver) Unarchive(data []byte) (interface{}, error) {
+	top, err := u.UnarchiveTop(data)
+	if err != nil {
+		return nil, err
+	}
+
+	root, ok := top["root"]
+	if !ok {
+		return nil, errors.New("keyedarchive: archive has no root object")
+	}
+	return root, nil
+}
+
+// UnarchiveTop decodes the keyed archive in data and returns all of its top-level objects,
+// keyed by the names they were archived with.
+func (u *Unarchiver) UnarchiveTop(data []byte) (map[string]interface{}, error) {
+	var archive struct {
+		Archiver string                 `plist:"$archiver"`
+		Version  uint64                 `plist:"$version"`
+		Top      map[string]interface{} `plist:"$top"`
+		Objects  []interface{}          `plist:"$objects"`
+	}
+	if _, err := plist.Unmarshal(data, &archive); err != nil {
+		return nil, err
+	}
+
+	if archive.Archiver != archiverName {
+		return nil, fmt.Errorf("keyedarchive: unsupported archiver %q", archive.Archiver)
+	}
+	if archive.Version != archiverVersion {
+		return nil, fmt.Errorf("keyedarchive: unsupported archive version %d", archive.Version)
+	}
+
+	g := &graph{Unarchiver: u, objects: archive.Objects}
+	return g.unarchive(archive.Top)
+}
+
+// graph holds the state of a single archive as it is decoded.
+type graph struct {
+	*Unarchiver
+	objects  []interface{} // the archive's $objects, as stored
+	resolved []interface{} // UID to the value it refers to
+
+	decoded  map[*Object]interface{} // objects to the values their class decoders produced
+	decoding map[*Object]bool        // objects whose decoding is in progress
+}
+
+// unarchive rebuilds the object graph in two phases: every archived object is allocated
+// before any of them is populated, so a reference can always be resolved to its target's
+// pointer, even when the target refers back to the object being populated.
+func (g *graph) unarchive(top map[string]interface{}) (m map[string]interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(unarchiveError); ok {
+				err = e
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	g.resolved = make([]interface{}, len(g.objects))
+	for i, raw := range g.objects {
+		if raw == nullObject {
+			g.resolved[i] = nil
+			continue
+		}
+		if dict, ok := raw.(map[string]interface{}); ok {
+			if _, ok := dict["$class"]; ok {
+				class, classes := g.class(dict)
+				if g.allowed != nil && !g.allowed[class] {
+					g.resolved[i] = &RawObject{Class: class, UID: plist.UID(i), Fields: dict}
+				} else {
+					g.resolved[i] = &Object{Class: class, Classes: classes}
+				}
+				continue
+			}
+		}
+		g.resolved[i] = raw
+	}
+
+	for i, raw := range g.objects {
+		if obj, ok := g.resolved[i].(*Object); ok {
+			g.populate(obj, raw.(map[string]interface{}))
+		}
+	}
+
+	g.decoded = make(map[*Object]interface{})
+	g.decoding = make(map[*Object]bool)
+	return g.decode(g.resolveValue(top)).(map[string]interface{}), nil
+}
+
+type unarchiveError struct {
+	error
+}
+
+func (g *graph) fail(format string, args ...interface{}) {
+	panic(unarchiveError{fmt.Errorf("keyedarchive: "+format, args...)})
+}
+
+func (g *graph) checkRange(uid plist.UID) {
+	if uint64(uid) >= uint64(len(g.objects)) {
+		g.fail("reference to object #%d is out of range (only %d exist)", uid, len(g.objects))
+	}
+}
+
+// object returns the value that uid refers to.
+func (g *graph) object(uid plist.UID) interface{} {
+	g.checkRange(uid)
+	return g.resolved[uid]
+}
+
+// class returns the name of the class of the archived object raw, and its class hierarchy.
+func (g *graph) class(raw map[string]interface{}) (string, []string) {
+	classRef, ok := raw["$class"].(plist.UID)
+	if !ok {
+		g.fail("$class is not a reference")
+	}
+	g.checkRange(classRef)
+	class, ok := g.objects[classRef].(map[string]interface{})
+	if !ok {
+		g.fail("class reference #%d does not refer to a class", classRef)
+	}
+
+	name, _ := class["$classname"].(string)
+	if name == "" {
+		g.fail("class reference #%d has no $classname", classRef)
+	}
+
+	var classes []string
+	if list, ok := class["$classes"].([]interface{}); ok {
+		for _, c := range list {
+			if s, ok := c.(string); ok {
+				classes = append(classes, s)
+			}
+		}
+	}
+	return name, classes
+}
+
+func (g *graph) populate(obj *Object, raw map[string]interface{}) {
+	obj.Fields = make(map[string]interface{}, len(raw)-1)
+	for k, v := range raw {
+		if k == "$class" {
+			continue
+		}
+		obj.Fields[k] = g.resolveValue(v)
+	}
+}
+
+// resolveValue replaces every reference found in v with the value it refers to.
+func (g *graph) resolveValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case plist.UID:
+		return g.object(v)
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, subval := range v {
+			resolved[i] = g.resolveValue(subval)
+		}
+		return resolved
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(v))
+		for k, subval := range v {
+			resolved[k] = g.resolveValue(subval)
+		}
+		return resolved
+	}
+	return v
+}
+
+// decode replaces every object found in v with the value its class decoder produces.
+// Objects are decoded depth-first, so that decoders see their children's decoded values.
+func (g *graph) decode(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *Object:
+		if d, ok := g.decoded[v]; ok {
+			return d
+		}
+		if g.decoding[v] {
+			// v refers to itself; leave this reference undecoded rather than recursing forever.
+			return v
+		}
+
+		g.decoding[v] = true
+		for k, subval := range v.Fields {
+			v.Fields[k] = g.decode(subval)
+		}
+
+		var d interface{} = v
+		if fn := g.decoderFor(v); fn != nil {
+			var err error
+			if d, err = fn(v); err != nil {
+				g.fail("decoding %s: %v", v.Class, err)
+			}
+		}
+
+		delete(g.decoding, v)
+		g.decoded[v] = d
+		return d
+	case []interface{}:
+		for i, subval := range v {
+			v[i] = g.decode(subval)
+		}
+	case map[string]interface{}:
+		for k, subval := range v {
+			v[k] = g.decode(subval)
+		}
+	}
+	return v
+}
@@ -0,0 +1,220 @@
+package keyedarchive
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"howett.net/plist"
+)
+
+func archive(t *testing.T, objects ...interface{}) []byte {
+	t.Helper()
+	data, err := plist.Marshal(map[string]interface{}{
+		"$archiver": "NSKeyedArchiver",
+		"$version":  100000,
+		"$top":      map[string]interface{}{"root": plist.UID(1)},
+		"$objects":  append([]interface{}{"$null"}, objects...),
+	}, plist.BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestUnarchiveCycle(t *testing.T) {
+	data := archive(t,
+		// 1: the parent, which refers to its child
+		map[string]interface{}{"$class": plist.UID(3), "name": plist.UID(5), "child": plist.UID(2)},
+		// 2: the child, which refers back to its parent
+		map[string]interface{}{"$class": plist.UID(4), "name": plist.UID(6), "parent": plist.UID(1), "sibling": plist.UID(0)},
+		map[string]interface{}{"$classname": "Parent", "$classes": []interface{}{"Parent", "NSObject"}},
+		map[string]interface{}{"$classname": "Child", "$classes": []interface{}{"Child", "NSObject"}},
+		"mother",
+		"daughter",
+	)
+
+	root, err := Unarchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent, ok := root.(*Object)
+	if !ok || parent.Class != "Parent" || parent.Fields["name"] != "mother" {
+		t.Fatalf("Unexpected root %#v", root)
+	}
+	if len(parent.Classes) != 2 || parent.Classes[1] != "NSObject" {
+		t.Errorf("Unexpected class hierarchy %v", parent.Classes)
+	}
+
+	child, ok := parent.Fields["child"].(*Object)
+	if !ok || child.Class != "Child" || child.Fields["name"] != "daughter" {
+		t.Fatalf("Unexpected child %#v", parent.Fields["child"])
+	}
+	if child.Fields["parent"] != parent {
+		t.Error("Expected the child to refer to the same parent object")
+	}
+	if v, ok := child.Fields["sibling"]; !ok || v != nil {
+		t.Errorf("Expected $null to decode as nil, received %#v", v)
+	}
+}
+
+func TestUnarchiveInvalid(t *testing.T) {
+	tests := map[string][]byte{
+		"dangling reference": archive(t, map[string]interface{}{"$class": plist.UID(2), "x": plist.UID(42)}, map[string]interface{}{"$classname": "X"}),
+		"bad class":          archive(t, map[string]interface{}{"$class": plist.UID(0)}),
+		"no classname":       archive(t, map[string]interface{}{"$class": plist.UID(2)}, map[string]interface{}{}),
+	}
+
+	for name, data := range tests {
+		if v, err := Unarchive(data); err == nil {
+			t.Errorf("%s: Expected error, received %#v", name, v)
+		}
+	}
+
+	if _, err := Unarchive([]byte(`{ "$archiver" = "NSKeyedArchiver"; "$version" = 1; }`)); err == nil {
+		t.Error("Expected error for an unsupported version")
+	}
+}
+
+func TestUnarchiveAllowedClasses(t *testing.T) {
+	data := archive(t,
+		map[string]interface{}{"$class": plist.UID(3), "payload": plist.UID(2)},
+		map[string]interface{}{"$class": plist.UID(4), "command": plist.UID(5)},
+		map[string]interface{}{"$classname": "Envelope"},
+		map[string]interface{}{"$classname": "Exploit"},
+		"rm -rf /",
+	)
+
+	u := &Unarchiver{}
+	u.AllowClasses("Envelope")
+	root, err := u.Unarchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelope, ok := root.(*Object)
+	if !ok || envelope.Class != "Envelope" {
+		t.Fatalf("Unexpected root %#v", root)
+	}
+
+	raw, ok := envelope.Fields["payload"].(*RawObject)
+	if !ok {
+		t.Fatalf("Expected a disallowed class to decode as a *RawObject, received %#v", envelope.Fields["payload"])
+	}
+	if raw.Class != "Exploit" || raw.UID != 2 || raw.Fields["command"] != plist.UID(5) {
+		t.Errorf("Unexpected raw object %#v", raw)
+	}
+
+	// Without an allow-list, every class is decoded.
+	root, err = Unarchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := root.(*Object).Fields["payload"].(*Object); !ok {
+		t.Errorf("Expected an *Object, received %#v", root.(*Object).Fields["payload"])
+	}
+}
+
+func TestUnarchiveFoundation(t *testing.T) {
+	class := func(names ...string) map[string]interface{} {
+		classes := make([]interface{}, len(names))
+		for i, name := range names {
+			classes[i] = name
+		}
+		return map[string]interface{}{"$classname": names[0], "$classes": classes}
+	}
+
+	data := archive(t,
+		// 1: root dictionary
+		map[string]interface{}{
+			"$class":     plist.UID(2),
+			"NS.keys":    []interface{}{plist.UID(3), plist.UID(4), plist.UID(5), plist.UID(6), plist.UID(7), plist.UID(8)},
+			"NS.objects": []interface{}{plist.UID(9), plist.UID(11), plist.UID(14), plist.UID(17), plist.UID(19), plist.UID(21)},
+		},
+		class("NSMutableDictionary", "NSDictionary", "NSObject"),
+		"date", "url", "items", "uuid", "range", "title",
+		// 9: NSDate
+		map[string]interface{}{"$class": plist.UID(10), "NS.time": 1.5},
+		class("NSDate", "NSObject"),
+		// 11: NSURL relative to another NSURL
+		map[string]interface{}{"$class": plist.UID(13), "NS.base": plist.UID(12), "NS.relative": "docs/index.html"},
+		map[string]interface{}{"$class": plist.UID(13), "NS.base": plist.UID(0), "NS.relative": "https://example.com/a/"},
+		class("NSURL", "NSObject"),
+		// 14: NSArray subclass holding a number and an NSNull
+		map[string]interface{}{"$class": plist.UID(15), "NS.objects": []interface{}{uint64(42), plist.UID(16)}},
+		class("MyArray", "NSArray", "NSObject"),
+		map[string]interface{}{"$class": plist.UID(22)},
+		// 17: NSUUID
+		map[string]interface{}{"$class": plist.UID(18), "NS.uuidbytes": []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0}},
+		class("NSUUID", "NSObject"),
+		// 19: NSValue holding a range
+		map[string]interface{}{"$class": plist.UID(20), "NS.special": uint64(4), "NS.rangeval.location": uint64(3), "NS.rangeval.length": uint64(7)},
+		class("NSValue", "NSObject"),
+		// 21: NSAttributedString
+		map[string]interface{}{"$class": plist.UID(23), "NSString": plist.UID(24), "NSAttributes": plist.UID(0)},
+		class("NSNull", "NSObject"),
+		class("NSAttributedString", "NSObject"),
+		map[string]interface{}{"$class": plist.UID(25), "NS.string": "Hello"},
+		class("NSMutableString", "NSString", "NSObject"),
+	)
+
+	root, err := Unarchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, ok := root.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected a map, received %#v", root)
+	}
+
+	if date, ok := m["date"].(time.Time); !ok || !date.Equal(plist.AppleEpoch.Add(1500*time.Millisecond)) {
+		t.Errorf("Unexpected date %#v", m["date"])
+	}
+	if u, ok := m["url"].(*url.URL); !ok || u.String() != "https://example.com/a/docs/index.html" {
+		t.Errorf("Unexpected URL %#v", m["url"])
+	}
+	if items := m["items"]; !reflect.DeepEqual(items, []interface{}{uint64(42), nil}) {
+		t.Errorf("Unexpected items %#v", items)
+	}
+	if uuid, ok := m["uuid"].(UUID); !ok || uuid.String() != "12345678-9ABC-DEF0-1234-56789ABCDEF0" {
+		t.Errorf("Unexpected UUID %#v", m["uuid"])
+	}
+	if r := m["range"]; r != (Range{3, 7}) {
+		t.Errorf("Unexpected range %#v", r)
+	}
+	if title := m["title"]; title != "Hello" {
+		t.Errorf("Unexpected title %#v", title)
+	}
+}
+
+func TestUnarchiveRegisterClass(t *testing.T) {
+	type point struct{ X, Y uint64 }
+
+	data := archive(t,
+		map[string]interface{}{"$class": plist.UID(2), "NS.objects": []interface{}{plist.UID(3), plist.UID(1)}},
+		map[string]interface{}{"$classname": "NSArray"},
+		map[string]interface{}{"$class": plist.UID(4), "x": uint64(1), "y": uint64(2)},
+		map[string]interface{}{"$classname": "Point"},
+	)
+
+	u := &Unarchiver{}
+	u.RegisterClass("Point", func(obj *Object) (interface{}, error) {
+		return point{obj.Fields["x"].(uint64), obj.Fields["y"].(uint64)}, nil
+	})
+	root, err := u.Unarchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	array, ok := root.([]interface{})
+	if !ok || len(array) != 2 || array[0] != (point{1, 2}) {
+		t.Fatalf("Unexpected root %#v", root)
+	}
+	// The array contains itself; that reference cannot be decoded.
+	if self, ok := array[1].(*Object); !ok || self.Class != "NSArray" {
+		t.Errorf("Expected a self-reference to remain an *Object, received %#v", array[1])
+	}
+}
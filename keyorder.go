@@ -0,0 +1,104 @@
+package plist
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// A KeyOrdering determines the order in which an Encoder writes the keys of each dictionary.
+type KeyOrdering int
+
+const (
+	// BytewiseKeyOrdering sorts keys by their bytes, so that "B" precedes "a" and "Item 10"
+	// precedes "Item 2". This is the default.
+	BytewiseKeyOrdering KeyOrdering = iota
+
+	// NaturalKeyOrdering sorts keys the way Apple's tools (such as plutil and Xcode) do:
+	// letters are compared without regard to case, and runs of digits are compared by their
+	// numeric value, so that "Item 2" precedes "Item 10". Keys that differ only in case are
+	// ordered bytewise.
+	NaturalKeyOrdering
+)
+
+// applyKeyOrdering arranges for every dictionary in pval to be sorted with less.
+func applyKeyOrdering(pval cfValue, less func(a, b string) bool) {
+	switch pval := pval.(type) {
+	case *cfDictionary:
+		pval.less = less
+		for _, v := range pval.values {
+			applyKeyOrdering(v, less)
+		}
+	case *cfArray:
+		for _, v := range pval.values {
+			applyKeyOrdering(v, less)
+		}
+	}
+}
+
+// naturalKeyLess reports whether a sorts before b in NaturalKeyOrdering.
+func naturalKeyLess(a, b string) bool {
+	if c := naturalCompare(a, b); c != 0 {
+		return c < 0
+	}
+	return a < b
+}
+
+// naturalCompare compares a and b case-insensitively, treating runs of ASCII digits as numbers.
+func naturalCompare(a, b string) int {
+	for len(a) > 0 && len(b) > 0 {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			da, db := digitRun(a), digitRun(b)
+			if c := compareDigits(a[:da], b[:db]); c != 0 {
+				return c
+			}
+			a, b = a[da:], b[db:]
+			continue
+		}
+
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+		if ra, rb = unicode.ToLower(ra), unicode.ToLower(rb); ra != rb {
+			if ra < rb {
+				return -1
+			}
+			return 1
+		}
+		a, b = a[na:], b[nb:]
+	}
+	return len(a) - len(b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func digitRun(s string) int {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+// compareDigits compares two runs of digits by their numeric value; a run with more leading
+// zeros sorts first among runs of equal value.
+func compareDigits(a, b string) int {
+	ta, tb := trimZeros(a), trimZeros(b)
+	if len(ta) != len(tb) {
+		return len(ta) - len(tb)
+	}
+	if ta != tb {
+		if ta < tb {
+			return -1
+		}
+		return 1
+	}
+	return len(b) - len(a)
+}
+
+func trimZeros(s string) string {
+	for len(s) > 1 && s[0] == '0' {
+		s = s[1:]
+	}
+	return s
+}
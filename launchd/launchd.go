@@ -0,0 +1,301 @@
+// Package launchd models launchd job definitions, as described in launchd.plist(5).
+//
+// Several launchd keys accept more than one shape of value; for example, KeepAlive may be either a
+// boolean or a dictionary of conditions. The types in this package decode every documented shape
+// and encode back to the most compact one that preserves their meaning.
+package launchd
+
+import (
+	"fmt"
+
+	"howett.net/plist"
+	"howett.net/plist/internal/extrakeys"
+)
+
+// Job represents a launchd job definition.
+type Job struct {
+	Label                  string            `plist:"Label"`
+	Disabled               *bool             `plist:"Disabled,omitempty"`
+	UserName               string            `plist:"UserName,omitempty"`
+	GroupName              string            `plist:"GroupName,omitempty"`
+	Program                string            `plist:"Program,omitempty"`
+	ProgramArguments       []string          `plist:"ProgramArguments,omitempty"`
+	EnableGlobbing         bool              `plist:"EnableGlobbing,omitempty"`
+	EnvironmentVariables   map[string]string `plist:"EnvironmentVariables,omitempty"`
+	WorkingDirectory       string            `plist:"WorkingDirectory,omitempty"`
+	RootDirectory          string            `plist:"RootDirectory,omitempty"`
+	Umask                  *int              `plist:"Umask,omitempty"`
+	LimitLoadToSessionType Strings           `plist:"LimitLoadToSessionType,omitempty"`
+	LimitLoadToHosts       []string          `plist:"LimitLoadToHosts,omitempty"`
+	LimitLoadFromHosts     []string          `plist:"LimitLoadFromHosts,omitempty"`
+
+	RunAtLoad             bool              `plist:"RunAtLoad,omitempty"`
+	KeepAlive             *KeepAlive        `plist:"KeepAlive,omitempty"`
+	StartInterval         int               `plist:"StartInterval,omitempty"`
+	StartCalendarInterval CalendarIntervals `plist:"StartCalendarInterval,omitempty"`
+	StartOnMount          bool              `plist:"StartOnMount,omitempty"`
+	WatchPaths            []string          `plist:"WatchPaths,omitempty"`
+	QueueDirectories      []string          `plist:"QueueDirectories,omitempty"`
+	LaunchOnlyOnce        bool              `plist:"LaunchOnlyOnce,omitempty"`
+	ThrottleInterval      int               `plist:"ThrottleInterval,omitempty"`
+	TimeOut               int               `plist:"TimeOut,omitempty"`
+	ExitTimeOut           int               `plist:"ExitTimeOut,omitempty"`
+
+	StandardInPath    string `plist:"StandardInPath,omitempty"`
+	StandardOutPath   string `plist:"StandardOutPath,omitempty"`
+	StandardErrorPath string `plist:"StandardErrorPath,omitempty"`
+	Debug             bool   `plist:"Debug,omitempty"`
+
+	AbandonProcessGroup bool            `plist:"AbandonProcessGroup,omitempty"`
+	ProcessType         string          `plist:"ProcessType,omitempty"`
+	Nice                int             `plist:"Nice,omitempty"`
+	LowPriorityIO       bool            `plist:"LowPriorityIO,omitempty"`
+	SoftResourceLimits  *ResourceLimits `plist:"SoftResourceLimits,omitempty"`
+	HardResourceLimits  *ResourceLimits `plist:"HardResourceLimits,omitempty"`
+
+	MachServices map[string]MachService `plist:"MachServices,omitempty"`
+
+	// Extra holds every key that is not represented by one of the fields above (such as Sockets.)
+	Extra map[string]interface{} `plist:"-"`
+}
+
+// jobFields has the same fields as Job, but none of its methods.
+type jobFields Job
+
+// UnmarshalPlist implements plist.Unmarshaler.
+func (j *Job) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	if err := unmarshal((*jobFields)(j)); err != nil {
+		return err
+	}
+
+	var all map[string]interface{}
+	if err := unmarshal(&all); err != nil {
+		return err
+	}
+	j.Extra = extrakeys.Strip((*jobFields)(j), all)
+	return nil
+}
+
+// MarshalPlist implements plist.Marshaler.
+func (j Job) MarshalPlist() (interface{}, error) {
+	return extrakeys.Merge((*jobFields)(&j), j.Extra), nil
+}
+
+// Strings is a list of strings that may be represented either as a single string or an array.
+type Strings []string
+
+// UnmarshalPlist implements plist.Unmarshaler.
+func (s *Strings) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	var one string
+	if err := unmarshal(&one); err == nil {
+		*s = Strings{one}
+		return nil
+	}
+	return unmarshal((*[]string)(s))
+}
+
+// MarshalPlist implements plist.Marshaler.
+func (s Strings) MarshalPlist() (interface{}, error) {
+	if len(s) == 1 {
+		return s[0], nil
+	}
+	return []string(s), nil
+}
+
+// KeepAlive determines whether launchd keeps a job running. It is encoded as a boolean when
+// Conditions is nil, and as a dictionary of conditions otherwise.
+type KeepAlive struct {
+	// Always is the value of KeepAlive when it is expressed as a boolean.
+	Always bool
+
+	// Conditions, if non-nil, holds the conditions under which the job is kept alive.
+	Conditions *KeepAliveConditions
+}
+
+// KeepAliveConditions holds the conditions that may be used in a KeepAlive dictionary.
+type KeepAliveConditions struct {
+	SuccessfulExit     *bool           `plist:"SuccessfulExit,omitempty"`
+	NetworkState       *bool           `plist:"NetworkState,omitempty"`
+	Crashed            *bool           `plist:"Crashed,omitempty"`
+	PathState          map[string]bool `plist:"PathState,omitempty"`
+	OtherJobEnabled    map[string]bool `plist:"OtherJobEnabled,omitempty"`
+	AfterInitialDemand map[string]bool `plist:"AfterInitialDemand,omitempty"`
+}
+
+// UnmarshalPlist implements plist.Unmarshaler.
+func (k *KeepAlive) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	var always bool
+	if err := unmarshal(&always); err == nil {
+		*k = KeepAlive{Always: always}
+		return nil
+	}
+
+	conditions := &KeepAliveConditions{}
+	if err := unmarshal(conditions); err != nil {
+		return err
+	}
+	*k = KeepAlive{Conditions: conditions}
+	return nil
+}
+
+// MarshalPlist implements plist.Marshaler.
+func (k KeepAlive) MarshalPlist() (interface{}, error) {
+	if k.Conditions != nil {
+		return k.Conditions, nil
+	}
+	return k.Always, nil
+}
+
+// CalendarInterval describes when a job should be started. Nil fields are wildcards.
+type CalendarInterval struct {
+	Minute  *int `plist:"Minute,omitempty"`
+	Hour    *int `plist:"Hour,omitempty"`
+	Day     *int `plist:"Day,omitempty"`
+	Weekday *int `plist:"Weekday,omitempty"`
+	Month   *int `plist:"Month,omitempty"`
+}
+
+// CalendarIntervals holds the value of StartCalendarInterval, which may be either a single
+// dictionary or an array of dictionaries. A single interval is encoded as a dictionary.
+type CalendarIntervals []CalendarInterval
+
+// UnmarshalPlist implements plist.Unmarshaler.
+func (c *CalendarIntervals) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	var one CalendarInterval
+	if err := unmarshal(&one); err == nil {
+		*c = CalendarIntervals{one}
+		return nil
+	}
+	return unmarshal((*[]CalendarInterval)(c))
+}
+
+// MarshalPlist implements plist.Marshaler.
+func (c CalendarIntervals) MarshalPlist() (interface{}, error) {
+	if len(c) == 1 {
+		return c[0], nil
+	}
+	return []CalendarInterval(c), nil
+}
+
+// MachService describes a Mach service advertised by a job. It is encoded as a boolean unless one
+// of ResetAtClose or HideUntilCheckIn is set.
+type MachService struct {
+	Enabled          bool
+	ResetAtClose     bool `plist:"ResetAtClose,omitempty"`
+	HideUntilCheckIn bool `plist:"HideUntilCheckIn,omitempty"`
+}
+
+type machServiceOptions struct {
+	ResetAtClose     bool `plist:"ResetAtClose,omitempty"`
+	HideUntilCheckIn bool `plist:"HideUntilCheckIn,omitempty"`
+}
+
+// UnmarshalPlist implements plist.Unmarshaler.
+func (m *MachService) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	var enabled bool
+	if err := unmarshal(&enabled); err == nil {
+		*m = MachService{Enabled: enabled}
+		return nil
+	}
+
+	var opts machServiceOptions
+	if err := unmarshal(&opts); err != nil {
+		return err
+	}
+	*m = MachService{Enabled: true, ResetAtClose: opts.ResetAtClose, HideUntilCheckIn: opts.HideUntilCheckIn}
+	return nil
+}
+
+// MarshalPlist implements plist.Marshaler.
+func (m MachService) MarshalPlist() (interface{}, error) {
+	if m.ResetAtClose || m.HideUntilCheckIn {
+		return machServiceOptions{ResetAtClose: m.ResetAtClose, HideUntilCheckIn: m.HideUntilCheckIn}, nil
+	}
+	return m.Enabled, nil
+}
+
+// ResourceLimits holds the values of SoftResourceLimits and HardResourceLimits.
+type ResourceLimits struct {
+	Core              *int `plist:"Core,omitempty"`
+	CPU               *int `plist:"CPU,omitempty"`
+	Data              *int `plist:"Data,omitempty"`
+	FileSize          *int `plist:"FileSize,omitempty"`
+	MemoryLock        *int `plist:"MemoryLock,omitempty"`
+	NumberOfFiles     *int `plist:"NumberOfFiles,omitempty"`
+	NumberOfProcesses *int `plist:"NumberOfProcesses,omitempty"`
+	ResidentSetSize   *int `plist:"ResidentSetSize,omitempty"`
+	Stack             *int `plist:"Stack,omitempty"`
+}
+
+// A ValidationError describes a problem with the value of a single key in a Job.
+type ValidationError struct {
+	Key     string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return "launchd: invalid " + e.Key + ": " + e.Message
+}
+
+func checkRange(key string, v *int, min, max int) error {
+	if v != nil && (*v < min || *v > max) {
+		return &ValidationError{key, fmt.Sprintf("%d is out of range [%d, %d]", *v, min, max)}
+	}
+	return nil
+}
+
+// Validate checks j for the mistakes launchd would reject (or silently ignore), returning a
+// *ValidationError describing the first one found.
+func (j *Job) Validate() error {
+	if j.Label == "" {
+		return &ValidationError{"Label", "a label is required"}
+	}
+
+	if j.Program == "" && len(j.ProgramArguments) == 0 {
+		return &ValidationError{"ProgramArguments", "either Program or ProgramArguments is required"}
+	}
+
+	if j.StartInterval < 0 {
+		return &ValidationError{"StartInterval", "must not be negative"}
+	}
+
+	for i, c := range j.StartCalendarInterval {
+		key := fmt.Sprintf("StartCalendarInterval[%d].", i)
+		for _, err := range []error{
+			checkRange(key+"Minute", c.Minute, 0, 59),
+			checkRange(key+"Hour", c.Hour, 0, 23),
+			checkRange(key+"Day", c.Day, 1, 31),
+			checkRange(key+"Weekday", c.Weekday, 0, 7),
+			checkRange(key+"Month", c.Month, 1, 12),
+		} {
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	switch j.ProcessType {
+	case "", "Background", "Standard", "Adaptive", "Interactive":
+	default:
+		return &ValidationError{"ProcessType", "unknown process type " + j.ProcessType}
+	}
+
+	if j.KeepAlive != nil && j.KeepAlive.Conditions != nil {
+		for path := range j.KeepAlive.Conditions.PathState {
+			if path == "" {
+				return &ValidationError{"KeepAlive.PathState", "paths must not be empty"}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Parse decodes a launchd job definition in any property list format.
+func Parse(data []byte) (*Job, error) {
+	job := &Job{}
+	if _, err := plist.Unmarshal(data, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
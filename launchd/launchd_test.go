@@ -0,0 +1,156 @@
+package launchd
+
+import (
+	"reflect"
+	"testing"
+
+	"howett.net/plist"
+)
+
+const sampleJob = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>com.example.agent</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>/usr/local/bin/agent</string>
+		<string>--daemon</string>
+	</array>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+		<key>PathState</key>
+		<dict>
+			<key>/tmp/run</key>
+			<true/>
+		</dict>
+	</dict>
+	<key>StartCalendarInterval</key>
+	<dict>
+		<key>Hour</key>
+		<integer>3</integer>
+		<key>Minute</key>
+		<integer>15</integer>
+	</dict>
+	<key>LimitLoadToSessionType</key>
+	<string>Aqua</string>
+	<key>MachServices</key>
+	<dict>
+		<key>com.example.agent.xpc</key>
+		<true/>
+		<key>com.example.agent.reset</key>
+		<dict>
+			<key>ResetAtClose</key>
+			<true/>
+		</dict>
+	</dict>
+	<key>Sockets</key>
+	<dict>
+		<key>Listener</key>
+		<dict>
+			<key>SockServiceName</key>
+			<string>8080</string>
+		</dict>
+	</dict>
+</dict>
+</plist>`
+
+func TestParse(t *testing.T) {
+	job, err := Parse([]byte(sampleJob))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := job.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	if job.KeepAlive == nil || job.KeepAlive.Conditions == nil {
+		t.Fatalf("expected KeepAlive conditions, received %#v", job.KeepAlive)
+	}
+	if c := job.KeepAlive.Conditions; c.SuccessfulExit == nil || *c.SuccessfulExit || !c.PathState["/tmp/run"] {
+		t.Errorf("unexpected KeepAlive conditions %#v", c)
+	}
+
+	if len(job.StartCalendarInterval) != 1 || *job.StartCalendarInterval[0].Hour != 3 || job.StartCalendarInterval[0].Day != nil {
+		t.Errorf("unexpected calendar intervals %#v", job.StartCalendarInterval)
+	}
+
+	if !reflect.DeepEqual(job.LimitLoadToSessionType, Strings{"Aqua"}) {
+		t.Errorf("unexpected session types %#v", job.LimitLoadToSessionType)
+	}
+
+	expectedServices := map[string]MachService{
+		"com.example.agent.xpc":   {Enabled: true},
+		"com.example.agent.reset": {Enabled: true, ResetAtClose: true},
+	}
+	if !reflect.DeepEqual(job.MachServices, expectedServices) {
+		t.Errorf("Expected %#v, received %#v", expectedServices, job.MachServices)
+	}
+
+	if _, ok := job.Extra["Sockets"]; !ok || len(job.Extra) != 1 {
+		t.Errorf("unexpected extra keys %v", job.Extra)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	job, err := Parse([]byte(sampleJob))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := plist.Marshal(job, plist.XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var original, roundTripped map[string]interface{}
+	if _, err := plist.Unmarshal([]byte(sampleJob), &original); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plist.Unmarshal(data, &roundTripped); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(original, roundTripped) {
+		t.Errorf("Expected %v, received %v", original, roundTripped)
+	}
+}
+
+func TestKeepAliveBoolean(t *testing.T) {
+	job, err := Parse([]byte(`{Label=x;Program=/bin/true;KeepAlive=<*BY>;}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.KeepAlive == nil || !job.KeepAlive.Always || job.KeepAlive.Conditions != nil {
+		t.Errorf("unexpected KeepAlive %#v", job.KeepAlive)
+	}
+
+	data, err := plist.Marshal(KeepAlive{Always: true}, plist.GNUStepFormat)
+	if err != nil || string(data) != "<*BY>" {
+		t.Errorf("unexpected encoding %s (%v)", data, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	hour := 24
+	tests := []struct {
+		job Job
+		key string
+	}{
+		{Job{Program: "/bin/true"}, "Label"},
+		{Job{Label: "x"}, "ProgramArguments"},
+		{Job{Label: "x", Program: "/bin/true", StartCalendarInterval: CalendarIntervals{{Hour: &hour}}}, "StartCalendarInterval[0].Hour"},
+		{Job{Label: "x", Program: "/bin/true", ProcessType: "Urgent"}, "ProcessType"},
+	}
+
+	for _, test := range tests {
+		err := test.job.Validate()
+		if verr, ok := err.(*ValidationError); !ok || verr.Key != test.key {
+			t.Errorf("Expected validation error for %s, received %v", test.key, err)
+		}
+	}
+}
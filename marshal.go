@@ -1,15 +1,25 @@
 package plist
 
 import (
+	"database/sql/driver"
 	"encoding"
+	"fmt"
 	"reflect"
+	"strconv"
 	"time"
+	"unicode/utf8"
 )
 
 var (
 	plistMarshalerType = reflect.TypeOf((*Marshaler)(nil)).Elem()
 	textMarshalerType  = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
 	timeType           = reflect.TypeOf((*time.Time)(nil)).Elem()
+	runesType          = reflect.TypeOf(Runes(nil))
+
+	stringSliceType = reflect.TypeOf([]string(nil))
+	intSliceType    = reflect.TypeOf([]int(nil))
+	stringMapType   = reflect.TypeOf(map[string]string(nil))
+	anyMapType      = reflect.TypeOf(map[string]interface{}(nil))
 )
 
 func implementsInterface(val reflect.Value, interfaceType reflect.Type) (interface{}, bool) {
@@ -56,18 +66,129 @@ func (p *Encoder) marshalStruct(typ reflect.Type, val reflect.Value) cfValue {
 		keys:   make([]string, 0, len(tinfo.fields)),
 		values: make([]cfValue, 0, len(tinfo.fields)),
 	}
+	var promoted []*cfDictionary
 	for _, finfo := range tinfo.fields {
 		value := finfo.value(val)
 		if !value.IsValid() {
 			continue
 		}
-		dict.keys = append(dict.keys, finfo.name)
-		dict.values = append(dict.values, p.marshal(value))
+		if finfo.embeddedInterface && embeddedStruct(value).IsValid() {
+			// The struct is marshaled in place, so that its fields appear at this level.
+			if subpval := p.marshal(value.Elem()); subpval != nil {
+				if subdict, ok := subpval.(*cfDictionary); ok {
+					promoted = append(promoted, subdict)
+				} else {
+					dict.keys = append(dict.keys, finfo.name)
+					dict.values = append(dict.values, subpval)
+				}
+			}
+			continue
+		}
+		fieldDuration := p.fieldDuration
+		p.fieldDuration = finfo.duration
+		subpval := p.marshalAt(finfo.name, value)
+		p.fieldDuration = fieldDuration
+		if subpval != nil {
+			dict.keys = append(dict.keys, finfo.name)
+			dict.values = append(dict.values, subpval)
+		}
+	}
+
+	// Promoted fields are shadowed by the outer struct's own, and by those of earlier embedded
+	// interfaces.
+	var seen map[string]bool
+	for _, subdict := range promoted {
+		if seen == nil {
+			seen = make(map[string]bool, len(dict.keys))
+			for _, k := range dict.keys {
+				seen[k] = true
+			}
+		}
+		for i, k := range subdict.keys {
+			if _, shadowed := tinfo.fieldIndex[k]; !shadowed && !seen[k] {
+				seen[k] = true
+				dict.keys = append(dict.keys, k)
+				dict.values = append(dict.values, subdict.values[i])
+			}
+		}
 	}
 
 	return dict
 }
 
+// embeddedStruct returns the struct held by the interface value v, directly or through a
+// pointer, or the zero Value if it holds anything else.
+func embeddedStruct(v reflect.Value) reflect.Value {
+	if v.IsNil() {
+		return reflect.Value{}
+	}
+	v = v.Elem()
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	return v
+}
+
+// marshalCommonType marshals the most frequently-encountered collection types without reflection.
+// It returns nil if val is not one of those types.
+func (p *Encoder) marshalCommonType(typ reflect.Type, val reflect.Value) cfValue {
+	if !val.CanInterface() {
+		return nil
+	}
+
+	if typ == anyMapType {
+		m := val.Interface().(map[string]interface{})
+		dict := &cfDictionary{
+			keys:   make([]string, 0, len(m)),
+			values: make([]cfValue, 0, len(m)),
+		}
+		for k, v := range m {
+			if subpval := p.marshalAt(k, reflect.ValueOf(v)); subpval != nil {
+				dict.keys = append(dict.keys, k)
+				dict.values = append(dict.values, subpval)
+			}
+		}
+		return dict
+	}
+
+	if p.preHook != nil || p.postHook != nil || p.filter != nil || p.progress != nil {
+		// Hooks and filters must observe every element of the remaining types.
+		return nil
+	}
+
+	switch typ {
+	case stringSliceType:
+		s := val.Interface().([]string)
+		values := make([]cfValue, len(s))
+		for i, v := range s {
+			values[i] = cfString(v)
+		}
+		return &cfArray{values}
+	case intSliceType:
+		s := val.Interface().([]int)
+		values := make([]cfValue, len(s))
+		for i, v := range s {
+			values[i] = &cfNumber{signed: true, value: uint64(v)}
+		}
+		return &cfArray{values}
+	case stringMapType:
+		m := val.Interface().(map[string]string)
+		dict := &cfDictionary{
+			keys:   make([]string, 0, len(m)),
+			values: make([]cfValue, 0, len(m)),
+		}
+		for k, v := range m {
+			dict.keys = append(dict.keys, k)
+			dict.values = append(dict.values, cfString(v))
+		}
+		return dict
+	}
+	return nil
+}
+
 func (p *Encoder) marshalTime(val reflect.Value) cfValue {
 	time := val.Interface().(time.Time)
 	return cfDate(time)
@@ -80,6 +201,42 @@ func innermostValue(val reflect.Value) reflect.Value {
 	return val
 }
 
+// marshalAt marshals val, which is stored under key in the value currently being marshaled.
+func (p *Encoder) marshalAt(key string, val reflect.Value) cfValue {
+	p.path = append(p.path, key)
+	if p.filter != nil && !p.filter(p.path) {
+		p.path = p.path[:len(p.path)-1]
+		return nil
+	}
+	pval := p.marshalHooked(val)
+	p.path = p.path[:len(p.path)-1]
+	return pval
+}
+
+// marshalHooked marshals val, calling the encoder's hooks around it.
+func (p *Encoder) marshalHooked(val reflect.Value) cfValue {
+	p.progress.object()
+	if p.preHook != nil {
+		var err error
+		val, err = p.preHook(p.path, val)
+		if err != nil {
+			panic(err)
+		}
+		if !val.IsValid() {
+			return nil
+		}
+	}
+
+	pval := p.marshal(val)
+
+	if p.postHook != nil && pval != nil {
+		if err := p.postHook(p.path, val); err != nil {
+			panic(err)
+		}
+	}
+	return pval
+}
+
 func (p *Encoder) marshal(val reflect.Value) cfValue {
 	if !val.IsValid() {
 		return nil
@@ -89,6 +246,12 @@ func (p *Encoder) marshal(val reflect.Value) cfValue {
 		return p.marshalPlistInterface(receiver.(Marshaler))
 	}
 
+	if val.Kind() != reflect.Ptr || !val.IsNil() {
+		if receiver, can := implementsInterface(val, valuerType); can {
+			return p.marshalValuer(receiver.(driver.Valuer))
+		}
+	}
+
 	// time.Time implements TextMarshaler, but we need to store it in RFC3339
 	if val.Type() == timeType {
 		return p.marshalTime(val)
@@ -100,6 +263,11 @@ func (p *Encoder) marshal(val reflect.Value) cfValue {
 		}
 	}
 
+	// big.Float implements TextMarshaler too, but it is a number.
+	if ival := innermostValue(val); ival.IsValid() && ival.Type() == bigFloatType {
+		return p.marshalBigFloat(ival)
+	}
+
 	// Check for text marshaler.
 	if receiver, can := implementsInterface(val, textMarshalerType); can {
 		return p.marshalTextInterface(receiver.(encoding.TextMarshaler))
@@ -119,10 +287,32 @@ func (p *Encoder) marshal(val reflect.Value) cfValue {
 		return cfUID(val.Uint())
 	}
 
+	if typ == durationType {
+		return p.marshalDuration(time.Duration(val.Int()))
+	}
+
+	if typ == runesType {
+		runes := val.Interface().(Runes)
+		for _, r := range runes {
+			if !utf8.ValidRune(r) {
+				panic(fmt.Errorf("plist: Runes value holds invalid code point %#x", r))
+			}
+		}
+		return cfString(runes)
+	}
+
+	if typ == orderedDictContentsType {
+		return p.marshalOrderedDict(val.Interface().(orderedDictContents))
+	}
+
 	if val.Kind() == reflect.Struct {
 		return p.marshalStruct(typ, val)
 	}
 
+	if pval := p.marshalCommonType(typ, val); pval != nil {
+		return pval
+	}
+
 	switch val.Kind() {
 	case reflect.String:
 		return cfString(val.String())
@@ -148,17 +338,20 @@ func (p *Encoder) marshal(val reflect.Value) cfValue {
 			}
 			return cfData(bytes)
 		} else {
-			values := make([]cfValue, val.Len())
+			values := make([]cfValue, 0, val.Len())
 			for i, length := 0, val.Len(); i < length; i++ {
-				if subpval := p.marshal(val.Index(i)); subpval != nil {
-					values[i] = subpval
+				// Elements that encode as nothing (nil pointers and interfaces, and anything left
+				// out by a hook, the field filter or SetSkipUnsupportedTypes) are dropped, as no
+				// format can hold a placeholder for them.
+				if subpval := p.marshalAt(strconv.Itoa(i), val.Index(i)); subpval != nil {
+					values = append(values, subpval)
 				}
 			}
 			return &cfArray{values}
 		}
 	case reflect.Map:
 		if typ.Key().Kind() != reflect.String {
-			panic(&unknownTypeError{typ})
+			return p.marshalUnsupported(typ)
 		}
 
 		l := val.Len()
@@ -167,13 +360,29 @@ func (p *Encoder) marshal(val reflect.Value) cfValue {
 			values: make([]cfValue, 0, l),
 		}
 		for _, keyv := range val.MapKeys() {
-			if subpval := p.marshal(val.MapIndex(keyv)); subpval != nil {
+			if subpval := p.marshalAt(keyv.String(), val.MapIndex(keyv)); subpval != nil {
 				dict.keys = append(dict.keys, keyv.String())
 				dict.values = append(dict.values, subpval)
 			}
 		}
 		return dict
+	case reflect.Func:
+		if pval, ok := p.marshalSeq(typ, val); ok {
+			return pval
+		}
+		return p.marshalUnsupported(typ)
 	default:
-		panic(&unknownTypeError{typ})
+		return p.marshalUnsupported(typ)
+	}
+}
+
+// marshalUnsupported handles a value of a type that has no property list representation.
+func (p *Encoder) marshalUnsupported(typ reflect.Type) cfValue {
+	if p.unsupportedPlaceholder != "" {
+		return cfString(p.unsupportedPlaceholder)
+	}
+	if p.skipUnsupported {
+		return nil
 	}
+	panic(&unknownTypeError{typ})
 }
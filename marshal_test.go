@@ -1,6 +1,7 @@
 package plist
 
 import (
+	"bytes"
 	"reflect"
 	"testing"
 	"time"
@@ -156,3 +157,196 @@ func TestInterfaceGeneralSliceMarshal(t *testing.T) {
 		t.Error("expect non-zero data")
 	}
 }
+
+func TestCommonTypeMarshal(t *testing.T) {
+	values := []interface{}{
+		[]string{"a", "b"},
+		[]int{1, -2},
+		map[string]string{"a": "b", "c": "d"},
+		map[string]interface{}{"a": 1, "b": []string{"c"}, "n": nil},
+	}
+
+	for _, v := range values {
+		fast, err := Marshal(v, GNUStepFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Hooks disable the fast paths for every type.
+		buf := &bytes.Buffer{}
+		enc := NewEncoderForFormat(buf, GNUStepFormat)
+		enc.SetHooks(func(path []string, v reflect.Value) (reflect.Value, error) { return v, nil }, nil)
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(fast, buf.Bytes()) {
+			t.Errorf("%#v: Expected %s, received %s", v, buf.Bytes(), fast)
+		}
+	}
+}
+
+func TestNilContainersMarshalAsEmpty(t *testing.T) {
+	type config struct {
+		Names    []string
+		Settings map[string]string
+		Any      interface{}
+		Omitted  []string `plist:",omitempty"`
+	}
+
+	out, err := Marshal(config{Any: map[string]int(nil)}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<plist version="1.0"><dict><key>Any</key><dict/><key>Names</key><array/><key>Settings</key><dict/></dict></plist>`
+	if !bytes.HasSuffix(out, []byte(expected)) {
+		t.Errorf("Expected document to end with %s, received %s", expected, out)
+	}
+}
+
+func TestIteratorMarshal(t *testing.T) {
+	// These have the shapes of iter.Seq[int] and iter.Seq2[string, bool].
+	count := func(yield func(int) bool) {
+		for i := 1; i <= 5; i++ {
+			if !yield(i * i) {
+				return
+			}
+		}
+	}
+	flags := func(yield func(string, bool) bool) {
+		_ = yield("b", false) && yield("a", true) && yield("b", true)
+	}
+	type doc struct {
+		Squares func(func(int) bool)
+		Flags   func(func(string, bool) bool)
+		None    func(func(int) bool)
+	}
+
+	out, err := Marshal(doc{Squares: count, Flags: flags}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<dict><key>Flags</key><dict><key>a</key><true/><key>b</key><true/></dict><key>Squares</key><array><integer>1</integer><integer>4</integer><integer>9</integer><integer>16</integer><integer>25</integer></array></dict>`
+	if !bytes.Contains(out, []byte(expected)) {
+		t.Errorf("Expected document to contain %s, received %s", expected, out)
+	}
+
+	intKeys := func(yield func(int, string) bool) { yield(1, "one") }
+	if _, err := Marshal(intKeys, XMLFormat); err == nil {
+		t.Error("Expected an error encoding an iterator with integer keys")
+	}
+	if _, err := Marshal(func() {}, XMLFormat); err == nil {
+		t.Error("Expected an error encoding a function that is not an iterator")
+	}
+}
+
+func TestMarshalArrayNilElements(t *testing.T) {
+	x := 7
+	in := []interface{}{(*int)(nil), &x, nil, "s"}
+	for _, format := range []int{BinaryFormat, XMLFormat, OpenStepFormat, GNUStepFormat} {
+		doc, err := Marshal(in, format)
+		if err != nil {
+			t.Errorf("%s: %v", FormatNames[format], err)
+			continue
+		}
+		var out []interface{}
+		if _, err := Unmarshal(doc, &out); err != nil {
+			t.Errorf("%s: %v", FormatNames[format], err)
+			continue
+		}
+		expected := []interface{}{uint64(7), "s"}
+		if format == OpenStepFormat {
+			expected[0] = "7"
+		}
+		if !reflect.DeepEqual(out, expected) {
+			t.Errorf("%s: Expected %#v, received %#v", FormatNames[format], expected, out)
+		}
+	}
+
+	// Missing values never reach the binary generator silently.
+	defer func() {
+		if _, ok := recover().(error); !ok {
+			t.Error("Expected an error for an array holding a missing value")
+		}
+	}()
+	var buf bytes.Buffer
+	NewBinaryEncoder(&buf).generate(&cfArray{[]cfValue{nil, cfString("s")}})
+}
+
+func TestRunesMarshal(t *testing.T) {
+	v := struct {
+		Runes Runes
+		Int32 []int32
+		Array [2]rune
+	}{Runes("héllo"), []int32{-1, 0x110000}, [2]rune{1, 2}}
+
+	out, err := Marshal(v, OpenStepFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only Runes values are strings; other []rune and []int32 values remain arrays.
+	expected := `{Array=(1,2,);Int32=(-1,1114112,);Runes="h\351llo";}`
+	if string(out) != expected {
+		t.Errorf("Expected %s, got %s", expected, out)
+	}
+
+	var decoded struct {
+		Runes Runes
+	}
+	if _, err := Unmarshal(out, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded.Runes) != "héllo" {
+		t.Errorf("Unexpected round trip %q", string(decoded.Runes))
+	}
+
+	if _, err := Marshal(Runes{'a', 0xD800}, XMLFormat); err == nil {
+		t.Error("Expected an error encoding an invalid code point")
+	}
+}
+
+func TestMustHelpers(t *testing.T) {
+	data := MustMarshal(map[string]string{"a": "b"}, OpenStepFormat)
+	if string(data) != "{a=b;}" {
+		t.Errorf("Unexpected output %s", data)
+	}
+
+	if indented := MustMarshalIndent([]string{"a"}, OpenStepFormat, "\t"); string(indented) != "(\n\ta,\n)" {
+		t.Errorf("Unexpected output %q", indented)
+	}
+
+	var m map[string]string
+	if format := MustUnmarshal(data, &m); format != OpenStepFormat || m["a"] != "b" {
+		t.Errorf("Unexpected result %v (%s)", m, FormatNames[format])
+	}
+
+	mustPanic := func(name string, fn func()) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: Expected a panic", name)
+			}
+		}()
+		fn()
+	}
+	mustPanic("MustMarshal", func() { MustMarshal(make(chan int), XMLFormat) })
+	mustPanic("MustMarshalIndent", func() { MustMarshalIndent(make(chan int), XMLFormat, "\t") })
+	mustPanic("MustUnmarshal", func() { MustUnmarshal([]byte("{ a = "), &m) })
+}
+
+func TestMarshalErrorPath(t *testing.T) {
+	v := map[string]interface{}{
+		"list": []interface{}{1, map[string]interface{}{"bad": make(chan int)}},
+	}
+	_, err := Marshal(v, XMLFormat)
+	perr, ok := err.(*PathError)
+	if !ok {
+		t.Fatalf("Expected a *PathError, received %#v", err)
+	}
+	if expected := []string{"list", "1", "bad"}; !reflect.DeepEqual(perr.Path, expected) {
+		t.Errorf("Expected path %v, received %v", expected, perr.Path)
+	}
+	if _, ok := perr.Err.(*unknownTypeError); !ok {
+		t.Errorf("Expected an unknown type error, received %#v", perr.Err)
+	}
+}
@@ -0,0 +1,134 @@
+package plist
+
+import (
+	"bytes"
+	"io"
+)
+
+// A MergeConflict describes a value that was changed in different ways by both sides of a
+// three-way merge. Base, Ours and Theirs hold the value in each document, in the form Unmarshal
+// uses when decoding into an empty interface, or nil where the document has no such value.
+type MergeConflict struct {
+	// Path holds the dictionary keys and array indices (in decimal) that lead from the root to
+	// the value, as for a TransformFunc.
+	Path []string
+
+	Base, Ours, Theirs interface{}
+}
+
+// Merge performs a three-way merge of the property lists ours and theirs, which were both
+// derived from base, and writes the result to w in the format of ours. All three may be in any
+// format. Changes made on only one side are kept; changes made identically on both sides are
+// kept once. Dictionaries changed on both sides are merged key by key, so that conflicts are
+// reported for individual values; anything else changed differently on both sides, including
+// an array, is a conflict.
+//
+// Conflicting values are written as they are in ours, and reported in the returned conflicts,
+// so that Merge can serve as a git merge driver: the merged document is always written, and the
+// merge has succeeded if there are no conflicts.
+func Merge(w io.Writer, base, ours, theirs []byte) ([]MergeConflict, error) {
+	var m *merger
+	err := reencode(w, ours, sameFormat, func(d *Decoder, enc *Encoder, opval cfValue) (cfValue, error) {
+		bpval, err := NewDecoder(bytes.NewReader(base)).parseDocument()
+		if err != nil {
+			return nil, err
+		}
+		tpval, err := NewDecoder(bytes.NewReader(theirs)).parseDocument()
+		if err != nil {
+			return nil, err
+		}
+
+		enc.Indent("\t")
+		m = &merger{decoder: d}
+		return m.merge(bpval, opval, tpval), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.conflicts, nil
+}
+
+type merger struct {
+	decoder   *Decoder
+	path      []string
+	conflicts []MergeConflict
+}
+
+// cfValueEqualOrAbsent reports whether a and b are equal, where nil stands for an absent value.
+func cfValueEqualOrAbsent(a, b cfValue) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return cfValueEqual(a, b)
+}
+
+// merge returns the result of merging ours and theirs, any of which (with base) may be nil if
+// the value is absent from that document.
+func (m *merger) merge(base, ours, theirs cfValue) cfValue {
+	switch {
+	case cfValueEqualOrAbsent(ours, theirs), cfValueEqualOrAbsent(base, theirs):
+		return ours
+	case cfValueEqualOrAbsent(base, ours):
+		return theirs
+	}
+
+	od, ok := ours.(*cfDictionary)
+	td, ok2 := theirs.(*cfDictionary)
+	bd, ok3 := base.(*cfDictionary)
+	if ok && ok2 && (ok3 || base == nil) {
+		if bd == nil {
+			bd = &cfDictionary{}
+		}
+		return m.mergeDictionaries(bd, od, td)
+	}
+
+	m.conflicts = append(m.conflicts, MergeConflict{
+		Path:   append([]string(nil), m.path...),
+		Base:   m.valueInterface(base),
+		Ours:   m.valueInterface(ours),
+		Theirs: m.valueInterface(theirs),
+	})
+	return ours
+}
+
+// mergeDictionaries merges each key of ours and theirs. The merged dictionary's keys are sorted
+// when it is written, as any dictionary's are.
+func (m *merger) mergeDictionaries(base, ours, theirs *cfDictionary) *cfDictionary {
+	bvals, ovals, tvals := dictionaryIndex(base), dictionaryIndex(ours), dictionaryIndex(theirs)
+	keys := append([]string(nil), ours.keys...)
+	for _, k := range theirs.keys {
+		if _, ok := ovals[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	merged := &cfDictionary{}
+	for _, k := range keys {
+		m.path = append(m.path, k)
+		if v := m.merge(bvals[k], ovals[k], tvals[k]); v != nil {
+			merged.keys = append(merged.keys, k)
+			merged.values = append(merged.values, v)
+		}
+		m.path = m.path[:len(m.path)-1]
+	}
+	return merged
+}
+
+func (m *merger) valueInterface(pval cfValue) interface{} {
+	if pval == nil {
+		return nil
+	}
+	return m.decoder.valueInterface(pval)
+}
+
+// dictionaryIndex returns the values of dict by key. Where a key is repeated, its first value
+// is used.
+func dictionaryIndex(dict *cfDictionary) map[string]cfValue {
+	index := make(map[string]cfValue, len(dict.keys))
+	for i, k := range dict.keys {
+		if _, ok := index[k]; !ok {
+			index[k] = dict.values[i]
+		}
+	}
+	return index
+}
@@ -0,0 +1,85 @@
+package plist
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	base := []byte(`{ name = app; version = 1; tags = (a, b); build = { debug = 0; opt = 2; }; old = x; }`)
+	ours := []byte(`{ name = app; version = 2; tags = (a, b); build = { debug = 1; opt = 2; }; mine = y; }`)
+	theirs := []byte(`<plist version="1.0"><dict>
+		<key>name</key><string>application</string>
+		<key>version</key><string>3</string>
+		<key>tags</key><array><string>a</string><string>c</string></array>
+		<key>build</key><dict><key>debug</key><string>0</string><key>opt</key><string>3</string></dict>
+		<key>old</key><string>x</string>
+		<key>theirs</key><string>z</string>
+	</dict></plist>`)
+
+	var out bytes.Buffer
+	conflicts, err := Merge(&out, base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedConflicts := []MergeConflict{
+		{Path: []string{"version"}, Base: "1", Ours: "2", Theirs: "3"},
+	}
+	if !reflect.DeepEqual(conflicts, expectedConflicts) {
+		t.Errorf("Expected conflicts %#v, received %#v", expectedConflicts, conflicts)
+	}
+
+	var merged map[string]interface{}
+	if format, err := Unmarshal(out.Bytes(), &merged); err != nil || format != OpenStepFormat {
+		t.Fatalf("Expected an OpenStep document, received %v (%v):\n%s", FormatNames[format], err, out.String())
+	}
+	expected := map[string]interface{}{
+		"name":    "application",
+		"version": "2",
+		"tags":    []interface{}{"a", "c"},
+		"build":   map[string]interface{}{"debug": "1", "opt": "3"},
+		"mine":    "y",
+		"theirs":  "z",
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Expected %#v, received %#v", expected, merged)
+	}
+}
+
+func TestMergeConflicts(t *testing.T) {
+	base := []byte(`{ a = (1); b = { c = 1; }; }`)
+	ours := []byte(`{ a = (1, 2); b = 2; n = { x = 1; }; }`)
+	theirs := []byte(`{ a = (1, 3); n = { x = 2; y = 1; }; }`)
+
+	var out bytes.Buffer
+	conflicts, err := Merge(&out, base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []MergeConflict{
+		{Path: []string{"a"}, Base: []interface{}{"1"}, Ours: []interface{}{"1", "2"}, Theirs: []interface{}{"1", "3"}},
+		{Path: []string{"b"}, Base: map[string]interface{}{"c": "1"}, Ours: "2"},
+		{Path: []string{"n", "x"}, Ours: "1", Theirs: "2"},
+	}
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Errorf("Expected conflicts %#v, received %#v", expected, conflicts)
+	}
+
+	var merged interface{}
+	if _, err := Unmarshal(out.Bytes(), &merged); err != nil {
+		t.Fatal(err)
+	}
+	expectedMerged := map[string]interface{}{
+		"a": []interface{}{"1", "2"},
+		"b": "2",
+		"n": map[string]interface{}{"x": "1", "y": "1"},
+	}
+	if !reflect.DeepEqual(merged, expectedMerged) {
+		t.Errorf("Expected %#v, received %#v", expectedMerged, merged)
+	}
+
+	if _, err := Merge(&out, base, []byte("{ a = "), theirs); err == nil {
+		t.Error("Expected an error for an invalid document")
+	}
+}
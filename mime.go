@@ -0,0 +1,45 @@
+package plist
+
+import (
+	"bytes"
+)
+
+// Media types for property lists.
+//
+// Property lists are conventionally served as application/x-plist whatever their format, but
+// application/x-bplist names binary property lists specifically. The text formats have no media
+// type of their own and are served as plain text.
+const (
+	MIMEType       = "application/x-plist" // XML or binary
+	MIMETypeBinary = "application/x-bplist"
+	MIMETypeText   = "text/plain; charset=utf-8"
+)
+
+// MIMETypes maps each format to the media type for documents in that format.
+var MIMETypes = map[int]string{
+	XMLFormat:      MIMEType,
+	BinaryFormat:   MIMETypeBinary,
+	OpenStepFormat: MIMETypeText,
+	GNUStepFormat:  MIMETypeText,
+	PrettyFormat:   MIMETypeText,
+}
+
+// DetectFormat returns the format of the property list in data, as a Decoder would determine
+// it, or InvalidFormat if data is not a valid property list. The whole document is parsed.
+//
+// Much plain text is also a valid OpenStep property list (a single unquoted word is a string),
+// so OpenStepFormat is only a weak indication that data was meant to be a property list.
+func DetectFormat(data []byte) int {
+	d := NewDecoder(bytes.NewReader(data))
+	if _, err := d.parseDocument(); err != nil {
+		return InvalidFormat
+	}
+	return d.Format
+}
+
+// SniffContentType returns the media type of the property list in data, according to its
+// format as reported by DetectFormat. It returns the empty string if data is not a valid
+// property list.
+func SniffContentType(data []byte) string {
+	return MIMETypes[DetectFormat(data)]
+}
@@ -0,0 +1,28 @@
+package plist
+
+import "testing"
+
+func TestSniffContentType(t *testing.T) {
+	bin, _ := Marshal([]string{"a"}, BinaryFormat)
+	tests := []struct {
+		data     []byte
+		format   int
+		mimeType string
+	}{
+		{bin, BinaryFormat, MIMETypeBinary},
+		{[]byte(`<?xml version="1.0"?><plist version="1.0"><true/></plist>`), XMLFormat, MIMEType},
+		{[]byte(`{ a = b; }`), OpenStepFormat, MIMETypeText},
+		{[]byte(`{ a = <*I1>; }`), GNUStepFormat, MIMETypeText},
+		{[]byte(`{ a = `), InvalidFormat, ""},
+		{bin[:len(bin)-1], InvalidFormat, ""},
+	}
+
+	for _, test := range tests {
+		if format := DetectFormat(test.data); format != test.format {
+			t.Errorf("%q: Expected format %s, received %s", test.data, FormatNames[test.format], FormatNames[format])
+		}
+		if mimeType := SniffContentType(test.data); mimeType != test.mimeType {
+			t.Errorf("%q: Expected %q, received %q", test.data, test.mimeType, mimeType)
+		}
+	}
+}
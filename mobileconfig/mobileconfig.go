@@ -0,0 +1,356 @@
+// Package mobileconfig signs and verifies configuration profiles (.mobileconfig files).
+//
+// A signed configuration profile is a CMS (PKCS #7) SignedData message, in DER form, that
+// encapsulates the profile's property list. Profiles produced by Sign are accepted by iOS and
+// macOS, and Verify and Unwrap accept the profiles those systems and Apple Configurator produce.
+//
+// Only the subset of CMS used by configuration profiles is supported: a single signer
+// identified by issuer and serial number, signed attributes, and SHA-256, SHA-384 or SHA-512
+// digests with RSA (PKCS #1 v1.5) or ECDSA keys.
+package mobileconfig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	_ "crypto/sha256" // for crypto.SHA256
+	_ "crypto/sha512" // for crypto.SHA384 and crypto.SHA512
+)
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidRSAEncryption   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSHA384WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSHA512WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+)
+
+// The ASN.1 structures below are those of RFC 5652, reduced to the fields that signed
+// configuration profiles use.
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []algorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     []asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue   `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo    `asn1:"set"`
+}
+
+type encapsulatedContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     []byte `asn1:"optional,explicit,tag:0"`
+}
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type signerInfo struct {
+	Version            int
+	SID                issuerAndSerialNumber
+	DigestAlgorithm    algorithmIdentifier
+	SignedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm algorithmIdentifier
+	Signature          []byte
+	UnsignedAttributes asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+// Sign signs profile, the property list of a configuration profile, with the given certificate
+// and its private key, and returns the signed profile. Any intermediates are included in the
+// signed profile so that devices can build a chain to a trusted root. The key must be an RSA or
+// ECDSA key; the profile is signed with SHA-256.
+func Sign(profile []byte, cert *x509.Certificate, key crypto.Signer, intermediates ...*x509.Certificate) ([]byte, error) {
+	var sigAlg asn1.ObjectIdentifier
+	switch key.Public().(type) {
+	case *rsa.PublicKey:
+		sigAlg = oidRSAEncryption
+	case *ecdsa.PublicKey:
+		sigAlg = oidECDSAWithSHA256
+	default:
+		return nil, fmt.Errorf("mobileconfig: unsupported key type %T", key.Public())
+	}
+
+	digest := crypto.SHA256.New()
+	digest.Write(profile)
+
+	attrs, err := signedAttributes(digest.Sum(nil), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// The signature covers the DER encoding of the attributes as a SET OF, rather than with the
+	// implicit [0] tag they carry in the SignerInfo.
+	h := crypto.SHA256.New()
+	h.Write(attrs)
+	signature, err := key.Sign(rand.Reader, h.Sum(nil), crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("mobileconfig: %v", err)
+	}
+
+	tagged := append([]byte(nil), attrs...)
+	tagged[0] = 0xA0 // [0] IMPLICIT, constructed
+
+	certs := make([]asn1.RawValue, 0, len(intermediates)+1)
+	for _, c := range append([]*x509.Certificate{cert}, intermediates...) {
+		certs = append(certs, asn1.RawValue{FullBytes: c.Raw})
+	}
+
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []algorithmIdentifier{{Algorithm: oidSHA256}},
+		EncapContentInfo: encapsulatedContentInfo{ContentType: oidData, Content: profile},
+		Certificates:     certs,
+		SignerInfos: []signerInfo{{
+			Version:            1,
+			SID:                issuerAndSerialNumber{asn1.RawValue{FullBytes: cert.RawIssuer}, cert.SerialNumber},
+			DigestAlgorithm:    algorithmIdentifier{Algorithm: oidSHA256},
+			SignedAttributes:   asn1.RawValue{FullBytes: tagged},
+			SignatureAlgorithm: algorithmIdentifier{Algorithm: sigAlg},
+			Signature:          signature,
+		}},
+	}
+	content, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, fmt.Errorf("mobileconfig: %v", err)
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content},
+	})
+}
+
+// signedAttributes returns the DER encoding of the signed attributes for a message with the
+// given digest, as a SET OF Attribute.
+func signedAttributes(digest []byte, signingTime time.Time) ([]byte, error) {
+	var attrs []attribute
+	for _, a := range []struct {
+		oid   asn1.ObjectIdentifier
+		value interface{}
+	}{
+		{oidContentType, oidData},
+		{oidSigningTime, signingTime.UTC()},
+		{oidMessageDigest, digest},
+	} {
+		value, err := asn1.Marshal(a.value)
+		if err != nil {
+			return nil, fmt.Errorf("mobileconfig: %v", err)
+		}
+		attrs = append(attrs, attribute{Type: a.oid, Values: []asn1.RawValue{{FullBytes: value}}})
+	}
+	return asn1.MarshalWithParams(attrs, "set")
+}
+
+// A SignedProfile is a configuration profile extracted from its signature.
+type SignedProfile struct {
+	Profile      []byte              // the property list of the profile
+	Signer       *x509.Certificate   // the certificate that signed the profile
+	Certificates []*x509.Certificate // every certificate included with the signature
+}
+
+// Unwrap extracts the profile from a signed configuration profile without verifying its
+// signature. Signer is the certificate the signature claims to be from, if it was included.
+func Unwrap(signed []byte) (*SignedProfile, error) {
+	sp, _, err := parse(signed)
+	return sp, err
+}
+
+// Verify extracts the profile from a signed configuration profile and checks its signature.
+// If roots is not nil, the signer's certificate must also chain to one of them, through the
+// certificates included with the signature if necessary; otherwise only the integrity of the
+// profile is checked, and the caller must decide whether to trust Signer.
+func Verify(signed []byte, roots *x509.CertPool) (*SignedProfile, error) {
+	sp, si, err := parse(signed)
+	if err != nil {
+		return nil, err
+	}
+	if sp.Signer == nil {
+		return nil, errors.New("mobileconfig: signer's certificate is not included")
+	}
+	if err := verifySignerInfo(si, sp); err != nil {
+		return nil, err
+	}
+
+	if roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, c := range sp.Certificates {
+			intermediates.AddCert(c)
+		}
+		_, err := sp.Signer.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("mobileconfig: %v", err)
+		}
+	}
+	return sp, nil
+}
+
+// parse decodes a signed profile, returning its contents and its only SignerInfo.
+func parse(signed []byte) (*SignedProfile, *signerInfo, error) {
+	var ci contentInfo
+	if rest, err := asn1.Unmarshal(signed, &ci); err != nil {
+		return nil, nil, fmt.Errorf("mobileconfig: %v", err)
+	} else if len(rest) > 0 {
+		return nil, nil, errors.New("mobileconfig: trailing data after signed profile")
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, nil, errors.New("mobileconfig: not a signed profile")
+	}
+
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, nil, fmt.Errorf("mobileconfig: %v", err)
+	}
+	if !sd.EncapContentInfo.ContentType.Equal(oidData) || sd.EncapContentInfo.Content == nil {
+		return nil, nil, errors.New("mobileconfig: signed profile has no content")
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, nil, fmt.Errorf("mobileconfig: signed profile has %d signers", len(sd.SignerInfos))
+	}
+
+	sp := &SignedProfile{Profile: sd.EncapContentInfo.Content}
+	for _, raw := range sd.Certificates {
+		cert, err := x509.ParseCertificate(raw.FullBytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("mobileconfig: %v", err)
+		}
+		sp.Certificates = append(sp.Certificates, cert)
+	}
+
+	si := &sd.SignerInfos[0]
+	for _, cert := range sp.Certificates {
+		if bytes.Equal(cert.RawIssuer, si.SID.Issuer.FullBytes) && cert.SerialNumber.Cmp(si.SID.SerialNumber) == 0 {
+			sp.Signer = cert
+			break
+		}
+	}
+	return sp, si, nil
+}
+
+// verifySignerInfo checks that si holds a valid signature over sp.Profile by sp.Signer.
+func verifySignerInfo(si *signerInfo, sp *SignedProfile) error {
+	var hash crypto.Hash
+	switch alg := si.DigestAlgorithm.Algorithm; {
+	case alg.Equal(oidSHA256):
+		hash = crypto.SHA256
+	case alg.Equal(oidSHA384):
+		hash = crypto.SHA384
+	case alg.Equal(oidSHA512):
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("mobileconfig: unsupported digest algorithm %v", alg)
+	}
+
+	sigAlg, err := signatureAlgorithm(si.SignatureAlgorithm.Algorithm, hash)
+	if err != nil {
+		return err
+	}
+
+	h := hash.New()
+	h.Write(sp.Profile)
+	digest := h.Sum(nil)
+
+	if len(si.SignedAttributes.FullBytes) == 0 {
+		// Without signed attributes, the signature covers the content itself.
+		if err := sp.Signer.CheckSignature(sigAlg, sp.Profile, si.Signature); err != nil {
+			return fmt.Errorf("mobileconfig: %v", err)
+		}
+		return nil
+	}
+
+	var messageDigest []byte
+	for rest := si.SignedAttributes.Bytes; len(rest) > 0; {
+		var attr attribute
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &attr); err != nil {
+			return fmt.Errorf("mobileconfig: %v", err)
+		}
+		if attr.Type.Equal(oidMessageDigest) && len(attr.Values) == 1 {
+			if _, err := asn1.Unmarshal(attr.Values[0].FullBytes, &messageDigest); err != nil {
+				return fmt.Errorf("mobileconfig: %v", err)
+			}
+		}
+	}
+	if messageDigest == nil {
+		return errors.New("mobileconfig: signed attributes have no message digest")
+	}
+	if !bytes.Equal(messageDigest, digest) {
+		return errors.New("mobileconfig: profile does not match its signature")
+	}
+
+	attrs := append([]byte(nil), si.SignedAttributes.FullBytes...)
+	attrs[0] = 0x31 // SET OF, as they were signed
+	if err := sp.Signer.CheckSignature(sigAlg, attrs, si.Signature); err != nil {
+		return fmt.Errorf("mobileconfig: %v", err)
+	}
+	return nil
+}
+
+// signatureAlgorithm determines the x509.SignatureAlgorithm for a SignerInfo. Many signers,
+// including Apple's, identify RSA signatures by the key algorithm alone.
+func signatureAlgorithm(oid asn1.ObjectIdentifier, hash crypto.Hash) (x509.SignatureAlgorithm, error) {
+	switch {
+	case oid.Equal(oidRSAEncryption):
+		switch hash {
+		case crypto.SHA256:
+			return x509.SHA256WithRSA, nil
+		case crypto.SHA384:
+			return x509.SHA384WithRSA, nil
+		case crypto.SHA512:
+			return x509.SHA512WithRSA, nil
+		}
+	case oid.Equal(oidSHA256WithRSA):
+		return x509.SHA256WithRSA, nil
+	case oid.Equal(oidSHA384WithRSA):
+		return x509.SHA384WithRSA, nil
+	case oid.Equal(oidSHA512WithRSA):
+		return x509.SHA512WithRSA, nil
+	case oid.Equal(oidECDSAWithSHA256):
+		return x509.ECDSAWithSHA256, nil
+	case oid.Equal(oidECDSAWithSHA384):
+		return x509.ECDSAWithSHA384, nil
+	case oid.Equal(oidECDSAWithSHA512):
+		return x509.ECDSAWithSHA512, nil
+	}
+	return x509.UnknownSignatureAlgorithm, fmt.Errorf("mobileconfig: unsupported signature algorithm %v", oid)
+}
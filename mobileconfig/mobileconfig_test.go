@@ -0,0 +1,112 @@
+package mobileconfig
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"howett.net/plist"
+)
+
+func testCertificate(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(42),
+		Subject:               pkix.Name{CommonName: "Example Profile Signer"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestSignAndVerify(t *testing.T) {
+	cert, key := testCertificate(t)
+
+	profile, err := plist.MarshalIndent(map[string]interface{}{
+		"PayloadType":       "Configuration",
+		"PayloadVersion":    1,
+		"PayloadIdentifier": "com.example.profile",
+		"PayloadContent":    []interface{}{},
+	}, plist.XMLFormat, "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := Sign(profile, cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	sp, err := Verify(signed, roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(sp.Profile) != string(profile) || !sp.Signer.Equal(cert) {
+		t.Errorf("Unexpected signed profile %+v", sp)
+	}
+
+	var v map[string]interface{}
+	if _, err := plist.Unmarshal(sp.Profile, &v); err != nil || v["PayloadIdentifier"] != "com.example.profile" {
+		t.Errorf("Expected the profile to survive signing (%v)", err)
+	}
+
+	if _, err := Verify(signed, x509.NewCertPool()); err == nil {
+		t.Error("Expected an error verifying against unrelated roots")
+	}
+
+	other, _ := testCertificate(t)
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(other)
+	if _, err := Verify(signed, otherRoots); err == nil {
+		t.Error("Expected an error verifying against another certificate")
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	cert, key := testCertificate(t)
+	signed, err := Sign([]byte("<plist><dict/></plist>"), cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Verify(signed, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	i := bytes.LastIndex(signed, []byte("dict"))
+	tampered := append([]byte(nil), signed...)
+	tampered[i] = 'D'
+
+	if _, err := Verify(tampered, nil); err == nil {
+		t.Error("Expected an error verifying a tampered profile")
+	}
+	sp, err := Unwrap(tampered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(sp.Profile) != "<plist><Dict/></plist>" {
+		t.Errorf("Unexpected unwrapped profile %q", sp.Profile)
+	}
+}
@@ -48,3 +48,32 @@ func mustParseBool(str string) bool {
 	}
 	return i
 }
+
+// MustMarshal is like Marshal but panics if v cannot be encoded.
+// It simplifies the creation of fixtures in tests and at initialization time.
+func MustMarshal(v interface{}, format int) []byte {
+	data, err := Marshal(v, format)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// MustMarshalIndent is like MarshalIndent but panics if v cannot be encoded.
+func MustMarshalIndent(v interface{}, format int, indent string) []byte {
+	data, err := MarshalIndent(v, format, indent)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// MustUnmarshal is like Unmarshal but panics if data cannot be decoded into v.
+// It returns the format of the property list.
+func MustUnmarshal(data []byte, v interface{}) int {
+	format, err := Unmarshal(data, v)
+	if err != nil {
+		panic(err)
+	}
+	return format
+}
@@ -0,0 +1,57 @@
+package plist
+
+import (
+	"bytes"
+	"reflect"
+)
+
+// NestedPlist holds a property list that is embedded, in binary form, in a data value of
+// another property list. Many system property lists store documents this way.
+//
+// When decoding into an empty interface with nested binary expansion enabled (see
+// Decoder.SetNestedBinaryExpansion), embedded documents are returned as NestedPlist values.
+// A NestedPlist can also be decoded from any data value holding a binary property list,
+// whether or not expansion is enabled. When encoded, Value is re-embedded as a binary
+// property list.
+type NestedPlist struct {
+	Value interface{}
+}
+
+var nestedPlistType = reflect.TypeOf(NestedPlist{})
+
+// MarshalPlist implements Marshaler.
+func (n NestedPlist) MarshalPlist() (interface{}, error) {
+	return Marshal(n.Value, BinaryFormat)
+}
+
+// UnmarshalPlist implements Unmarshaler.
+func (n *NestedPlist) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	var data []byte
+	if err := unmarshal(&data); err != nil {
+		return err
+	}
+	n.Value = nil
+	_, err := Unmarshal(data, &n.Value)
+	return err
+}
+
+// parseNested returns the document embedded in data, or nil if data does not hold a valid
+// binary property list.
+func (p *Decoder) parseNested(data cfData) cfValue {
+	if !bytes.HasPrefix(data, []byte("bplist")) {
+		return nil
+	}
+	bp := newBplistParser(bytes.NewReader(data))
+	bp.workers = p.workers
+	pval, err := bp.parseDocument()
+	if err != nil {
+		return nil
+	}
+	return pval
+}
+
+// isByteContainer reports whether typ is a slice or array of bytes, which data values are
+// always stored in as-is.
+func isByteContainer(typ reflect.Type) bool {
+	return (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) && typ.Elem().Kind() == reflect.Uint8
+}
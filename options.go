@@ -0,0 +1,158 @@
+package plist
+
+import (
+	"os"
+	"reflect"
+	"time"
+)
+
+// A DecoderOption configures a Decoder. Options are applied, in order, by NewDecoder. Most are
+// equivalent to calling the Decoder method named in their documentation; WithLax and
+// WithRequiredFormat configure settings that are only available as options.
+type DecoderOption func(*Decoder)
+
+// WithLax makes the Decoder accept strings in place of numbers, booleans and dates in every
+// format, as it always does for OpenStep property lists (which can only store strings.)
+func WithLax() DecoderOption {
+	return func(p *Decoder) { p.lax = true }
+}
+
+// WithRequiredFormat makes Decode fail unless the document is in the given format.
+func WithRequiredFormat(format int) DecoderOption {
+	return func(p *Decoder) { p.requiredFormat = format }
+}
+
+// WithTypeDecoder is equivalent to calling RegisterTypeDecoder.
+func WithTypeDecoder(typ reflect.Type, fn TypeDecoderFunc) DecoderOption {
+	return func(p *Decoder) { p.RegisterTypeDecoder(typ, fn) }
+}
+
+// WithBinaryParallelism is equivalent to calling SetBinaryParallelism.
+func WithBinaryParallelism(n int) DecoderOption {
+	return func(p *Decoder) { p.SetBinaryParallelism(n) }
+}
+
+// WithRealToIntegerConversion is equivalent to calling SetRealToIntegerConversion(true).
+func WithRealToIntegerConversion() DecoderOption {
+	return func(p *Decoder) { p.SetRealToIntegerConversion(true) }
+}
+
+// WithNumericDateEpoch is equivalent to calling SetNumericDateEpoch.
+func WithNumericDateEpoch(epoch time.Time) DecoderOption {
+	return func(p *Decoder) { p.SetNumericDateEpoch(epoch) }
+}
+
+// WithXMLStringTrimming is equivalent to calling SetXMLStringTrimming(true).
+func WithXMLStringTrimming() DecoderOption {
+	return func(p *Decoder) { p.SetXMLStringTrimming(true) }
+}
+
+// WithXMLLimits is equivalent to calling SetXMLLimits.
+func WithXMLLimits(limits XMLLimits) DecoderOption {
+	return func(p *Decoder) { p.SetXMLLimits(limits) }
+}
+
+// WithXMLVersionRequired is equivalent to calling SetXMLVersionRequired(true).
+func WithXMLVersionRequired() DecoderOption {
+	return func(p *Decoder) { p.SetXMLVersionRequired(true) }
+}
+
+// WithKeepEmptyArrayStrings is equivalent to calling SetKeepEmptyArrayStrings(true).
+func WithKeepEmptyArrayStrings() DecoderOption {
+	return func(p *Decoder) { p.SetKeepEmptyArrayStrings(true) }
+}
+
+// WithRejectDuplicateKeys is equivalent to calling SetRejectDuplicateKeys(true).
+func WithRejectDuplicateKeys() DecoderOption {
+	return func(p *Decoder) { p.SetRejectDuplicateKeys(true) }
+}
+
+// WithTextEncoding is equivalent to calling SetTextEncoding.
+func WithTextEncoding(enc TextEncoding) DecoderOption {
+	return func(p *Decoder) { p.SetTextEncoding(enc) }
+}
+
+// WithCollectStats is equivalent to calling SetCollectStats(true).
+func WithCollectStats() DecoderOption {
+	return func(p *Decoder) { p.SetCollectStats(true) }
+}
+
+// WithEmptyDocumentError is equivalent to calling SetEmptyDocumentError(true).
+func WithEmptyDocumentError() DecoderOption {
+	return func(p *Decoder) { p.SetEmptyDocumentError(true) }
+}
+
+// WithDecodeHooks is equivalent to calling SetHooks.
+func WithDecodeHooks(pre PreHook, post PostHook) DecoderOption {
+	return func(p *Decoder) { p.SetHooks(pre, post) }
+}
+
+// WithNestedBinaryExpansion is equivalent to calling SetNestedBinaryExpansion(true).
+func WithNestedBinaryExpansion() DecoderOption {
+	return func(p *Decoder) { p.SetNestedBinaryExpansion(true) }
+}
+
+// WithProgressFunc is equivalent to calling SetProgressFunc.
+func WithProgressFunc(fn ProgressFunc) DecoderOption {
+	return func(p *Decoder) { p.SetProgressFunc(fn) }
+}
+
+// WithOrderedDictionaries is equivalent to calling SetOrderedDictionaries(true).
+func WithOrderedDictionaries() DecoderOption {
+	return func(p *Decoder) { p.SetOrderedDictionaries(true) }
+}
+
+// WithBase64Data is equivalent to calling SetBase64Data(true).
+func WithBase64Data() DecoderOption {
+	return func(p *Decoder) { p.SetBase64Data(true) }
+}
+
+// WithDateRepresentation is equivalent to calling SetDateRepresentation.
+func WithDateRepresentation(rep DateRepresentation) DecoderOption {
+	return func(p *Decoder) { p.SetDateRepresentation(rep) }
+}
+
+// WithDurationFormat is equivalent to calling SetDurationFormat.
+func WithDurationFormat(format DurationFormat) DecoderOption {
+	return func(p *Decoder) { p.SetDurationFormat(format) }
+}
+
+// WithVariableExpansion is equivalent to calling SetVariableExpansion(MapVariables(vars)).
+func WithVariableExpansion(vars map[string]string) DecoderOption {
+	return func(p *Decoder) { p.SetVariableExpansion(MapVariables(vars)) }
+}
+
+// WithEnvironmentExpansion is equivalent to calling SetVariableExpansion(os.LookupEnv).
+func WithEnvironmentExpansion() DecoderOption {
+	return func(p *Decoder) { p.SetVariableExpansion(os.LookupEnv) }
+}
+
+// WithBuildSettings is equivalent to calling SetBuildSettings.
+func WithBuildSettings(settings map[string]string) DecoderOption {
+	return func(p *Decoder) { p.SetBuildSettings(settings) }
+}
+
+// WithSkipToBinaryHeader is equivalent to calling SetSkipToBinaryHeader(true).
+func WithSkipToBinaryHeader() DecoderOption {
+	return func(p *Decoder) { p.SetSkipToBinaryHeader(true) }
+}
+
+// WithGzipDecompression is equivalent to calling SetGzipDecompression(limit).
+func WithGzipDecompression(limit int64) DecoderOption {
+	return func(p *Decoder) { p.SetGzipDecompression(limit) }
+}
+
+// WithStrict is equivalent to calling SetStrict(true).
+func WithStrict() DecoderOption {
+	return func(p *Decoder) { p.SetStrict(true) }
+}
+
+// WithUnsetFieldReporting is equivalent to calling SetReportUnsetFields(true).
+func WithUnsetFieldReporting() DecoderOption {
+	return func(p *Decoder) { p.SetReportUnsetFields(true) }
+}
+
+// WithUnusedKeyReporting is equivalent to calling SetReportUnusedKeys(true).
+func WithUnusedKeyReporting() DecoderOption {
+	return func(p *Decoder) { p.SetReportUnusedKeys(true) }
+}
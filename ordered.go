@@ -0,0 +1,196 @@
+package plist
+
+import (
+	"reflect"
+	"time"
+)
+
+// An OrderedDict is a dictionary that remembers the order of its keys. It can be used anywhere
+// a map would be to decode or encode a property list dictionary, when that order matters: keys
+// are decoded in the order they appear in the document and encoded in the order they were set,
+// rather than sorted.
+//
+// Values are stored as they would be decoded into an empty interface, except that dictionaries
+// nested inside an OrderedDict are themselves decoded as *OrderedDict. Values set by the caller
+// may be of any type that can be encoded. The zero value is an empty OrderedDict ready to use.
+type OrderedDict struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// orderedDictContents is an OrderedDict without its methods, which the Encoder and Decoder
+// recognize in order to write and read its keys in order.
+type orderedDictContents OrderedDict
+
+var orderedDictContentsType = reflect.TypeOf(orderedDictContents{})
+
+// NewOrderedDict returns an empty OrderedDict.
+func NewOrderedDict() *OrderedDict {
+	return &OrderedDict{}
+}
+
+// Len returns the number of keys in d.
+func (d *OrderedDict) Len() int {
+	return len(d.keys)
+}
+
+// Keys returns the keys of d in order.
+func (d *OrderedDict) Keys() []string {
+	return append([]string(nil), d.keys...)
+}
+
+// Get returns the value stored under key, and whether there is one.
+func (d *OrderedDict) Get(key string) (interface{}, bool) {
+	v, ok := d.values[key]
+	return v, ok
+}
+
+// Set stores v under key. A new key is added after all the others; an existing key keeps its
+// position.
+func (d *OrderedDict) Set(key string, v interface{}) {
+	if d.values == nil {
+		d.values = make(map[string]interface{})
+	}
+	if _, ok := d.values[key]; !ok {
+		d.keys = append(d.keys, key)
+	}
+	d.values[key] = v
+}
+
+// Delete removes key and its value from d.
+func (d *OrderedDict) Delete(key string) {
+	if _, ok := d.values[key]; !ok {
+		return
+	}
+	delete(d.values, key)
+	for i, k := range d.keys {
+		if k == key {
+			d.keys = append(d.keys[:i], d.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// String returns the string stored under key. ok is false if there is no such key or its value
+// is not a string.
+func (d *OrderedDict) String(key string) (s string, ok bool) {
+	s, ok = d.values[key].(string)
+	return
+}
+
+// Int returns the integer stored under key. ok is false if there is no such key, its value is
+// not an integer, or it does not fit in an int64.
+func (d *OrderedDict) Int(key string) (int64, bool) {
+	v := reflect.ValueOf(d.values[key])
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := v.Uint(); u <= 1<<63-1 {
+			return int64(u), true
+		}
+	}
+	return 0, false
+}
+
+// Uint returns the unsigned integer stored under key. ok is false if there is no such key, its
+// value is not an integer, or it is negative.
+func (d *OrderedDict) Uint(key string) (uint64, bool) {
+	v := reflect.ValueOf(d.values[key])
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i := v.Int(); i >= 0 {
+			return uint64(i), true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), true
+	}
+	return 0, false
+}
+
+// Float returns the real number stored under key. ok is false if there is no such key or its
+// value is not a real number.
+func (d *OrderedDict) Float(key string) (float64, bool) {
+	v := reflect.ValueOf(d.values[key])
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// Bool returns the boolean stored under key. ok is false if there is no such key or its value
+// is not a boolean.
+func (d *OrderedDict) Bool(key string) (b bool, ok bool) {
+	b, ok = d.values[key].(bool)
+	return
+}
+
+// Data returns the data stored under key. ok is false if there is no such key or its value is
+// not a byte slice.
+func (d *OrderedDict) Data(key string) (b []byte, ok bool) {
+	b, ok = d.values[key].([]byte)
+	return
+}
+
+// Date returns the date stored under key. ok is false if there is no such key or its value is
+// not a time.Time.
+func (d *OrderedDict) Date(key string) (t time.Time, ok bool) {
+	t, ok = d.values[key].(time.Time)
+	return
+}
+
+// Dict returns the dictionary stored under key. ok is false if there is no such key or its value
+// is not an *OrderedDict.
+func (d *OrderedDict) Dict(key string) (dict *OrderedDict, ok bool) {
+	dict, ok = d.values[key].(*OrderedDict)
+	return
+}
+
+// Array returns the array stored under key. ok is false if there is no such key or its value is
+// not a []interface{}.
+func (d *OrderedDict) Array(key string) (a []interface{}, ok bool) {
+	a, ok = d.values[key].([]interface{})
+	return
+}
+
+// MarshalPlist implements Marshaler.
+func (d OrderedDict) MarshalPlist() (interface{}, error) {
+	return orderedDictContents(d), nil
+}
+
+// UnmarshalPlist implements Unmarshaler.
+func (d *OrderedDict) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	return unmarshal((*orderedDictContents)(d))
+}
+
+// marshalOrderedDict marshals the contents of an OrderedDict to a dictionary that keeps its
+// key order.
+func (p *Encoder) marshalOrderedDict(d orderedDictContents) cfValue {
+	dict := &cfDictionary{
+		keys:    make([]string, 0, len(d.keys)),
+		values:  make([]cfValue, 0, len(d.keys)),
+		ordered: true,
+	}
+	for _, k := range d.keys {
+		if subpval := p.marshalAt(k, reflect.ValueOf(d.values[k])); subpval != nil {
+			dict.keys = append(dict.keys, k)
+			dict.values = append(dict.values, subpval)
+		}
+	}
+	return dict
+}
+
+// orderedDictInterface returns the contents of dict, and of every dictionary inside it, as
+// OrderedDicts.
+func (p *Decoder) orderedDictInterface(dict *cfDictionary) *OrderedDict {
+	defer func(ordered bool) { p.orderedDicts = ordered }(p.orderedDicts)
+	p.orderedDicts = true
+
+	d := &OrderedDict{}
+	for i, k := range dict.keys {
+		p.progress.object()
+		d.Set(k, p.valueInterface(dict.values[i]))
+	}
+	return d
+}
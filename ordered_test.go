@@ -0,0 +1,88 @@
+package plist
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOrderedDict(t *testing.T) {
+	doc := `<plist version="1.0"><dict>
+	<key>zebra</key><integer>-1</integer>
+	<key>apple</key><dict><key>y</key><true/><key>x</key><string>s</string></dict>
+	<key>mango</key><array><dict><key>b</key><real>1.5</real><key>a</key><data>AQI=</data></dict></array>
+</dict></plist>`
+
+	var d OrderedDict
+	if _, err := Unmarshal([]byte(doc), &d); err != nil {
+		t.Fatal(err)
+	}
+	if keys := d.Keys(); !reflect.DeepEqual(keys, []string{"zebra", "apple", "mango"}) {
+		t.Errorf("Expected keys in document order, received %v", keys)
+	}
+	if i, ok := d.Int("zebra"); !ok || i != -1 {
+		t.Errorf("Expected zebra = -1, received %v %v", i, ok)
+	}
+	if _, ok := d.String("zebra"); ok {
+		t.Error("Expected String to fail for an integer")
+	}
+	apple, ok := d.Dict("apple")
+	if !ok || !reflect.DeepEqual(apple.Keys(), []string{"y", "x"}) {
+		t.Fatalf("Expected a nested OrderedDict with keys [y x], received %v", d.values["apple"])
+	}
+	if b, ok := apple.Bool("y"); !ok || !b {
+		t.Error("Expected apple.y = true")
+	}
+	mango, _ := d.Array("mango")
+	if nested, ok := mango[0].(*OrderedDict); !ok || !reflect.DeepEqual(nested.Keys(), []string{"b", "a"}) {
+		t.Errorf("Expected an OrderedDict inside the array, received %#v", mango[0])
+	}
+
+	d.Set("banana", uint8(3))
+	d.Set("zebra", "striped")
+	d.Delete("apple")
+	out, err := MarshalIndent(struct{ Fruit OrderedDict }{d}, XMLFormat, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "<dict><key>Fruit</key><dict><key>zebra</key><string>striped</string><key>mango</key><array><dict><key>b</key><real>1.5</real><key>a</key><data>AQI=</data></dict></array><key>banana</key><integer>3</integer></dict></dict>"
+	if !strings.Contains(string(out), expected) {
+		t.Errorf("Expected keys to be written in order:\n%s", out)
+	}
+
+	for _, format := range []int{BinaryFormat, GNUStepFormat} {
+		b, err := Marshal(&d, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back OrderedDict
+		if _, err := Unmarshal(b, &back); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(back.Keys(), []string{"zebra", "mango", "banana"}) {
+			t.Errorf("%s: Expected keys to round-trip in order, received %v", FormatNames[format], back.Keys())
+		}
+	}
+}
+
+func TestOrderedDictionariesOption(t *testing.T) {
+	var v interface{}
+	d := NewDecoder(strings.NewReader(`{ b = 1; a = { d = 2; c = 3; }; }`), WithOrderedDictionaries())
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	dict, ok := v.(*OrderedDict)
+	if !ok || !reflect.DeepEqual(dict.Keys(), []string{"b", "a"}) {
+		t.Fatalf("Expected an OrderedDict with keys [b a], received %#v", v)
+	}
+	if nested, ok := dict.Dict("a"); !ok || !reflect.DeepEqual(nested.Keys(), []string{"d", "c"}) {
+		t.Errorf("Expected a nested OrderedDict with keys [d c], received %#v", dict.values["a"])
+	}
+
+	if _, err := Unmarshal([]byte(`{ b = 1; }`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.(map[string]interface{}); !ok {
+		t.Errorf("Expected a map without the option, received %T", v)
+	}
+}
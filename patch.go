@@ -0,0 +1,244 @@
+package plist
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// A PatchOp is the kind of change made by a PatchOperation.
+type PatchOp string
+
+const (
+	// PatchAdd adds a value under a new dictionary key, or inserts it into an array at the
+	// given index, moving the elements after it along. The index may be the length of the
+	// array, to append to it. Adding a key that is already present replaces its value.
+	PatchAdd PatchOp = "add"
+	// PatchRemove removes a value from its dictionary or array.
+	PatchRemove PatchOp = "remove"
+	// PatchReplace replaces an existing value.
+	PatchReplace PatchOp = "replace"
+)
+
+// A PatchOperation is a single change to a property list.
+type PatchOperation struct {
+	Op PatchOp `plist:"op"`
+
+	// Path holds the dictionary keys and array indices (in decimal) that lead from the root to
+	// the value that is changed, as for a TransformFunc. The root itself has an empty path.
+	Path []string `plist:"path"`
+
+	// Value is the value added or stored by PatchAdd and PatchReplace, which is marshaled as
+	// if by Marshal. CreatePatch sets it to the form Unmarshal uses when decoding into an empty
+	// interface.
+	Value interface{} `plist:"value,omitempty"`
+}
+
+// A Patch is a list of changes to a property list, applied in order. Like a JSON Patch, it can
+// be computed from two versions of a document and then used to turn the first into the second,
+// so that only the changes need to be distributed. A Patch can itself be encoded and decoded
+// as a property list.
+type Patch []PatchOperation
+
+// CreatePatch returns a Patch that turns the property list in from into the one in to. The two
+// documents may be in any format. Dictionaries and arrays are compared element by element, so
+// that the patch changes only the values that differ.
+func CreatePatch(from, to []byte) (patch Patch, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+	}()
+
+	var pvals [2]cfValue
+	var d *Decoder
+	for i, doc := range [][]byte{from, to} {
+		d = NewDecoder(bytes.NewReader(doc))
+		if pvals[i], err = d.parseDocument(); err != nil {
+			return nil, err
+		}
+	}
+
+	pd := &patchDiffer{decoder: d, patch: Patch{}}
+	pd.diff(pvals[0], pvals[1])
+	return pd.patch, nil
+}
+
+type patchDiffer struct {
+	decoder *Decoder
+	path    []string
+	patch   Patch
+}
+
+func (pd *patchDiffer) add(op PatchOp, pval cfValue) {
+	operation := PatchOperation{Op: op, Path: append([]string{}, pd.path...)}
+	if pval != nil {
+		pd.decoder.unmarshal(pval, reflect.ValueOf(&operation.Value).Elem())
+	}
+	pd.patch = append(pd.patch, operation)
+}
+
+func (pd *patchDiffer) diffAt(elem string, from, to cfValue) {
+	pd.path = append(pd.path, elem)
+	pd.diff(from, to)
+	pd.path = pd.path[:len(pd.path)-1]
+}
+
+func (pd *patchDiffer) diff(from, to cfValue) {
+	if cfValueEqual(from, to) {
+		return
+	}
+
+	switch from := from.(type) {
+	case *cfDictionary:
+		if to, ok := to.(*cfDictionary); ok {
+			fromvals, tovals := dictionaryIndex(from), dictionaryIndex(to)
+			for i, k := range from.keys {
+				if subval, ok := tovals[k]; ok {
+					pd.diffAt(k, from.values[i], subval)
+				} else {
+					pd.path = append(pd.path, k)
+					pd.add(PatchRemove, nil)
+					pd.path = pd.path[:len(pd.path)-1]
+				}
+			}
+			for i, k := range to.keys {
+				if _, ok := fromvals[k]; !ok {
+					pd.path = append(pd.path, k)
+					pd.add(PatchAdd, to.values[i])
+					pd.path = pd.path[:len(pd.path)-1]
+				}
+			}
+			return
+		}
+	case *cfArray:
+		if to, ok := to.(*cfArray); ok {
+			n := len(from.values)
+			if len(to.values) < n {
+				n = len(to.values)
+			}
+			for i := 0; i < n; i++ {
+				pd.diffAt(strconv.Itoa(i), from.values[i], to.values[i])
+			}
+			// Remove surplus elements from the end, so that the indices of those before them
+			// stay the same.
+			for i := len(from.values) - 1; i >= n; i-- {
+				pd.path = append(pd.path, strconv.Itoa(i))
+				pd.add(PatchRemove, nil)
+				pd.path = pd.path[:len(pd.path)-1]
+			}
+			for i := n; i < len(to.values); i++ {
+				pd.path = append(pd.path, strconv.Itoa(i))
+				pd.add(PatchAdd, to.values[i])
+				pd.path = pd.path[:len(pd.path)-1]
+			}
+			return
+		}
+	}
+
+	pd.add(PatchReplace, to)
+}
+
+// ApplyPatch parses the property list in src, which may be in any format, applies patch to it
+// and writes the result to w in the format of the original. It fails, writing nothing, if any
+// operation refers to a value that does not exist, adds to something that is neither a
+// dictionary nor an array, or removes the root value.
+func ApplyPatch(w io.Writer, src []byte, patch Patch) error {
+	return reencode(w, src, sameFormat, func(d *Decoder, enc *Encoder, pval cfValue) (cfValue, error) {
+		for _, op := range patch {
+			var newval cfValue
+			switch op.Op {
+			case PatchAdd, PatchReplace:
+				if newval = enc.marshal(reflect.ValueOf(op.Value)); newval == nil {
+					return nil, fmt.Errorf("plist: patch operation %s at %s has no value", op.Op, patchPath(op.Path))
+				}
+			case PatchRemove:
+				if len(op.Path) == 0 {
+					return nil, errors.New("plist: cannot remove the root value")
+				}
+			default:
+				return nil, fmt.Errorf("plist: unknown patch operation %q", op.Op)
+			}
+			var err error
+			if pval, err = applyPatchOperation(pval, op, op.Path, newval); err != nil {
+				return nil, err
+			}
+		}
+		return pval, nil
+	})
+}
+
+// applyPatchOperation returns pval with op applied to the value at path, which is relative to
+// pval. Containers along the path are copied rather than changed in place, as a binary property
+// list may refer to one object from several places.
+func applyPatchOperation(pval cfValue, op PatchOperation, path []string, newval cfValue) (cfValue, error) {
+	if len(path) == 0 {
+		return newval, nil
+	}
+
+	elem, last := path[0], len(path) == 1
+	switch pval := pval.(type) {
+	case *cfDictionary:
+		dict := &cfDictionary{
+			keys:    append([]string(nil), pval.keys...),
+			values:  append([]cfValue(nil), pval.values...),
+			ordered: pval.ordered,
+		}
+		i := 0
+		for i < len(dict.keys) && dict.keys[i] != elem {
+			i++
+		}
+		if i == len(dict.keys) {
+			if !last || op.Op != PatchAdd {
+				break
+			}
+			dict.keys = append(dict.keys, elem)
+			dict.values = append(dict.values, newval)
+			return dict, nil
+		}
+
+		if last && op.Op == PatchRemove {
+			dict.keys = append(dict.keys[:i], dict.keys[i+1:]...)
+			dict.values = append(dict.values[:i], dict.values[i+1:]...)
+			return dict, nil
+		}
+		subval, err := applyPatchOperation(dict.values[i], op, path[1:], newval)
+		if err != nil {
+			return nil, err
+		}
+		dict.values[i] = subval
+		return dict, nil
+	case *cfArray:
+		i, err := strconv.Atoi(elem)
+		if err != nil || i < 0 || i > len(pval.values) || (i == len(pval.values) && !(last && op.Op == PatchAdd)) {
+			break
+		}
+		values := append([]cfValue(nil), pval.values...)
+		switch {
+		case last && op.Op == PatchAdd:
+			values = append(values[:i], append([]cfValue{newval}, values[i:]...)...)
+		case last && op.Op == PatchRemove:
+			values = append(values[:i], values[i+1:]...)
+		default:
+			if values[i], err = applyPatchOperation(values[i], op, path[1:], newval); err != nil {
+				return nil, err
+			}
+		}
+		return &cfArray{values}, nil
+	default:
+		return nil, fmt.Errorf("plist: cannot apply patch operation %s at %s: %s is not a dictionary or an array", op.Op, patchPath(op.Path), pval.typeName())
+	}
+	return nil, fmt.Errorf("plist: cannot apply patch operation %s at %s: there is no element %q", op.Op, patchPath(op.Path), elem)
+}
+
+func patchPath(path []string) string {
+	return "/" + strings.Join(path, "/")
+}
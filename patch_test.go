@@ -0,0 +1,89 @@
+package plist
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCreateAndApplyPatch(t *testing.T) {
+	from := []byte(`{ name = app; tags = (a, b, c); build = { debug = 0; opt = 2; }; old = x; }`)
+	to := []byte(`{ name = application; tags = (a, d); build = { debug = 0; opt = 3; flags = (x); }; list = (1); }`)
+
+	patch, err := CreatePatch(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Patch{
+		{Op: PatchReplace, Path: []string{"build", "opt"}, Value: "3"},
+		{Op: PatchAdd, Path: []string{"build", "flags"}, Value: []interface{}{"x"}},
+		{Op: PatchReplace, Path: []string{"name"}, Value: "application"},
+		{Op: PatchRemove, Path: []string{"old"}},
+		{Op: PatchReplace, Path: []string{"tags", "1"}, Value: "d"},
+		{Op: PatchRemove, Path: []string{"tags", "2"}},
+		{Op: PatchAdd, Path: []string{"list"}, Value: []interface{}{"1"}},
+	}
+	if !reflect.DeepEqual(patch, expected) {
+		t.Fatalf("Expected patch %#v, received %#v", expected, patch)
+	}
+
+	// The patch survives a round trip through a property list of its own.
+	encoded, err := Marshal(patch, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Patch
+	if _, err := Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := ApplyPatch(&out, from, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if equal, err := Equal(out.Bytes(), to); err != nil || !equal {
+		t.Errorf("Expected the patched document to equal %s, received %s", to, out.String())
+	}
+
+	if patch, err := CreatePatch(from, from); err != nil || len(patch) != 0 {
+		t.Errorf("Expected an empty patch between equal documents, received %#v (%v)", patch, err)
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	src := []byte(`{ a = (1, 2); b = { c = 1; }; }`)
+
+	tests := []struct {
+		patch    Patch
+		expected string
+		err      string
+	}{
+		{Patch{{Op: PatchAdd, Path: []string{"a", "0"}, Value: "0"}, {Op: PatchAdd, Path: []string{"a", "3"}, Value: "3"}}, `{a=(0,1,2,3,);b={c=1;};}`, ""},
+		{Patch{{Op: PatchRemove, Path: []string{"b", "c"}}, {Op: PatchReplace, Path: []string{"a", "1"}, Value: []int{4}}}, `{a=(1,(4,),);b={};}`, ""},
+		{Patch{{Op: PatchAdd, Path: []string{"b", "c"}, Value: "2"}}, `{a=(1,2,);b={c=2;};}`, ""},
+		{Patch{{Op: PatchReplace, Path: nil, Value: "x"}}, `x`, ""},
+		{Patch{{Op: PatchReplace, Path: []string{"d"}, Value: "x"}}, "", `there is no element "d"`},
+		{Patch{{Op: PatchAdd, Path: []string{"a", "3"}, Value: "x"}}, "", `there is no element "3"`},
+		{Patch{{Op: PatchAdd, Path: []string{"b", "c", "d"}, Value: "x"}}, "", "string is not a dictionary or an array"},
+		{Patch{{Op: PatchAdd, Path: []string{"d"}}}, "", "has no value"},
+		{Patch{{Op: PatchRemove}}, "", "cannot remove the root value"},
+		{Patch{{Op: "move", Path: []string{"a"}}}, "", "unknown patch operation"},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		err := ApplyPatch(&out, src, test.patch)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%v: expected an error containing %q, received %v", test.patch, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", test.patch, err)
+		} else if out.String() != test.expected {
+			t.Errorf("%v: expected %s, received %s", test.patch, test.expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package plist
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A PathMatcher reports whether it matches the path of a value in a document. As for a
+// TransformFunc, path holds the dictionary keys and array indices (in decimal) that lead from the
+// root to the value.
+type PathMatcher func(path []string) bool
+
+// MatchGlobs returns a PathMatcher that matches any path matched by one of patterns, using the
+// syntax of MatchPath.
+func MatchGlobs(patterns ...string) PathMatcher {
+	return func(path []string) bool {
+		for _, pattern := range patterns {
+			if MatchPath(pattern, path) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchRegexps returns a PathMatcher that matches any path matched by one of res when its
+// elements are joined by slashes (for example, "Accounts/0/Serial".) Anchor the expressions to
+// match whole paths.
+func MatchRegexps(res ...*regexp.Regexp) PathMatcher {
+	return func(path []string) bool {
+		joined := strings.Join(path, "/")
+		for _, re := range res {
+			if re.MatchString(joined) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SelectPaths returns a copy of doc, a document in the form Unmarshal uses when decoding into an
+// empty interface, containing only the values matched by match, along with everything inside
+// them and the dictionaries and arrays that lead to them. Dictionaries and arrays that contain
+// no matched value are left out; the root is always kept, even if it is then empty.
+//
+// Dictionaries and arrays (including OrderedDicts) are copied, so that changing the result does
+// not change doc; other values, such as []byte, are shared with it.
+func SelectPaths(doc interface{}, match PathMatcher) interface{} {
+	v, _ := filterPaths(doc, nil, match, true)
+	return v
+}
+
+// ExcludePaths returns a copy of doc, a document in the form Unmarshal uses when decoding into an
+// empty interface, without the values matched by match or anything inside them. The root cannot
+// be excluded. Values are copied as by SelectPaths.
+//
+// For example, the keys that only make sense on one machine can be removed from a document
+// before it is checked in:
+//
+//	clean := plist.ExcludePaths(doc, plist.MatchGlobs("**/LastOpened*", "Window Frames"))
+func ExcludePaths(doc interface{}, match PathMatcher) interface{} {
+	v, _ := filterPaths(doc, nil, match, false)
+	return v
+}
+
+// matchNothing is used to copy the whole of a subtree selected by SelectPaths.
+func matchNothing([]string) bool { return false }
+
+// filterPaths returns the filtered copy of v, and whether to keep it in its container. When
+// include is set, values are kept only if they (or something inside them) are matched; when it
+// is not, they are kept unless they are matched.
+func filterPaths(v interface{}, path []string, match PathMatcher, include bool) (interface{}, bool) {
+	if len(path) > 0 && match(path) {
+		if !include {
+			return nil, false
+		}
+		v, _ = filterPaths(v, path, matchNothing, false)
+		return v, true
+	}
+
+	// path is extended below for each child; make sure that doesn't overwrite a caller's path.
+	path = path[:len(path):len(path)]
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, subv := range v {
+			if subv, keep := filterPaths(subv, append(path, k), match, include); keep {
+				m[k] = subv
+			}
+		}
+		return m, !include || len(m) > 0
+	case *OrderedDict:
+		d := &OrderedDict{}
+		for _, k := range v.keys {
+			if subv, keep := filterPaths(v.values[k], append(path, k), match, include); keep {
+				d.Set(k, subv)
+			}
+		}
+		return d, !include || d.Len() > 0
+	case []interface{}:
+		a := make([]interface{}, 0, len(v))
+		for i, subv := range v {
+			if subv, keep := filterPaths(subv, append(path, strconv.Itoa(i)), match, include); keep {
+				a = append(a, subv)
+			}
+		}
+		return a, !include || len(a) > 0
+	}
+	return v, !include
+}
@@ -0,0 +1,78 @@
+package plist
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func pathFilterDocument() map[string]interface{} {
+	return map[string]interface{}{
+		"Name": "Finder",
+		"Window Frames": map[string]interface{}{
+			"Main": "0 0 640 480",
+		},
+		"Recent": []interface{}{
+			map[string]interface{}{"Path": "/tmp", "LastOpened": "yesterday"},
+			map[string]interface{}{"Path": "/var", "LastOpenedBy": "root"},
+		},
+	}
+}
+
+func TestSelectPaths(t *testing.T) {
+	doc := pathFilterDocument()
+	selected := SelectPaths(doc, MatchGlobs("Name", "Recent/*/Path"))
+	expected := map[string]interface{}{
+		"Name": "Finder",
+		"Recent": []interface{}{
+			map[string]interface{}{"Path": "/tmp"},
+			map[string]interface{}{"Path": "/var"},
+		},
+	}
+	if !reflect.DeepEqual(selected, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, selected)
+	}
+
+	if selected := SelectPaths(doc, MatchGlobs("Nothing")); !reflect.DeepEqual(selected, map[string]interface{}{}) {
+		t.Errorf("Expected an empty document, got %#v", selected)
+	}
+
+	if !reflect.DeepEqual(doc, pathFilterDocument()) {
+		t.Error("SelectPaths modified the original document")
+	}
+}
+
+func TestExcludePaths(t *testing.T) {
+	doc := pathFilterDocument()
+	excluded := ExcludePaths(doc, MatchRegexps(regexp.MustCompile(`/LastOpened`), regexp.MustCompile(`^Window Frames$`)))
+	expected := map[string]interface{}{
+		"Name": "Finder",
+		"Recent": []interface{}{
+			map[string]interface{}{"Path": "/tmp"},
+			map[string]interface{}{"Path": "/var"},
+		},
+	}
+	if !reflect.DeepEqual(excluded, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, excluded)
+	}
+
+	excluded.(map[string]interface{})["Recent"].([]interface{})[0].(map[string]interface{})["Path"] = "/changed"
+	if !reflect.DeepEqual(doc, pathFilterDocument()) {
+		t.Error("Changing the result of ExcludePaths modified the original document")
+	}
+}
+
+func TestExcludePathsOrdered(t *testing.T) {
+	doc := NewOrderedDict()
+	doc.Set("b", 1)
+	doc.Set("secret", "hunter2")
+	doc.Set("a", 2)
+
+	excluded := ExcludePaths(doc, MatchGlobs("secret")).(*OrderedDict)
+	if keys := excluded.Keys(); !reflect.DeepEqual(keys, []string{"b", "a"}) {
+		t.Errorf("Expected keys [b a], got %v", keys)
+	}
+	if doc.Len() != 3 {
+		t.Error("ExcludePaths modified the original document")
+	}
+}
@@ -1,7 +1,10 @@
 package plist
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"strings"
 )
 
 // Property list format constants
@@ -16,6 +19,10 @@ const (
 	BinaryFormat   = 2
 	OpenStepFormat = 3
 	GNUStepFormat  = 4
+
+	// PrettyFormat is the human-readable format of `plutil -p`. It is intended for logging and
+	// debugging: it elides long data values and cannot be decoded.
+	PrettyFormat = 5
 )
 
 var FormatNames = map[int]string{
@@ -24,8 +31,13 @@ var FormatNames = map[int]string{
 	BinaryFormat:   "Binary",
 	OpenStepFormat: "OpenStep",
 	GNUStepFormat:  "GNUStep",
+	PrettyFormat:   "Pretty",
 }
 
+// ErrEmptyDocument is returned by a Decoder configured with SetEmptyDocumentError when its
+// input holds no property list.
+var ErrEmptyDocument = errors.New("plist: empty document")
+
 type unknownTypeError struct {
 	typ reflect.Type
 }
@@ -60,10 +72,54 @@ func (e plistParseError) Error() string {
 	return s
 }
 
+type limitExceededError struct {
+	what  string
+	limit int64
+}
+
+func (e *limitExceededError) Error() string {
+	return fmt.Sprintf("plist: exceeded limit of %d %s", e.limit, e.what)
+}
+
+// A PathError records where in a document encoding or decoding failed. Errors that occur
+// while marshaling or unmarshaling a value inside a dictionary or array are returned as
+// PathErrors.
+type PathError struct {
+	// Path holds the dictionary keys and array indices (in decimal) that lead from the root to
+	// the value that could not be encoded or decoded. Keys are those of the property list, not
+	// the names of the struct fields they correspond to.
+	Path []string
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("%v (at %s)", e.Err, strings.Join(e.Path, "/"))
+}
+
+// Unwrap returns the underlying error.
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// withPath returns err as a PathError recording path, unless path is empty or err already
+// records one.
+func withPath(err error, path []string) error {
+	if _, ok := err.(*PathError); ok || len(path) == 0 {
+		return err
+	}
+	return &PathError{Path: append([]string(nil), path...), Err: err}
+}
+
 // A UID represents a unique object identifier. UIDs are serialized in a manner distinct from
 // that of integers.
 type UID uint64
 
+// Runes holds a string as a sequence of Unicode code points. It is encoded as a property list
+// string, rather than as an array of integers as other []rune (and []int32) values are, and
+// strings can be decoded into it. Encoding fails if it holds a value that is not a valid code
+// point.
+type Runes []rune
+
 // Marshaler is the interface implemented by types that can marshal themselves into valid
 // property list objects. The returned value is marshaled in place of the original value
 // implementing Marshaler
@@ -81,3 +137,18 @@ type Marshaler interface {
 type Unmarshaler interface {
 	UnmarshalPlist(unmarshal func(interface{}) error) error
 }
+
+// A PreHook is called by an Encoder or Decoder before it processes each value. path is the sequence
+// of dictionary keys and array indices (formatted as decimal strings) leading to the value; the root
+// value has an empty path. path is only valid for the duration of the call.
+//
+// For an Encoder, v is the value about to be encoded. For a Decoder, v is the value about to be
+// decoded into. The hook returns the value that should be used in its place, which may be v itself.
+// Returning the zero reflect.Value causes the value to be skipped entirely.
+//
+// If a hook returns an error, encoding or decoding stops and the error is returned.
+type PreHook func(path []string, v reflect.Value) (reflect.Value, error)
+
+// A PostHook is called by an Encoder or Decoder after it has processed each value. Its arguments
+// have the same meaning as those of a PreHook.
+type PostHook func(path []string, v reflect.Value) error
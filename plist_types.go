@@ -2,6 +2,7 @@ package plist
 
 import (
 	"hash/crc32"
+	"math"
 	"sort"
 	"time"
 	"strconv"
@@ -19,6 +20,9 @@ type cfValue interface {
 type cfDictionary struct {
 	keys   sort.StringSlice
 	values []cfValue
+
+	less    func(a, b string) bool // key ordering; byte order if nil
+	ordered bool                   // keys are kept in the order they were added, and never sorted
 }
 
 func (*cfDictionary) typeName() string {
@@ -34,6 +38,9 @@ func (p *cfDictionary) Len() int {
 }
 
 func (p *cfDictionary) Less(i, j int) bool {
+	if p.less != nil {
+		return p.less(p.keys[i], p.keys[j])
+	}
 	return p.keys.Less(i, j)
 }
 
@@ -43,6 +50,9 @@ func (p *cfDictionary) Swap(i, j int) {
 }
 
 func (p *cfDictionary) sort() {
+	if p.ordered {
+		return
+	}
 	sort.Sort(p)
 }
 
@@ -111,11 +121,18 @@ func (cfReal) typeName() string {
 	return "real"
 }
 
+// cfRealHash identifies a real by its bit pattern, so that NaN (which is not equal to itself)
+// can be found in a map and -0 is not confused with 0.
+type cfRealHash struct {
+	wide bool
+	bits uint64
+}
+
 func (p *cfReal) hash() interface{} {
 	if p.wide {
-		return p.value
+		return cfRealHash{true, math.Float64bits(p.value)}
 	}
-	return float32(p.value)
+	return cfRealHash{false, uint64(math.Float32bits(float32(p.value)))}
 }
 
 type cfBoolean bool
@@ -155,9 +172,8 @@ func (cfData) typeName() string {
 }
 
 func (p cfData) hash() interface{} {
-	// Data are uniqued by their checksums.
-	// Todo: Look at calculating this only once and storing it somewhere;
-	// crc32 is fairly quick, however.
+	// Data are uniqued by their checksums. The binary generator calls this only once per value,
+	// as it flattens the document.
 	return crc32.ChecksumIEEE([]byte(p))
 }
 
@@ -167,6 +183,15 @@ func (cfDate) typeName() string {
 	return "date"
 }
 
+// cfDateHash identifies an instant in time, without the location and monotonic clock
+// reading carried by a time.Time; two cfDates that will be encoded identically must hash
+// identically, or the generated document would depend on how each time.Time was produced.
+type cfDateHash struct {
+	sec  int64
+	nsec int
+}
+
 func (p cfDate) hash() interface{} {
-	return time.Time(p)
+	t := time.Time(p)
+	return cfDateHash{t.Unix(), t.Nanosecond()}
 }
@@ -0,0 +1,15 @@
+//go:build go1.18
+// +build go1.18
+
+package plistfuzz
+
+import (
+	"testing"
+)
+
+// AddSeeds adds the documents returned by Seeds(formats...) to f's seed corpus.
+func AddSeeds(f *testing.F, formats ...int) {
+	for _, seed := range Seeds(formats...) {
+		f.Add(seed)
+	}
+}
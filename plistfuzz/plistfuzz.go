@@ -0,0 +1,113 @@
+// Package plistfuzz provides fuzz targets for the property list parsers in howett.net/plist,
+// and a seed corpus for them drawn from the package's own test documents, so that projects
+// that embed the package can fuzz it along with their own code.
+//
+// Each target has the signature Go's native fuzzing expects of the function passed to
+// (*testing.F).Fuzz:
+//
+//	func FuzzPlist(f *testing.F) {
+//		plistfuzz.AddSeeds(f)
+//		f.Fuzz(plistfuzz.Decode)
+//	}
+//
+// A target feeds its input to the parsers and, if the input parses, re-encodes the result; a
+// fuzzing failure is any panic along the way.
+package plistfuzz
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"howett.net/plist"
+)
+
+// Decode decodes data, in whichever format it is, into an empty interface, and encodes the
+// result as XML.
+func Decode(t *testing.T, data []byte) {
+	var v interface{}
+	if _, err := plist.Unmarshal(data, &v); err != nil {
+		return
+	}
+	if _, err := plist.Marshal(v, plist.XMLFormat); err != nil {
+		t.Logf("re-encoding decoded value: %v", err)
+	}
+}
+
+// Binary decodes data as a binary property list, and encodes the result in the same format.
+func Binary(t *testing.T, data []byte) {
+	regenerate(t, data, plist.BinaryFormat)
+}
+
+// XML decodes data as an XML property list, and encodes the result in the same format.
+func XML(t *testing.T, data []byte) {
+	regenerate(t, data, plist.XMLFormat)
+}
+
+// Text decodes data as an OpenStep or GNUStep property list, and encodes the result in the
+// same format.
+func Text(t *testing.T, data []byte) {
+	regenerate(t, data, plist.OpenStepFormat, plist.GNUStepFormat)
+}
+
+// regenerate decodes data if it is in one of formats, and encodes it again in the format it
+// was in. Encoding errors are expected (an XML string cannot hold every character, for
+// example), but panics are not.
+func regenerate(t *testing.T, data []byte, formats ...int) {
+	var v interface{}
+	d := plist.NewDecoder(bytes.NewReader(data))
+	if err := d.Decode(&v); err != nil || !hasFormat(formats, d.Format) {
+		return
+	}
+	if _, err := plist.Marshal(v, d.Format); err != nil {
+		t.Logf("re-encoding decoded value: %v", err)
+	}
+}
+
+func hasFormat(formats []int, format int) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// A seed is a document from the plist package's tests.
+type seed struct {
+	format int
+	doc    []byte
+}
+
+// Seeds returns the documents in the given formats from the plist package's tests, or the
+// documents in every format if none are given.
+func Seeds(formats ...int) [][]byte {
+	var docs [][]byte
+	for _, s := range seeds {
+		if len(formats) == 0 || hasFormat(formats, s.format) {
+			docs = append(docs, s.doc)
+		}
+	}
+	return docs
+}
+
+// WriteCorpus writes each of seeds to dir, which is created if necessary, in the format
+// "go test" reads from testdata/fuzz/FuzzName, so that a corpus can be kept alongside a fuzz
+// target. Files are named after a hash of their contents, as the fuzzer names them.
+func WriteCorpus(dir string, seeds [][]byte) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	for _, seed := range seeds {
+		name := fmt.Sprintf("%x", sha256.Sum256(seed))[:16]
+		contents := fmt.Sprintf("go test fuzz v1\n[]byte(%q)\n", seed)
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
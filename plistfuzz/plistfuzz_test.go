@@ -0,0 +1,63 @@
+//go:build go1.18
+// +build go1.18
+
+package plistfuzz
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"howett.net/plist"
+)
+
+var corpusDir = flag.String("corpus", "", "write the seed corpus for each fuzz target to `dir`")
+
+func FuzzDecode(f *testing.F) {
+	AddSeeds(f)
+	f.Fuzz(Decode)
+}
+
+func FuzzBinary(f *testing.F) {
+	AddSeeds(f, plist.BinaryFormat)
+	f.Fuzz(Binary)
+}
+
+func FuzzXML(f *testing.F) {
+	AddSeeds(f, plist.XMLFormat)
+	f.Fuzz(XML)
+}
+
+func FuzzText(f *testing.F) {
+	AddSeeds(f, plist.OpenStepFormat, plist.GNUStepFormat)
+	f.Fuzz(Text)
+}
+
+// TestWriteCorpus writes a seed corpus for each fuzz target when run with -corpus:
+//
+//	go test -run TestWriteCorpus -corpus testdata/fuzz
+func TestWriteCorpus(t *testing.T) {
+	if *corpusDir == "" {
+		t.Skip("no -corpus directory given")
+	}
+	corpora := map[string][][]byte{
+		"FuzzDecode": Seeds(),
+		"FuzzBinary": Seeds(plist.BinaryFormat),
+		"FuzzXML":    Seeds(plist.XMLFormat),
+		"FuzzText":   Seeds(plist.OpenStepFormat, plist.GNUStepFormat),
+	}
+	for name, seeds := range corpora {
+		if err := WriteCorpus(filepath.Join(*corpusDir, name), seeds); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSeeds(t *testing.T) {
+	if len(Seeds(plist.BinaryFormat)) == 0 || len(Seeds(plist.XMLFormat)) == 0 || len(Seeds(plist.OpenStepFormat, plist.GNUStepFormat)) == 0 {
+		t.Fatal("Expected seeds in every format")
+	}
+	if len(Seeds()) != len(seeds) {
+		t.Errorf("Expected Seeds() to return all %d seeds, received %d", len(seeds), len(Seeds()))
+	}
+}
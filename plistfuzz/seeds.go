@@ -0,0 +1,154 @@
+// Code generated by "go test -run TestFuzzSeeds -update-fuzz-seeds" in howett.net/plist. DO NOT EDIT.
+
+package plistfuzz
+
+import "howett.net/plist"
+
+var seeds = []seed{
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><string>Hello</string></plist>")},
+	{plist.BinaryFormat, []byte("bplist00UHello\b\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x0e")},
+	{plist.OpenStepFormat, []byte("Hello")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><string>&#39;</string></plist>")},
+	{plist.BinaryFormat, []byte("bplist00Q'\b\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\n")},
+	{plist.OpenStepFormat, []byte("\"'\"")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><dict><key>Name</key><string>Dustin</string></dict></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xd1\x01\x02TNameVDustin\b\v\x10\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x17")},
+	{plist.OpenStepFormat, []byte("{Name=Dustin;}")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><dict><key>Name</key><string>Dustin</string><key>Notempty</key><integer>10</integer></dict></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xd2\x01\x02\x03\x04TNameXNotemptyVDustin\x10\n\b\r\x12\x1b\"\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x05\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00$")},
+	{plist.OpenStepFormat, []byte("{Name=Dustin;Notempty=10;}")},
+	{plist.GNUStepFormat, []byte("{Name=Dustin;Notempty=<*I10>;}")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><dict><key>EmbedB</key><dict><key>FieldA</key><string>A.B.C.A1</string><key>FieldA2</key><string>A.B.C.A2</string><key>FieldB</key><string>A.B.B</string><key>FieldC</key><string>A.B.C.C</string></dict><key>FieldA</key><string>A.A</string><key>FieldA2</key><string/><key>FieldB</key><string>A.C.B</string><key>FieldC</key><string>A.C.C</string></dict></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xd5\x01\x02\x03\x04\x05\x06\v\f\r\x0eVEmbedBVFieldAWFieldA2VFieldBVFieldC\xd4\x02\x03\x04\x05\a\b\t\nXA.B.C.A1XA.B.C.A2UA.B.BWA.B.C.CSA.APUA.C.BUA.C.C\b\x13\x1a!)07@IRX`dek\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x0f\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00q")},
+	{plist.OpenStepFormat, []byte("{EmbedB={FieldA=\"A.B.C.A1\";FieldA2=\"A.B.C.A2\";FieldB=\"A.B.B\";FieldC=\"A.B.C.C\";};FieldA=\"A.A\";FieldA2=\"\";FieldB=\"A.C.B\";FieldC=\"A.C.C\";}")},
+	{plist.GNUStepFormat, []byte("{EmbedB={FieldA=A.B.C.A1;FieldA2=A.B.C.A2;FieldB=A.B.B;FieldC=A.B.C.C;};FieldA=A.A;FieldA2=\"\";FieldB=A.C.B;FieldC=A.C.C;}")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><data>aGVsbG8=</data></plist>")},
+	{plist.BinaryFormat, []byte("bplist00Ehello\b\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x0e")},
+	{plist.OpenStepFormat, []byte("<68656c6c 6f>")},
+	{plist.GNUStepFormat, []byte("<[aGVsbG8=]>")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><array><integer>104</integer><integer>101</integer><integer>108</integer><integer>108</integer><integer>111</integer></array></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xa5\x01\x02\x03\x03\x04\x10h\x10e\x10l\x10o\b\x0e\x10\x12\x14\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x05\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x16")},
+	{plist.OpenStepFormat, []byte("(104,101,108,108,111,)")},
+	{plist.GNUStepFormat, []byte("(<*I104>,<*I101>,<*I108>,<*I108>,<*I111>,)")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><array><integer>104</integer><integer>105</integer><integer>33</integer></array></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xa3\x01\x02\x03\x10h\x10i\x10!\b\f\x0e\x10\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x12")},
+	{plist.OpenStepFormat, []byte("(104,105,33,)")},
+	{plist.GNUStepFormat, []byte("(<*I104>,<*I105>,<*I33>,)")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><array><integer>255</integer><integer>4095</integer><integer>65535</integer><integer>1048575</integer><integer>16777215</integer><integer>268435455</integer><integer>4294967295</integer><integer>9223372036854775807</integer><integer>16045690985305262846</integer></array></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xa9\x01\x02\x03\x04\x05\x06\a\b\t\x10\xff\x11\x0f\xff\x11\xff\xff\x12\x00\x0f\xff\xff\x12\x00\xff\xff\xff\x12\x0f\xff\xff\xff\x12\xff\xff\xff\xff\x13\x7f\xff\xff\xff\xff\xff\xff\xff\x14\x00\x00\x00\x00\x00\x00\x00\x00ޭ\xbe\xef\xfa\xce\xca\xfe\b\x12\x14\x17\x1a\x1f$).7\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\n\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00H")},
+	{plist.OpenStepFormat, []byte("(255,4095,65535,1048575,16777215,268435455,4294967295,9223372036854775807,16045690985305262846,)")},
+	{plist.GNUStepFormat, []byte("(<*I255>,<*I4095>,<*I65535>,<*I1048575>,<*I16777215>,<*I268435455>,<*I4294967295>,<*I9223372036854775807>,<*I16045690985305262846>,)")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><array><integer>0x68</integer><integer>0X65</integer><integer>0x78</integer><integer>0X69</integer><integer>0x6e</integer><integer>0X74</integer><integer>-0x2a</integer></array></plist>")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><array><integer>0111</integer><integer>099</integer><integer>0116</integer><integer>0105</integer><integer>0110</integer><integer>0116</integer><integer>-042</integer></array></plist>")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><array><real>3.4028234663852886e+38</real><real>1.7976931348623157e+308</real></array></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xa2\x01\x02\"\x7f\x7f\xff\xff#\x7f\xef\xff\xff\xff\xff\xff\xff\b\v\x10\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x19")},
+	{plist.OpenStepFormat, []byte("(3.4028234663852886e+38,1.7976931348623157e+308,)")},
+	{plist.GNUStepFormat, []byte("(<*R3.4028234663852886e+38>,<*R1.7976931348623157e+308>,)")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><true/></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\t\b\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\t")},
+	{plist.OpenStepFormat, []byte("1")},
+	{plist.GNUStepFormat, []byte("<*BY>")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><real>3.141592653589793</real></plist>")},
+	{plist.BinaryFormat, []byte("bplist00#@\t!\xfbTD-\x18\b\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x11")},
+	{plist.OpenStepFormat, []byte("3.141592653589793")},
+	{plist.GNUStepFormat, []byte("<*R3.141592653589793>")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><dict><key>float</key><real>1</real><key>uint64</key><integer>1</integer></dict></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xd2\x01\x02\x03\x04UfloatVuint64#?\xf0\x00\x00\x00\x00\x00\x00\x10\x01\b\r\x13\x1a#\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x05\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00%")},
+	{plist.OpenStepFormat, []byte("{float=1;uint64=1;}")},
+	{plist.GNUStepFormat, []byte("{float=<*R1>;uint64=<*I1>;}")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><dict><key>booleans</key><array><true/><false/></array><key>data</key><data>AQIDBA==</data><key>date</key><date>2013-11-27T00:34:00Z</date><key>floats</key><array><real>32</real><real>64</real></array><key>intarray</key><array><integer>1</integer><integer>8</integer><integer>16</integer><integer>32</integer><integer>64</integer><integer>2</integer><integer>9</integer><integer>17</integer><integer>33</integer><integer>65</integer></array><key>strings</key><array><string>Hello, ASCII</string><string>Hello, 世界</string></array></dict></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xd6\x01\x02\x03\x04\x05\x06\a\n\v\f\x0f\x1aXbooleansTdataTdateVfloatsXintarrayWstrings\xa2\b\t\t\bD\x01\x02\x03\x043A\xb8Eux\x00\x00\x00\xa2\r\x0e\"B\x00\x00\x00#@P\x00\x00\x00\x00\x00\x00\xaa\x10\x11\x12\x13\x14\x15\x16\x17\x18\x19\x10\x01\x10\b\x10\x10\x10 \x10@\x10\x02\x10\t\x10\x11\x10!\x10A\xa2\x1b\x1c\\Hello, ASCIIi\x00H\x00e\x00l\x00l\x00o\x00,\x00 N\x16uL\b\x15\x1e#(/8@CDEJSV[doqsuwy{}\x7f\x81\x83\x86\x93\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x1d\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\xa6")},
+	{plist.OpenStepFormat, []byte("{booleans=(1,0,);data=<01020304>;date=\"2013-11-27 00:34:00 +0000\";floats=(32,64,);intarray=(1,8,16,32,64,2,9,17,33,65,);strings=(\"Hello, ASCII\",\"Hello, \\U4e16\\U754c\",);}")},
+	{plist.GNUStepFormat, []byte("{booleans=(<*BY>,<*BN>,);data=<01020304>;date=<*D2013-11-27 00:34:00 +0000>;floats=(<*R32>,<*R64>,);intarray=(<*I1>,<*I8>,<*I16>,<*I32>,<*I64>,<*I2>,<*I9>,<*I17>,<*I33>,<*I65>,);strings=(\"Hello, ASCII\",\"Hello, \\U4e16\\U754c\",);}")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><dict><key>float</key><real>1.5</real><key>uint64</key><integer>1</integer></dict></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xd2\x01\x02\x03\x04UfloatVuint64#?\xf8\x00\x00\x00\x00\x00\x00\x10\x01\b\r\x13\x1a#\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x05\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00%")},
+	{plist.OpenStepFormat, []byte("{float=1.5;uint64=1;}")},
+	{plist.GNUStepFormat, []byte("{float=<*R1.5>;uint64=<*I1>;}")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><dict><key>CFBundleInfoDictionaryVersion</key><string>6.0</string><key>band-size</key><integer>8388608</integer><key>bundle-backingstore-version</key><integer>1</integer><key>diskimage-bundle-type</key><string>com.apple.diskimage.sparsebundle</string><key>size</key><integer>4398046511104</integer></dict></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xd5\x01\x02\x03\x04\x05\x06\a\b\t\n_\x10\x1dCFBundleInfoDictionaryVersionYband-size_\x10\x1bbundle-backingstore-version_\x10\x15diskimage-bundle-typeTsizeS6.0\x12\x00\x80\x00\x00\x10\x01_\x10 com.apple.diskimage.sparsebundle\x13\x00\x00\x04\x00\x00\x00\x00\x00\b\x133=[sx|\x81\x83\xa6\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\v\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\xaf")},
+	{plist.OpenStepFormat, []byte("{CFBundleInfoDictionaryVersion=\"6.0\";\"band-size\"=8388608;\"bundle-backingstore-version\"=1;\"diskimage-bundle-type\"=\"com.apple.diskimage.sparsebundle\";size=4398046511104;}")},
+	{plist.GNUStepFormat, []byte("{CFBundleInfoDictionaryVersion=6.0;band-size=<*I8388608>;bundle-backingstore-version=<*I1>;diskimage-bundle-type=com.apple.diskimage.sparsebundle;size=<*I4398046511104>;}")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><array><data>SGVsbG8=</data><data>V29ybGQ=</data></array></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xa2\x01\x02EHelloEWorld\b\v\x11\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x17")},
+	{plist.OpenStepFormat, []byte("(<48656c6c 6f>,<576f726c 64>,)")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><date>2013-11-27T00:34:00Z</date></plist>")},
+	{plist.BinaryFormat, []byte("bplist003A\xb8Eux\x00\x00\x00\b\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x11")},
+	{plist.OpenStepFormat, []byte("\"2013-11-27 00:34:00 +0000\"")},
+	{plist.GNUStepFormat, []byte("<*D2013-11-27 00:34:00 +0000>")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><real>nan</real></plist>")},
+	{plist.BinaryFormat, []byte("bplist00#\x7f\xf8\x00\x00\x00\x00\x00\x01\b\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x11")},
+	{plist.OpenStepFormat, []byte("NaN")},
+	{plist.GNUStepFormat, []byte("<*RNaN>")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><real>inf</real></plist>")},
+	{plist.BinaryFormat, []byte("bplist00#\x7f\xf0\x00\x00\x00\x00\x00\x00\b\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x11")},
+	{plist.OpenStepFormat, []byte("+Inf")},
+	{plist.GNUStepFormat, []byte("<*R+Inf>")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><real>-inf</real></plist>")},
+	{plist.BinaryFormat, []byte("bplist00#\xff\xf0\x00\x00\x00\x00\x00\x00\b\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x11")},
+	{plist.OpenStepFormat, []byte("-Inf")},
+	{plist.GNUStepFormat, []byte("<*R-Inf>")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><array><string>Hello, ASCII</string><string>Hello, 世界</string></array></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xa2\x01\x02\\Hello, ASCIIi\x00H\x00e\x00l\x00l\x00o\x00,\x00 N\x16uL\b\v\x18\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00+")},
+	{plist.OpenStepFormat, []byte("(\"Hello, ASCII\",\"Hello, \\U4e16\\U754c\",)")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><array><integer>1</integer><integer>2</integer><integer>3</integer><integer>4</integer><integer>5</integer><integer>6</integer><integer>7</integer><integer>8</integer><integer>9</integer><integer>10</integer><integer>11</integer><integer>12</integer><integer>13</integer><integer>14</integer><integer>15</integer><integer>16</integer></array></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xaf\x10\x10\x01\x02\x03\x04\x05\x06\a\b\t\n\v\f\r\x0e\x0f\x10\x10\x01\x10\x02\x10\x03\x10\x04\x10\x05\x10\x06\x10\a\x10\b\x10\t\x10\n\x10\v\x10\f\x10\r\x10\x0e\x10\x0f\x10\x10\b\x1b\x1d\x1f!#%')+-/13579\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x11\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00;")},
+	{plist.OpenStepFormat, []byte("(1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,)")},
+	{plist.GNUStepFormat, []byte("(<*I1>,<*I2>,<*I3>,<*I4>,<*I5>,<*I6>,<*I7>,<*I8>,<*I9>,<*I10>,<*I11>,<*I12>,<*I13>,<*I14>,<*I15>,<*I16>,)")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><string>truthful</string></plist>")},
+	{plist.BinaryFormat, []byte("bplist00Xtruthful\b\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x11")},
+	{plist.OpenStepFormat, []byte("truthful")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><string>unimaginable</string></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\\unimaginable\b\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x15")},
+	{plist.OpenStepFormat, []byte("unimaginable")},
+	{plist.BinaryFormat, []byte("bplist00\xaf\x10\x10\x01\x02\x03\x04\x05\x06\a\x02\b\x03\x05\x06\x01\x04\a\bUHello\"B\x00\x00\x00#@@\x00\x00\x00\x00\x00\x00Ddata\"B\x80\x00\x00#@P\x00\x00\x00\x00\x00\x00\x10d3A\xb8Eux\x00\x00\x00\b\x1b!&/49BD\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\t\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00M")},
+	{plist.OpenStepFormat, []byte("{\"\\a\"=\"\\b\";\"\t\r\"=\"\n\";\"\\v\"=\"\\f\";\"\\\\\"=\"\\\"\";\"\\310\"=wat;\"\\U0100\"=hundred;}")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><array><integer>-1</integer><integer>-127</integer><integer>-255</integer><integer>-32767</integer><integer>-65535</integer><integer>-9223372036854775808</integer></array></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xa6\x01\x02\x03\x04\x05\x06\x13\xff\xff\xff\xff\xff\xff\xff\xff\x13\xff\xff\xff\xff\xff\xff\xff\x81\x13\xff\xff\xff\xff\xff\xff\xff\x01\x13\xff\xff\xff\xff\xff\xff\x80\x01\x13\xff\xff\xff\xff\xff\xff\x00\x01\x13\x80\x00\x00\x00\x00\x00\x00\x00\b\x0f\x18!*3<\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\a\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00E")},
+	{plist.OpenStepFormat, []byte("(-1,-127,-255,-32767,-65535,-9223372036854775808,)")},
+	{plist.GNUStepFormat, []byte("(<*I-1>,<*I-127>,<*I-255>,<*I-32767>,<*I-65535>,<*I-9223372036854775808>,)")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><dict><key/><string>Hello</string></dict></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xd1\x01\x02PUHello\b\v\f\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x12")},
+	{plist.OpenStepFormat, []byte("{\"\"=Hello;}")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><array><dict><key>CF$UID</key><integer>255</integer></dict><dict><key>CF$UID</key><integer>65535</integer></dict><dict><key>CF$UID</key><integer>16777215</integer></dict><dict><key>CF$UID</key><integer>4294967295</integer></dict><dict><key>CF$UID</key><integer>1099511627775</integer></dict></array></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xa5\x01\x02\x03\x04\x05\x80\xff\x81\xff\xff\x83\x00\xff\xff\xff\x83\xff\xff\xff\xff\x87\x00\x00\x00\xff\xff\xff\xff\xff\b\x0e\x10\x13\x18\x1d\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x06\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00&")},
+	{plist.OpenStepFormat, []byte("({CF$UID=255;},{CF$UID=65535;},{CF$UID=16777215;},{CF$UID=4294967295;},{CF$UID=1099511627775;},)")},
+	{plist.GNUStepFormat, []byte("({CF$UID=<*I255>;},{CF$UID=<*I65535>;},{CF$UID=<*I16777215>;},{CF$UID=<*I4294967295>;},{CF$UID=<*I1099511627775>;},)")},
+	{plist.XMLFormat, []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n<plist version=\"1.0\"><dict><key>identifier</key><dict><key>CF$UID</key><integer>1024</integer></dict></dict></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xd1\x01\x02Zidentifier\x81\x04\x00\b\v\x16\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x19")},
+	{plist.OpenStepFormat, []byte("{identifier={CF$UID=1024;};}")},
+	{plist.GNUStepFormat, []byte("{identifier={CF$UID=<*I1024>;};}")},
+	{plist.GNUStepFormat, []byte("(<*I100>,(<*I2>,<*I4>,<*I6>,<*I8>,),)")},
+	{plist.OpenStepFormat, []byte("-1")},
+	{plist.GNUStepFormat, []byte("<*I-1>")},
+	{plist.GNUStepFormat, []byte("{a=b;}")},
+	{plist.GNUStepFormat, []byte("{blah=<*I1024>;}")},
+	{plist.OpenStepFormat, []byte("{\n\t\t\t\tA=1 /* A is 1 because it is the first letter */;\n\t\t\t\tB=2; // B is 2 because comment-to-end-of-line.\n\t\t\t\tC=3;\n\t\t\t\tS = /not/a/comment/;\n\t\t\t\tS2 = /not*a/*comm*en/t;\n\t\t\t}")},
+	{plist.OpenStepFormat, []byte("{\n\t\t\t\tW=\"\\w\";\n\t\t\t\tA=\"\\a\";\n\t\t\t\tB=\"\\b\";\n\t\t\t\tV=\"\\v\";\n\t\t\t\tF=\"\\f\";\n\t\t\t\tT=\"\\t\";\n\t\t\t\tR=\"\\r\";\n\t\t\t\tN=\"\\n\";\n\t\t\t\tHex1=\"\\xAB\";\n\t\t\t\tUnicode1=\"\\u00AC\";\n\t\t\t\tUnicode2=\"\\U00AD\";\n\t\t\t\tOctal1=\"\\033\";\n\t\t\t}")},
+	{plist.OpenStepFormat, []byte("(A,,,\"\",)")},
+	{plist.OpenStepFormat, []byte("<>")},
+	{plist.OpenStepFormat, []byte("\ufeffHello")},
+	{plist.OpenStepFormat, []byte("\xff\xfeH\x00e\x00l\x00l\x00o\x00")},
+	{plist.OpenStepFormat, []byte("\xfe\xff\x00H\x00e\x00l\x00l\x00o")},
+	{plist.OpenStepFormat, []byte("H\x00e\x00l\x00l\x00o\x00")},
+	{plist.OpenStepFormat, []byte("\x00H\x00e\x00l\x00l\x00o")},
+	{plist.OpenStepFormat, []byte("\x00\"\x00H\x00e\x00l\x00l\x00o\x00,\x00 N\x16uL\x00\"")},
+	{plist.OpenStepFormat, []byte("\"Key\" = \"Value\";\n\t\t\t\"Key2\" = \"Value2\";")},
+	{plist.OpenStepFormat, []byte("\"Key\";\n\t\t\t\"Key2\";")},
+	{plist.OpenStepFormat, []byte("\"\\x1\\u02\\U003\\4\\0057\"")},
+	{plist.OpenStepFormat, []byte("\"\\xaB\\uCdEf\"")},
+	{plist.OpenStepFormat, []byte("<0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001>")},
+	{plist.OpenStepFormat, []byte("")},
+	{plist.OpenStepFormat, []byte(" \n\t")},
+	{plist.BinaryFormat, []byte("bplist00\xa8\x01\x02\x03\x04\x05\x06\a\b\x13\xff\xff\xff\xff\xff\xff\xff\x80\x10\x7f\x13\xff\xff\xff\xff\xff\xff\x80\x00\x11\x7f\xff\x13\xff\xff\xff\xff\x80\x00\x00\x00\x12\x7f\xff\xff\xff\x13\x80\x00\x00\x00\x00\x00\x00\x00\x13\x7f\xff\xff\xff\xff\xff\xff\xff\b\x11\x1a\x1c%(16?\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\t\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00H")},
+	{plist.XMLFormat, []byte("<plist><dict><key>key</key><string>value</string><key>key</key><string>second value</string></dict></plist>")},
+	{plist.BinaryFormat, []byte("bplist00\xd2\x01\x01\x02\x03SkeyUvalue\\second value\b\r\x11\x17\x00\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00$")},
+	{plist.OpenStepFormat, []byte("{\"key\" = \"value\"; \"key\" = \"second value\";}")},
+	{plist.GNUStepFormat, []byte("(<[aGVs^^bG8=]>,<[ a G V s b G 8 = ]>)")},
+	{plist.GNUStepFormat, []byte("(<*I\"1048576\">, <*I\"1234>, <*B\"Y>)")},
+	{plist.GNUStepFormat, []byte("{Intppp=<*I3>;}")},
+	{plist.GNUStepFormat, []byte("{}")},
+	{plist.GNUStepFormat, []byte("{O=sentinel;}")},
+	{plist.GNUStepFormat, []byte("{O=sentinel;One=one;}")},
+	{plist.GNUStepFormat, []byte("{O=sentinel;One=one;Two=two;}")},
+	{plist.GNUStepFormat, []byte("{O=sentinel;One=\"\";Three=\"\";Two=\"\";}")},
+}
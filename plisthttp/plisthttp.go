@@ -0,0 +1,194 @@
+// Package plisthttp implements the glue needed to exchange property lists over HTTP, as MDM
+// servers and many iOS and macOS clients do: decoding request bodies and encoding responses in
+// the format the client asks for.
+//
+// Property lists are conventionally sent with the media type application/x-plist, whether they
+// are XML or binary; application/x-bplist (or application/x-apple-binary-plist) names binary
+// property lists specifically. XML property lists are also accepted as application/xml and
+// text/xml.
+package plisthttp
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"howett.net/plist"
+)
+
+// ContentType is the media type used for property lists in either format.
+const ContentType = plist.MIMEType
+
+// mediaTypes lists the media types this package understands, in order of preference when a
+// client accepts several equally. A format of AutomaticFormat means either binary or XML.
+var mediaTypes = []struct {
+	name   string
+	format int
+}{
+	{ContentType, plist.AutomaticFormat},
+	{plist.MIMETypeBinary, plist.BinaryFormat},
+	{"application/x-apple-binary-plist", plist.BinaryFormat},
+	{"application/xml", plist.XMLFormat},
+	{"text/xml", plist.XMLFormat},
+}
+
+// MaxRequestSize is the largest request body, in bytes, that DecodeRequest will read. Larger
+// bodies are rejected with an error before they are decoded. A value of 0 or less removes the
+// limit.
+var MaxRequestSize int64 = 10 << 20
+
+var (
+	// ErrUnsupportedMediaType is returned by DecodeRequest for requests whose Content-Type
+	// does not name a property list. Servers usually respond with
+	// http.StatusUnsupportedMediaType.
+	ErrUnsupportedMediaType = errors.New("plisthttp: request body is not a property list")
+
+	// ErrNotAcceptable is returned by WriteResponse for requests whose Accept header does not
+	// permit a property list. Servers usually respond with http.StatusNotAcceptable.
+	ErrNotAcceptable = errors.New("plisthttp: client does not accept property lists")
+)
+
+// DecodeRequest decodes the property list in the body of r into v, as plist.Unmarshal would.
+// The request's Content-Type must name a property list media type, and the body must be a
+// binary or XML property list; requests with an XML media type must contain XML. No more than
+// MaxRequestSize bytes of the body are read.
+func DecodeRequest(r *http.Request, v interface{}) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ErrUnsupportedMediaType
+	}
+	format := -1
+	for _, t := range mediaTypes {
+		if t.name == mediaType {
+			format = t.format
+		}
+	}
+	if format < 0 {
+		return ErrUnsupportedMediaType
+	}
+
+	rd := r.Body
+	if MaxRequestSize > 0 {
+		rd = http.MaxBytesReader(nil, rd, MaxRequestSize)
+	}
+	body, err := ioutil.ReadAll(rd)
+	if err != nil {
+		return err
+	}
+
+	if format == plist.AutomaticFormat {
+		format = plist.XMLFormat
+		if bytes.HasPrefix(body, []byte("bplist")) {
+			format = plist.BinaryFormat
+		}
+	}
+	return plist.NewDecoder(bytes.NewReader(body), plist.WithRequiredFormat(format)).Decode(v)
+}
+
+// Negotiate chooses the format in which to respond to r, based on its Accept header. It returns
+// plist.XMLFormat or plist.BinaryFormat and the media type to send as the response's
+// Content-Type. ok is false if the client does not accept any property list media type.
+//
+// Clients that send no Accept header, or accept application/x-plist, are sent XML, which every
+// property list reader understands.
+func Negotiate(r *http.Request) (format int, contentType string, ok bool) {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return plist.XMLFormat, ContentType, true
+	}
+
+	ranges := parseAccept(accept)
+	best := 0.0
+	for _, t := range mediaTypes {
+		if q := quality(ranges, t.name); q > best {
+			best, format, contentType = q, t.format, t.name
+		}
+	}
+	if best == 0 {
+		return 0, "", false
+	}
+	if format == plist.AutomaticFormat {
+		format = plist.XMLFormat
+	}
+	return format, contentType, true
+}
+
+// WriteResponse encodes v as a property list in the format negotiated for r, and writes it to w
+// with the given status code. If the client does not accept property lists, nothing is written
+// and ErrNotAcceptable is returned. v is encoded in full before anything is written, so a
+// value that cannot be encoded leaves w untouched.
+func WriteResponse(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	format, contentType, ok := Negotiate(r)
+	if !ok {
+		return ErrNotAcceptable
+	}
+
+	body, err := plist.Marshal(v, format)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	return err
+}
+
+// mediaRange is one entry of an Accept header.
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		slash := strings.IndexByte(mediaType, '/')
+		if slash < 0 {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType[:slash], mediaType[slash+1:], q})
+	}
+	return ranges
+}
+
+// quality returns the quality the client assigns to mediaType: that of the most specific range
+// matching it, or 0 if none does.
+func quality(ranges []mediaRange, mediaType string) float64 {
+	slash := strings.IndexByte(mediaType, '/')
+	typ, subtype := mediaType[:slash], mediaType[slash+1:]
+
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		var s int
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
@@ -0,0 +1,123 @@
+package plisthttp
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"howett.net/plist"
+)
+
+type checkin struct {
+	MessageType string
+	UDID        string
+}
+
+func TestDecodeRequest(t *testing.T) {
+	in := checkin{"Authenticate", "0000-1111"}
+	bin, _ := plist.Marshal(in, plist.BinaryFormat)
+	xml, _ := plist.Marshal(in, plist.XMLFormat)
+
+	tests := []struct {
+		contentType string
+		body        []byte
+		valid       bool
+	}{
+		{"application/x-plist", xml, true},
+		{"application/x-plist", bin, true},
+		{"application/x-bplist", bin, true},
+		{"text/xml; charset=utf-8", xml, true},
+		{"application/xml", bin, false},
+		{"application/x-bplist", xml, false},
+		{"application/x-plist", []byte(`{ MessageType = Authenticate; }`), false},
+		{"application/json", xml, false},
+		{"", xml, false},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("PUT", "/checkin", bytes.NewReader(test.body))
+		r.Header.Set("Content-Type", test.contentType)
+		var out checkin
+		err := DecodeRequest(r, &out)
+		if test.valid && (err != nil || out != in) {
+			t.Errorf("%s: Expected %v, received %v (%v)", test.contentType, in, out, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%s: Expected an error", test.contentType)
+		}
+	}
+}
+
+func TestDecodeRequestTooLarge(t *testing.T) {
+	in := checkin{"Authenticate", strings.Repeat("0", 256)}
+	xml, _ := plist.Marshal(in, plist.XMLFormat)
+
+	defer func(size int64) { MaxRequestSize = size }(MaxRequestSize)
+	for _, size := range []int64{int64(len(xml)), 0} {
+		MaxRequestSize = size
+		r := httptest.NewRequest("PUT", "/checkin", bytes.NewReader(xml))
+		r.Header.Set("Content-Type", ContentType)
+		var out checkin
+		if err := DecodeRequest(r, &out); err != nil || out != in {
+			t.Errorf("limit %d: Expected %v, received %v (%v)", size, in, out, err)
+		}
+	}
+
+	MaxRequestSize = int64(len(xml)) - 1
+	r := httptest.NewRequest("PUT", "/checkin", bytes.NewReader(xml))
+	r.Header.Set("Content-Type", ContentType)
+	var out checkin
+	if err := DecodeRequest(r, &out); err == nil {
+		t.Errorf("limit %d: Expected an error for a %d-byte body", MaxRequestSize, len(xml))
+	}
+}
+
+func TestWriteResponse(t *testing.T) {
+	tests := []struct {
+		accept      string
+		contentType string
+		format      int
+	}{
+		{"", ContentType, plist.XMLFormat},
+		{"*/*", ContentType, plist.XMLFormat},
+		{"application/x-bplist", "application/x-bplist", plist.BinaryFormat},
+		{"application/x-plist;q=0.5, application/x-bplist", "application/x-bplist", plist.BinaryFormat},
+		{"text/*, application/json", "text/xml", plist.XMLFormat},
+		{"application/*;q=0.1, application/x-plist;q=0, text/html", "application/x-bplist", plist.BinaryFormat},
+		{"application/json, text/html", "", -1},
+		{"*/*;q=0", "", -1},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+		w := httptest.NewRecorder()
+		err := WriteResponse(w, r, http.StatusCreated, checkin{MessageType: "TokenUpdate"})
+		if test.format < 0 {
+			if err != ErrNotAcceptable || w.Body.Len() != 0 {
+				t.Errorf("%q: Expected ErrNotAcceptable and no body, received %v", test.accept, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.accept, err)
+			continue
+		}
+
+		var out checkin
+		format, err := plist.Unmarshal(w.Body.Bytes(), &out)
+		if w.Code != http.StatusCreated || w.Header().Get("Content-Type") != test.contentType || format != test.format || err != nil || out.MessageType != "TokenUpdate" {
+			t.Errorf("%q: Expected %d %s (%s), received %d %s (%s, %v)", test.accept, http.StatusCreated, test.contentType, plist.FormatNames[test.format],
+				w.Code, w.Header().Get("Content-Type"), plist.FormatNames[format], err)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/", strings.NewReader(""))
+	w := httptest.NewRecorder()
+	if err := WriteResponse(w, r, http.StatusOK, make(chan int)); err == nil || w.Body.Len() != 0 {
+		t.Error("Expected an error and no body for an unencodable value")
+	}
+}
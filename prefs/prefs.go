@@ -0,0 +1,222 @@
+// Package prefs reads and writes macOS preference domains in the manner of the defaults(1) tool.
+//
+// A preference domain is a property list stored in one of a handful of well-known locations.
+// This package knows how those locations are derived (including the per-host "ByHost" variants),
+// preserves the on-disk format of existing files when writing, and replaces files atomically
+// so that a concurrent reader never observes a partially-written document.
+package prefs
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"howett.net/plist"
+)
+
+// GlobalDomain is the name of the domain whose values are visible to every application.
+const GlobalDomain = "NSGlobalDomain"
+
+// globalDomainFile is the file name (sans extension) used to store GlobalDomain.
+const globalDomainFile = ".GlobalPreferences"
+
+// Scope determines which preferences directory a Domain is stored in.
+type Scope int
+
+const (
+	// CurrentUser selects ~/Library/Preferences.
+	CurrentUser Scope = iota
+
+	// AnyUser selects /Library/Preferences.
+	AnyUser
+)
+
+// ErrNoHostUUID is returned when a ByHost domain is used without a host UUID.
+var ErrNoHostUUID = errors.New("prefs: ByHost domain requires a host UUID")
+
+// A Domain identifies a single preferences domain, such as "com.apple.finder".
+type Domain struct {
+	// Name is the domain's name. GlobalDomain refers to the global preferences file.
+	Name string
+
+	// Scope selects the user or system preferences directory.
+	Scope Scope
+
+	// If ByHost is set, the domain refers to the per-host variant of Name, which is stored in the
+	// ByHost subdirectory and named after HostUUID (the machine's hardware UUID.)
+	ByHost   bool
+	HostUUID string
+
+	// Dir, if not empty, overrides the preferences directory chosen by Scope.
+	Dir string
+}
+
+// NewDomain returns a Domain for name in the current user's preferences.
+func NewDomain(name string) *Domain {
+	return &Domain{Name: name}
+}
+
+func (d *Domain) dir() (string, error) {
+	if d.Dir != "" {
+		return d.Dir, nil
+	}
+
+	switch d.Scope {
+	case AnyUser:
+		return "/Library/Preferences", nil
+	default:
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, "Library", "Preferences"), nil
+	}
+}
+
+// Path returns the location of the property list backing d.
+func (d *Domain) Path() (string, error) {
+	dir, err := d.dir()
+	if err != nil {
+		return "", err
+	}
+
+	name := d.Name
+	if name == GlobalDomain {
+		name = globalDomainFile
+	}
+
+	if d.ByHost {
+		if d.HostUUID == "" {
+			return "", ErrNoHostUUID
+		}
+		return filepath.Join(dir, "ByHost", name+"."+d.HostUUID+".plist"), nil
+	}
+	return filepath.Join(dir, name+".plist"), nil
+}
+
+// Load reads every key in d. A domain with no backing file is treated as empty.
+// Load also returns the format of the backing file, or BinaryFormat if it does not exist.
+func (d *Domain) Load() (map[string]interface{}, int, error) {
+	path, err := d.Path()
+	if err != nil {
+		return nil, plist.InvalidFormat, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return make(map[string]interface{}), plist.BinaryFormat, nil
+	} else if err != nil {
+		return nil, plist.InvalidFormat, err
+	}
+
+	values := make(map[string]interface{})
+	format, err := plist.Unmarshal(data, &values)
+	if err != nil {
+		return nil, plist.InvalidFormat, err
+	}
+	return values, format, nil
+}
+
+// Read returns the value stored under key, or nil if there is none.
+func (d *Domain) Read(key string) (interface{}, error) {
+	values, _, err := d.Load()
+	if err != nil {
+		return nil, err
+	}
+	return values[key], nil
+}
+
+// ReadInto decodes the value stored under key into v, following the rules of plist.Unmarshal.
+// It returns false if the domain contains no such key.
+func (d *Domain) ReadInto(key string, v interface{}) (bool, error) {
+	value, err := d.Read(key)
+	if err != nil || value == nil {
+		return false, err
+	}
+
+	// Round-trip through the binary format, which preserves every property list type.
+	data, err := plist.Marshal(value, plist.BinaryFormat)
+	if err != nil {
+		return false, err
+	}
+	_, err = plist.Unmarshal(data, v)
+	return err == nil, err
+}
+
+// Write stores value under key, replacing the domain's backing file atomically.
+func (d *Domain) Write(key string, value interface{}) error {
+	return d.update(func(values map[string]interface{}) {
+		values[key] = value
+	})
+}
+
+// Delete removes key from the domain. It is not an error to delete a key that does not exist.
+func (d *Domain) Delete(key string) error {
+	return d.update(func(values map[string]interface{}) {
+		delete(values, key)
+	})
+}
+
+func (d *Domain) update(fn func(map[string]interface{})) error {
+	values, format, err := d.Load()
+	if err != nil {
+		return err
+	}
+
+	fn(values)
+
+	path, err := d.Path()
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	enc := plist.NewEncoderForFormat(buf, format)
+	if format != plist.BinaryFormat {
+		enc.Indent("\t")
+	}
+	if err := enc.Encode(values); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, buf.Bytes(), 0600)
+}
+
+// WriteFileAtomic writes data to a temporary file next to path and renames it into place,
+// ensuring that readers observe either the old or the new contents of path in their entirety.
+// If path already exists, its permissions are retained.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	if fi, err := os.Stat(path); err == nil {
+		perm = fi.Mode().Perm()
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,114 @@
+package prefs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"howett.net/plist"
+)
+
+func TestDomainPath(t *testing.T) {
+	tests := []struct {
+		domain   Domain
+		expected string
+	}{
+		{Domain{Name: "com.example.app", Dir: "/p"}, "/p/com.example.app.plist"},
+		{Domain{Name: GlobalDomain, Dir: "/p"}, "/p/.GlobalPreferences.plist"},
+		{Domain{Name: "com.example.app", Dir: "/p", ByHost: true, HostUUID: "ABCD"}, "/p/ByHost/com.example.app.ABCD.plist"},
+		{Domain{Name: "com.example.app", Scope: AnyUser}, "/Library/Preferences/com.example.app.plist"},
+	}
+
+	for _, test := range tests {
+		path, err := test.domain.Path()
+		if err != nil {
+			t.Error(err)
+		}
+		if path != filepath.FromSlash(test.expected) {
+			t.Errorf("Expected %s, received %s", test.expected, path)
+		}
+	}
+
+	if _, err := (&Domain{Name: "x", ByHost: true}).Path(); err != ErrNoHostUUID {
+		t.Errorf("Expected ErrNoHostUUID, received %v", err)
+	}
+}
+
+func TestDomainReadWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prefs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := &Domain{Name: "com.example.app", Dir: dir}
+	if v, err := d.Read("Missing"); err != nil || v != nil {
+		t.Fatalf("Expected nothing from empty domain, received %v, %v", v, err)
+	}
+
+	if err := d.Write("Count", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Write("Names", []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	if ok, err := d.ReadInto("Names", &names); !ok || err != nil || len(names) != 2 || names[1] != "b" {
+		t.Errorf("unexpected names %v (%v, %v)", names, ok, err)
+	}
+
+	var count int
+	if ok, err := d.ReadInto("Count", &count); !ok || err != nil || count != 3 {
+		t.Errorf("unexpected count %v (%v, %v)", count, ok, err)
+	}
+
+	if err := d.Delete("Count"); err != nil {
+		t.Fatal(err)
+	}
+	values, format, err := d.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != plist.BinaryFormat {
+		t.Errorf("Expected binary format, received %s", plist.FormatNames[format])
+	}
+	if _, ok := values["Count"]; ok || len(values) != 1 {
+		t.Errorf("unexpected values after delete: %v", values)
+	}
+}
+
+func TestDomainWritePreservesFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prefs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := &Domain{Name: "com.example.app", Dir: dir}
+	path, _ := d.Path()
+	if err := ioutil.WriteFile(path, []byte(`<plist version="1.0"><dict><key>A</key><true/></dict></plist>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Write("B", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	values, format, err := d.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != plist.XMLFormat || values["A"] != true || values["B"] != "b" {
+		t.Errorf("unexpected document %v (%s)", values, plist.FormatNames[format])
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0644 {
+		t.Errorf("permissions were not preserved: %v", fi.Mode())
+	}
+}
@@ -0,0 +1,141 @@
+package plist
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	prettyTimeLayout    = "2006-01-02 15:04:05 -0700"
+	prettyDataInlineMax = 24 // data longer than this is elided in the middle
+	prettyDataHead      = 16
+	prettyDataTail      = 8
+)
+
+// prettyPlistGenerator produces the human-readable dump format of `plutil -p`.
+type prettyPlistGenerator struct {
+	*bufio.Writer
+
+	indent string
+	depth  int
+}
+
+func (p *prettyPlistGenerator) generateDocument(root cfValue) {
+	p.writePlistValue(root)
+	p.WriteByte('\n')
+	p.Flush()
+}
+
+func (p *prettyPlistGenerator) writeIndent() {
+	for i := 0; i < p.depth; i++ {
+		p.WriteString(p.indent)
+	}
+}
+
+// writeData writes data as CoreFoundation describes it, in groups of four bytes; long data
+// is shown by its beginning and end only.
+func (p *prettyPlistGenerator) writeData(data []byte) {
+	p.WriteString("{length = ")
+	p.WriteString(strconv.Itoa(len(data)))
+	p.WriteString(", bytes = 0x")
+
+	writeGroups := func(b []byte) {
+		for i := 0; i < len(b); i += 4 {
+			if i > 0 {
+				p.WriteByte(' ')
+			}
+			end := i + 4
+			if end > len(b) {
+				end = len(b)
+			}
+			p.WriteString(hex.EncodeToString(b[i:end]))
+		}
+	}
+
+	if len(data) <= prettyDataInlineMax {
+		writeGroups(data)
+	} else {
+		writeGroups(data[:prettyDataHead])
+		p.WriteString(" ... ")
+		writeGroups(data[len(data)-prettyDataTail:])
+	}
+	p.WriteByte('}')
+}
+
+func (p *prettyPlistGenerator) writePlistValue(pval cfValue) {
+	switch pval := pval.(type) {
+	case cfString:
+		p.WriteByte('"')
+		p.WriteString(string(pval))
+		p.WriteByte('"')
+	case *cfNumber:
+		if pval.signed {
+			p.WriteString(strconv.FormatInt(int64(pval.value), 10))
+		} else {
+			p.WriteString(strconv.FormatUint(pval.value, 10))
+		}
+	case *cfReal:
+		bits := 64
+		if !pval.wide {
+			bits = 32
+		}
+		p.WriteString(strconv.FormatFloat(pval.value, 'g', -1, bits))
+	case cfBoolean:
+		if pval {
+			p.WriteString("true")
+		} else {
+			p.WriteString("false")
+		}
+	case cfData:
+		p.writeData([]byte(pval))
+	case cfDate:
+		p.WriteString(time.Time(pval).In(time.UTC).Format(prettyTimeLayout))
+	case cfUID:
+		p.WriteString("<CFKeyedArchiverUID>{value = ")
+		p.WriteString(strconv.FormatUint(uint64(pval), 10))
+		p.WriteByte('}')
+	case *cfArray:
+		p.WriteString("[\n")
+		p.depth++
+		for i, v := range pval.values {
+			p.writeIndent()
+			p.WriteString(strconv.Itoa(i))
+			p.WriteString(" => ")
+			p.writePlistValue(v)
+			p.WriteByte('\n')
+		}
+		p.depth--
+		p.writeIndent()
+		p.WriteByte(']')
+	case *cfDictionary:
+		pval.sort()
+		p.WriteString("{\n")
+		p.depth++
+		for i, k := range pval.keys {
+			p.writeIndent()
+			p.writePlistValue(cfString(k))
+			p.WriteString(" => ")
+			p.writePlistValue(pval.values[i])
+			p.WriteByte('\n')
+		}
+		p.depth--
+		p.writeIndent()
+		p.WriteByte('}')
+	}
+}
+
+func (p *prettyPlistGenerator) Indent(i string) {
+	if i != "" {
+		p.indent = i
+	}
+}
+
+func newPrettyPlistGenerator(w io.Writer) *prettyPlistGenerator {
+	return &prettyPlistGenerator{
+		Writer: bufio.NewWriter(mustWriter{w}),
+		indent: "  ",
+	}
+}
@@ -0,0 +1,46 @@
+package plist
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPrettyFormat(t *testing.T) {
+	value := map[string]interface{}{
+		"Name":    "Example",
+		"Count":   -3,
+		"Ratio":   0.5,
+		"Enabled": true,
+		"Created": time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+		"Small":   []byte("hello"),
+		"Large":   make([]byte, 100),
+		"Object":  UID(7),
+		"Items":   []interface{}{"a", uint64(2), map[string]interface{}{}},
+	}
+
+	expected := `{
+  "Count" => -3
+  "Created" => 2019-01-02 03:04:05 +0000
+  "Enabled" => true
+  "Items" => [
+    0 => "a"
+    1 => 2
+    2 => {
+    }
+  ]
+  "Large" => {length = 100, bytes = 0x00000000 00000000 00000000 00000000 ... 00000000 00000000}
+  "Name" => "Example"
+  "Object" => <CFKeyedArchiverUID>{value = 7}
+  "Ratio" => 0.5
+  "Small" => {length = 5, bytes = 0x68656c6c 6f}
+}
+`
+
+	out, err := Marshal(value, PrettyFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != expected {
+		t.Errorf("Expected:\n%s\nReceived:\n%s", expected, out)
+	}
+}
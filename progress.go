@@ -0,0 +1,86 @@
+package plist
+
+import (
+	"io"
+)
+
+// Progress describes how far an Encoder or Decoder has got through a document.
+type Progress struct {
+	// Bytes is the number of bytes of the document read or written so far. A Decoder reports
+	// the furthest position it has read to in its input.
+	Bytes int64
+
+	// Objects is the number of values (including every dictionary and array, and the values
+	// inside them) encoded or decoded so far.
+	Objects int64
+}
+
+// A ProgressFunc is called periodically by an Encoder or Decoder to report its progress: after
+// every read from or write to the underlying stream, after every progressInterval values, and
+// once more when the document is complete.
+type ProgressFunc func(Progress)
+
+// progressInterval is the number of values between progress reports.
+const progressInterval = 1024
+
+// progressTracker accumulates Progress and reports it. Its methods do nothing on a nil tracker,
+// so that callers need not check whether progress is being reported.
+type progressTracker struct {
+	Progress
+	fn ProgressFunc
+}
+
+func (t *progressTracker) object() {
+	if t == nil {
+		return
+	}
+	t.Objects++
+	if t.Objects%progressInterval == 0 {
+		t.fn(t.Progress)
+	}
+}
+
+func (t *progressTracker) done() {
+	if t == nil {
+		return
+	}
+	t.fn(t.Progress)
+}
+
+// progressReader reports the furthest position read to in an input stream.
+type progressReader struct {
+	io.ReadSeeker
+	t   *progressTracker
+	pos int64
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(b)
+	r.pos += int64(n)
+	if r.pos > r.t.Bytes {
+		r.t.Bytes = r.pos
+		r.t.fn(r.t.Progress)
+	}
+	return n, err
+}
+
+func (r *progressReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.ReadSeeker.Seek(offset, whence)
+	if err == nil {
+		r.pos = pos
+	}
+	return pos, err
+}
+
+// progressWriter reports the number of bytes written to an output stream.
+type progressWriter struct {
+	io.Writer
+	t *progressTracker
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	n, err := w.Writer.Write(b)
+	w.t.Bytes += int64(n)
+	w.t.fn(w.t.Progress)
+	return n, err
+}
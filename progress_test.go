@@ -0,0 +1,49 @@
+package plist
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	v := make([]interface{}, 3000)
+	for i := range v {
+		v[i] = map[string]interface{}{"i": i}
+	}
+
+	var reports []Progress
+	record := func(p Progress) { reports = append(reports, p) }
+
+	buf := &bytes.Buffer{}
+	enc := NewEncoderForFormat(buf, XMLFormat)
+	enc.SetProgressFunc(record)
+	if err := enc.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	checkProgress(t, "encode", reports, int64(buf.Len()), 1+3000*2)
+
+	for _, format := range []int{XMLFormat, BinaryFormat, GNUStepFormat} {
+		doc, _ := Marshal(v, format)
+		reports = nil
+		var out interface{}
+		if err := NewDecoder(bytes.NewReader(doc), WithProgressFunc(record)).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		checkProgress(t, "decode "+FormatNames[format], reports, int64(len(doc)), 1+3000*2)
+	}
+}
+
+func checkProgress(t *testing.T, what string, reports []Progress, bytes, objects int64) {
+	if len(reports) < 3 {
+		t.Errorf("%s: Expected several progress reports, received %v", what, reports)
+		return
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i].Bytes < reports[i-1].Bytes || reports[i].Objects < reports[i-1].Objects {
+			t.Errorf("%s: Progress went backwards: %v then %v", what, reports[i-1], reports[i])
+		}
+	}
+	if last := reports[len(reports)-1]; last.Bytes != bytes || last.Objects != objects {
+		t.Errorf("%s: Expected final progress %d bytes, %d objects; received %+v", what, bytes, objects, last)
+	}
+}
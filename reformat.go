@@ -0,0 +1,57 @@
+package plist
+
+import "bytes"
+
+// Indent appends to dst an indented form of the property list in src, which may be in any
+// format. The document keeps its format and its exact values, including the width of each
+// number and any UIDs; no Go types are involved.
+//
+// Each element of an XML or text property list begins on a new line beginning with prefix,
+// followed by one or more copies of indent according to its nesting depth. The first line is
+// not prefixed. Binary property lists have no whitespace to adjust; once src has been checked,
+// it is appended to dst unchanged.
+func Indent(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	return reformat(dst, src, prefix, indent)
+}
+
+// Compact appends to dst the property list in src, which may be in any format, with all
+// insignificant whitespace removed. Like Indent, it preserves the document's format and values.
+func Compact(dst *bytes.Buffer, src []byte) error {
+	return reformat(dst, src, "", "")
+}
+
+func reformat(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	var buf bytes.Buffer
+	var format int
+	err := reencode(&buf, src, sameFormat, func(d *Decoder, enc *Encoder, pval cfValue) (cfValue, error) {
+		format = d.Format
+		enc.Indent(indent)
+		return pval, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if format == BinaryFormat {
+		dst.Write(src)
+		return nil
+	}
+
+	out := buf.Bytes()
+	if prefix == "" {
+		dst.Write(out)
+		return nil
+	}
+
+	for len(out) > 0 {
+		i := bytes.IndexByte(out, '\n')
+		if i < 0 || i == len(out)-1 {
+			dst.Write(out)
+			break
+		}
+		dst.Write(out[:i+1])
+		dst.WriteString(prefix)
+		out = out[i+1:]
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package plist
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIndentAndCompact(t *testing.T) {
+	src := []byte(`{ a = <*I-1>; b = <*R1.5>; c = (x, <*D2013-11-27 00:34:00.25 +0000>); d = { "CF$UID" = <*I3>; }; }`)
+
+	var indented bytes.Buffer
+	if err := Indent(&indented, src, "//", "  "); err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n//  a = <*I-1>;\n//  b = <*R1.5>;\n//  c = (\n//    x,\n//    <*D2013-11-27 00:34:00.25 +0000>,\n//  );\n//  d = {\n//    CF$UID = <*I3>;\n//  };\n//}"
+	if indented.String() != expected {
+		t.Errorf("Expected:\n%s\nReceived:\n%s", expected, indented.String())
+	}
+
+	var compacted bytes.Buffer
+	if err := Compact(&compacted, src); err != nil {
+		t.Fatal(err)
+	}
+	expected = `{a=<*I-1>;b=<*R1.5>;c=(x,<*D2013-11-27 00:34:00.25 +0000>,);d={CF$UID=<*I3>;};}`
+	if compacted.String() != expected {
+		t.Errorf("Expected:\n%s\nReceived:\n%s", expected, compacted.String())
+	}
+
+	xml := []byte(`<plist version="1.0"><array><integer>1</integer><real>2</real></array></plist>`)
+	var out bytes.Buffer
+	if err := Indent(&out, xml, "", "\t"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("<array>\n\t\t<integer>1</integer>\n\t\t<real>2</real>\n\t</array>")) {
+		t.Errorf("Unexpected XML output:\n%s", out.String())
+	}
+
+	bin, err := Marshal(map[string]interface{}{"a": uint8(1), "b": []interface{}{"x"}}, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := Indent(&out, bin, "//", "\t"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), bin) {
+		t.Errorf("Expected a binary property list to be unchanged:\n%x\nReceived:\n%x", bin, out.Bytes())
+	}
+
+	if err := Compact(&out, []byte("{ a = ")); err == nil {
+		t.Error("Expected an error for an invalid document")
+	}
+}
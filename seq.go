@@ -0,0 +1,80 @@
+package plist
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// Iterator functions (iter.Seq and iter.Seq2 in Go 1.23 and later) are recognized by their
+// shape rather than by type, so that any function of the form
+//
+//	func(yield func(V) bool)
+//	func(yield func(K, V) bool)
+//
+// can be encoded, whichever version of Go the package is built with.
+
+var boolType = reflect.TypeOf(false)
+
+// seqYieldType returns the type of the yield function taken by typ if typ is an iterator
+// function, or nil.
+func seqYieldType(typ reflect.Type) reflect.Type {
+	if typ.Kind() != reflect.Func || typ.NumIn() != 1 || typ.NumOut() != 0 {
+		return nil
+	}
+	yield := typ.In(0)
+	if yield.Kind() != reflect.Func || yield.NumOut() != 1 || yield.Out(0) != boolType {
+		return nil
+	}
+	if yield.NumIn() != 1 && yield.NumIn() != 2 {
+		return nil
+	}
+	return yield
+}
+
+// marshalSeq marshals the values produced by an iterator function. Single-valued iterators are
+// encoded as arrays, in the order they produce their values, and iterators of key-value pairs
+// whose keys are strings as dictionaries; a key produced more than once keeps its last value.
+// It returns false if typ is not such an iterator.
+func (p *Encoder) marshalSeq(typ reflect.Type, val reflect.Value) (cfValue, bool) {
+	yieldType := seqYieldType(typ)
+	if yieldType == nil {
+		return nil, false
+	}
+	if yieldType.NumIn() == 2 && yieldType.In(0).Kind() != reflect.String {
+		return nil, false
+	}
+	if val.IsNil() {
+		return nil, true
+	}
+
+	continueIteration := []reflect.Value{reflect.ValueOf(true)}
+	if yieldType.NumIn() == 1 {
+		a := &cfArray{}
+		yield := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+			if subpval := p.marshalAt(strconv.Itoa(len(a.values)), args[0]); subpval != nil {
+				a.values = append(a.values, subpval)
+			}
+			return continueIteration
+		})
+		val.Call([]reflect.Value{yield})
+		return a, true
+	}
+
+	dict := &cfDictionary{}
+	index := make(map[string]int)
+	yield := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+		k := args[0].String()
+		if subpval := p.marshalAt(k, args[1]); subpval != nil {
+			if i, ok := index[k]; ok {
+				dict.values[i] = subpval
+			} else {
+				index[k] = len(dict.keys)
+				dict.keys = append(dict.keys, k)
+				dict.values = append(dict.values, subpval)
+			}
+		}
+		return continueIteration
+	})
+	val.Call([]reflect.Value{yield})
+	return dict, true
+}
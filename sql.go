@@ -0,0 +1,65 @@
+package plist
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"time"
+)
+
+// scanner is implemented by the types that database/sql scans column values into, such as
+// sql.NullString. It is declared here to avoid a dependency on database/sql.
+type scanner interface {
+	Scan(src interface{}) error
+}
+
+var (
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	scannerType = reflect.TypeOf((*scanner)(nil)).Elem()
+)
+
+// marshalValuer marshals the value of a driver.Valuer, such as sql.NullString. A NULL value
+// has no property list representation and is omitted, as a nil pointer would be.
+func (p *Encoder) marshalValuer(valuer driver.Valuer) cfValue {
+	v, err := valuer.Value()
+	if err != nil {
+		panic(err)
+	}
+	if v == nil {
+		return nil
+	}
+	return p.marshal(reflect.ValueOf(v))
+}
+
+// unmarshalScanner stores a scalar value in a type that implements Scan, such as sql.NullInt64,
+// passing it one of the types database/sql would. It returns false for dictionaries and arrays,
+// which are left to be decoded as usual.
+func (p *Decoder) unmarshalScanner(pval cfValue, s scanner) bool {
+	var src interface{}
+	switch pval := pval.(type) {
+	case cfString:
+		src = string(pval)
+	case *cfNumber:
+		if pval.signed || pval.value <= 1<<63-1 {
+			src = int64(pval.value)
+		} else {
+			src = pval.value
+		}
+	case *cfReal:
+		src = pval.value
+	case cfBoolean:
+		src = bool(pval)
+	case cfData:
+		src = []byte(pval)
+	case cfDate:
+		src = time.Time(pval)
+	case cfUID:
+		src = int64(pval)
+	default:
+		return false
+	}
+
+	if err := s.Scan(src); err != nil {
+		panic(err)
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package plist
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+type provisioningRow struct {
+	Serial   string
+	Name     sql.NullString
+	Slot     sql.NullInt64
+	Battery  sql.NullFloat64 `plist:",omitempty"`
+	Enrolled sql.NullTime
+	Managed  sql.NullBool
+}
+
+func TestSQLNullTypes(t *testing.T) {
+	enrolled := time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC)
+	in := provisioningRow{
+		Serial:   "C02XYZ",
+		Name:     sql.NullString{String: "Lab 3", Valid: true},
+		Slot:     sql.NullInt64{Int64: 7, Valid: true},
+		Enrolled: sql.NullTime{Time: enrolled, Valid: true},
+	}
+
+	out, err := Marshal(in, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<dict><key>Enrolled</key><date>2020-02-03T04:05:06Z</date><key>Name</key><string>Lab 3</string><key>Serial</key><string>C02XYZ</string><key>Slot</key><integer>7</integer></dict>`
+	if !bytes.Contains(out, []byte(expected)) {
+		t.Errorf("Expected document to contain %s, received %s", expected, out)
+	}
+
+	var back provisioningRow
+	if _, err := Unmarshal(out, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back != in {
+		t.Errorf("Expected %+v, received %+v", in, back)
+	}
+
+	var lax provisioningRow
+	if _, err := Unmarshal([]byte(`{ Slot = 12; Battery = 0.5; Managed = true; }`), &lax); err != nil {
+		t.Fatal(err)
+	}
+	if lax.Slot != (sql.NullInt64{Int64: 12, Valid: true}) || lax.Battery != (sql.NullFloat64{Float64: 0.5, Valid: true}) || lax.Managed != (sql.NullBool{Bool: true, Valid: true}) {
+		t.Errorf("Expected OpenStep strings to be scanned, received %+v", lax)
+	}
+
+	if _, err := Unmarshal([]byte(`{ Slot = many; }`), &lax); err == nil {
+		t.Error("Expected an error scanning a non-numeric string into a NullInt64")
+	}
+}
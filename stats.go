@@ -0,0 +1,173 @@
+package plist
+
+import (
+	"bytes"
+	"math"
+	"runtime"
+)
+
+// DecodeStats describes the structure of a decoded property list.
+type DecodeStats struct {
+	// Values counts the values in the document by type. Keys are the property list type
+	// names: "string", "integer", "real", "boolean", "date", "data", "UID", "array" and
+	// "dictionary". Binary property lists may reference one object from several places;
+	// such objects are counted once for every place they appear (though they are examined only
+	// once), so that a document that expands to a great many values can be recognized. Counts
+	// and totals too large to represent are reported as the largest value their type can hold.
+	Values map[string]int
+
+	Objects       int   // total number of values, the sum of the counts in Values
+	MaxDepth      int   // nesting depth of the deepest value; a lone scalar has depth 1
+	StringBytes   int64 // total length of all strings and dictionary keys, in bytes of UTF-8
+	DataBytes     int64 // total length of all data values
+	LargestString int   // length of the longest string or dictionary key, in bytes of UTF-8
+	LargestData   int   // length of the longest data value
+	DuplicateKeys int   // number of dictionary keys that repeat an earlier key in the same dictionary
+}
+
+// DocumentStats parses the property list in doc, which may be in any format, and returns
+// statistics about its structure without decoding it into any Go value. It can be used to
+// vet a document of unknown origin before processing it further.
+func DocumentStats(doc []byte) (stats *DecodeStats, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+	}()
+
+	pval, err := NewDecoder(bytes.NewReader(doc)).parseDocument()
+	if err != nil {
+		return nil, err
+	}
+	return collectStats(pval), nil
+}
+
+func collectStats(pval cfValue) *DecodeStats {
+	c := &statsCollector{containers: make(map[cfValue]*DecodeStats)}
+	if isContainer(pval) {
+		return c.container(pval)
+	}
+	stats := &DecodeStats{Values: make(map[string]int)}
+	stats.addScalar(pval, 1)
+	return stats
+}
+
+// statsCollector gathers the statistics for each array and dictionary once, however many
+// places it appears in, so that a small binary property list whose objects refer to one another
+// many times over cannot make collection take exponential time.
+type statsCollector struct {
+	containers map[cfValue]*DecodeStats
+}
+
+func isContainer(pval cfValue) bool {
+	switch pval.(type) {
+	case *cfArray, *cfDictionary:
+		return true
+	}
+	return false
+}
+
+// container returns the statistics for the array or dictionary pval and everything inside it,
+// with pval at depth 1. The result must not be modified.
+func (c *statsCollector) container(pval cfValue) *DecodeStats {
+	if s, ok := c.containers[pval]; ok {
+		return s
+	}
+
+	s := &DecodeStats{Values: map[string]int{pval.typeName(): 1}, Objects: 1, MaxDepth: 1}
+	add := func(subval cfValue) {
+		if isContainer(subval) {
+			s.merge(c.container(subval))
+		} else {
+			s.addScalar(subval, 2)
+		}
+	}
+	switch pval := pval.(type) {
+	case *cfArray:
+		for _, subval := range pval.values {
+			add(subval)
+		}
+	case *cfDictionary:
+		seen := make(map[string]bool, len(pval.keys))
+		for i, key := range pval.keys {
+			s.addString(key)
+			if seen[key] {
+				s.DuplicateKeys = saturatingAdd(s.DuplicateKeys, 1)
+			}
+			seen[key] = true
+			add(pval.values[i])
+		}
+	}
+	c.containers[pval] = s
+	return s
+}
+
+// addScalar counts a value that is neither an array nor a dictionary, found at depth.
+func (s *DecodeStats) addScalar(pval cfValue, depth int) {
+	if pval == nil {
+		return
+	}
+
+	s.Values[pval.typeName()] = saturatingAdd(s.Values[pval.typeName()], 1)
+	s.Objects = saturatingAdd(s.Objects, 1)
+	if depth > s.MaxDepth {
+		s.MaxDepth = depth
+	}
+
+	switch pval := pval.(type) {
+	case cfString:
+		s.addString(string(pval))
+	case cfData:
+		s.DataBytes = saturatingAdd64(s.DataBytes, int64(len(pval)))
+		if len(pval) > s.LargestData {
+			s.LargestData = len(pval)
+		}
+	}
+}
+
+// merge adds the statistics of a container found one level below the top of s.
+func (s *DecodeStats) merge(sub *DecodeStats) {
+	for typ, n := range sub.Values {
+		s.Values[typ] = saturatingAdd(s.Values[typ], n)
+	}
+	s.Objects = saturatingAdd(s.Objects, sub.Objects)
+	if sub.MaxDepth+1 > s.MaxDepth {
+		s.MaxDepth = sub.MaxDepth + 1
+	}
+	s.StringBytes = saturatingAdd64(s.StringBytes, sub.StringBytes)
+	s.DataBytes = saturatingAdd64(s.DataBytes, sub.DataBytes)
+	if sub.LargestString > s.LargestString {
+		s.LargestString = sub.LargestString
+	}
+	if sub.LargestData > s.LargestData {
+		s.LargestData = sub.LargestData
+	}
+	s.DuplicateKeys = saturatingAdd(s.DuplicateKeys, sub.DuplicateKeys)
+}
+
+func (s *DecodeStats) addString(str string) {
+	s.StringBytes = saturatingAdd64(s.StringBytes, int64(len(str)))
+	if len(str) > s.LargestString {
+		s.LargestString = len(str)
+	}
+}
+
+const largestInt = int(^uint(0) >> 1)
+
+// saturatingAdd returns a+b for non-negative a and b, or the largest int if that overflows.
+func saturatingAdd(a, b int) int {
+	if a > largestInt-b {
+		return largestInt
+	}
+	return a + b
+}
+
+func saturatingAdd64(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
@@ -0,0 +1,33 @@
+package plist
+
+import (
+	"fmt"
+)
+
+// rejectDuplicateKeys panics if any dictionary in pval defines a key more than once. Containers
+// in seen, which a binary property list may refer to from several places, are checked only once.
+func rejectDuplicateKeys(pval cfValue, seen map[cfValue]bool) {
+	switch pval := pval.(type) {
+	case *cfArray:
+		if seen[pval] {
+			return
+		}
+		seen[pval] = true
+		for _, subval := range pval.values {
+			rejectDuplicateKeys(subval, seen)
+		}
+	case *cfDictionary:
+		if seen[pval] {
+			return
+		}
+		seen[pval] = true
+		keys := make(map[string]bool, len(pval.keys))
+		for i, k := range pval.keys {
+			if keys[k] {
+				panic(fmt.Errorf("plist: dictionary key %q appears more than once", k))
+			}
+			keys[k] = true
+			rejectDuplicateKeys(pval.values[i], seen)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Package stringsdict models the .stringsdict property lists used to localize strings whose
+// wording depends on a number, such as "1 file" and "2 files".
+//
+// Each entry in a .stringsdict file has a format string (NSStringLocalizedFormatKey) that
+// refers to one or more variables with the syntax %#@name@. Each variable is described by a
+// dictionary naming its rule type and giving the text to use for every plural category.
+package stringsdict
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"howett.net/plist"
+)
+
+const (
+	formatKey    = "NSStringLocalizedFormatKey"
+	specTypeKey  = "NSStringFormatSpecTypeKey"
+	valueTypeKey = "NSStringFormatValueTypeKey"
+
+	// PluralRuleType is the NSStringFormatSpecTypeKey of variables that vary by plural category.
+	PluralRuleType = "NSStringPluralRuleType"
+)
+
+// Categories lists the CLDR plural categories a plural variable may define, in canonical order.
+var Categories = []string{"zero", "one", "two", "few", "many", "other"}
+
+// File represents the contents of a .stringsdict file, keyed by localization key.
+type File map[string]Entry
+
+// Entry is the localization of a single key.
+type Entry struct {
+	// Format is the entry's format string (NSStringLocalizedFormatKey.)
+	Format string
+
+	// Variables holds the variables the format string refers to, keyed by name.
+	Variables map[string]Variable
+}
+
+// Variable describes how one variable in an entry's format string is replaced.
+type Variable struct {
+	SpecType  string // NSStringFormatSpecTypeKey, usually PluralRuleType
+	ValueType string // NSStringFormatValueTypeKey, the printf conversion for the number, such as "d"
+
+	// Forms holds the text for each plural category, keyed by category name ("one", "other" and so on.)
+	Forms map[string]string
+}
+
+// entryValue is a value in an entry's dictionary: either the format string or a variable.
+type entryValue struct {
+	format   string
+	variable *Variable
+}
+
+// UnmarshalPlist implements plist.Unmarshaler.
+func (e *entryValue) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&e.format); err == nil {
+		return nil
+	}
+	e.variable = &Variable{}
+	return unmarshal(e.variable)
+}
+
+// UnmarshalPlist implements plist.Unmarshaler.
+func (e *Entry) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	var values map[string]entryValue
+	if err := unmarshal(&values); err != nil {
+		return err
+	}
+
+	*e = Entry{}
+	for k, v := range values {
+		switch {
+		case k == formatKey && v.variable == nil:
+			e.Format = v.format
+		case v.variable != nil:
+			if e.Variables == nil {
+				e.Variables = make(map[string]Variable)
+			}
+			e.Variables[k] = *v.variable
+		default:
+			return fmt.Errorf("stringsdict: %s is not a variable dictionary", k)
+		}
+	}
+	return nil
+}
+
+// MarshalPlist implements plist.Marshaler.
+func (e Entry) MarshalPlist() (interface{}, error) {
+	m := make(map[string]interface{}, len(e.Variables)+1)
+	for k, v := range e.Variables {
+		m[k] = v
+	}
+	m[formatKey] = e.Format
+	return m, nil
+}
+
+// UnmarshalPlist implements plist.Unmarshaler.
+func (v *Variable) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	var m map[string]string
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+
+	*v = Variable{SpecType: m[specTypeKey], ValueType: m[valueTypeKey]}
+	delete(m, specTypeKey)
+	delete(m, valueTypeKey)
+	if len(m) > 0 {
+		v.Forms = m
+	}
+	return nil
+}
+
+// MarshalPlist implements plist.Marshaler.
+func (v Variable) MarshalPlist() (interface{}, error) {
+	m := make(map[string]string, len(v.Forms)+2)
+	for k, form := range v.Forms {
+		m[k] = form
+	}
+	if v.SpecType != "" {
+		m[specTypeKey] = v.SpecType
+	}
+	if v.ValueType != "" {
+		m[valueTypeKey] = v.ValueType
+	}
+	return m, nil
+}
+
+// A ValidationError describes a problem with a single entry or variable in a File.
+type ValidationError struct {
+	Key     string // the entry's key, followed by the variable's name if the problem is with a variable
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return "stringsdict: invalid " + e.Key + ": " + e.Message
+}
+
+// variableReference matches a reference to a variable, such as %#@files@.
+var variableReference = regexp.MustCompile(`%#@([^@]*)@`)
+
+func isCategory(name string) bool {
+	for _, c := range Categories {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]Variable) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedCategories(forms map[string]string) []string {
+	categories := make([]string, 0, len(forms))
+	for category := range forms {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// Validate checks f for the mistakes that make Foundation ignore an entry, returning a
+// *ValidationError describing the first one found (in key order). Each entry must have a
+// format string, every variable it refers to (in its format string or in the text of another
+// variable) must be defined, and every plural variable must have a value type, an "other"
+// form and no categories but those in Categories.
+func (f File) Validate() error {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := f[k].validate(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e Entry) validate(key string) error {
+	if e.Format == "" {
+		return &ValidationError{key, formatKey + " is required"}
+	}
+
+	texts := []string{e.Format}
+	for _, name := range sortedKeys(e.Variables) {
+		forms := e.Variables[name].Forms
+		for _, category := range sortedCategories(forms) {
+			texts = append(texts, forms[category])
+		}
+	}
+	for _, text := range texts {
+		for _, ref := range variableReference.FindAllStringSubmatch(text, -1) {
+			if _, ok := e.Variables[ref[1]]; !ok {
+				return &ValidationError{key, "undefined variable " + ref[1]}
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(e.Variables) {
+		v := e.Variables[name]
+		vkey := key + "." + name
+		if v.SpecType != PluralRuleType {
+			// Other rule types (such as NSStringDeviceSpecificRuleType) have their own
+			// vocabularies, which are not checked.
+			continue
+		}
+		if v.ValueType == "" {
+			return &ValidationError{vkey, valueTypeKey + " is required"}
+		}
+		if _, ok := v.Forms["other"]; !ok {
+			return &ValidationError{vkey, `the "other" category is required`}
+		}
+		for _, category := range sortedCategories(v.Forms) {
+			if !isCategory(category) {
+				return &ValidationError{vkey, "unknown plural category " + category}
+			}
+		}
+	}
+	return nil
+}
+
+// Parse decodes a .stringsdict document in any property list format.
+func Parse(data []byte) (File, error) {
+	var f File
+	if _, err := plist.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
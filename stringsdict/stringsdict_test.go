@@ -0,0 +1,107 @@
+package stringsdict
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"howett.net/plist"
+)
+
+const sample = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+	<dict>
+		<key>%d files</key>
+		<dict>
+			<key>NSStringLocalizedFormatKey</key>
+			<string>%#@files@</string>
+			<key>files</key>
+			<dict>
+				<key>NSStringFormatSpecTypeKey</key>
+				<string>NSStringPluralRuleType</string>
+				<key>NSStringFormatValueTypeKey</key>
+				<string>d</string>
+				<key>one</key>
+				<string>%d file</string>
+				<key>other</key>
+				<string>%d files</string>
+			</dict>
+		</dict>
+	</dict>
+</plist>`
+
+func TestParseAndRoundTrip(t *testing.T) {
+	f, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := File{
+		"%d files": {
+			Format: "%#@files@",
+			Variables: map[string]Variable{
+				"files": {
+					SpecType:  PluralRuleType,
+					ValueType: "d",
+					Forms:     map[string]string{"one": "%d file", "other": "%d files"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(f, expected) {
+		t.Fatalf("Unexpected file %#v", f)
+	}
+	if err := f.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	data, err := plist.MarshalIndent(f, plist.XMLFormat, "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != sample {
+		t.Errorf("Round trip mismatch:\n%s", data)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	plural := func(forms map[string]string) Variable {
+		return Variable{SpecType: PluralRuleType, ValueType: "d", Forms: forms}
+	}
+
+	tests := []struct {
+		entry   Entry
+		message string
+	}{
+		{Entry{Variables: map[string]Variable{}}, "NSStringLocalizedFormatKey is required"},
+		{Entry{Format: "%#@count@"}, "undefined variable count"},
+		{Entry{Format: "%#@a@", Variables: map[string]Variable{
+			"a": plural(map[string]string{"other": "%#@b@"}),
+		}}, "undefined variable b"},
+		{Entry{Format: "%#@a@ %#@m@", Variables: map[string]Variable{
+			"a": plural(map[string]string{"other": "%#@z@", "one": "%#@y@"}),
+			"m": plural(map[string]string{"other": "%#@c@", "few": "%#@b@"}),
+		}}, "undefined variable y"},
+		{Entry{Format: "%#@n@", Variables: map[string]Variable{
+			"n": plural(map[string]string{"one": "%d"}),
+		}}, `"other" category is required`},
+		{Entry{Format: "%#@n@", Variables: map[string]Variable{
+			"n": plural(map[string]string{"other": "%d", "several": "%d"}),
+		}}, "unknown plural category several"},
+		{Entry{Format: "%#@n@", Variables: map[string]Variable{
+			"n": {SpecType: PluralRuleType, Forms: map[string]string{"other": "%d"}},
+		}}, "NSStringFormatValueTypeKey is required"},
+	}
+
+	for _, test := range tests {
+		// The same problem is reported every time, whatever the map iteration order.
+		for i := 0; i < 10; i++ {
+			err := File{"key": test.entry}.Validate()
+			if err == nil || !strings.Contains(err.Error(), test.message) {
+				t.Errorf("Expected an error containing %q, got %v", test.message, err)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package plist
+
+import (
+	"unicode/utf8"
+)
+
+// A TextEncoding identifies the character set of an OpenStep or GNUStep property list (or a
+// strings file) that is not stored in Unicode.
+type TextEncoding int
+
+const (
+	// UTF8TextEncoding, the default, treats documents without a byte order mark as UTF-8.
+	UTF8TextEncoding TextEncoding = iota
+
+	// Latin1TextEncoding treats documents without a byte order mark as ISO 8859-1.
+	Latin1TextEncoding
+
+	// MacRomanTextEncoding treats documents without a byte order mark as Mac OS Roman, the
+	// encoding of most documents written by NeXTSTEP and early versions of Mac OS X.
+	MacRomanTextEncoding
+
+	// DetectTextEncoding treats documents without a byte order mark as UTF-8 if they are valid
+	// UTF-8, and otherwise guesses between Mac OS Roman and ISO 8859-1. Bytes 0x80 to 0x9F are
+	// control characters in ISO 8859-1 but common letters in Mac OS Roman, so a document that
+	// contains any of them is taken to be Mac OS Roman.
+	DetectTextEncoding
+)
+
+// macRomanHigh maps the bytes 0x80 to 0xFF of Mac OS Roman onto Unicode.
+var macRomanHigh = []rune("" +
+	"ÄÅÇÉÑÖÜáàâäãåçéè" +
+	"êëíìîïñóòôöõúùûü" +
+	"†°¢£§•¶ß®©™´¨≠ÆØ" +
+	"∞±≤≥¥µ∂∑∏π∫ªºΩæø" +
+	"¿¡¬√ƒ≈∆«»…\u00A0ÀÃÕŒœ" +
+	"–—“”‘’÷◊ÿŸ⁄€‹›ﬁﬂ" +
+	"‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔ" +
+	"\uF8FFÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ")
+
+// detectTextEncoding guesses the encoding of a document that has no byte order mark.
+func detectTextEncoding(buffer []byte) TextEncoding {
+	if utf8.Valid(buffer) {
+		return UTF8TextEncoding
+	}
+	for _, b := range buffer {
+		if b >= 0x80 && b <= 0x9F {
+			return MacRomanTextEncoding
+		}
+	}
+	return Latin1TextEncoding
+}
+
+// convert8Bit decodes a document in one of the 8-bit encodings.
+func convert8Bit(buffer []byte, enc TextEncoding) string {
+	if enc == DetectTextEncoding {
+		enc = detectTextEncoding(buffer)
+	}
+
+	var table []rune
+	switch enc {
+	case Latin1TextEncoding:
+	case MacRomanTextEncoding:
+		table = macRomanHigh
+	default:
+		return zeroCopy8BitString(buffer, 0, len(buffer))
+	}
+
+	runes := make([]rune, len(buffer))
+	for i, b := range buffer {
+		if b >= 0x80 && table != nil {
+			runes[i] = table[b-0x80]
+		} else {
+			runes[i] = rune(b)
+		}
+	}
+	return string(runes)
+}
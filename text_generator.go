@@ -1,9 +1,11 @@
 package plist
 
 import (
+	"bytes"
 	"encoding/hex"
 	"io"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,12 +18,18 @@ type textPlistGenerator struct {
 	indent string
 	depth  int
 
+	fractionalSeconds bool
+	iso8601Dates      bool // write OpenStep dates in RFC 3339 form
+	gnustepLayout     bool // lay containers out exactly as gnustep-base does
+	inline            inlineLimits
+
 	dictKvDelimiter, dictEntryDelimiter, arrayDelimiter []byte
 }
 
 var (
-	textPlistTimeLayout = "2006-01-02 15:04:05 -0700"
-	padding             = "0000"
+	textPlistTimeLayout           = "2006-01-02 15:04:05 -0700"
+	textPlistFractionalTimeLayout = "2006-01-02 15:04:05.999999999 -0700"
+	padding                       = "0000"
 )
 
 func (p *textPlistGenerator) generateDocument(pval cfValue) {
@@ -107,6 +115,13 @@ func (p *textPlistGenerator) writePlistValue(pval cfValue) {
 	switch pval := pval.(type) {
 	case *cfDictionary:
 		pval.sort()
+		if p.gnustepLayout {
+			p.writeGNUStepDictionary(pval)
+			return
+		}
+		if p.writeInline(pval, pval.values) {
+			return
+		}
 		p.writer.Write([]byte(`{`))
 		p.deltaIndent(1)
 		for i, k := range pval.keys {
@@ -120,6 +135,13 @@ func (p *textPlistGenerator) writePlistValue(pval cfValue) {
 		p.writeIndent()
 		p.writer.Write([]byte(`}`))
 	case *cfArray:
+		if p.gnustepLayout {
+			p.writeGNUStepArray(pval)
+			return
+		}
+		if p.writeInline(pval, pval.values) {
+			return
+		}
 		p.writer.Write([]byte(`(`))
 		p.deltaIndent(1)
 		for _, v := range pval.values {
@@ -191,17 +213,120 @@ func (p *textPlistGenerator) writePlistValue(pval cfValue) {
 		p.writer.Write([]byte(`>`))
 	case cfDate:
 		if p.format == GNUStepFormat {
+			layout := textPlistTimeLayout
+			if p.fractionalSeconds {
+				layout = textPlistFractionalTimeLayout
+			}
 			p.writer.Write([]byte(`<*D`))
-			io.WriteString(p.writer, time.Time(pval).In(time.UTC).Format(textPlistTimeLayout))
+			io.WriteString(p.writer, time.Time(pval).In(time.UTC).Format(layout))
 			p.writer.Write([]byte(`>`))
 		} else {
-			io.WriteString(p.writer, p.plistQuotedString(time.Time(pval).In(time.UTC).Format(textPlistTimeLayout)))
+			layout := textPlistTimeLayout
+			if p.iso8601Dates {
+				layout = time.RFC3339
+			}
+			io.WriteString(p.writer, p.plistQuotedString(time.Time(pval).In(time.UTC).Format(layout)))
 		}
 	case cfUID:
 		p.writePlistValue(pval.toDict())
 	}
 }
 
+// writeInline writes the dictionary or array pval, whose members are values, on a single line if
+// the generator's inline limits allow it, and reports whether it did.
+func (p *textPlistGenerator) writeInline(pval cfValue, values []cfValue) bool {
+	if p.indent == "" || !p.inline.allows(values) {
+		return false
+	}
+
+	var buf bytes.Buffer
+	q := *p
+	q.writer = &buf
+	q.indent = ""
+	switch pval := pval.(type) {
+	case *cfDictionary:
+		buf.WriteByte('{')
+		for i, k := range pval.keys {
+			buf.WriteByte(' ')
+			buf.WriteString(q.plistQuotedString(k))
+			buf.WriteString(" = ")
+			q.writePlistValue(pval.values[i])
+			buf.WriteByte(';')
+		}
+		if len(pval.keys) > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteByte('}')
+	case *cfArray:
+		buf.WriteByte('(')
+		for i, v := range pval.values {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			q.writePlistValue(v)
+		}
+		buf.WriteByte(')')
+	}
+
+	if !p.inline.fits(buf.Bytes()) {
+		return false
+	}
+	p.writer.Write(buf.Bytes())
+	return true
+}
+
+// gnustepIndent returns the indentation gnustep-base writes before the members of a container
+// nested depth levels deep: four columns per level, with every eight columns written as a tab.
+func gnustepIndent(depth int) string {
+	columns := depth * 4
+	return strings.Repeat("\t", columns/8) + strings.Repeat(" ", columns%8)
+}
+
+// writeGNUStepDictionary writes a dictionary in the layout used by gnustep-base, which puts
+// every entry on its own line regardless of the Encoder's indent.
+func (p *textPlistGenerator) writeGNUStepDictionary(pval *cfDictionary) {
+	if len(pval.keys) == 0 {
+		p.writer.Write([]byte(`{}`))
+		return
+	}
+
+	p.writer.Write([]byte("{\n"))
+	p.depth++
+	for i, k := range pval.keys {
+		io.WriteString(p.writer, gnustepIndent(p.depth))
+		io.WriteString(p.writer, p.plistQuotedString(k))
+		p.writer.Write([]byte(` = `))
+		p.writePlistValue(pval.values[i])
+		p.writer.Write([]byte(";\n"))
+	}
+	p.depth--
+	io.WriteString(p.writer, gnustepIndent(p.depth))
+	p.writer.Write([]byte(`}`))
+}
+
+// writeGNUStepArray writes an array in the layout used by gnustep-base, which (unlike the
+// default layout) does not follow the last element with a comma.
+func (p *textPlistGenerator) writeGNUStepArray(pval *cfArray) {
+	if len(pval.values) == 0 {
+		p.writer.Write([]byte(`()`))
+		return
+	}
+
+	p.writer.Write([]byte("(\n"))
+	p.depth++
+	for i, v := range pval.values {
+		io.WriteString(p.writer, gnustepIndent(p.depth))
+		p.writePlistValue(v)
+		if i < len(pval.values)-1 {
+			p.writer.Write([]byte(`,`))
+		}
+		p.writer.Write([]byte("\n"))
+	}
+	p.depth--
+	io.WriteString(p.writer, gnustepIndent(p.depth))
+	p.writer.Write([]byte(`)`))
+}
+
 func (p *textPlistGenerator) Indent(i string) {
 	p.indent = i
 	if i == "" {
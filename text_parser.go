@@ -27,6 +27,13 @@ type textPlistParser struct {
 	start int
 	pos   int
 	width int
+
+	keepEmptyArrayStrings bool         // don't discard "" from arrays
+	rejectDuplicateKeys   bool         // fail when a dictionary defines a key twice
+	encoding              TextEncoding // the encoding of documents without a byte order mark
+	keepUIDDicts          bool         // don't turn CF$UID dictionaries into UIDs
+
+	emptyDocument bool // set when the document contains nothing but whitespace and comments
 }
 
 func convertU16(buffer []byte, bo binary.ByteOrder) (string, error) {
@@ -41,7 +48,41 @@ func convertU16(buffer []byte, bo binary.ByteOrder) (string, error) {
 	return string(utf16.Decode(tmp)), nil
 }
 
-func guessEncodingAndConvert(buffer []byte) (string, error) {
+func convertU32(buffer []byte, bo binary.ByteOrder) (string, error) {
+	if len(buffer)%4 != 0 {
+		return "", errors.New("truncated utf32")
+	}
+
+	tmp := make([]rune, len(buffer)/4)
+	for i := 0; i < len(buffer); i += 4 {
+		r := rune(bo.Uint32(buffer[i : i+4]))
+		if !utf8.ValidRune(r) {
+			return "", fmt.Errorf("invalid utf32 code point %#x", uint32(r))
+		}
+		tmp[i/4] = r
+	}
+	return string(tmp), nil
+}
+
+func guessEncodingAndConvert(buffer []byte, enc TextEncoding) (string, error) {
+	if len(buffer) >= 4 {
+		// UTF-32 guesses; these must come first, as a UTF-32LE BOM begins with a UTF-16LE BOM.
+
+		switch {
+		// stream is big-endian (BOM is 00 00 FE FF or head is 00 00 00 XX)
+		case (buffer[0] == 0 && buffer[1] == 0 && buffer[2] == 0xFE && buffer[3] == 0xFF):
+			return convertU32(buffer[4:], binary.BigEndian)
+		case (buffer[0] == 0 && buffer[1] == 0 && buffer[2] == 0 && buffer[3] != 0):
+			return convertU32(buffer, binary.BigEndian)
+
+		// stream is little-endian (BOM is FF FE 00 00 or head is XX 00 00 00)
+		case (buffer[0] == 0xFF && buffer[1] == 0xFE && buffer[2] == 0 && buffer[3] == 0):
+			return convertU32(buffer[4:], binary.LittleEndian)
+		case (buffer[0] != 0 && buffer[1] == 0 && buffer[2] == 0 && buffer[3] == 0):
+			return convertU32(buffer, binary.LittleEndian)
+		}
+	}
+
 	if len(buffer) >= 3 && buffer[0] == 0xEF && buffer[1] == 0xBB && buffer[2] == 0xBF {
 		// UTF-8 BOM
 		return zeroCopy8BitString(buffer, 3, len(buffer)-3), nil
@@ -63,8 +104,8 @@ func guessEncodingAndConvert(buffer []byte) (string, error) {
 		}
 	}
 
-	// fallback: assume ASCII (not great!)
-	return zeroCopy8BitString(buffer, 0, len(buffer)), nil
+	// fallback: an 8-bit encoding, UTF-8 unless the caller says otherwise
+	return convert8Bit(buffer, enc), nil
 }
 
 func (p *textPlistParser) parseDocument() (pval cfValue, parseError error) {
@@ -83,11 +124,17 @@ func (p *textPlistParser) parseDocument() (pval cfValue, parseError error) {
 		panic(err)
 	}
 
-	p.input, err = guessEncodingAndConvert(buffer)
+	p.input, err = guessEncodingAndConvert(buffer, p.encoding)
 	if err != nil {
 		panic(err)
 	}
 
+	p.skipWhitespaceAndComments()
+	if p.peek() == eof {
+		p.emptyDocument = true
+		return &cfDictionary{}, nil
+	}
+
 	val := p.parsePlistValue()
 
 	p.skipWhitespaceAndComments()
@@ -111,9 +158,28 @@ func (p *textPlistParser) parseDocument() (pval cfValue, parseError error) {
 const eof rune = -1
 
 func (p *textPlistParser) error(e string, args ...interface{}) {
-	line := strings.Count(p.input[:p.pos], "\n")
-	char := p.pos - strings.LastIndex(p.input[:p.pos], "\n") - 1
-	panic(fmt.Errorf("%s at line %d character %d", fmt.Sprintf(e, args...), line, char))
+	line, char := p.location(p.pos)
+	panic(excerptError{fmt.Errorf("%s at line %d character %d", fmt.Sprintf(e, args...), line, char), p.excerpt(lastRuneStart(p.input, p.pos))})
+}
+
+// excerpt quotes the line of the input holding pos, with a caret beneath the character at pos.
+func (p *textPlistParser) excerpt(pos int) string {
+	start := strings.LastIndex(p.input[:pos], "\n") + 1
+	end := strings.IndexByte(p.input[pos:], '\n')
+	if end < 0 {
+		end = len(p.input)
+	} else {
+		end += pos
+	}
+	line := p.input[start:end]
+	return sourceExcerpt(line, pos-start)
+}
+
+// location returns the line and character that pos refers to.
+func (p *textPlistParser) location(pos int) (line, char int) {
+	line = strings.Count(p.input[:pos], "\n")
+	char = pos - strings.LastIndex(p.input[:pos], "\n") - 1
+	return
 }
 
 func (p *textPlistParser) next() rune {
@@ -325,10 +391,15 @@ func (p *textPlistParser) parseDictionary(ignoreEof bool) cfValue {
 	var keypv cfValue
 	keys := make([]string, 0, 32)
 	values := make([]cfValue, 0, 32)
+	var keyPositions map[string]int
+	if p.rejectDuplicateKeys {
+		keyPositions = make(map[string]int)
+	}
 outer:
 	for {
 		p.skipWhitespaceAndComments()
 
+		keyPos := p.pos
 		switch p.next() {
 		case eof:
 			if !ignoreEof {
@@ -369,11 +440,24 @@ outer:
 			p.error("missing = in dictionary")
 		}
 
-		keys = append(keys, string(keypv.(cfString)))
+		key := string(keypv.(cfString))
+		if keyPositions != nil {
+			if firstPos, ok := keyPositions[key]; ok {
+				line, char := p.location(keyPos)
+				firstLine, firstChar := p.location(firstPos)
+				panic(excerptError{fmt.Errorf("duplicate key %q at line %d character %d (first defined at line %d character %d)", key, line, char, firstLine, firstChar), p.excerpt(keyPos)})
+			}
+			keyPositions[key] = keyPos
+		}
+
+		keys = append(keys, key)
 		values = append(values, val)
 	}
 
 	dict := &cfDictionary{keys: keys, values: values}
+	if p.keepUIDDicts {
+		return dict
+	}
 	return dict.maybeUID(p.format == OpenStepFormat)
 }
 
@@ -397,9 +481,9 @@ outer:
 		}
 
 		pval := p.parsePlistValue() // whitespace is consumed within
-		if str, ok := pval.(cfString); ok && string(str) == "" {
-			// Empty strings in arrays are apparently skipped?
-			// TODO: Figure out why this was implemented.
+		if str, ok := pval.(cfString); ok && string(str) == "" && !p.keepEmptyArrayStrings {
+			// Empty strings in arrays are skipped by default, as they always have been.
+			// Decoder.SetKeepEmptyArrayStrings turns this off for lossless round-tripping.
 			continue
 		}
 		values = append(values, pval)
@@ -2,8 +2,12 @@ package plist
 
 import (
 	"bytes"
+	"encoding/binary"
 	"io/ioutil"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func BenchmarkOpenStepGenerate(b *testing.B) {
@@ -38,3 +42,220 @@ func BenchmarkGNUStepParse(b *testing.B) {
 }
 
 // The valid text test cases have been merged into the common/global test cases.
+
+func TestGNUStepFractionalSeconds(t *testing.T) {
+	date := time.Date(2013, 11, 27, 0, 34, 0, 250000000, time.UTC)
+
+	buf := &bytes.Buffer{}
+	enc := NewEncoderForFormat(buf, GNUStepFormat)
+	enc.SetGNUStepFractionalSeconds(true)
+	if err := enc.Encode(date); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "<*D2013-11-27 00:34:00.25 +0000>"
+	if buf.String() != expected {
+		t.Errorf("Expected %s, received %s", expected, buf.String())
+	}
+
+	var decoded time.Time
+	if _, err := Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(date) {
+		t.Errorf("Expected %v, received %v", date, decoded)
+	}
+
+	truncated, err := Marshal(date, GNUStepFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(truncated) != "<*D2013-11-27 00:34:00 +0000>" {
+		t.Errorf("unexpected default encoding %s", truncated)
+	}
+}
+
+func TestKeepEmptyArrayStrings(t *testing.T) {
+	input := []string{"", "a", "", "b", ""}
+	encoded, err := Marshal(input, OpenStepFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lossy []string
+	if _, err := Unmarshal(encoded, &lossy); err != nil {
+		t.Fatal(err)
+	}
+	if len(lossy) != 2 {
+		t.Errorf("Expected empty strings to be dropped by default, received %q", lossy)
+	}
+
+	var lossless []string
+	d := NewDecoder(bytes.NewReader(encoded))
+	d.SetKeepEmptyArrayStrings(true)
+	if err := d.Decode(&lossless); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lossless, input) {
+		t.Errorf("Expected %q, received %q (from %s)", input, lossless, encoded)
+	}
+}
+
+func TestStringsDuplicateKeys(t *testing.T) {
+	doc := "\"greeting\" = \"Hello\";\n\"farewell\" = \"Goodbye\";\n\"greeting\" = \"Hi\";\n"
+
+	var lenient map[string]string
+	if _, err := Unmarshal([]byte(doc), &lenient); err != nil {
+		t.Fatal(err)
+	}
+	if lenient["greeting"] != "Hi" {
+		t.Errorf("Expected the last definition to win, received %q", lenient["greeting"])
+	}
+
+	var strict map[string]string
+	d := NewDecoder(strings.NewReader(doc))
+	d.SetRejectDuplicateKeys(true)
+	err := d.Decode(&strict)
+	if err == nil {
+		t.Fatalf("Expected error, received %v", strict)
+	}
+
+	expected := `duplicate key "greeting" at line 2 character 0 (first defined at line 0 character 0)`
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected error to contain %q, received %q", expected, err.Error())
+	}
+}
+
+func TestUTF32TextPlist(t *testing.T) {
+	doc := `{ name = "Ünïcødé 😀"; list = (a, b); }`
+	expected := map[string]interface{}{
+		"name": "Ünïcødé 😀",
+		"list": []interface{}{"a", "b"},
+	}
+
+	encode := func(bo binary.ByteOrder, bom bool) []byte {
+		var buf bytes.Buffer
+		if bom {
+			binary.Write(&buf, bo, uint32(0xFEFF))
+		}
+		for _, r := range doc {
+			binary.Write(&buf, bo, uint32(r))
+		}
+		return buf.Bytes()
+	}
+
+	for _, bo := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		for _, bom := range []bool{true, false} {
+			var v map[string]interface{}
+			if _, err := Unmarshal(encode(bo, bom), &v); err != nil {
+				t.Errorf("%v (BOM: %v): %v", bo, bom, err)
+				continue
+			}
+			if !reflect.DeepEqual(v, expected) {
+				t.Errorf("%v (BOM: %v): Expected %v, received %v", bo, bom, expected, v)
+			}
+		}
+	}
+}
+
+func TestTextEncoding(t *testing.T) {
+	// "café" and a curly quote, in each encoding.
+	latin1 := []byte("{ name = \"caf\xE9\"; }")
+	macRoman := []byte("{ name = \"caf\x8E \xD2\"; }")
+
+	tests := []struct {
+		doc      []byte
+		enc      TextEncoding
+		expected string
+	}{
+		{latin1, Latin1TextEncoding, "café"},
+		{latin1, DetectTextEncoding, "café"},
+		{macRoman, MacRomanTextEncoding, "café “"},
+		{macRoman, DetectTextEncoding, "café “"},
+		{[]byte("{ name = \"café\"; }"), DetectTextEncoding, "café"},
+		{[]byte("\xEF\xBB\xBF{ name = \"café\"; }"), MacRomanTextEncoding, "café"},
+	}
+
+	for _, test := range tests {
+		var v map[string]string
+		d := NewDecoder(bytes.NewReader(test.doc), WithTextEncoding(test.enc))
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if v["name"] != test.expected {
+			t.Errorf("Expected %q from %q, got %q", test.expected, test.doc, v["name"])
+		}
+	}
+}
+
+func TestGNUStepBaseCompatibility(t *testing.T) {
+	value := map[string]interface{}{
+		"Name":  "Example App",
+		"Empty": map[string]interface{}{},
+		"Paths": []interface{}{"/usr/local", []interface{}{1, true}, []interface{}{}},
+		"Nested": map[string]interface{}{
+			"Deeper": map[string]interface{}{"Deepest": map[string]interface{}{"Value": 1.5}},
+		},
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoderForFormat(&buf, GNUStepFormat)
+	enc.SetGNUStepBaseCompatibility(true)
+	if err := enc.Encode(value); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "{\n" +
+		"    Empty = {};\n" +
+		"    Name = \"Example App\";\n" +
+		"    Nested = {\n" +
+		"\tDeeper = {\n" +
+		"\t    Deepest = {\n" +
+		"\t\tValue = <*R1.5>;\n" +
+		"\t    };\n" +
+		"\t};\n" +
+		"    };\n" +
+		"    Paths = (\n" +
+		"\t/usr/local,\n" +
+		"\t(\n" +
+		"\t    <*I1>,\n" +
+		"\t    <*BY>\n" +
+		"\t),\n" +
+		"\t()\n" +
+		"    );\n" +
+		"}"
+	if buf.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	enc = NewEncoderForFormat(&buf, OpenStepFormat)
+	enc.SetGNUStepBaseCompatibility(true)
+	if err := enc.Encode(value); err == nil {
+		t.Error("Expected an error using gnustep-base compatibility with OpenStep output")
+	}
+}
+
+func TestOpenStepISO8601Dates(t *testing.T) {
+	v := struct{ Date time.Time }{time.Date(2013, 11, 27, 0, 34, 0, 0, time.UTC)}
+
+	buf := &bytes.Buffer{}
+	enc := NewEncoderForFormat(buf, OpenStepFormat)
+	enc.SetOpenStepISO8601Dates(true)
+	if err := enc.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{Date="2013-11-27T00:34:00Z";}`
+	if buf.String() != expected {
+		t.Errorf("Expected %s, received %s", expected, buf.String())
+	}
+
+	for _, doc := range []string{buf.String(), `{ Date = "2013-11-27T09:34:00+09:00"; }`, `{ Date = "2013-11-27 00:34:00 +0000"; }`} {
+		var decoded struct{ Date time.Time }
+		if _, err := Unmarshal([]byte(doc), &decoded); err != nil {
+			t.Errorf("%s: %v", doc, err)
+		} else if !decoded.Date.Equal(v.Date) {
+			t.Errorf("%s: expected %v, received %v", doc, v.Date, decoded.Date)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package plist
+
+import (
+	"errors"
+	"io"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// RemoveValue can be returned by a TransformFunc to remove the value it was called for from
+// its containing dictionary or array. It is not returned as an error by any function.
+var RemoveValue = errors.New("remove this value")
+
+// A TransformFunc is called by Transform for each value in a property list. path holds the
+// dictionary keys and array indices (in decimal) that lead from the root to the value; the
+// root itself has an empty path. v holds the value in the form Unmarshal would use when
+// decoding into an empty interface. The maps and slices in v may be shared with the values
+// passed for its children, and must not be modified.
+//
+// To leave the value unchanged, the function returns nil and a nil error; Transform then
+// visits the value's children, if it has any. To replace the value, it returns the
+// replacement, which is marshaled as if by Marshal and whose children are not visited. To
+// remove the value, it returns RemoveValue as the error. Any other error stops Transform.
+type TransformFunc func(path []string, v interface{}) (interface{}, error)
+
+// Transform parses the property list in src, which may be in any format, passes each of its
+// values to fn and writes the transformed document to w in the format of the original.
+// Values that fn leaves unchanged are written exactly as they were parsed.
+// The root value cannot be removed.
+func Transform(w io.Writer, src []byte, fn TransformFunc) error {
+	return reencode(w, src, sameFormat, func(d *Decoder, enc *Encoder, pval cfValue) (cfValue, error) {
+		t := &transformer{decoder: d, encoder: enc, fn: fn, values: make(map[cfValue]interface{})}
+		pval, keep := t.transform(pval)
+		if !keep {
+			return nil, errors.New("plist: cannot remove the root value")
+		}
+		return pval, nil
+	})
+}
+
+type transformer struct {
+	decoder *Decoder
+	encoder *Encoder
+	fn      TransformFunc
+	path    []string
+
+	// values holds each array and dictionary already converted for fn, so that a container is
+	// converted once, however many containers (or paths, as binary property lists share their
+	// objects) it appears under.
+	values map[cfValue]interface{}
+}
+
+// value returns pval in the form Unmarshal would use when decoding into an empty interface.
+func (t *transformer) value(pval cfValue) interface{} {
+	switch pval := pval.(type) {
+	case *cfArray:
+		if v, ok := t.values[pval]; ok {
+			return v
+		}
+		out := make([]interface{}, len(pval.values))
+		for i, subval := range pval.values {
+			out[i] = t.value(subval)
+		}
+		t.values[pval] = out
+		return out
+	case *cfDictionary:
+		if v, ok := t.values[pval]; ok {
+			return v
+		}
+		out := make(map[string]interface{}, len(pval.keys))
+		for i, k := range pval.keys {
+			out[k] = t.value(pval.values[i])
+		}
+		t.values[pval] = out
+		return out
+	}
+	return t.decoder.valueInterface(pval)
+}
+
+// transform returns the value to store in place of pval, and whether to keep it at all. pval
+// itself is never modified, as a binary property list may share it between several paths;
+// containers are copied instead.
+func (t *transformer) transform(pval cfValue) (cfValue, bool) {
+	replacement, err := t.fn(t.path, t.value(pval))
+	if err == RemoveValue {
+		return nil, false
+	} else if err != nil {
+		panic(err)
+	}
+
+	if replacement != nil {
+		newval := t.encoder.marshal(reflect.ValueOf(replacement))
+		if newval == nil {
+			return nil, false
+		}
+		return newval, true
+	}
+
+	switch pval := pval.(type) {
+	case *cfArray:
+		a := &cfArray{values: make([]cfValue, 0, len(pval.values))}
+		for i, subval := range pval.values {
+			t.path = append(t.path, strconv.Itoa(i))
+			if newval, keep := t.transform(subval); keep {
+				a.values = append(a.values, newval)
+			}
+			t.path = t.path[:len(t.path)-1]
+		}
+		return a, true
+	case *cfDictionary:
+		dict := &cfDictionary{
+			keys:    make([]string, 0, len(pval.keys)),
+			values:  make([]cfValue, 0, len(pval.values)),
+			ordered: pval.ordered,
+		}
+		for i, subval := range pval.values {
+			key := pval.keys[i]
+			t.path = append(t.path, key)
+			if newval, keep := t.transform(subval); keep {
+				dict.keys = append(dict.keys, key)
+				dict.values = append(dict.values, newval)
+			}
+			t.path = t.path[:len(t.path)-1]
+		}
+		return dict, true
+	}
+	return pval, true
+}
+
+// MatchPath reports whether the path of a value, as passed to a TransformFunc, matches
+// pattern. The pattern is a list of elements separated by slashes; each element matches
+// one path element using the syntax of path.Match, except for "**", which matches any
+// number of path elements (including none). For example, "**/Password" matches a Password
+// key anywhere in a document, and "Accounts/*/Serial" matches the Serial key of every
+// member of Accounts.
+func MatchPath(pattern string, elems []string) bool {
+	pattern = strings.Trim(pattern, "/")
+	if pattern == "" {
+		return len(elems) == 0
+	}
+	return matchPath(strings.Split(pattern, "/"), elems)
+}
+
+func matchPath(pattern, elems []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(elems); i++ {
+				if matchPath(pattern[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], elems[0]); !ok {
+			return false
+		}
+		pattern, elems = pattern[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
@@ -0,0 +1,127 @@
+package plist
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	src := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+	<key>Accounts</key><array>
+		<dict><key>Name</key><string>work</string><key>Password</key><string>hunter2</string><key>Serial</key><string>C02XYZ</string></dict>
+		<dict><key>Name</key><string>home</string><key>Password</key><string>letmein</string></dict>
+	</array>
+	<key>Debug</key><dict><key>Password</key><string>root</string><key>Scratch</key><data>AAEC</data></dict>
+	<key>Ratio</key><real>2</real>
+</dict></plist>`)
+
+	buf := &bytes.Buffer{}
+	err := Transform(buf, src, func(path []string, v interface{}) (interface{}, error) {
+		switch {
+		case MatchPath("**/Password", path):
+			return "<redacted>", nil
+		case MatchPath("Accounts/*/Serial", path), MatchPath("Debug/Scratch", path):
+			return nil, RemoveValue
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	format, err := Unmarshal(buf.Bytes(), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != XMLFormat {
+		t.Errorf("Expected the document to stay XML, received %s", FormatNames[format])
+	}
+
+	expected := map[string]interface{}{
+		"Accounts": []interface{}{
+			map[string]interface{}{"Name": "work", "Password": "<redacted>"},
+			map[string]interface{}{"Name": "home", "Password": "<redacted>"},
+		},
+		"Debug": map[string]interface{}{"Password": "<redacted>"},
+		"Ratio": float64(2),
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Expected %v, received %v", expected, decoded)
+	}
+
+	stop := errors.New("stop")
+	err = Transform(&bytes.Buffer{}, src, func(path []string, v interface{}) (interface{}, error) {
+		return nil, stop
+	})
+	if err != stop {
+		t.Errorf("Expected the callback's error, received %v", err)
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    []string
+		match   bool
+	}{
+		{"", nil, true},
+		{"", []string{"a"}, false},
+		{"a/b", []string{"a", "b"}, true},
+		{"/a/b/", []string{"a", "b"}, true},
+		{"a/*", []string{"a", "b"}, true},
+		{"a/*", []string{"a", "b", "c"}, false},
+		{"**/c", []string{"c"}, true},
+		{"**/c", []string{"a", "b", "c"}, true},
+		{"a/**", []string{"a"}, true},
+		{"a/**/d", []string{"a", "b", "c", "d"}, true},
+		{"a/**/d", []string{"a", "b", "c"}, false},
+		{"Serial*", []string{"SerialNumber"}, true},
+	}
+
+	for _, test := range tests {
+		if match := MatchPath(test.pattern, test.path); match != test.match {
+			t.Errorf("MatchPath(%q, %q) = %v, expected %v", test.pattern, test.path, match, test.match)
+		}
+	}
+}
+
+func TestTransformSharedObjects(t *testing.T) {
+	// A and B both refer to the same dictionary.
+	src := buildBplist(
+		[]byte{0xD2, 1, 2, 3, 3},
+		[]byte{0x51, 'A'},
+		[]byte{0x51, 'B'},
+		[]byte{0xD2, 4, 6, 5, 7},
+		[]byte{0x56, 'S', 'e', 'r', 'i', 'a', 'l'},
+		[]byte{0x53, 'C', '0', '2'},
+		[]byte{0x54, 'N', 'a', 'm', 'e'},
+		[]byte{0x54, 'w', 'o', 'r', 'k'},
+	)
+
+	buf := &bytes.Buffer{}
+	err := Transform(buf, src, func(path []string, v interface{}) (interface{}, error) {
+		if MatchPath("A/Serial", path) {
+			return nil, RemoveValue
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]map[string]string
+	if _, err := Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]map[string]string{
+		"A": {"Name": "work"},
+		"B": {"Name": "work", "Serial": "C02"},
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Expected %v, received %v", expected, decoded)
+	}
+}
@@ -0,0 +1,567 @@
+// Package typedstream decodes the archives written by NSArchiver, the predecessor of
+// NSKeyedArchiver, which are known by the "typedstream" format they use.
+//
+// Typedstreams still turn up in data values inside property lists, such as old preference
+// files and the attachments stored by Notes and Messages. A typedstream is a sequence of
+// groups of values, each group introduced by an Objective-C type encoding ("@" for an object,
+// "i" for an int, "{_NSRange=QQ}" for a structure, and so on.) Objects are stored with their
+// class hierarchy, followed by whatever groups of values their classes chose to write.
+//
+// Since every value is self-describing, the whole stream can be decoded without knowing the
+// classes involved. Objects of common Foundation classes are decoded into native values, as
+// listed at Unarchive; every other object is returned as an *Object holding its groups of values.
+package typedstream
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"howett.net/plist"
+)
+
+const (
+	signatureLittleEndian = "streamtyped"
+	signatureBigEndian    = "typedstream"
+)
+
+// Tags are stored where an integer would otherwise begin; single-byte integers therefore
+// cannot take their values.
+const (
+	tagInteger2      = -127 // a 2-byte integer follows
+	tagInteger4      = -126 // a 4-byte integer follows
+	tagFloatingPoint = -125 // a float or double follows
+	tagNew           = -124 // a new object, class or string follows
+	tagNil           = -123
+	tagEndOfObject   = -122
+
+	firstTag = -128
+	lastTag  = -111
+
+	// referenceBase is the integer that refers to the first shared string or object.
+	referenceBase = -110
+)
+
+// maxDepth bounds the nesting of objects and structures, to protect the stack from
+// maliciously deep streams.
+const maxDepth = 512
+
+// A Class is an archived class: its name, the version of its archived form, and its superclass.
+type Class struct {
+	Name    string
+	Version int64
+	Super   *Class
+}
+
+// An Object is an archived instance of a class that is not decoded into a native value.
+type Object struct {
+	Class *Class
+
+	// Contents holds the groups of values the object archived, in order.
+	Contents []Group
+}
+
+// A Group is a group of values archived together, with the type encoding that describes them.
+//
+// Values are decoded according to their type: signed integers ("c", "s", "i", "l" and "q")
+// as int64, unsigned integers as uint64, "f" as float32, "d" as float64, C strings ("*"),
+// atoms ("%") and selectors (":") as string, "+" (the byte strings used by NSString) as
+// []byte, classes ("#") as *Class, objects ("@") as described at Unarchive, arrays of chars
+// as []byte, other arrays as []interface{}, and structures as Struct. Nil strings, classes and
+// objects are nil.
+type Group struct {
+	Encoding string
+	Values   []interface{}
+}
+
+// A Struct is an archived C structure.
+type Struct struct {
+	Name   string
+	Fields []interface{}
+}
+
+// Is reports whether data begins with a typedstream header.
+func Is(data []byte) bool {
+	return len(data) >= 13 && data[1] == 11 &&
+		(string(data[2:13]) == signatureLittleEndian || string(data[2:13]) == signatureBigEndian)
+}
+
+// Unarchive decodes the typedstream in data and returns the object archived as its root, as
+// written by +[NSArchiver archivedDataWithRootObject:]. Instances of common Foundation classes
+// are decoded into native values:
+//
+//	NSString, NSMutableString                   string
+//	NSAttributedString (and mutable)            string, without its attributes
+//	NSData, NSMutableData                       []byte
+//	NSDate                                      time.Time
+//	NSNumber                                    int64, uint64, float32 or float64
+//	NSArray, NSSet (and mutable)                []interface{}
+//	NSDictionary (and mutable)                  map[string]interface{}, if its keys are strings
+//
+// Every other object is returned as an *Object.
+func Unarchive(data []byte) (interface{}, error) {
+	groups, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 || len(groups[0].Values) == 0 {
+		return nil, fmt.Errorf("typedstream: stream is empty")
+	}
+	return groups[0].Values[0], nil
+}
+
+// Decode decodes every group of values in the typedstream in data.
+func Decode(data []byte) (groups []Group, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(streamError); ok {
+				err = e
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	d := &decoder{data: data}
+	d.readHeader()
+	for d.pos < len(d.data) {
+		groups = append(groups, d.readGroup(d.next()))
+	}
+	return groups, nil
+}
+
+type streamError struct {
+	error
+}
+
+// decoder holds the state of a single typedstream as it is decoded.
+type decoder struct {
+	data  []byte
+	pos   int
+	order binary.ByteOrder
+	depth int
+
+	strings []string      // shared strings, by reference number
+	objects []interface{} // classes, C strings and objects, by reference number
+}
+
+func (d *decoder) fail(format string, args ...interface{}) {
+	panic(streamError{fmt.Errorf("typedstream: "+format, args...)})
+}
+
+func (d *decoder) readHeader() {
+	if !Is(d.data) {
+		d.fail("not a typedstream")
+	}
+	if version := d.data[0]; version != 3 && version != 4 {
+		d.fail("unsupported streamer version %d", version)
+	}
+
+	if string(d.data[2:13]) == signatureBigEndian {
+		d.order = binary.BigEndian
+	} else {
+		d.order = binary.LittleEndian
+	}
+	d.pos = 13
+	d.readInteger(d.next(), true) // system version
+}
+
+// next returns the next byte, which begins an integer or a tagged value.
+func (d *decoder) next() int8 {
+	if d.pos >= len(d.data) {
+		d.fail("unexpected end of stream")
+	}
+	d.pos++
+	return int8(d.data[d.pos-1])
+}
+
+func (d *decoder) read(n int) []byte {
+	if n < 0 || n > len(d.data)-d.pos {
+		d.fail("%d bytes at offset %d run past the end of the stream", n, d.pos)
+	}
+	d.pos += n
+	return d.data[d.pos-n : d.pos]
+}
+
+func (d *decoder) readInteger(head int8, signed bool) int64 {
+	switch head {
+	case tagInteger2:
+		v := d.order.Uint16(d.read(2))
+		if signed {
+			return int64(int16(v))
+		}
+		return int64(v)
+	case tagInteger4:
+		v := d.order.Uint32(d.read(4))
+		if signed {
+			return int64(int32(v))
+		}
+		return int64(v)
+	}
+
+	if head >= firstTag && head <= lastTag {
+		d.fail("unexpected tag %d at offset %d", head, d.pos-1)
+	}
+	if signed {
+		return int64(head)
+	}
+	return int64(uint8(head))
+}
+
+func (d *decoder) readFloat(head int8, size int) float64 {
+	if head != tagFloatingPoint {
+		return float64(d.readInteger(head, true))
+	}
+	if size == 4 {
+		return float64(math.Float32frombits(d.order.Uint32(d.read(4))))
+	}
+	return math.Float64frombits(d.order.Uint64(d.read(8)))
+}
+
+// reference returns the index in a table of length n referred to by the integer beginning
+// with head.
+func (d *decoder) reference(head int8, n int) int {
+	i := d.readInteger(head, true) - referenceBase
+	if i < 0 || i >= int64(n) {
+		d.fail("reference to #%d is out of range (only %d exist)", i, n)
+	}
+	return int(i)
+}
+
+// readUnsharedString reads a length-prefixed string of bytes.
+func (d *decoder) readUnsharedString(head int8) []byte {
+	if head == tagNil {
+		return nil
+	}
+	return d.read(int(d.readInteger(head, false)))
+}
+
+// readSharedString reads a string that is stored once and referred to thereafter, such as a
+// type encoding or a class name.
+func (d *decoder) readSharedString(head int8) (string, bool) {
+	switch head {
+	case tagNil:
+		return "", false
+	case tagNew:
+		s := d.readUnsharedString(d.next())
+		if s == nil {
+			d.fail("shared string at offset %d is nil", d.pos)
+		}
+		d.strings = append(d.strings, string(s))
+		return string(s), true
+	}
+	return d.strings[d.reference(head, len(d.strings))], true
+}
+
+// readCString reads a C string, which is shared as an object.
+func (d *decoder) readCString(head int8) interface{} {
+	switch head {
+	case tagNil:
+		return nil
+	case tagNew:
+		s, ok := d.readSharedString(d.next())
+		if !ok {
+			d.fail("C string at offset %d is nil", d.pos)
+		}
+		d.objects = append(d.objects, s)
+		return s
+	}
+	s, ok := d.objects[d.reference(head, len(d.objects))].(string)
+	if !ok {
+		d.fail("C string at offset %d refers to an object", d.pos)
+	}
+	return s
+}
+
+// readClass reads a class and its superclasses. Each class not seen before is stored in full,
+// and the chain ends with nil or a reference to a class that was.
+func (d *decoder) readClass(head int8) *Class {
+	var first, last *Class
+	for ; head == tagNew; head = d.next() {
+		name, ok := d.readSharedString(d.next())
+		if !ok {
+			d.fail("class at offset %d has no name", d.pos)
+		}
+		c := &Class{Name: name, Version: d.readInteger(d.next(), true)}
+		d.objects = append(d.objects, c)
+		if last == nil {
+			first = c
+		} else {
+			last.Super = c
+		}
+		last = c
+	}
+
+	var tail *Class
+	if head != tagNil {
+		var ok bool
+		tail, ok = d.objects[d.reference(head, len(d.objects))].(*Class)
+		if !ok {
+			d.fail("class at offset %d refers to an object", d.pos)
+		}
+	}
+	if last == nil {
+		return tail
+	}
+	last.Super = tail
+	return first
+}
+
+func (d *decoder) readObject(head int8) interface{} {
+	switch head {
+	case tagNil:
+		return nil
+	case tagNew:
+	default:
+		return d.objects[d.reference(head, len(d.objects))]
+	}
+
+	d.enter()
+	defer d.leave()
+
+	// The object is registered before its class, so that its contents can refer to it.
+	i := len(d.objects)
+	obj := &Object{}
+	d.objects = append(d.objects, obj)
+
+	obj.Class = d.readClass(d.next())
+	if obj.Class == nil {
+		d.fail("object at offset %d has no class", d.pos)
+	}
+	for head := d.next(); head != tagEndOfObject; head = d.next() {
+		obj.Contents = append(obj.Contents, d.readGroup(head))
+	}
+
+	v := decodeFoundation(obj)
+	d.objects[i] = v
+	return v
+}
+
+func (d *decoder) enter() {
+	d.depth++
+	if d.depth > maxDepth {
+		d.fail("values are nested more than %d deep", maxDepth)
+	}
+}
+
+func (d *decoder) leave() {
+	d.depth--
+}
+
+func (d *decoder) readGroup(head int8) Group {
+	encoding, ok := d.readSharedString(head)
+	if !ok {
+		d.fail("group at offset %d has no type encoding", d.pos)
+	}
+
+	g := Group{Encoding: encoding}
+	for _, typ := range d.splitEncoding(encoding) {
+		g.Values = append(g.Values, d.readValue(typ))
+	}
+	return g
+}
+
+func (d *decoder) readValue(typ string) interface{} {
+	switch typ[0] {
+	case 'c', 's', 'i', 'l', 'q':
+		return d.readInteger(d.next(), true)
+	case 'C', 'S', 'I', 'L', 'Q', 'B':
+		return uint64(d.readInteger(d.next(), false))
+	case 'f':
+		return float32(d.readFloat(d.next(), 4))
+	case 'd':
+		return d.readFloat(d.next(), 8)
+	case '*':
+		return d.readCString(d.next())
+	case '%', ':':
+		if s, ok := d.readSharedString(d.next()); ok {
+			return s
+		}
+		return nil
+	case '+':
+		if b := d.readUnsharedString(d.next()); b != nil {
+			return append([]byte(nil), b...)
+		}
+		return nil
+	case '#':
+		if c := d.readClass(d.next()); c != nil {
+			return c
+		}
+		return nil
+	case '@':
+		return d.readObject(d.next())
+	case '[':
+		n, elem := d.splitArray(typ)
+		if elem == "c" || elem == "C" {
+			return append([]byte(nil), d.read(n)...)
+		}
+		if n > len(d.data)-d.pos {
+			d.fail("array %s is longer than the rest of the stream", typ)
+		}
+		d.enter()
+		defer d.leave()
+		values := make([]interface{}, n)
+		for i := range values {
+			values[i] = d.readValue(elem)
+		}
+		return values
+	case '{':
+		d.enter()
+		defer d.leave()
+		name, fields := typ[1:len(typ)-1], ""
+		if i := strings.IndexByte(name, '='); i >= 0 {
+			name, fields = name[:i], name[i+1:]
+		}
+		s := Struct{Name: name}
+		for _, field := range d.splitEncoding(fields) {
+			s.Fields = append(s.Fields, d.readValue(field))
+		}
+		return s
+	}
+	d.fail("unsupported type encoding %q", typ)
+	return nil
+}
+
+// splitEncoding splits a type encoding into the encodings of the values it describes,
+// discarding type qualifiers such as "r" (const).
+func (d *decoder) splitEncoding(encoding string) []string {
+	var types []string
+	for i := 0; i < len(encoding); {
+		if strings.IndexByte("rnNoORV", encoding[i]) >= 0 {
+			i++
+			continue
+		}
+		end := d.typeEnd(encoding, i)
+		types = append(types, encoding[i:end])
+		i = end
+	}
+	return types
+}
+
+// typeEnd returns the end of the type encoding beginning at encoding[i].
+func (d *decoder) typeEnd(encoding string, i int) int {
+	switch encoding[i] {
+	case '[', '{', '(':
+		depth := 0
+		for j := i; j < len(encoding); j++ {
+			switch encoding[j] {
+			case '[', '{', '(':
+				depth++
+			case ']', '}', ')':
+				depth--
+				if depth == 0 {
+					return j + 1
+				}
+			}
+		}
+		d.fail("unterminated type encoding %q", encoding)
+	case '^':
+		if i+1 < len(encoding) {
+			return d.typeEnd(encoding, i+1)
+		}
+		d.fail("incomplete type encoding %q", encoding)
+	}
+	return i + 1
+}
+
+// splitArray returns the length and element type of an array type encoding, such as "[16c]".
+func (d *decoder) splitArray(typ string) (int, string) {
+	inner := typ[1 : len(typ)-1]
+	i := 0
+	for i < len(inner) && inner[i] >= '0' && inner[i] <= '9' {
+		i++
+	}
+	n, err := strconv.Atoi(inner[:i])
+	if err != nil || i == len(inner) {
+		d.fail("invalid array type encoding %q", typ)
+	}
+	return n, inner[i:]
+}
+
+// decodeFoundation converts an object of a common Foundation class into a native value. Objects
+// whose contents do not have the expected shape are returned unchanged.
+func decodeFoundation(obj *Object) interface{} {
+	c := obj.Contents
+	switch obj.Class.Name {
+	case "NSString", "NSMutableString":
+		if len(c) == 1 && c[0].Encoding == "+" {
+			if b, ok := c[0].Values[0].([]byte); ok {
+				return string(b)
+			}
+		}
+	case "NSAttributedString", "NSMutableAttributedString":
+		if len(c) > 0 && c[0].Encoding == "@" {
+			if s, ok := c[0].Values[0].(string); ok {
+				return s
+			}
+		}
+	case "NSData", "NSMutableData":
+		if len(c) == 2 && strings.HasPrefix(c[1].Encoding, "[") {
+			if b, ok := c[1].Values[0].([]byte); ok {
+				return b
+			}
+		}
+	case "NSDate":
+		if len(c) == 1 && c[0].Encoding == "d" {
+			return plist.TimeFromAbsoluteTime(c[0].Values[0].(float64))
+		}
+	case "NSNumber":
+		// NSNumber is archived as an NSValue: the type encoding of its value, then the value.
+		if len(c) == 2 && c[0].Encoding == "*" && len(c[1].Values) == 1 {
+			switch v := c[1].Values[0].(type) {
+			case int64, uint64, float32, float64:
+				return v
+			}
+		}
+	case "NSArray", "NSMutableArray", "NSSet", "NSMutableSet":
+		if values, ok := countedObjects(c, 1); ok {
+			return values
+		}
+	case "NSDictionary", "NSMutableDictionary":
+		values, ok := countedObjects(c, 2)
+		if !ok {
+			break
+		}
+		m := make(map[string]interface{}, len(values)/2)
+		for i := 0; i < len(values); i += 2 {
+			k, ok := values[i].(string)
+			if !ok {
+				return obj
+			}
+			m[k] = values[i+1]
+		}
+		return m
+	}
+	return obj
+}
+
+// countedObjects returns the objects in groups that begin with a count, followed by count
+// multiples of per objects.
+func countedObjects(groups []Group, per int) ([]interface{}, bool) {
+	if len(groups) == 0 || len(groups[0].Values) != 1 {
+		return nil, false
+	}
+	var count int64
+	switch n := groups[0].Values[0].(type) {
+	case int64:
+		count = n
+	case uint64:
+		count = int64(n)
+	default:
+		return nil, false
+	}
+
+	values := []interface{}{}
+	for _, g := range groups[1:] {
+		if strings.Trim(g.Encoding, "@") != "" {
+			return nil, false
+		}
+		values = append(values, g.Values...)
+	}
+	if int64(len(values)) != count*int64(per) {
+		return nil, false
+	}
+	return values, true
+}
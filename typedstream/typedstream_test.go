@@ -0,0 +1,134 @@
+package typedstream
+
+import (
+	"reflect"
+	"testing"
+)
+
+// stream assembles a typedstream from strings, which are written with a length prefix, and
+// byte values.
+func stream(parts ...interface{}) []byte {
+	b := []byte{0x04, 0x0B}
+	b = append(b, "streamtyped"...)
+	b = append(b, 0x81, 0xE8, 0x03)
+	for _, p := range parts {
+		switch p := p.(type) {
+		case string:
+			b = append(b, byte(len(p)))
+			b = append(b, p...)
+		case int:
+			b = append(b, byte(p))
+		case []byte:
+			b = append(b, p...)
+		}
+	}
+	return b
+}
+
+const (
+	n    = 0x84 // new
+	null = 0x85
+	end  = 0x86
+)
+
+// ref returns the reference to the shared string or object with the given index.
+func ref(i int) int {
+	return 0x92 + i
+}
+
+func TestUnarchiveFoundation(t *testing.T) {
+	data := stream(
+		// Shared strings: 0 "@", 1 "NSDictionary", 2 "NSObject", 3 "i", 4 "NSString", 5 "+",
+		// 6 "NSArray", 7 "NSNumber", 8 "NSValue", 9 "*", 10 "d", 11 "NSData", 12 "[3c]".
+		// Objects: 0 the dictionary, 1 NSDictionary, 2 NSObject, 3 "name", 4 NSString,
+		// 5 "hello", 6 "items", 7 the array, 8 NSArray, 9 42, 10 NSNumber, 11 NSValue, 12 "i",
+		// 13 1.5, 14 "d", 15 the data, 16 NSData.
+		n, "@", n, n, n, "NSDictionary", 0, n, n, "NSObject", 0, null,
+		n, "i", 2,
+		ref(0), n, n, n, "NSString", 1, ref(2), n, "+", "name", end,
+		ref(0), n, ref(4), ref(5), "hello", end,
+		ref(0), n, ref(4), ref(5), "items", end,
+		ref(0), n, n, n, "NSArray", 0, ref(2), ref(3), 3,
+		ref(0), n, n, n, "NSNumber", 0, n, n, "NSValue", 0, ref(2),
+		n, "*", n, ref(3), ref(3), 42, end,
+		ref(0), n, ref(10), ref(9), n, n, "d", ref(10), []byte{0x83, 0, 0, 0, 0, 0, 0, 0xF8, 0x3F}, end,
+		ref(0), n, n, n, "NSData", 0, ref(2), ref(3), 3, n, "[3c]", []byte{1, 2, 3}, end,
+		end,
+		end,
+		// A second group, referring back to the dictionary.
+		ref(0), ref(0),
+	)
+
+	if !Is(data) {
+		t.Fatal("Expected the stream to be recognized")
+	}
+
+	groups, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"name":  "hello",
+		"items": []interface{}{int64(42), 1.5, []byte{1, 2, 3}},
+	}
+	if len(groups) != 2 || !reflect.DeepEqual(groups[0].Values[0], expected) {
+		t.Fatalf("Expected %v, got %+v", expected, groups)
+	}
+	if reflect.ValueOf(groups[1].Values[0]).Pointer() != reflect.ValueOf(groups[0].Values[0]).Pointer() {
+		t.Error("Expected a reference to the dictionary to return the same value")
+	}
+
+	root, err := Unarchive(data)
+	if err != nil || !reflect.DeepEqual(root, expected) {
+		t.Errorf("Expected root %v, got %v (%v)", expected, root, err)
+	}
+}
+
+func TestUnarchiveObject(t *testing.T) {
+	data := stream(
+		n, "@", n, n, n, "Point", 2, n, n, "NSObject", 0, null,
+		n, "{_P=ff}", []byte{0x83, 0, 0, 0x80, 0x3F}, 3,
+		n, ":", n, "drawRect:",
+		end,
+	)
+
+	root, err := Unarchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Object{
+		Class: &Class{Name: "Point", Version: 2, Super: &Class{Name: "NSObject"}},
+		Contents: []Group{
+			{Encoding: "{_P=ff}", Values: []interface{}{Struct{Name: "_P", Fields: []interface{}{float32(1), float32(3)}}}},
+			{Encoding: ":", Values: []interface{}{"drawRect:"}},
+		},
+	}
+	if !reflect.DeepEqual(root, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, root)
+	}
+}
+
+func TestUnarchiveInvalid(t *testing.T) {
+	valid := stream(n, "@", n, n, n, "NSString", 1, null, n, "+", "hello", end)
+	if _, err := Unarchive(valid); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 13; i < len(valid); i++ {
+		if i == 16 {
+			continue // a header alone is an empty stream
+		}
+		if _, err := Decode(valid[:i]); err == nil {
+			t.Errorf("Expected an error decoding %d bytes", i)
+		}
+	}
+
+	if _, err := Decode(stream(n, "@", ref(5))); err == nil {
+		t.Error("Expected an error for an out-of-range reference")
+	}
+	if _, err := Decode([]byte("bplist00")); err == nil {
+		t.Error("Expected an error for data that is not a typedstream")
+	}
+}
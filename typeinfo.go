@@ -2,31 +2,17 @@ package plist
 
 import (
 	"reflect"
-	"strings"
 	"sync"
-)
 
-func isEmptyValue(v reflect.Value) bool {
-	switch v.Kind() {
-	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
-		return v.Len() == 0
-	case reflect.Bool:
-		return !v.Bool()
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return v.Int() == 0
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		return v.Uint() == 0
-	case reflect.Float32, reflect.Float64:
-		return v.Float() == 0
-	case reflect.Interface, reflect.Ptr:
-		return v.IsNil()
-	}
-	return false
-}
+	"howett.net/plist/internal/plisttag"
+)
 
 // typeInfo holds details for the plist representation of a type.
 type typeInfo struct {
 	fields []fieldInfo
+
+	// fieldIndex maps each field's name to its position in fields.
+	fieldIndex map[string]int
 }
 
 // fieldInfo holds details for the plist representation of a single field.
@@ -47,6 +33,14 @@ type fieldInfo struct {
 	// As an optimization, we store it as a bit field. This means anonymous embedded structs more than 64 entries
 	// may forget their omitempty states.
 	omitEmptyDepthMap uint64
+
+	// duration is the format chosen by the field's tag for the durations it holds, if any.
+	duration *DurationFormat
+
+	// embeddedInterface is set for an untagged anonymous interface field. When it holds a struct
+	// (or a pointer to one), that struct's fields are promoted into the outer struct's dictionary,
+	// as an embedded struct's would be.
+	embeddedInterface bool
 }
 
 var tinfoMap = make(map[reflect.Type]*typeInfo)
@@ -66,8 +60,8 @@ func getTypeInfo(typ reflect.Type) (*typeInfo, error) {
 		n := typ.NumField()
 		for i := 0; i < n; i++ {
 			f := typ.Field(i)
-			if f.PkgPath != "" || f.Tag.Get("plist") == "-" {
-				continue // Private field
+			if plisttag.Ignored(f) {
+				continue // Private or excluded field
 			}
 
 			finfo, err := structFieldInfo(typ, &f)
@@ -103,6 +97,10 @@ func getTypeInfo(typ reflect.Type) (*typeInfo, error) {
 			}
 		}
 	}
+	tinfo.fieldIndex = make(map[string]int, len(tinfo.fields))
+	for i := range tinfo.fields {
+		tinfo.fieldIndex[tinfo.fields[i].name] = i
+	}
 	tinfoLock.Lock()
 	tinfoMap[typ] = tinfo
 	tinfoLock.Unlock()
@@ -113,19 +111,15 @@ func getTypeInfo(typ reflect.Type) (*typeInfo, error) {
 func structFieldInfo(typ reflect.Type, f *reflect.StructField) (*fieldInfo, error) {
 	finfo := &fieldInfo{idx: f.Index}
 
-	// Split the tag from the xml namespace if necessary.
-	tag := f.Tag.Get("plist")
-
 	// Parse flags.
-	tokens := strings.Split(tag, ",")
-	tag = tokens[0]
-	if len(tokens) > 1 {
-		tag = tokens[0]
-		for _, flag := range tokens[1:] {
-			switch flag {
-			case "omitempty":
-				finfo.omitEmptyDepthMap = 1 << uint(len(f.Index)-1)
-			}
+	tag, flags := plisttag.Parse(*f)
+	for _, flag := range flags {
+		switch flag {
+		case "omitempty":
+			finfo.omitEmptyDepthMap = 1 << uint(len(f.Index)-1)
+		case "nanoseconds", "seconds", "durationstring":
+			format := durationFlags[flag]
+			finfo.duration = &format
 		}
 	}
 
@@ -133,6 +127,7 @@ func structFieldInfo(typ reflect.Type, f *reflect.StructField) (*fieldInfo, erro
 		// If the name part of the tag is completely empty,
 		// use the field name
 		finfo.name = f.Name
+		finfo.embeddedInterface = f.Anonymous && f.Type.Kind() == reflect.Interface
 		return finfo, nil
 	}
 
@@ -213,7 +208,7 @@ func (finfo *fieldInfo) value(v reflect.Value) reflect.Value {
 
 		v = v.Field(x)
 
-		if (finfo.omitEmptyDepthMap&(1<<uint(i))) != 0 && isEmptyValue(v) {
+		if (finfo.omitEmptyDepthMap&(1<<uint(i))) != 0 && plisttag.IsEmpty(v) {
 			return reflect.Value{}
 		}
 	}
@@ -0,0 +1,139 @@
+package plist
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// A UIDResolver follows UID references into a document's object table, such as the $objects
+// array of a keyed archive. It resolves references only as they are reached, and remembers the
+// result of resolving each one so that objects referred to more than once are resolved once and
+// shared.
+type UIDResolver struct {
+	lookup func(UID) (interface{}, bool)
+	size   int
+
+	resolved  map[UID]interface{}
+	resolving map[UID]bool // references to references being followed
+}
+
+// NewUIDResolver returns a UIDResolver for the object table table, which is either an array
+// indexed by UID or a dictionary whose keys are UIDs in decimal, in the form Unmarshal uses when
+// decoding into an empty interface.
+func NewUIDResolver(table interface{}) (*UIDResolver, error) {
+	r := &UIDResolver{resolved: make(map[UID]interface{}), resolving: make(map[UID]bool)}
+	switch table := table.(type) {
+	case []interface{}:
+		r.size = len(table)
+		r.lookup = func(uid UID) (interface{}, bool) {
+			if uint64(uid) >= uint64(len(table)) {
+				return nil, false
+			}
+			return table[uid], true
+		}
+	case map[string]interface{}:
+		r.size = len(table)
+		r.lookup = func(uid UID) (interface{}, bool) {
+			v, ok := table[strconv.FormatUint(uint64(uid), 10)]
+			return v, ok
+		}
+	default:
+		return nil, fmt.Errorf("plist: an object table must be an array or a dictionary, not %T", table)
+	}
+	return r, nil
+}
+
+// Lookup returns the entry in the object table that uid refers to, without resolving any
+// references it contains.
+func (r *UIDResolver) Lookup(uid UID) (interface{}, error) {
+	v, ok := r.lookup(uid)
+	if !ok {
+		return nil, fmt.Errorf("plist: UID %d does not refer to any of the %d objects in the table", uid, r.size)
+	}
+	return v, nil
+}
+
+// Resolve returns a copy of v in which every UID, including those inside the objects they
+// refer to, is replaced by the object it refers to. Each object is resolved once, to a single
+// map or slice that is shared by every reference to it, including references from the results
+// of earlier calls. An array or dictionary that refers to itself, directly or indirectly,
+// therefore resolves to a map or slice that contains itself; code that walks the result must
+// allow for such cycles. An object table entry that is itself a reference leading back to it is
+// left as a UID.
+func (r *UIDResolver) Resolve(v interface{}) (interface{}, error) {
+	resolved, err := r.resolve(v)
+	if err != nil {
+		// Objects left partly resolved must not be shared by later calls.
+		r.resolved = make(map[UID]interface{})
+		r.resolving = make(map[UID]bool)
+		return nil, err
+	}
+	return resolved, nil
+}
+
+func (r *UIDResolver) resolve(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case UID:
+		if resolved, ok := r.resolved[v]; ok {
+			return resolved, nil
+		}
+		if r.resolving[v] {
+			return v, nil
+		}
+		obj, err := r.Lookup(v)
+		if err != nil {
+			return nil, err
+		}
+
+		// An array or dictionary is recorded before its contents are resolved, so that
+		// references back to it from inside resolve to it as well.
+		switch obj := obj.(type) {
+		case []interface{}:
+			resolved := make([]interface{}, len(obj))
+			r.resolved[v] = resolved
+			return resolved, r.resolveArray(resolved, obj)
+		case map[string]interface{}:
+			resolved := make(map[string]interface{}, len(obj))
+			r.resolved[v] = resolved
+			return resolved, r.resolveDictionary(resolved, obj)
+		}
+
+		r.resolving[v] = true
+		resolved, err := r.resolve(obj)
+		delete(r.resolving, v)
+		if err != nil {
+			return nil, err
+		}
+		r.resolved[v] = resolved
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		return resolved, r.resolveArray(resolved, v)
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(v))
+		return resolved, r.resolveDictionary(resolved, v)
+	}
+	return v, nil
+}
+
+// resolveArray stores the resolution of each element of v in resolved.
+func (r *UIDResolver) resolveArray(resolved, v []interface{}) (err error) {
+	for i, subv := range v {
+		if resolved[i], err = r.resolve(subv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveDictionary stores the resolution of each value in v in resolved.
+func (r *UIDResolver) resolveDictionary(resolved, v map[string]interface{}) error {
+	for k, subv := range v {
+		subresolved, err := r.resolve(subv)
+		if err != nil {
+			return err
+		}
+		resolved[k] = subresolved
+	}
+	return nil
+}
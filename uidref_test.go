@@ -0,0 +1,109 @@
+package plist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUIDResolver(t *testing.T) {
+	objects := []interface{}{
+		"$null",
+		map[string]interface{}{"name": UID(2), "friend": UID(3)},
+		"Alice",
+		map[string]interface{}{"name": "Bob", "friend": UID(1)},
+	}
+	r, err := NewUIDResolver(objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := r.Lookup(2); err != nil || v != "Alice" {
+		t.Errorf("Expected Lookup(2) to return Alice, got %#v (%v)", v, err)
+	}
+	if _, err := r.Lookup(4); err == nil {
+		t.Error("Expected an error looking up an out-of-range UID")
+	}
+
+	resolved, err := r.Resolve(UID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := resolved.(map[string]interface{})
+	bob, ok := alice["friend"].(map[string]interface{})
+	if !ok || alice["name"] != "Alice" || bob["name"] != "Bob" {
+		t.Fatalf("Unexpected resolution %v", alice)
+	}
+	// The cycle resolves to the object it started from.
+	if friend, ok := bob["friend"].(map[string]interface{}); !ok || reflect.ValueOf(friend).Pointer() != reflect.ValueOf(alice).Pointer() {
+		t.Errorf("Expected Bob's friend to be Alice")
+	}
+
+	// Later calls share the objects already resolved.
+	resolved, err = r.Resolve(UID(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.ValueOf(resolved).Pointer() != reflect.ValueOf(bob).Pointer() {
+		t.Errorf("Expected Resolve(3) to return Bob as resolved from Alice")
+	}
+
+	if _, err := r.Resolve([]interface{}{UID(9)}); err == nil {
+		t.Error("Expected an error resolving an out-of-range UID")
+	}
+}
+
+func TestUIDResolverDictionaryTable(t *testing.T) {
+	r, err := NewUIDResolver(map[string]interface{}{"7": "seven", "8": []interface{}{UID(7), UID(7)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := r.Resolve(UID(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, []interface{}{"seven", "seven"}) {
+		t.Errorf("Unexpected resolution %#v", v)
+	}
+
+	if _, err := NewUIDResolver("not a table"); err == nil {
+		t.Error("Expected an error creating a resolver for a string")
+	}
+}
+
+func TestUIDResolverSharedObjects(t *testing.T) {
+	// Each object refers to the next twice and to the first once, so there are 2^n paths
+	// through the table, and a cycle along every one of them.
+	const n = 64
+	objects := make([]interface{}, n+1)
+	for i := 0; i < n; i++ {
+		objects[i] = []interface{}{UID(i + 1), UID(i + 1), UID(0)}
+	}
+	objects[n] = "end"
+	r, err := NewUIDResolver(objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := r.Resolve(UID(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := v.([]interface{})
+	for i := 0; i < n; i++ {
+		if reflect.ValueOf(v.([]interface{})[2]).Pointer() != reflect.ValueOf(first).Pointer() {
+			t.Fatalf("Expected object %d to refer back to the first", i)
+		}
+		v = v.([]interface{})[0]
+	}
+	if v != "end" {
+		t.Errorf("Expected the last object to be resolved, got %#v", v)
+	}
+
+	loop, err := NewUIDResolver([]interface{}{UID(1), UID(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := loop.Resolve(UID(0)); err != nil || v != UID(0) {
+		t.Errorf("Expected a loop of references to be left as a UID, got %#v (%v)", v, err)
+	}
+}
@@ -2,9 +2,13 @@ package plist
 
 import (
 	"encoding"
+	"encoding/base64"
 	"fmt"
+	"math"
 	"reflect"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -29,14 +33,17 @@ func isEmptyInterface(v reflect.Value) bool {
 
 func (p *Decoder) unmarshalPlistInterface(pval cfValue, unmarshalable Unmarshaler) {
 	err := unmarshalable.UnmarshalPlist(func(i interface{}) (err error) {
-		defer func() {
+		defer func(path []string) {
 			if r := recover(); r != nil {
 				if _, ok := r.(runtime.Error); ok {
 					panic(r)
 				}
-				err = r.(error)
+				// Record where the error happened, and return to where decoding resumes
+				// if the Unmarshaler recovers from it.
+				err = withPath(r.(error), p.path)
+				p.path = path
 			}
-		}()
+		}(p.path)
 		p.unmarshal(pval, reflect.ValueOf(i))
 		return
 	})
@@ -53,6 +60,49 @@ func (p *Decoder) unmarshalTextInterface(pval cfString, unmarshalable encoding.T
 	}
 }
 
+func (p *Decoder) unmarshalWithTypeDecoder(pval cfValue, val reflect.Value, fn TypeDecoderFunc) {
+	v, err := fn(p.valueInterface(pval))
+	if err != nil {
+		panic(err)
+	}
+
+	rval := reflect.ValueOf(v)
+	if !rval.IsValid() {
+		// The type decoder chose not to produce a value; leave the destination untouched.
+		return
+	}
+
+	typ := val.Type()
+	if !rval.Type().AssignableTo(typ) {
+		if !rval.Type().ConvertibleTo(typ) {
+			panic(fmt.Errorf("plist: type decoder for `%v' returned value of incompatible type `%v'", typ, rval.Type()))
+		}
+		rval = rval.Convert(typ)
+	}
+	val.Set(rval)
+}
+
+func (p *Decoder) unmarshalIntegralReal(f float64, val reflect.Value) {
+	if math.IsNaN(f) || math.IsInf(f, 0) || math.Trunc(f) != f {
+		panic(fmt.Errorf("plist: cannot decode non-integral real %v into value of type `%v'", f, val.Type()))
+	}
+
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f < math.MinInt64 || f >= math.MaxInt64 || val.OverflowInt(int64(f)) {
+			panic(fmt.Errorf("plist: real %v overflows value of type `%v'", f, val.Type()))
+		}
+		val.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if f < 0 || f >= math.MaxUint64 || val.OverflowUint(uint64(f)) {
+			panic(fmt.Errorf("plist: real %v overflows value of type `%v'", f, val.Type()))
+		}
+		val.SetUint(uint64(f))
+	default:
+		panic(&incompatibleDecodeTypeError{val.Type(), "real"})
+	}
+}
+
 func (p *Decoder) unmarshalTime(pval cfDate, val reflect.Value) {
 	val.Set(reflect.ValueOf(time.Time(pval)))
 }
@@ -79,7 +129,15 @@ func (p *Decoder) unmarshalLaxString(s string, val reflect.Value) {
 		if val.Type() == timeType {
 			t, err := time.Parse(textPlistTimeLayout, s)
 			if err != nil {
-				panic(err)
+				if iso, isoErr := time.Parse(time.RFC3339, s); isoErr == nil {
+					t, err = iso, nil
+				}
+			}
+			if err != nil {
+				if !p.hasDateEpoch {
+					panic(err)
+				}
+				t = timeFromEpochSeconds(p.dateEpoch, mustParseFloat(s, 64))
 			}
 			val.Set(reflect.ValueOf(t.In(time.UTC)))
 			return
@@ -90,24 +148,74 @@ func (p *Decoder) unmarshalLaxString(s string, val reflect.Value) {
 	}
 }
 
+// unmarshalAt unmarshals pval, which is stored under key in the value currently being unmarshaled.
+func (p *Decoder) unmarshalAt(key string, pval cfValue, val reflect.Value) {
+	p.path = append(p.path, key)
+	p.unmarshalHooked(pval, val)
+	p.path = p.path[:len(p.path)-1]
+}
+
+// unmarshalHooked unmarshals pval into val, calling the decoder's hooks around it.
+func (p *Decoder) unmarshalHooked(pval cfValue, val reflect.Value) {
+	p.progress.object()
+	if p.preHook != nil {
+		var err error
+		val, err = p.preHook(p.path, val)
+		if err != nil {
+			panic(err)
+		}
+		if !val.IsValid() {
+			return
+		}
+	}
+
+	p.unmarshal(pval, val)
+
+	if p.postHook != nil {
+		if err := p.postHook(p.path, val); err != nil {
+			panic(err)
+		}
+	}
+}
+
 func (p *Decoder) unmarshal(pval cfValue, val reflect.Value) {
 	if pval == nil {
 		return
 	}
 
-	for val.Kind() == reflect.Ptr {
+	for {
+		if len(p.typeDecoders) > 0 && val.IsValid() {
+			if fn, ok := p.typeDecoders[val.Type()]; ok {
+				p.unmarshalWithTypeDecoder(pval, val, fn)
+				return
+			}
+		}
+
+		if val.Kind() != reflect.Ptr {
+			break
+		}
 		if val.IsNil() {
 			val.Set(reflect.New(val.Type().Elem()))
 		}
 		val = val.Elem()
 	}
 
+	if data, ok := pval.(cfData); ok && p.expandNested && !isEmptyInterface(val) && !isByteContainer(val.Type()) && val.Type() != nestedPlistType {
+		if nested := p.parseNested(data); nested != nil {
+			pval = nested
+		}
+	}
+
 	if isEmptyInterface(val) {
 		v := p.valueInterface(pval)
 		val.Set(reflect.ValueOf(v))
 		return
 	}
 
+	if p.unmarshalCommonType(pval, val) {
+		return
+	}
+
 	incompatibleTypeError := &incompatibleDecodeTypeError{val.Type(), pval.typeName()}
 
 	if receiver, can := implementsInterface(val, plistUnmarshalerType); can {
@@ -115,6 +223,12 @@ func (p *Decoder) unmarshal(pval cfValue, val reflect.Value) {
 		return
 	}
 
+	if receiver, can := implementsInterface(val, scannerType); can {
+		if p.unmarshalScanner(pval, receiver.(scanner)) {
+			return
+		}
+	}
+
 	// time.Time implements TextMarshaler, but we need to parse it as RFC3339
 	if date, ok := pval.(cfDate); ok {
 		if val.Type() == timeType {
@@ -124,6 +238,14 @@ func (p *Decoder) unmarshal(pval cfValue, val reflect.Value) {
 		panic(incompatibleTypeError)
 	}
 
+	if val.Type() == bigFloatType && p.unmarshalBigFloat(pval, val) {
+		return
+	}
+
+	if val.Type() == durationType && p.unmarshalDuration(pval, val) {
+		return
+	}
+
 	if val.Type() != timeType {
 		if receiver, can := implementsInterface(val, textUnmarshalerType); can {
 			if str, ok := pval.(cfString); ok {
@@ -137,13 +259,32 @@ func (p *Decoder) unmarshal(pval cfValue, val reflect.Value) {
 
 	typ := val.Type()
 
+	if typ == timeType && p.hasDateEpoch {
+		switch pval := pval.(type) {
+		case *cfNumber:
+			secs := float64(pval.value)
+			if pval.signed {
+				secs = float64(int64(pval.value))
+			}
+			val.Set(reflect.ValueOf(timeFromEpochSeconds(p.dateEpoch, secs)))
+			return
+		case *cfReal:
+			val.Set(reflect.ValueOf(timeFromEpochSeconds(p.dateEpoch, pval.value)))
+			return
+		}
+	}
+
 	switch pval := pval.(type) {
 	case cfString:
 		if val.Kind() == reflect.String {
 			val.SetString(string(pval))
 			return
 		}
-		if p.lax {
+		if val.Type() == runesType {
+			val.Set(reflect.ValueOf(Runes(string(pval))))
+			return
+		}
+		if p.lax && !p.strict {
 			p.unmarshalLaxString(string(pval), val)
 			return
 		}
@@ -162,6 +303,8 @@ func (p *Decoder) unmarshal(pval cfValue, val reflect.Value) {
 		if val.Kind() == reflect.Float32 || val.Kind() == reflect.Float64 {
 			// TODO: Consider warning on a downcast (storing a 64-bit value in a 32-bit reflect)
 			val.SetFloat(pval.value)
+		} else if p.realsToIntegers {
+			p.unmarshalIntegralReal(pval.value, val)
 		} else {
 			panic(incompatibleTypeError)
 		}
@@ -194,6 +337,8 @@ func (p *Decoder) unmarshal(pval cfValue, val reflect.Value) {
 	case cfUID:
 		if val.Type() == uidType {
 			val.SetUint(uint64(pval))
+		} else if p.strict {
+			panic(incompatibleTypeError)
 		} else {
 			switch val.Kind() {
 			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -211,6 +356,86 @@ func (p *Decoder) unmarshal(pval cfValue, val reflect.Value) {
 	}
 }
 
+// unmarshalCommonType unmarshals into the most frequently-encountered collection types without
+// reflection. It returns false (having made no changes to val) if val is not one of those types, or
+// if pval contains a value that requires the full treatment.
+func (p *Decoder) unmarshalCommonType(pval cfValue, val reflect.Value) bool {
+	if p.preHook != nil || p.postHook != nil || p.progress != nil || len(p.typeDecoders) > 0 || !val.CanInterface() {
+		return false
+	}
+
+	switch val.Type() {
+	case stringSliceType:
+		a, ok := pval.(*cfArray)
+		if !ok {
+			return false
+		}
+		for _, v := range a.values {
+			if _, ok := v.(cfString); !ok {
+				return false
+			}
+		}
+		s := val.Interface().([]string)
+		if s == nil {
+			s = make([]string, 0, len(a.values))
+		}
+		for _, v := range a.values {
+			s = append(s, string(v.(cfString)))
+		}
+		val.Set(reflect.ValueOf(s))
+	case intSliceType:
+		a, ok := pval.(*cfArray)
+		if !ok {
+			return false
+		}
+		for _, v := range a.values {
+			if _, ok := v.(*cfNumber); !ok {
+				return false
+			}
+		}
+		s := val.Interface().([]int)
+		if s == nil {
+			s = make([]int, 0, len(a.values))
+		}
+		for _, v := range a.values {
+			s = append(s, int(v.(*cfNumber).value))
+		}
+		val.Set(reflect.ValueOf(s))
+	case stringMapType:
+		dict, ok := pval.(*cfDictionary)
+		if !ok {
+			return false
+		}
+		for _, v := range dict.values {
+			if _, ok := v.(cfString); !ok {
+				return false
+			}
+		}
+		if val.IsNil() {
+			val.Set(reflect.MakeMap(stringMapType))
+		}
+		m := val.Interface().(map[string]string)
+		for i, k := range dict.keys {
+			m[k] = string(dict.values[i].(cfString))
+		}
+	case anyMapType:
+		dict, ok := pval.(*cfDictionary)
+		if !ok {
+			return false
+		}
+		if val.IsNil() {
+			val.Set(reflect.MakeMap(anyMapType))
+		}
+		m := val.Interface().(map[string]interface{})
+		for i, k := range dict.keys {
+			m[k] = p.valueInterface(dict.values[i])
+		}
+	default:
+		return false
+	}
+	return true
+}
+
 func (p *Decoder) unmarshalArray(a *cfArray, val reflect.Value) {
 	var n int
 	if val.Kind() == reflect.Slice {
@@ -237,8 +462,8 @@ func (p *Decoder) unmarshalArray(a *cfArray, val reflect.Value) {
 	}
 
 	// Recur to read element into slice.
-	for _, sval := range a.values {
-		p.unmarshal(sval, val.Index(n))
+	for i, sval := range a.values {
+		p.unmarshalAt(strconv.Itoa(i), sval, val.Index(n))
 		n++
 	}
 	return
@@ -246,6 +471,11 @@ func (p *Decoder) unmarshalArray(a *cfArray, val reflect.Value) {
 
 func (p *Decoder) unmarshalDictionary(dict *cfDictionary, val reflect.Value) {
 	typ := val.Type()
+	if typ == orderedDictContentsType {
+		val.Set(reflect.ValueOf(orderedDictContents(*p.orderedDictInterface(dict))))
+		return
+	}
+
 	switch val.Kind() {
 	case reflect.Struct:
 		tinfo, err := getTypeInfo(typ)
@@ -253,15 +483,21 @@ func (p *Decoder) unmarshalDictionary(dict *cfDictionary, val reflect.Value) {
 			panic(err)
 		}
 
-		entries := make(map[string]cfValue, len(dict.keys))
-		for i, k := range dict.keys {
-			sval := dict.values[i]
-			entries[k] = sval
+		var set []bool
+		if p.reportUnset {
+			set = make([]bool, len(tinfo.fields))
+		}
+
+		rest := p.unmarshalStructFields(dict, val, tinfo, set)
+		if p.reportUnused {
+			for _, k := range rest.keys {
+				p.unused = append(p.unused, strings.Join(append(p.path, k), "/"))
+			}
 		}
 
-		for _, finfo := range tinfo.fields {
-			if ent, ok := entries[finfo.name]; ok {
-				p.unmarshal(ent, finfo.valueForWriting(val))
+		for fi := range set {
+			if !set[fi] {
+				p.unset = append(p.unset, strings.Join(append(p.path, tinfo.fields[fi].name), "/"))
 			}
 		}
 	case reflect.Map:
@@ -275,7 +511,7 @@ func (p *Decoder) unmarshalDictionary(dict *cfDictionary, val reflect.Value) {
 			keyv := reflect.ValueOf(k).Convert(typ.Key())
 			mapElem := reflect.New(typ.Elem()).Elem()
 
-			p.unmarshal(sval, mapElem)
+			p.unmarshalAt(k, sval, mapElem)
 			val.SetMapIndex(keyv, mapElem)
 		}
 	default:
@@ -283,6 +519,63 @@ func (p *Decoder) unmarshalDictionary(dict *cfDictionary, val reflect.Value) {
 	}
 }
 
+// unmarshalStructFields stores the entries of dict in the fields of the struct val that they
+// name, including those promoted from structs held in embedded interfaces. If set is not nil,
+// each field stored is marked in it, as is each embedded interface any of whose promoted fields
+// were stored. It returns the entries that match none of the fields.
+func (p *Decoder) unmarshalStructFields(dict *cfDictionary, val reflect.Value, tinfo *typeInfo, set []bool) *cfDictionary {
+	rest := &cfDictionary{}
+	for i, k := range dict.keys {
+		if fi, ok := tinfo.fieldIndex[k]; ok {
+			finfo := &tinfo.fields[fi]
+			fieldDuration := p.fieldDuration
+			p.fieldDuration = finfo.duration
+			p.unmarshalAt(finfo.name, dict.values[i], finfo.valueForWriting(val))
+			p.fieldDuration = fieldDuration
+			if set != nil {
+				set[fi] = true
+			}
+		} else {
+			rest.keys = append(rest.keys, k)
+			rest.values = append(rest.values, dict.values[i])
+		}
+	}
+
+	for fi := range tinfo.fields {
+		if len(rest.keys) > 0 && tinfo.fields[fi].embeddedInterface {
+			remaining := p.unmarshalEmbeddedInterface(rest, tinfo.fields[fi].valueForWriting(val))
+			if set != nil && len(remaining.keys) < len(rest.keys) {
+				// The embedded field is set if any of the fields promoted from it were.
+				set[fi] = true
+			}
+			rest = remaining
+		}
+	}
+	return rest
+}
+
+// unmarshalEmbeddedInterface stores the entries of dict in the fields of the struct held by the
+// embedded interface val, if it holds one, and returns the entries that match none of them. A
+// struct held by value is replaced by an updated copy, as the one in the interface cannot be set.
+func (p *Decoder) unmarshalEmbeddedInterface(dict *cfDictionary, val reflect.Value) *cfDictionary {
+	target := embeddedStruct(val)
+	if !target.IsValid() {
+		return dict
+	}
+	if !target.CanSet() {
+		copied := reflect.New(target.Type()).Elem()
+		copied.Set(target)
+		defer val.Set(copied)
+		target = copied
+	}
+
+	tinfo, err := getTypeInfo(target.Type())
+	if err != nil {
+		panic(err)
+	}
+	return p.unmarshalStructFields(dict, target, tinfo, nil)
+}
+
 /* *Interface is modelled after encoding/json */
 func (p *Decoder) valueInterface(pval cfValue) interface{} {
 	switch pval := pval.(type) {
@@ -304,11 +597,22 @@ func (p *Decoder) valueInterface(pval cfValue) interface{} {
 	case *cfArray:
 		return p.arrayInterface(pval)
 	case *cfDictionary:
+		if p.orderedDicts {
+			return p.orderedDictInterface(pval)
+		}
 		return p.dictionaryInterface(pval)
 	case cfData:
+		if p.expandNested {
+			if nested := p.parseNested(pval); nested != nil {
+				return NestedPlist{Value: p.valueInterface(nested)}
+			}
+		}
+		if p.base64Data {
+			return base64.StdEncoding.EncodeToString(pval)
+		}
 		return []byte(pval)
 	case cfDate:
-		return time.Time(pval)
+		return p.dateRep.represent(time.Time(pval))
 	case cfUID:
 		return UID(pval)
 	}
@@ -318,6 +622,7 @@ func (p *Decoder) valueInterface(pval cfValue) interface{} {
 func (p *Decoder) arrayInterface(a *cfArray) []interface{} {
 	out := make([]interface{}, len(a.values))
 	for i, subv := range a.values {
+		p.progress.object()
 		out[i] = p.valueInterface(subv)
 	}
 	return out
@@ -327,6 +632,7 @@ func (p *Decoder) dictionaryInterface(dict *cfDictionary) map[string]interface{}
 	out := make(map[string]interface{})
 	for i, k := range dict.keys {
 		subv := dict.values[i]
+		p.progress.object()
 		out[k] = p.valueInterface(subv)
 	}
 	return out
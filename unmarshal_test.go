@@ -1,7 +1,10 @@
 package plist
 
 import (
+	"bytes"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -23,6 +26,24 @@ func BenchmarkStructUnmarshal(b *testing.B) {
 	}
 }
 
+func BenchmarkSparseStructUnmarshal(b *testing.B) {
+	type Data struct {
+		First string `plist:"key0"`
+		Last  string `plist:"key31"`
+	}
+	dict := &cfDictionary{}
+	for i := 0; i < 32; i++ {
+		dict.keys = append(dict.keys, fmt.Sprintf("key%d", i))
+		dict.values = append(dict.values, cfString("value"))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var xval Data
+		d := &Decoder{}
+		d.unmarshal(dict, reflect.ValueOf(&xval))
+	}
+}
+
 func BenchmarkInterfaceUnmarshal(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		var xval interface{}
@@ -57,3 +78,207 @@ func TestCustomDateUnmarshal(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestCommonTypeUnmarshal(t *testing.T) {
+	doc := []byte(`{Strings=(a,b);Ints=(<*I1>,<*I-2>);Empty=();StringMap={a=b;};AnyMap={a=<*I1>;b=(c);};}`)
+
+	type Data struct {
+		Strings   []string
+		Ints      []int
+		Empty     []string
+		StringMap map[string]string
+		AnyMap    map[string]interface{}
+	}
+
+	d := Data{Strings: []string{"z"}}
+	if _, err := Unmarshal(doc, &d); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Data{
+		Strings:   []string{"z", "a", "b"},
+		Ints:      []int{1, -2},
+		Empty:     []string{},
+		StringMap: map[string]string{"a": "b"},
+		AnyMap:    map[string]interface{}{"a": uint64(1), "b": []interface{}{"c"}},
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Logf("Expected: %#v", expected)
+		t.Logf("Received: %#v", d)
+		t.Fail()
+	}
+
+	// OpenStep documents only contain strings, which must be parsed.
+	var lax []int
+	if _, err := Unmarshal([]byte(`(3,4)`), &lax); err != nil || !reflect.DeepEqual(lax, []int{3, 4}) {
+		t.Errorf("unexpected lax decode %v (%v)", lax, err)
+	}
+}
+
+func TestUnmarshalErrorPath(t *testing.T) {
+	type content struct {
+		ExpiryDate time.Time
+	}
+	type payload struct {
+		Content content
+	}
+	var v struct {
+		Payloads []payload
+		Name     string
+	}
+
+	doc := []byte(`{ Payloads = ({}, {}, { Content = { ExpiryDate = <*I1>; }; }); }`)
+	_, err := Unmarshal(doc, &v)
+	perr, ok := err.(*PathError)
+	if !ok {
+		t.Fatalf("Expected a *PathError, received %#v", err)
+	}
+	if expected := []string{"Payloads", "2", "Content", "ExpiryDate"}; !reflect.DeepEqual(perr.Path, expected) {
+		t.Errorf("Expected path %v, received %v", expected, perr.Path)
+	}
+	if _, ok := perr.Err.(*incompatibleDecodeTypeError); !ok {
+		t.Errorf("Expected a type mismatch, received %#v", perr.Err)
+	}
+	if !strings.HasSuffix(err.Error(), " (at Payloads/2/Content/ExpiryDate)") {
+		t.Errorf("Unexpected error message %q", err.Error())
+	}
+
+	// The path is not left behind for the next document.
+	d := NewDecoder(bytes.NewReader([]byte(`{ Name = <*I1>; }`)))
+	if err := d.Decode(&v); err == nil || !strings.HasSuffix(err.Error(), " (at Name)") {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if err := d.Decode(&v); err == nil || !strings.HasSuffix(err.Error(), " (at Name)") {
+		t.Errorf("Unexpected error %v decoding a second time", err)
+	}
+}
+
+type EmbeddedInterfaceDetails interface{}
+
+type embeddedInterfaceWiFi struct {
+	SSID     string
+	Password string `plist:",omitempty"`
+}
+
+type embeddedInterfacePayload struct {
+	PayloadType string
+	EmbeddedInterfaceDetails
+}
+
+// EmbeddedInterfaceKind is an embedded interface with methods, as used for composition.
+type EmbeddedInterfaceKind interface {
+	Kind() string
+}
+
+type embeddedInterfaceVPN struct {
+	Server string
+}
+
+func (embeddedInterfaceVPN) Kind() string { return "vpn" }
+
+type embeddedInterfaceKindPayload struct {
+	PayloadType string
+	EmbeddedInterfaceKind
+}
+
+func TestEmbeddedInterface(t *testing.T) {
+	in := embeddedInterfacePayload{"com.apple.wifi.managed", &embeddedInterfaceWiFi{SSID: "Office"}}
+	doc, err := Marshal(in, OpenStepFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{PayloadType="com.apple.wifi.managed";SSID=Office;}`; string(doc) != expected {
+		t.Errorf("Expected %s, received %s", expected, doc)
+	}
+
+	// A pointer is filled in place.
+	wifi := &embeddedInterfaceWiFi{}
+	out := embeddedInterfacePayload{EmbeddedInterfaceDetails: wifi}
+	if _, err := Unmarshal([]byte(`{ PayloadType = x; SSID = Office; Password = hunter2; }`), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.PayloadType != "x" || *wifi != (embeddedInterfaceWiFi{"Office", "hunter2"}) {
+		t.Errorf("Unexpected result %+v, %+v", out, wifi)
+	}
+
+	// A struct held by value is replaced.
+	out = embeddedInterfacePayload{EmbeddedInterfaceDetails: embeddedInterfaceWiFi{Password: "old"}}
+	if _, err := Unmarshal([]byte(`{ SSID = Office; }`), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.EmbeddedInterfaceDetails != (embeddedInterfaceWiFi{"Office", "old"}) {
+		t.Errorf("Unexpected result %#v", out.EmbeddedInterfaceDetails)
+	}
+
+	// The outer struct's fields shadow the promoted ones.
+	type shadowed struct {
+		SSID string
+	}
+	doc, err = Marshal(struct {
+		SSID string
+		EmbeddedInterfaceDetails
+	}{"Outer", shadowed{"Inner"}}, OpenStepFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{SSID=Outer;}`; string(doc) != expected {
+		t.Errorf("Expected %s, received %s", expected, doc)
+	}
+
+	// A nil interface is omitted, and its keys are left unused.
+	doc, err = Marshal(embeddedInterfacePayload{PayloadType: "x"}, OpenStepFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{PayloadType=x;}`; string(doc) != expected {
+		t.Errorf("Expected %s, received %s", expected, doc)
+	}
+	d := NewDecoder(bytes.NewReader([]byte(`{ PayloadType = x; SSID = Office; }`)))
+	d.SetReportUnusedKeys(true)
+	out = embeddedInterfacePayload{}
+	if err := d.Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if unused := d.UnusedKeys(); !reflect.DeepEqual(unused, []string{"SSID"}) {
+		t.Errorf("Expected SSID to be unused, received %v", unused)
+	}
+
+	// An embedded interface counts as set if any of its promoted fields are.
+	for doc, expected := range map[string][]string{
+		`{ PayloadType = x; SSID = Office; }`: nil,
+		`{ PayloadType = x; }`:                {"EmbeddedInterfaceDetails"},
+	} {
+		d := NewDecoder(strings.NewReader(doc), WithUnsetFieldReporting())
+		out = embeddedInterfacePayload{EmbeddedInterfaceDetails: &embeddedInterfaceWiFi{}}
+		if err := d.Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		if unset := d.UnsetFields(); !reflect.DeepEqual(unset, expected) {
+			t.Errorf("%s: expected unset fields %v, received %v", doc, expected, unset)
+		}
+	}
+
+	// Interfaces with methods work in both directions, whether the struct is held by pointer or
+	// by value.
+	for _, kind := range []EmbeddedInterfaceKind{&embeddedInterfaceVPN{"vpn.example.com"}, embeddedInterfaceVPN{"vpn.example.com"}} {
+		doc, err := Marshal(embeddedInterfaceKindPayload{"com.apple.vpn.managed", kind}, OpenStepFormat)
+		if err != nil {
+			t.Fatalf("%T: %v", kind, err)
+		}
+		if expected := `{PayloadType="com.apple.vpn.managed";Server="vpn.example.com";}`; string(doc) != expected {
+			t.Errorf("%T: Expected %s, received %s", kind, expected, doc)
+		}
+
+		var empty EmbeddedInterfaceKind = &embeddedInterfaceVPN{}
+		if _, ok := kind.(embeddedInterfaceVPN); ok {
+			empty = embeddedInterfaceVPN{}
+		}
+		out := embeddedInterfaceKindPayload{EmbeddedInterfaceKind: empty}
+		if _, err := Unmarshal(doc, &out); err != nil {
+			t.Fatalf("%T: %v", kind, err)
+		}
+		if !reflect.DeepEqual(out.EmbeddedInterfaceKind, kind) {
+			t.Errorf("%T: Expected %#v, received %#v", kind, kind, out.EmbeddedInterfaceKind)
+		}
+	}
+}
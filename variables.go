@@ -0,0 +1,75 @@
+package plist
+
+// A VariableLookupFunc returns the value of the variable name, and whether it is defined.
+// os.LookupEnv is one.
+type VariableLookupFunc func(name string) (string, bool)
+
+// MapVariables returns a VariableLookupFunc that looks variables up in vars.
+func MapVariables(vars map[string]string) VariableLookupFunc {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func isVariableNameByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// expandString replaces each ${NAME} or $(NAME) in s with the value lookup returns for NAME.
+// References to undefined variables, and anything else following a $, are left as they are.
+func expandString(s string, lookup VariableLookupFunc) string {
+	var out []byte
+	last := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) || (s[i+1] != '{' && s[i+1] != '(') {
+			continue
+		}
+		closing := byte('}')
+		if s[i+1] == '(' {
+			closing = ')'
+		}
+		end := i + 2
+		for end < len(s) && isVariableNameByte(s[end]) {
+			end++
+		}
+		if end == i+2 || end == len(s) || s[end] != closing {
+			continue
+		}
+		value, ok := lookup(s[i+2 : end])
+		if !ok {
+			continue
+		}
+		out = append(out, s[last:i]...)
+		out = append(out, value...)
+		last = end + 1
+		i = end
+	}
+	if out == nil {
+		return s
+	}
+	return string(append(out, s[last:]...))
+}
+
+// expandStrings returns pval with every string value (but not dictionary key) passed through
+// expand. Dictionaries and arrays are copied rather than changed in place, as a binary property
+// list may refer to one object from several places.
+func expandStrings(pval cfValue, expand func(string) string) cfValue {
+	switch pval := pval.(type) {
+	case cfString:
+		return cfString(expand(string(pval)))
+	case *cfArray:
+		values := make([]cfValue, len(pval.values))
+		for i, subval := range pval.values {
+			values[i] = expandStrings(subval, expand)
+		}
+		return &cfArray{values}
+	case *cfDictionary:
+		dict := &cfDictionary{keys: pval.keys, values: make([]cfValue, len(pval.values)), ordered: pval.ordered}
+		for i, subval := range pval.values {
+			dict.values[i] = expandStrings(subval, expand)
+		}
+		return dict
+	}
+	return pval
+}
@@ -0,0 +1,97 @@
+package plist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+)
+
+// transcodeXMLInput converts a UTF-16 XML document, which encoding/xml cannot read, into UTF-8.
+// UTF-16 documents are recognized by their byte order mark, or by the "<?" that must begin a
+// document without one. The byte order mark of a UTF-8 document is removed, so that the XML
+// declaration begins the document as it must. Other documents are returned unchanged.
+func transcodeXMLInput(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(4)
+
+	var order binary.ByteOrder
+	bom := 0
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		br.Discard(3)
+		return br
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		order, bom = binary.BigEndian, 2
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		order, bom = binary.LittleEndian, 2
+	case bytes.Equal(head, []byte{0, '<', 0, '?'}):
+		order = binary.BigEndian
+	case bytes.Equal(head, []byte{'<', 0, '?', 0}):
+		order = binary.LittleEndian
+	default:
+		return br
+	}
+
+	buffer, err := ioutil.ReadAll(br)
+	if err != nil {
+		return br
+	}
+	s, err := convertU16(buffer[bom:], order)
+	if err != nil {
+		// Leave the document for encoding/xml to reject.
+		return bytes.NewReader(buffer)
+	}
+	return strings.NewReader(s)
+}
+
+// xmlCharsetReader is the CharsetReader for XML property lists. It is consulted when a document's
+// XML declaration names an encoding other than UTF-8.
+func xmlCharsetReader(label string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(label) {
+	case "utf-16", "utf-16be", "utf-16le", "utf16", "ucs-2", "unicode":
+		// transcodeXMLInput has already converted the document to UTF-8.
+		return input, nil
+	case "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "iso8859-1", "iso_8859-1", "latin1", "latin-1", "l1":
+		return &eightBitReader{r: input}, nil
+	case "macintosh", "macroman", "mac", "x-mac-roman":
+		return &eightBitReader{r: input, table: macRomanHigh}, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %q", label)
+}
+
+// eightBitReader converts a stream in an 8-bit encoding to UTF-8. table maps the bytes 0x80 to
+// 0xFF onto Unicode; if it is nil, they map onto U+0080 to U+00FF, as in ISO 8859-1.
+type eightBitReader struct {
+	r       io.Reader
+	table   []rune
+	pending []byte
+}
+
+func (e *eightBitReader) Read(p []byte) (int, error) {
+	if len(e.pending) == 0 {
+		// Each byte becomes at most three bytes of UTF-8.
+		buf := make([]byte, len(p)/utf8.UTFMax+1)
+		n, err := e.r.Read(buf)
+		for _, b := range buf[:n] {
+			r := rune(b)
+			if b >= 0x80 && e.table != nil {
+				r = e.table[b-0x80]
+			}
+			e.pending = append(e.pending, string(r)...)
+		}
+		if len(e.pending) == 0 {
+			return 0, err
+		}
+	}
+
+	n := copy(p, e.pending)
+	e.pending = e.pending[n:]
+	return n, nil
+}
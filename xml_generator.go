@@ -2,17 +2,20 @@ package plist
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/base64"
 	"encoding/xml"
 	"io"
 	"math"
 	"strconv"
+	"strings"
 	"time"
 )
 
 const (
 	xmlHEADER     string = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
 	xmlDOCTYPE           = `<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n"
+	xmlDOCTYPE09         = `<!DOCTYPE plist SYSTEM "file://localhost/System/Library/DTDs/PropertyList.dtd">` + "\n"
 	xmlArrayTag          = "array"
 	xmlDataTag           = "data"
 	xmlDateTag           = "date"
@@ -44,13 +47,43 @@ type xmlPlistGenerator struct {
 	indent     string
 	depth      int
 	putNewline bool
+
+	version  string // "1.0" if empty
+	fragment bool   // write only the root element, without a prolog or <plist> element
+	inline   inlineLimits
+	xcode    bool // write documents exactly as CoreFoundation does
 }
 
 func (p *xmlPlistGenerator) generateDocument(root cfValue) {
+	if p.fragment {
+		p.writePlistValue(root)
+		p.Flush()
+		return
+	}
+
+	version := p.version
+	if version == "" {
+		version = "1.0"
+	}
+
 	p.WriteString(xmlHEADER)
-	p.WriteString(xmlDOCTYPE)
+	if version == "0.9" {
+		p.WriteString(xmlDOCTYPE09)
+	} else {
+		p.WriteString(xmlDOCTYPE)
+	}
+
+	if p.xcode {
+		// CoreFoundation does not indent the root element.
+		p.indent = "\t"
+		p.WriteString(`<plist version="1.0">` + "\n")
+		p.writePlistValue(root)
+		p.WriteString("\n</plist>\n")
+		p.Flush()
+		return
+	}
 
-	p.openTag(`plist version="1.0"`)
+	p.openTag(`plist version="` + version + `"`)
 	p.writePlistValue(root)
 	p.closeTag(xmlPlistTag)
 	p.Flush()
@@ -72,7 +105,7 @@ func (p *xmlPlistGenerator) closeTag(n string) {
 
 func (p *xmlPlistGenerator) element(n string, v string) {
 	p.writeIndent(0)
-	if len(v) == 0 {
+	if len(v) == 0 && !(p.xcode && (n == xmlStringTag || n == xmlKeyTag)) {
 		p.WriteByte('<')
 		p.WriteString(n)
 		p.WriteString("/>")
@@ -81,9 +114,13 @@ func (p *xmlPlistGenerator) element(n string, v string) {
 		p.WriteString(n)
 		p.WriteByte('>')
 
-		err := xml.EscapeText(p.Writer, []byte(v))
-		if err != nil {
-			panic(err)
+		if p.xcode {
+			xcodeEscaper.WriteString(p.Writer, v)
+		} else {
+			err := xml.EscapeText(p.Writer, []byte(v))
+			if err != nil {
+				panic(err)
+			}
 		}
 
 		p.WriteString("</")
@@ -94,6 +131,13 @@ func (p *xmlPlistGenerator) element(n string, v string) {
 
 func (p *xmlPlistGenerator) writeDictionary(dict *cfDictionary) {
 	dict.sort()
+	if len(dict.keys) == 0 {
+		p.element(xmlDictTag, "")
+		return
+	}
+	if p.writeInline(dict, dict.values) {
+		return
+	}
 	p.openTag(xmlDictTag)
 	for i, k := range dict.keys {
 		p.element(xmlKeyTag, k)
@@ -103,6 +147,13 @@ func (p *xmlPlistGenerator) writeDictionary(dict *cfDictionary) {
 }
 
 func (p *xmlPlistGenerator) writeArray(a *cfArray) {
+	if len(a.values) == 0 {
+		p.element(xmlArrayTag, "")
+		return
+	}
+	if p.writeInline(a, a.values) {
+		return
+	}
 	p.openTag(xmlArrayTag)
 	for _, v := range a.values {
 		p.writePlistValue(v)
@@ -110,6 +161,26 @@ func (p *xmlPlistGenerator) writeArray(a *cfArray) {
 	p.closeTag(xmlArrayTag)
 }
 
+// writeInline writes the dictionary or array pval, whose members are values, on a single line if
+// the generator's inline limits allow it, and reports whether it did.
+func (p *xmlPlistGenerator) writeInline(pval cfValue, values []cfValue) bool {
+	if p.indent == "" || !p.inline.allows(values) {
+		return false
+	}
+
+	var buf bytes.Buffer
+	q := newXMLPlistGenerator(&buf)
+	q.writePlistValue(pval)
+	q.Flush()
+
+	if !p.inline.fits(buf.Bytes()) {
+		return false
+	}
+	p.writeIndent(0)
+	p.Write(buf.Bytes())
+	return true
+}
+
 func (p *xmlPlistGenerator) writePlistValue(pval cfValue) {
 	if pval == nil {
 		return
@@ -133,6 +204,10 @@ func (p *xmlPlistGenerator) writePlistValue(pval cfValue) {
 			p.element(xmlFalseTag, "")
 		}
 	case cfData:
+		if p.xcode {
+			p.writeXcodeData(pval)
+			return
+		}
 		p.element(xmlDataTag, base64.StdEncoding.EncodeToString([]byte(pval)))
 	case cfDate:
 		p.element(xmlDateTag, time.Time(pval).In(time.UTC).Format(time.RFC3339))
@@ -145,6 +220,30 @@ func (p *xmlPlistGenerator) writePlistValue(pval cfValue) {
 	}
 }
 
+// xcodeEscaper escapes text as CoreFoundation does, leaving quotes and whitespace alone.
+var xcodeEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// xcodeDataLineLength is the number of base64 characters CoreFoundation writes to a line.
+const xcodeDataLineLength = 76
+
+// writeXcodeData writes data as CoreFoundation does, with its base64 encoding broken into lines
+// that are indented to the same level as the <data> element.
+func (p *xmlPlistGenerator) writeXcodeData(data cfData) {
+	p.writeIndent(0)
+	p.WriteString("<" + xmlDataTag + ">")
+	indent := strings.Repeat(p.indent, p.depth)
+	encoded := base64.StdEncoding.EncodeToString([]byte(data))
+	for len(encoded) > 0 {
+		n := xcodeDataLineLength
+		if n > len(encoded) {
+			n = len(encoded)
+		}
+		p.WriteString("\n" + indent + encoded[:n])
+		encoded = encoded[n:]
+	}
+	p.WriteString("\n" + indent + "</" + xmlDataTag + ">")
+}
+
 func (p *xmlPlistGenerator) writeIndent(delta int) {
 	if len(p.indent) == 0 {
 		return
@@ -1,6 +1,7 @@
 package plist
 
 import (
+	"bufio"
 	"encoding/base64"
 	"encoding/xml"
 	"errors"
@@ -13,9 +14,151 @@ import (
 
 type xmlPlistParser struct {
 	reader             io.Reader
+	source             *excerptReader // the input to xmlDecoder, for quoting in errors
 	xmlDecoder         *xml.Decoder
 	whitespaceReplacer *strings.Replacer
 	ntags              int
+
+	trimStrings   bool // normalize whitespace in <string>s, unless an xml:space attribute forbids it
+	preserveSpace bool // set while inside an element bearing xml:space="preserve"
+
+	requireVersion bool // the root element must be a <plist> of a supported version
+	keepUIDDicts   bool // don't turn CF$UID dictionaries into UIDs
+
+	emptyDocument bool // set when the document has no root element
+
+	recovering   bool     // keep the values read before an error, rather than failing (see RecoverXML)
+	recoverErr   error    // the error that ended a recovering parse
+	unterminated []string // the containers left open by recoverErr, innermost first
+
+	events *eventEmitter // if non-nil, elements are delivered here rather than returned
+}
+
+// xmlPlistVersions contains every <plist> version attribute that the parser understands.
+var xmlPlistVersions = map[string]bool{
+	"0.9": true, // produced by pre-release versions of Mac OS X
+	"1.0": true,
+}
+
+// checkXMLPlistVersion ensures that root is a <plist> element carrying a supported version.
+func checkXMLPlistVersion(root xml.StartElement) {
+	if root.Name.Local != "plist" {
+		panic(fmt.Errorf("expected root element <plist>, found <%s>", root.Name.Local))
+	}
+	for _, attr := range root.Attr {
+		if attr.Name.Space == "" && attr.Name.Local == "version" {
+			if !xmlPlistVersions[attr.Value] {
+				panic(fmt.Errorf("unsupported plist version %q", attr.Value))
+			}
+			return
+		}
+	}
+	panic(errors.New("<plist> element has no version attribute"))
+}
+
+// XMLLimits bounds the amount of work the Decoder will do to parse an XML property list.
+// A limit of zero means that there is no limit.
+//
+// encoding/xml never expands entities declared in a document's DTD; the only entities it
+// recognizes are the five predefined by XML and numeric character references, each of which
+// expands to a single character. Entity expansion is therefore already bounded by the size of
+// the document, and inputs such as "billion laughs" are rejected rather than expanded.
+type XMLLimits struct {
+	// MaxTokens is the maximum number of XML tokens (elements, character data, comments, and
+	// so on) that the Decoder will read.
+	MaxTokens int64
+
+	// MaxCharData is the maximum total number of bytes of character data the Decoder will read.
+	MaxCharData int64
+}
+
+// limitedTokenReader enforces a set of XMLLimits on the tokens read from an xml.Decoder.
+type limitedTokenReader struct {
+	*xml.Decoder
+	limits   XMLLimits
+	tokens   int64
+	charData int64
+}
+
+func (l *limitedTokenReader) Token() (xml.Token, error) {
+	token, err := l.Decoder.Token()
+	if err != nil {
+		return token, err
+	}
+
+	l.tokens++
+	if l.limits.MaxTokens > 0 && l.tokens > l.limits.MaxTokens {
+		// Raise this error out-of-band: a failure from Token() would otherwise be mistaken
+		// for the input not being XML at all.
+		panic(&limitExceededError{"XML tokens", l.limits.MaxTokens})
+	}
+
+	if charData, ok := token.(xml.CharData); ok {
+		l.charData += int64(len(charData))
+		if l.limits.MaxCharData > 0 && l.charData > l.limits.MaxCharData {
+			panic(&limitExceededError{"bytes of XML character data", l.limits.MaxCharData})
+		}
+	}
+	return token, err
+}
+
+// setLimits arranges for every token the parser reads to be checked against limits.
+func (p *xmlPlistParser) setLimits(limits XMLLimits) {
+	if limits.MaxTokens == 0 && limits.MaxCharData == 0 {
+		return
+	}
+	p.xmlDecoder = xml.NewTokenDecoder(&limitedTokenReader{Decoder: p.xmlDecoder, limits: limits})
+}
+
+// xmlSpaceValue returns the value of element's xml:space attribute, if it has one.
+func xmlSpaceValue(element xml.StartElement) (string, bool) {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == "space" && (attr.Name.Space == "xml" || attr.Name.Space == "http://www.w3.org/XML/1998/namespace") {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// trimIndentation removes leading and trailing whitespace from s, along with the indentation at
+// the beginning of each line (as is introduced when a multi-line string is pretty-printed.)
+func trimIndentation(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimLeft(lines[i], " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// xmlDeclarationError is raised for XML documents that attempt to declare their own entities.
+type xmlDeclarationError struct {
+	directive string
+}
+
+func (e xmlDeclarationError) Error() string {
+	return fmt.Sprintf("refusing to process document type or entity declaration %q", e.directive)
+}
+
+// checkXMLDirective rejects any directive that could declare entities: standalone
+// <!ENTITY> declarations and <!DOCTYPE> declarations with an internal subset.
+// A plain <!DOCTYPE> naming an external DTD is permitted; the DTD is never fetched.
+func checkXMLDirective(directive xml.Directive) {
+	s := strings.TrimSpace(string(directive))
+	keyword := s
+	if i := strings.IndexAny(s, " \t\r\n"); i >= 0 {
+		keyword = s[:i]
+	}
+
+	switch keyword {
+	case "DOCTYPE":
+		if !strings.ContainsRune(s, '[') {
+			return
+		}
+	case "ENTITY", "ELEMENT", "ATTLIST", "NOTATION":
+	default:
+		return
+	}
+	panic(xmlDeclarationError{keyword})
 }
 
 func (p *xmlPlistParser) parseDocument() (pval cfValue, parseError error) {
@@ -27,20 +170,43 @@ func (p *xmlPlistParser) parseDocument() (pval cfValue, parseError error) {
 			if _, ok := r.(invalidPlistError); ok {
 				parseError = r.(error)
 			} else {
-				// Wrap all non-invalid-plist errors.
-				parseError = plistParseError{"XML", r.(error)}
+				// Wrap all non-invalid-plist errors, quoting the document where they are
+				// problems with its content.
+				err := r.(error)
+				switch err.(type) {
+				case eventHandlerError, *limitExceededError:
+				default:
+					err = p.source.wrap(err)
+				}
+				parseError = plistParseError{"XML", err}
 			}
 		}
 	}()
+	sawDeclaration := false
 	for {
 		if token, err := p.xmlDecoder.Token(); err == nil {
-			if element, ok := token.(xml.StartElement); ok {
-				pval = p.parseXMLElement(element)
+			if directive, ok := token.(xml.Directive); ok {
+				checkXMLDirective(directive)
+			} else if procInst, ok := token.(xml.ProcInst); ok && procInst.Target == "xml" {
+				sawDeclaration = true
+			} else if element, ok := token.(xml.StartElement); ok {
+				if p.requireVersion {
+					checkXMLPlistVersion(element)
+				}
+				if p.events != nil {
+					p.emitXMLElement(element)
+				} else {
+					pval = p.parseXMLElement(element)
+				}
 				if p.ntags == 0 {
 					panic(invalidPlistError{"XML", errors.New("no elements encountered")})
 				}
 				return
 			}
+		} else if err == io.EOF && sawDeclaration {
+			// An XML document with nothing but a prolog is an empty property list.
+			p.emptyDocument = true
+			return &cfDictionary{}, nil
 		} else {
 			// The first XML parse turned out to be invalid:
 			// we do not have an XML property list.
@@ -50,6 +216,12 @@ func (p *xmlPlistParser) parseDocument() (pval cfValue, parseError error) {
 }
 
 func (p *xmlPlistParser) parseXMLElement(element xml.StartElement) cfValue {
+	if space, ok := xmlSpaceValue(element); ok {
+		// xml:space is inherited by every element nested inside this one.
+		defer func(preserve bool) { p.preserveSpace = preserve }(p.preserveSpace)
+		p.preserveSpace = space == "preserve"
+	}
+
 	var charData xml.CharData
 	switch element.Name.Local {
 	case "plist":
@@ -57,7 +229,8 @@ func (p *xmlPlistParser) parseXMLElement(element xml.StartElement) cfValue {
 		for {
 			token, err := p.xmlDecoder.Token()
 			if err != nil {
-				panic(err)
+				p.truncated(err, element.Name.Local)
+				return nil
 			}
 
 			if el, ok := token.(xml.EndElement); ok && el.Name.Local == "plist" {
@@ -65,7 +238,7 @@ func (p *xmlPlistParser) parseXMLElement(element xml.StartElement) cfValue {
 			}
 
 			if el, ok := token.(xml.StartElement); ok {
-				return p.parseXMLElement(el)
+				return p.parseChildElement(el)
 			}
 		}
 		return nil
@@ -76,6 +249,9 @@ func (p *xmlPlistParser) parseXMLElement(element xml.StartElement) cfValue {
 			panic(err)
 		}
 
+		if p.trimStrings && !p.preserveSpace {
+			return cfString(trimIndentation(string(charData)))
+		}
 		return cfString(charData)
 	case "integer":
 		p.ntags++
@@ -151,7 +327,8 @@ func (p *xmlPlistParser) parseXMLElement(element xml.StartElement) cfValue {
 		for {
 			token, err := p.xmlDecoder.Token()
 			if err != nil {
-				panic(err)
+				p.truncated(err, element.Name.Local)
+				break
 			}
 
 			if el, ok := token.(xml.EndElement); ok && el.Name.Local == "dict" {
@@ -170,14 +347,24 @@ func (p *xmlPlistParser) parseXMLElement(element xml.StartElement) cfValue {
 					if key == nil {
 						panic(errors.New("missing key in dictionary"))
 					}
-					keys = append(keys, *key)
-					values = append(values, p.parseXMLElement(el))
-					key = nil
+					value := p.parseChildElement(el)
+					if value != nil || p.recoverErr == nil {
+						keys = append(keys, *key)
+						values = append(values, value)
+						key = nil
+					}
+					if p.recoverErr != nil {
+						p.unterminated = append(p.unterminated, element.Name.Local)
+						break
+					}
 				}
 			}
 		}
 
 		dict := &cfDictionary{keys: keys, values: values}
+		if p.keepUIDDicts {
+			return dict
+		}
 		return dict.maybeUID(false)
 	case "array":
 		p.ntags++
@@ -185,7 +372,8 @@ func (p *xmlPlistParser) parseXMLElement(element xml.StartElement) cfValue {
 		for {
 			token, err := p.xmlDecoder.Token()
 			if err != nil {
-				panic(err)
+				p.truncated(err, element.Name.Local)
+				break
 			}
 
 			if el, ok := token.(xml.EndElement); ok && el.Name.Local == "array" {
@@ -193,7 +381,16 @@ func (p *xmlPlistParser) parseXMLElement(element xml.StartElement) cfValue {
 			}
 
 			if el, ok := token.(xml.StartElement); ok {
-				values = append(values, p.parseXMLElement(el))
+				value := p.parseChildElement(el)
+				if p.recoverErr != nil {
+					if value != nil {
+						// A container that was cut short keeps what it has.
+						values = append(values, value)
+					}
+					p.unterminated = append(p.unterminated, element.Name.Local)
+					break
+				}
+				values = append(values, value)
 			}
 		}
 		return &cfArray{values}
@@ -207,5 +404,18 @@ func (p *xmlPlistParser) parseXMLElement(element xml.StartElement) cfValue {
 }
 
 func newXMLPlistParser(r io.Reader) *xmlPlistParser {
-	return &xmlPlistParser{r, xml.NewDecoder(r), strings.NewReplacer("\t", "", "\n", "", " ", "", "\r", ""), 0}
+	input := transcodeXMLInput(r)
+	br, ok := input.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(input)
+	}
+	source := newExcerptReader(br)
+	d := xml.NewDecoder(source)
+	d.CharsetReader = xmlCharsetReader
+	return &xmlPlistParser{
+		reader:             r,
+		source:             source,
+		xmlDecoder:         d,
+		whitespaceReplacer: strings.NewReplacer("\t", "", "\n", "", " ", "", "\r", ""),
+	}
 }
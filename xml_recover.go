@@ -0,0 +1,88 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"runtime"
+)
+
+// An XMLRecovery holds the values RecoverXML salvaged from a damaged XML property list.
+type XMLRecovery struct {
+	// Err is the error that ended the document early, or nil if it was complete.
+	Err error
+
+	// Unterminated lists the elements (such as "dict" and "array") that were still open when
+	// the document ended, innermost first. RecoverXML closed each of them.
+	Unterminated []string
+
+	root cfValue
+}
+
+// Unmarshal stores the recovered root value in v, as Unmarshal would. Values that were only
+// partially read when the document ended are absent from the recovered document.
+func (r *XMLRecovery) Unmarshal(v interface{}) error {
+	return unmarshalRecovered(r.root, XMLFormat, v)
+}
+
+// RecoverXML makes a best-effort attempt to read an XML property list that ends early, as
+// preference files written by a process that crashed often do, or that is damaged part way
+// through. Parsing stops at the first error; every container that is still open is closed, and
+// every value read before the error is kept. The error is reported in the result's Err field.
+//
+// RecoverXML returns an error only if data is not an XML property list or no root value was
+// read before the error.
+func RecoverXML(data []byte) (*XMLRecovery, error) {
+	p := newXMLPlistParser(bytes.NewReader(data))
+	p.recovering = true
+
+	pval, err := p.parseDocument()
+	if err != nil {
+		return nil, err
+	}
+	if pval == nil {
+		if p.recoverErr != nil {
+			return nil, plistParseError{"XML", p.recoverErr}
+		}
+		return nil, errors.New("plist: no root value could be recovered")
+	}
+
+	rec := &XMLRecovery{Unterminated: p.unterminated, root: pval}
+	if p.recoverErr != nil {
+		rec.Err = plistParseError{"XML", p.recoverErr}
+	}
+	return rec, nil
+}
+
+// truncated handles an error reading the tokens of the container element name. It is fatal
+// unless the parser is recovering, in which case the container is closed where it stands.
+func (p *xmlPlistParser) truncated(err error, name string) {
+	if !p.recovering {
+		panic(err)
+	}
+	if p.recoverErr == nil {
+		p.recoverErr = err
+	}
+	p.unterminated = append(p.unterminated, name)
+}
+
+// parseChildElement parses an element nested in a container. While recovering, any failure
+// ends the document: it is recorded, and nil is returned for the container to discard.
+func (p *xmlPlistParser) parseChildElement(element xml.StartElement) (pval cfValue) {
+	if !p.recovering {
+		return p.parseXMLElement(element)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			if p.recoverErr == nil {
+				p.recoverErr = r.(error)
+			}
+			pval = nil
+		}
+	}()
+	return p.parseXMLElement(element)
+}
@@ -2,8 +2,13 @@ package plist
 
 import (
 	"bytes"
+	"encoding/binary"
+	"encoding/xml"
 	"io/ioutil"
+	"reflect"
+	"strings"
 	"testing"
+	"unicode/utf16"
 )
 
 func BenchmarkXMLGenerate(b *testing.B) {
@@ -61,3 +66,442 @@ func TestVariousIllegalXMLPlists(t *testing.T) {
 		}
 	}
 }
+
+func TestXMLStringWhitespace(t *testing.T) {
+	doc := "<plist version=\"1.0\"><array>\n" +
+		"\t<string>\n\t\tfirst line\n\t\tsecond line\n\t</string>\n" +
+		"\t<string xml:space=\"preserve\">  kept  </string>\n" +
+		"\t<string> \ttabs&#xD;\n</string>\n" +
+		"</array></plist>"
+
+	var exact []string
+	if _, err := Unmarshal([]byte(doc), &exact); err != nil {
+		t.Fatal(err)
+	}
+	expectedExact := []string{"\n\t\tfirst line\n\t\tsecond line\n\t", "  kept  ", " \ttabs\r\n"}
+	if !reflect.DeepEqual(exact, expectedExact) {
+		t.Errorf("Expected %q, received %q", expectedExact, exact)
+	}
+
+	var trimmed []string
+	d := NewDecoder(bytes.NewReader([]byte(doc)))
+	d.SetXMLStringTrimming(true)
+	if err := d.Decode(&trimmed); err != nil {
+		t.Fatal(err)
+	}
+	expectedTrimmed := []string{"first line\nsecond line", "  kept  ", "tabs"}
+	if !reflect.DeepEqual(trimmed, expectedTrimmed) {
+		t.Errorf("Expected %q, received %q", expectedTrimmed, trimmed)
+	}
+
+	// Significant whitespace must survive a round trip.
+	s := " \t leading\r\n\ttrailing \n"
+	data, err := MarshalIndent(s, XMLFormat, "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded string
+	if _, err := Unmarshal(data, &decoded); err != nil || decoded != s {
+		t.Errorf("Expected %q, received %q (%v)", s, decoded, err)
+	}
+}
+
+func TestXMLLimits(t *testing.T) {
+	doc := []byte(`<plist version="1.0"><array><string>aaaa</string><string>bbbb</string><string>cccc</string></array></plist>`)
+
+	tests := []struct {
+		limits XMLLimits
+		valid  bool
+	}{
+		{XMLLimits{}, true},
+		{XMLLimits{MaxTokens: 100, MaxCharData: 100}, true},
+		{XMLLimits{MaxTokens: 5}, false},
+		{XMLLimits{MaxCharData: 10}, false},
+	}
+
+	for _, test := range tests {
+		var v []string
+		d := NewDecoder(bytes.NewReader(doc))
+		d.SetXMLLimits(test.limits)
+		err := d.Decode(&v)
+		if test.valid && (err != nil || len(v) != 3) {
+			t.Errorf("%+v: unexpected result %v (%v)", test.limits, v, err)
+		} else if !test.valid {
+			if err == nil {
+				t.Errorf("%+v: Expected error, received %v", test.limits, v)
+			} else if d.Format != InvalidFormat {
+				t.Errorf("%+v: limit error must not fall back to another format (got %s)", test.limits, FormatNames[d.Format])
+			}
+			t.Logf("Error: %v", err)
+		}
+	}
+}
+
+func TestXMLRefusesEntityDeclarations(t *testing.T) {
+	tests := []struct {
+		doc   string
+		valid bool
+	}{
+		{`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0"><string>hello</string></plist>`, true},
+		{`<!DOCTYPE plist SYSTEM "file:///etc/passwd"><plist version="1.0"><string>hello</string></plist>`, true},
+		{`<!DOCTYPE plist [<!ENTITY lol "lol">]><plist version="1.0"><string>&lol;</string></plist>`, false},
+		{`<!DOCTYPE plist [<!ENTITY xxe SYSTEM "file:///etc/passwd">]><plist version="1.0"><string>&xxe;</string></plist>`, false},
+		{`<!ENTITY lol "lol"><plist version="1.0"><string>hello</string></plist>`, false},
+	}
+
+	for _, test := range tests {
+		var s string
+		d := NewDecoder(strings.NewReader(test.doc))
+		err := d.Decode(&s)
+		if test.valid && (err != nil || s != "hello") {
+			t.Errorf("%s: unexpected result %q (%v)", test.doc, s, err)
+		} else if !test.valid {
+			if err == nil {
+				t.Errorf("%s: Expected error, received %q", test.doc, s)
+			} else if d.Format != InvalidFormat {
+				t.Errorf("%s: declaration error must not fall back to another format", test.doc)
+			}
+		}
+	}
+}
+
+func TestXMLVersionRequired(t *testing.T) {
+	tests := []struct {
+		doc   string
+		valid bool
+	}{
+		{`<plist version="1.0"><string>hello</string></plist>`, true},
+		{`<plist><string>hello</string></plist>`, false},
+		{`<plist version="2.0"><string>hello</string></plist>`, false},
+		{`<string>hello</string>`, false},
+	}
+
+	for _, test := range tests {
+		var s string
+		d := NewDecoder(strings.NewReader(test.doc))
+		if err := d.Decode(&s); err != nil || s != "hello" {
+			t.Errorf("%s: lenient decode failed: %q (%v)", test.doc, s, err)
+		}
+
+		s = ""
+		d = NewDecoder(strings.NewReader(test.doc))
+		d.SetXMLVersionRequired(true)
+		err := d.Decode(&s)
+		if test.valid && (err != nil || s != "hello") {
+			t.Errorf("%s: unexpected result %q (%v)", test.doc, s, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%s: Expected error, received %q", test.doc, s)
+		}
+	}
+}
+
+func TestXMLVersion09(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf)
+	enc.SetXMLVersion("0.9")
+	if err := enc.Encode([]string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<plist version="0.9">`) {
+		t.Errorf("Expected a version 0.9 document, received %s", buf.String())
+	}
+
+	var v []string
+	d := NewDecoder(bytes.NewReader(buf.Bytes()))
+	d.SetXMLVersionRequired(true)
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, []string{"a", "b"}) {
+		t.Errorf("Expected [a b], received %v", v)
+	}
+
+	enc = NewEncoder(&bytes.Buffer{})
+	enc.SetXMLVersion("3.0")
+	if err := enc.Encode("hello"); err == nil {
+		t.Error("Expected an error encoding an unsupported version")
+	}
+}
+
+func TestXMLFragment(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := NewEncoderForFormat(buf, XMLFormat)
+	enc.Indent("\t")
+	enc.SetFragment(true)
+	if err := enc.Encode(map[string]interface{}{"a": []int{1}}); err != nil {
+		t.Fatal(err)
+	}
+	expected := "<dict>\n\t<key>a</key>\n\t<array>\n\t\t<integer>1</integer>\n\t</array>\n</dict>"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, received %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	enc = NewEncoderForFormat(buf, XMLFormat)
+	enc.SetFragment(true)
+	if err := enc.Encode("a < b"); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "<string>a &lt; b</string>" {
+		t.Errorf("Expected a bare string element, received %q", buf.String())
+	}
+}
+
+func TestXMLFragmentDecoding(t *testing.T) {
+	in := map[string]interface{}{"a": []interface{}{uint64(1), "two"}, "b": true}
+	buf := &bytes.Buffer{}
+	enc := NewEncoderForFormat(buf, XMLFormat)
+	enc.SetFragment(true)
+	if err := enc.Encode(in); err != nil {
+		t.Fatal(err)
+	}
+
+	var out map[string]interface{}
+	format, err := Unmarshal(buf.Bytes(), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != XMLFormat || !reflect.DeepEqual(in, out) {
+		t.Errorf("Expected %v (XML), received %v (%s)", in, out, FormatNames[format])
+	}
+
+	for _, doc := range []string{"<true/>", " <integer>3</integer>\n", "<array/>"} {
+		var v interface{}
+		if format, err := Unmarshal([]byte(doc), &v); err != nil || format != XMLFormat {
+			t.Errorf("%q: Expected an XML fragment, received %s, %v", doc, FormatNames[format], err)
+		}
+	}
+
+	d := NewDecoder(bytes.NewReader(buf.Bytes()))
+	d.SetXMLVersionRequired(true)
+	if err := d.Decode(&out); err == nil {
+		t.Error("Expected a fragment to be rejected when a version is required")
+	}
+}
+
+func TestEncodeXMLElement(t *testing.T) {
+	type payload struct {
+		Name    string
+		Tracks  []int
+		Enabled bool
+		Data    []byte
+	}
+	in := payload{Name: "<Library & Co>", Tracks: []int{1, 2, 3}, Enabled: true, Data: []byte{1, 2}}
+
+	var buf bytes.Buffer
+	e := xml.NewEncoder(&buf)
+	start := xml.StartElement{Name: xml.Name{Local: "export"}}
+	e.EncodeToken(start)
+	if err := EncodeXMLElement(e, in); err != nil {
+		t.Fatal(err)
+	}
+	e.EncodeToken(start.End())
+	e.Flush()
+
+	doc := buf.String()
+	if !strings.HasPrefix(doc, "<export><dict><key>") || !strings.HasSuffix(doc, "</dict></export>") {
+		t.Fatalf("unexpected output %s", doc)
+	}
+
+	fragment := strings.TrimSuffix(strings.TrimPrefix(doc, "<export>"), "</export>")
+	var out payload
+	if _, err := Unmarshal([]byte("<plist>"+fragment+"</plist>"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip mismatch:\nin  %#v\nout %#v", in, out)
+	}
+
+	if err := EncodeXMLElement(xml.NewEncoder(&buf), make(chan int)); err == nil {
+		t.Error("expected an error encoding an unsupported type")
+	}
+}
+
+func TestRecoverXML(t *testing.T) {
+	complete := `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>Name</key>
+	<string>example</string>
+	<key>Recent</key>
+	<array>
+		<string>one</string>
+		<string>two</string>
+	</array>
+	<key>Window</key>
+	<dict>
+		<key>Width</key>
+		<integer>640</integer>
+	</dict>
+</dict>
+</plist>`
+
+	rec, err := RecoverXML([]byte(complete))
+	if err != nil || rec.Err != nil || len(rec.Unterminated) != 0 {
+		t.Fatalf("Complete document: %v %+v", err, rec)
+	}
+
+	tests := []struct {
+		cut          string // the document is truncated just before this text
+		expected     map[string]interface{}
+		unterminated []string
+	}{
+		{"<string>two", map[string]interface{}{"Name": "example", "Recent": []interface{}{"one"}}, []string{"array", "dict"}},
+		{"two</string>", map[string]interface{}{"Name": "example", "Recent": []interface{}{"one"}}, []string{"array", "dict"}},
+		{"<integer>640", map[string]interface{}{"Name": "example", "Recent": []interface{}{"one", "two"}, "Window": map[string]interface{}{}}, []string{"dict", "dict"}},
+		{"</dict>\n</plist>", map[string]interface{}{"Name": "example", "Recent": []interface{}{"one", "two"}, "Window": map[string]interface{}{"Width": uint64(640)}}, []string{"dict"}},
+	}
+
+	for _, test := range tests {
+		truncated := complete[:strings.LastIndex(complete, test.cut)]
+		if _, err := Unmarshal([]byte(truncated), new(interface{})); err == nil {
+			t.Errorf("%q: expected the truncated document to fail to decode", test.cut)
+		}
+
+		rec, err := RecoverXML([]byte(truncated))
+		if err != nil {
+			t.Errorf("%q: %v", test.cut, err)
+			continue
+		}
+		if rec.Err == nil {
+			t.Errorf("%q: expected the recovery to report an error", test.cut)
+		}
+
+		var v map[string]interface{}
+		if err := rec.Unmarshal(&v); err != nil {
+			t.Errorf("%q: %v", test.cut, err)
+		}
+		if !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.cut, test.expected, v)
+		}
+		if !reflect.DeepEqual(rec.Unterminated, test.unterminated) {
+			t.Errorf("%q: expected unterminated %v, got %v", test.cut, test.unterminated, rec.Unterminated)
+		}
+	}
+
+	if _, err := RecoverXML([]byte(`<plist version="1.0"><string>cut`)); err == nil {
+		t.Error("Expected an error when no root value can be recovered")
+	}
+}
+
+func TestXMLEncodingDeclarations(t *testing.T) {
+	encodeUTF16 := func(s string, order binary.ByteOrder, bom bool) []byte {
+		var b []byte
+		if bom {
+			b = append(b, 0, 0)
+			order.PutUint16(b, 0xFEFF)
+		}
+		for _, r := range utf16.Encode([]rune(s)) {
+			b = append(b, 0, 0)
+			order.PutUint16(b[len(b)-2:], r)
+		}
+		return b
+	}
+	body := `<plist version="1.0"><string>café ☕</string></plist>`
+	declared := func(enc string) string {
+		return `<?xml version="1.0" encoding="` + enc + `"?>` + body
+	}
+
+	docs := map[string][]byte{
+		"UTF-16BE with BOM":    encodeUTF16(declared("UTF-16"), binary.BigEndian, true),
+		"UTF-16LE with BOM":    encodeUTF16(declared("UTF-16"), binary.LittleEndian, true),
+		"UTF-16LE without BOM": encodeUTF16(declared("UTF-16"), binary.LittleEndian, false),
+	}
+	for name, doc := range docs {
+		var s string
+		if _, err := Unmarshal(doc, &s); err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if s != "café ☕" {
+			t.Errorf("%s: expected %q, got %q", name, "café ☕", s)
+		}
+	}
+
+	eightBit := map[string]string{
+		"ISO-8859-1": "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><plist version=\"1.0\"><string>caf\xE9</string></plist>",
+		"macintosh":  "<?xml version=\"1.0\" encoding=\"macintosh\"?><plist version=\"1.0\"><string>caf\x8E</string></plist>",
+	}
+	for name, doc := range eightBit {
+		var s string
+		if _, err := Unmarshal([]byte(doc), &s); err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if s != "café" {
+			t.Errorf("%s: expected %q, got %q", name, "café", s)
+		}
+	}
+}
+
+func TestXMLByteOrderMark(t *testing.T) {
+	docs := []string{
+		"\xEF\xBB\xBF<?xml version=\"1.0\" encoding=\"UTF-8\"?><plist version=\"1.0\"><string>x</string></plist>",
+		"\xEF\xBB\xBF<plist version=\"1.0\"><string>x</string></plist>",
+	}
+	for _, doc := range docs {
+		var s string
+		d := NewDecoder(strings.NewReader(doc), WithXMLVersionRequired())
+		if err := d.Decode(&s); err != nil {
+			t.Errorf("%q: %v", doc, err)
+		} else if d.Format != XMLFormat || s != "x" {
+			t.Errorf("%q: expected an XML property list holding x, got %s holding %q", doc, FormatNames[d.Format], s)
+		}
+	}
+
+	var v interface{}
+	d := NewDecoder(strings.NewReader("\xEF\xBB\xBF<?xml version=\"1.0\"?>"))
+	if err := d.Decode(&v); err != nil || d.Format != XMLFormat {
+		t.Errorf("Expected an empty XML property list, got %s (%v)", FormatNames[d.Format], err)
+	}
+
+	_, err := Unmarshal([]byte("\xEF\xBB\xBF<?xml version=\"1.0\"?><plist version=\"1.0\"><strin>x</strin></plist>"), &v)
+	if err == nil || !strings.Contains(err.Error(), "XML") {
+		t.Errorf("Expected an XML parse error, got %v", err)
+	}
+}
+
+func TestXcodeCompatibility(t *testing.T) {
+	val := map[string]interface{}{
+		"Item 10": `"Quotes" & <brackets>`,
+		"Item 2":  "",
+		"data":    bytes.Repeat([]byte{1, 2, 3}, 20),
+		"list":    []interface{}{true, map[string]string{}},
+	}
+	expected := xmlPreamble + `<plist version="1.0">
+<dict>
+	<key>data</key>
+	<data>
+	AQIDAQIDAQIDAQIDAQIDAQIDAQIDAQIDAQIDAQIDAQIDAQIDAQIDAQIDAQIDAQIDAQIDAQIDAQID
+	AQID
+	</data>
+	<key>Item 2</key>
+	<string></string>
+	<key>Item 10</key>
+	<string>"Quotes" &amp; &lt;brackets&gt;</string>
+	<key>list</key>
+	<array>
+		<true/>
+		<dict/>
+	</array>
+</dict>
+</plist>
+`
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.Indent("  ") // ignored
+	enc.SetXcodeCompatibility(true)
+	if err := enc.Encode(val); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nReceived:\n%s", expected, buf.String())
+	}
+
+	var decoded map[string]interface{}
+	if _, err := Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded["data"], val["data"]) || decoded["Item 10"] != val["Item 10"] {
+		t.Errorf("Round trip mismatch: %#v", decoded)
+	}
+}
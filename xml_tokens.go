@@ -0,0 +1,107 @@
+package plist
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// EncodeXMLElement writes the XML property list encoding of v to e as a sequence of tokens,
+// so that a property list value can be embedded in a larger document produced with
+// encoding/xml. Only the value's own element (such as <dict>…</dict>) is written: there is no
+// XML header, DOCTYPE or enclosing <plist> element. v is marshaled as by Marshal.
+//
+// Indentation is controlled by e. EncodeXMLElement flushes e before returning.
+func EncodeXMLElement(e *xml.Encoder, v interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+	}()
+
+	enc := &Encoder{format: XMLFormat}
+	pval := enc.marshal(reflect.ValueOf(v))
+	if pval == nil {
+		return errors.New("plist: no root element to encode")
+	}
+
+	t := xmlTokenWriter{e}
+	t.writePlistValue(pval)
+	return e.Flush()
+}
+
+// xmlTokenWriter writes property list values to an xml.Encoder; it produces the same
+// elements as xmlPlistGenerator.
+type xmlTokenWriter struct {
+	*xml.Encoder
+}
+
+func (t xmlTokenWriter) token(tok xml.Token) {
+	if err := t.EncodeToken(tok); err != nil {
+		panic(err)
+	}
+}
+
+func (t xmlTokenWriter) element(n string, v string) {
+	start := xml.StartElement{Name: xml.Name{Local: n}}
+	t.token(start)
+	if v != "" {
+		t.token(xml.CharData(v))
+	}
+	t.token(start.End())
+}
+
+func (t xmlTokenWriter) container(n string, body func()) {
+	start := xml.StartElement{Name: xml.Name{Local: n}}
+	t.token(start)
+	body()
+	t.token(start.End())
+}
+
+func (t xmlTokenWriter) writePlistValue(pval cfValue) {
+	switch pval := pval.(type) {
+	case cfString:
+		t.element(xmlStringTag, string(pval))
+	case *cfNumber:
+		if pval.signed {
+			t.element(xmlIntegerTag, strconv.FormatInt(int64(pval.value), 10))
+		} else {
+			t.element(xmlIntegerTag, strconv.FormatUint(pval.value, 10))
+		}
+	case *cfReal:
+		t.element(xmlRealTag, formatXMLFloat(pval.value))
+	case cfBoolean:
+		if bool(pval) {
+			t.element(xmlTrueTag, "")
+		} else {
+			t.element(xmlFalseTag, "")
+		}
+	case cfData:
+		t.element(xmlDataTag, base64.StdEncoding.EncodeToString([]byte(pval)))
+	case cfDate:
+		t.element(xmlDateTag, time.Time(pval).In(time.UTC).Format(time.RFC3339))
+	case *cfDictionary:
+		pval.sort()
+		t.container(xmlDictTag, func() {
+			for i, k := range pval.keys {
+				t.element(xmlKeyTag, k)
+				t.writePlistValue(pval.values[i])
+			}
+		})
+	case *cfArray:
+		t.container(xmlArrayTag, func() {
+			for _, v := range pval.values {
+				t.writePlistValue(v)
+			}
+		})
+	case cfUID:
+		t.writePlistValue(pval.toDict())
+	}
+}